@@ -0,0 +1,357 @@
+package api
+
+import (
+    "dockerpanel/backend/pkg/database"
+    "dockerpanel/backend/pkg/notify"
+    "errors"
+    "fmt"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+// 告警规则、通知渠道与历史记录，只对管理员开放
+func RegisterAlertRoutes(r *gin.Engine) {
+    group := r.Group("/api/alerts")
+    {
+        group.GET("/rules", listAlertRules)
+        group.POST("/rules", createAlertRule)
+        group.PUT("/rules/:id", updateAlertRule)
+        group.DELETE("/rules/:id", deleteAlertRule)
+        group.POST("/rules/:id/mute", muteAlertRule)
+
+        group.GET("/channels", listAlertChannels)
+        group.POST("/channels", createAlertChannel)
+        group.PUT("/channels/:id", updateAlertChannel)
+        group.DELETE("/channels/:id", deleteAlertChannel)
+        group.POST("/channels/:id/test", testAlertChannel)
+
+        group.GET("/events", listAlertEvents)
+        group.GET("/deliveries", listAlertDeliveries)
+    }
+}
+
+func idParam(c *gin.Context) (int64, bool) {
+    id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 ID: " + c.Param("id")})
+        return 0, false
+    }
+    return id, true
+}
+
+// 分页参数，默认每页 50 条
+func pageParams(c *gin.Context) (page, pageSize int) {
+    page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+    pageSize, _ = strconv.Atoi(c.DefaultQuery("pageSize", "50"))
+    if page < 1 {
+        page = 1
+    }
+    if pageSize < 1 || pageSize > 500 {
+        pageSize = 50
+    }
+    return page, pageSize
+}
+
+func listAlertRules(c *gin.Context) {
+    rules, err := database.ListAlertRules()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "获取告警规则失败: " + err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, rules)
+}
+
+// 检查规则的类型、阈值、主机和通知渠道
+func validateAlertRule(r *database.AlertRule) error {
+    r.Name = strings.TrimSpace(r.Name)
+    r.Target = strings.TrimSpace(r.Target)
+    if r.Name == "" {
+        return fmt.Errorf("规则名称不能为空")
+    }
+    switch r.Type {
+    case database.AlertContainerExited, database.AlertUnhealthy, database.AlertImageUpdate:
+    case database.AlertRestartLoop:
+        if r.Threshold < 2 || r.Duration < 1 {
+            return fmt.Errorf("重启循环需要设置至少 2 次退出和至少 1 分钟的时间窗口")
+        }
+    case database.AlertHostUnreachable:
+        if r.Duration < 0 || r.Duration > 24*60 {
+            return fmt.Errorf("持续时间应在 0 到 1440 分钟之间")
+        }
+    case database.AlertCPUHigh, database.AlertMemoryHigh, database.AlertDiskHigh:
+        if r.Threshold <= 0 {
+            return fmt.Errorf("阈值必须大于 0")
+        }
+        if r.Type != database.AlertCPUHigh && r.Threshold >= 100 {
+            return fmt.Errorf("使用率阈值应小于 100")
+        }
+        if r.Duration < 0 || r.Duration > 24*60 {
+            return fmt.Errorf("持续时间应在 0 到 1440 分钟之间")
+        }
+    default:
+        return fmt.Errorf("不支持的规则类型: %s", r.Type)
+    }
+
+    if r.HostID != database.AllHosts && r.HostID != 0 {
+        if _, err := database.GetHost(r.HostID); err != nil {
+            return err
+        }
+    }
+    for _, id := range r.Channels {
+        if _, err := database.GetAlertChannel(id); err != nil {
+            return fmt.Errorf("通知渠道 %d: %v", id, err)
+        }
+    }
+    for _, w := range r.Silences {
+        if err := w.Validate(); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func alertRuleError(c *gin.Context, message string, err error) {
+    status := http.StatusInternalServerError
+    switch {
+    case errors.Is(err, database.ErrAlertRuleNotFound):
+        status = http.StatusNotFound
+    case errors.Is(err, database.ErrAlertRuleExists):
+        status = http.StatusConflict
+    }
+    c.JSON(status, gin.H{"error": message + ": " + err.Error()})
+}
+
+func createAlertRule(c *gin.Context) {
+    var rule database.AlertRule
+    if err := c.ShouldBindJSON(&rule); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据: " + err.Error()})
+        return
+    }
+    if err := validateAlertRule(&rule); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    rule.MutedUntil = ""
+    if err := database.CreateAlertRule(&rule); err != nil {
+        alertRuleError(c, "添加告警规则失败", err)
+        return
+    }
+    alerts.reload()
+    c.JSON(http.StatusOK, rule)
+}
+
+func updateAlertRule(c *gin.Context) {
+    id, ok := idParam(c)
+    if !ok {
+        return
+    }
+    var rule database.AlertRule
+    if err := c.ShouldBindJSON(&rule); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据: " + err.Error()})
+        return
+    }
+    rule.ID = id
+    if err := validateAlertRule(&rule); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if err := database.UpdateAlertRule(&rule); err != nil {
+        alertRuleError(c, "修改告警规则失败", err)
+        return
+    }
+    alerts.reload()
+    c.JSON(http.StatusOK, gin.H{"message": "告警规则已保存"})
+}
+
+func deleteAlertRule(c *gin.Context) {
+    id, ok := idParam(c)
+    if !ok {
+        return
+    }
+    if err := database.DeleteAlertRule(id); err != nil {
+        alertRuleError(c, "删除告警规则失败", err)
+        return
+    }
+    alerts.reload()
+    c.JSON(http.StatusOK, gin.H{"message": "告警规则已删除"})
+}
+
+// 临时静默规则 minutes 分钟，0 表示取消静默。静默期间触发的告警只记录不通知
+func muteAlertRule(c *gin.Context) {
+    id, ok := idParam(c)
+    if !ok {
+        return
+    }
+    var req struct {
+        Minutes int `json:"minutes"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据: " + err.Error()})
+        return
+    }
+    if req.Minutes < 0 || req.Minutes > 30*24*60 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "静默时间应在 0 到 30 天之间"})
+        return
+    }
+    until := ""
+    if req.Minutes > 0 {
+        until = time.Now().Add(time.Duration(req.Minutes) * time.Minute).Format("2006-01-02 15:04:05")
+    }
+    if err := database.MuteAlertRule(id, until); err != nil {
+        alertRuleError(c, "设置静默失败", err)
+        return
+    }
+    alerts.reload()
+    c.JSON(http.StatusOK, gin.H{"muted_until": until})
+}
+
+func listAlertChannels(c *gin.Context) {
+    channels, err := database.ListAlertChannels()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "获取通知渠道失败: " + err.Error()})
+        return
+    }
+    result := make([]*database.AlertChannel, len(channels))
+    for i, ch := range channels {
+        result[i] = ch.Redacted()
+    }
+    c.JSON(http.StatusOK, result)
+}
+
+func alertChannelError(c *gin.Context, message string, err error) {
+    status := http.StatusInternalServerError
+    switch {
+    case errors.Is(err, database.ErrAlertChannelNotFound):
+        status = http.StatusNotFound
+    case errors.Is(err, database.ErrAlertChannelExists):
+        status = http.StatusConflict
+    }
+    c.JSON(status, gin.H{"error": message + ": " + err.Error()})
+}
+
+func bindAlertChannel(c *gin.Context) (*database.AlertChannel, bool) {
+    var ch database.AlertChannel
+    if err := c.ShouldBindJSON(&ch); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据: " + err.Error()})
+        return nil, false
+    }
+    ch.Name = strings.TrimSpace(ch.Name)
+    if ch.Name == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "渠道名称不能为空"})
+        return nil, false
+    }
+    for key, value := range ch.Config {
+        if key != "password" {
+            ch.Config[key] = strings.TrimSpace(value)
+        }
+    }
+    return &ch, true
+}
+
+func createAlertChannel(c *gin.Context) {
+    ch, ok := bindAlertChannel(c)
+    if !ok {
+        return
+    }
+    if err := notify.Validate(notify.Channel{Type: ch.Type, Config: ch.Config}); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if err := database.CreateAlertChannel(ch); err != nil {
+        alertChannelError(c, "添加通知渠道失败", err)
+        return
+    }
+    c.JSON(http.StatusOK, ch.Redacted())
+}
+
+func updateAlertChannel(c *gin.Context) {
+    id, ok := idParam(c)
+    if !ok {
+        return
+    }
+    ch, ok := bindAlertChannel(c)
+    if !ok {
+        return
+    }
+    ch.ID = id
+    // 必填项不包含密码和密钥，未修改时留空即可
+    if err := notify.Validate(notify.Channel{Type: ch.Type, Config: ch.Config}); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if err := database.UpdateAlertChannel(ch); err != nil {
+        alertChannelError(c, "修改通知渠道失败", err)
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"message": "通知渠道已保存"})
+}
+
+func deleteAlertChannel(c *gin.Context) {
+    id, ok := idParam(c)
+    if !ok {
+        return
+    }
+    if err := database.DeleteAlertChannel(id); err != nil {
+        alertChannelError(c, "删除通知渠道失败", err)
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"message": "通知渠道已删除"})
+}
+
+// 发送一条测试消息，结果同样记入发送记录
+func testAlertChannel(c *gin.Context) {
+    id, ok := idParam(c)
+    if !ok {
+        return
+    }
+    ch, err := database.GetAlertChannel(id)
+    if err != nil {
+        alertChannelError(c, "获取通知渠道失败", err)
+        return
+    }
+    msg := notify.Message{
+        Status: "test",
+        Title:  "[测试] Docker 面板告警通知",
+        Text:   fmt.Sprintf("这是一条来自 Docker 面板的测试消息，用于确认通知渠道 %s 配置正确", ch.Name),
+        Time:   time.Now().Format("2006-01-02 15:04:05"),
+    }
+    if err := deliverAlert(ch, 0, "test", msg); err != nil {
+        c.JSON(http.StatusBadGateway, gin.H{"error": "发送测试消息失败: " + err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"message": "测试消息已发送"})
+}
+
+// GET /api/alerts/events?rule_id=&status=firing|resolved&page=&pageSize=
+func listAlertEvents(c *gin.Context) {
+    page, pageSize := pageParams(c)
+    ruleID, _ := strconv.ParseInt(c.Query("rule_id"), 10, 64)
+    events, total, err := database.ListAlertEvents(database.AlertEventFilter{
+        RuleID: ruleID,
+        Status: c.Query("status"),
+        Limit:  pageSize,
+        Offset: (page - 1) * pageSize,
+    })
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "查询告警记录失败: " + err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"items": events, "total": total, "page": page, "pageSize": pageSize})
+}
+
+// GET /api/alerts/deliveries?event_id=&channel_id=&page=&pageSize=
+func listAlertDeliveries(c *gin.Context) {
+    page, pageSize := pageParams(c)
+    eventID, _ := strconv.ParseInt(c.Query("event_id"), 10, 64)
+    channelID, _ := strconv.ParseInt(c.Query("channel_id"), 10, 64)
+    deliveries, total, err := database.ListAlertDeliveries(eventID, channelID, pageSize, (page-1)*pageSize)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "查询发送记录失败: " + err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"items": deliveries, "total": total, "page": page, "pageSize": pageSize})
+}
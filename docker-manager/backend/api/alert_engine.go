@@ -0,0 +1,719 @@
+package api
+
+import (
+    "context"
+    "dockerpanel/backend/pkg/database"
+    "dockerpanel/backend/pkg/docker"
+    "dockerpanel/backend/pkg/notify"
+    "fmt"
+    "log"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/docker/docker/api/types"
+    "github.com/docker/docker/api/types/events"
+    "github.com/docker/docker/api/types/filters"
+)
+
+// 告警引擎：容器事件到达时即时判断退出、重启循环和健康状态；
+// 后台定期根据指标采集器的最近读数判断 CPU、内存和磁盘，并核对健康状态与镜像更新
+const (
+    alertEvalInterval   = 30 * time.Second
+    imageUpdateInterval = 6 * time.Hour
+    // 手动停止时 kill 事件与随后的 die 事件的最大间隔
+    manualStopWindow = time.Minute
+    // 异常退出的容器重新启动后需持续运行这么久才恢复告警，避免重启循环时反复触发和恢复
+    exitResolveDelay = 2 * time.Minute
+
+    defaultAlertRetentionDays = 90
+    // 通知发送失败时的重试次数
+    notifyAttempts = 3
+
+    localHostName = "本机"
+    hostSubject   = "host"
+)
+
+type alertKey struct {
+    ruleID  int64
+    hostID  int64
+    subject string
+}
+
+type alertEngine struct {
+    mu        sync.Mutex
+    rules     []*database.AlertRule // 已启用的规则
+    hosts     map[int64]*database.DockerHost
+    active    map[alertKey]*database.AlertEvent
+    pending   map[alertKey]time.Time // 阈值条件开始满足的时间
+    exits     map[string][]time.Time // 主机 ID/容器名称 -> 非手动退出的时间
+    stopping  map[string]time.Time   // 主机 ID/容器 ID -> 收到 kill 事件的时间
+    restarted map[string]time.Time   // 主机 ID/容器名称 -> 异常退出后重新启动的时间
+    lastCheck map[int64]time.Time    // 各主机上次检查镜像更新的时间
+}
+
+var alerts = &alertEngine{
+    hosts:     make(map[int64]*database.DockerHost),
+    active:    make(map[alertKey]*database.AlertEvent),
+    pending:   make(map[alertKey]time.Time),
+    exits:     make(map[string][]time.Time),
+    stopping:  make(map[string]time.Time),
+    restarted: make(map[string]time.Time),
+    lastCheck: make(map[int64]time.Time),
+}
+
+// StartAlertEngine 启动告警判断。上次运行遗留的未恢复告警无法继续跟踪，启动时统一关闭
+func StartAlertEngine() {
+    if err := database.ResolveStaleAlertEvents(); err != nil {
+        log.Printf("关闭遗留告警失败: %v", err)
+    }
+    alerts.reload()
+    go func() {
+        lastPrune := time.Time{}
+        for {
+            time.Sleep(alertEvalInterval)
+            alerts.evaluate()
+            if time.Since(lastPrune) >= 24*time.Hour {
+                pruneAlertHistory()
+                lastPrune = time.Now()
+            }
+        }
+    }()
+}
+
+// 重新读取规则和主机，规则修改后立即调用
+func (e *alertEngine) reload() {
+    rules, err := database.ListAlertRules()
+    if err != nil {
+        log.Printf("读取告警规则失败: %v", err)
+        return
+    }
+    hosts, err := database.ListHosts()
+    if err != nil {
+        log.Printf("读取主机列表失败: %v", err)
+        return
+    }
+
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    e.rules = nil
+    enabled := make(map[int64]*database.AlertRule)
+    for _, r := range rules {
+        if r.Enabled {
+            e.rules = append(e.rules, r)
+            enabled[r.ID] = r
+        }
+    }
+    e.hosts = make(map[int64]*database.DockerHost, len(hosts))
+    for _, h := range hosts {
+        e.hosts[h.ID] = h
+    }
+
+    // 规则被删除或停用后，未恢复的告警直接关闭，不再发送通知
+    for key := range e.active {
+        if _, ok := enabled[key.ruleID]; !ok {
+            e.resolve(key, nil)
+        }
+    }
+    for key := range e.pending {
+        if _, ok := enabled[key.ruleID]; !ok {
+            delete(e.pending, key)
+        }
+    }
+}
+
+func (e *alertEngine) hostName(hostID int64) string {
+    if h, ok := e.hosts[hostID]; ok {
+        return h.Name
+    }
+    return localHostName
+}
+
+func ruleAppliesToHost(r *database.AlertRule, hostID int64) bool {
+    return r.HostID == database.AllHosts || r.HostID == hostID
+}
+
+// 规则的 Target 为空时匹配所有容器，否则匹配容器名称或 compose 项目名称
+func ruleMatches(r *database.AlertRule, name, project string) bool {
+    return r.Target == "" || r.Target == name || (project != "" && r.Target == project)
+}
+
+// 调用方需持有 e.mu
+func (e *alertEngine) rulesOf(ruleType string, hostID int64) []*database.AlertRule {
+    var result []*database.AlertRule
+    for _, r := range e.rules {
+        if r.Type == ruleType && ruleAppliesToHost(r, hostID) {
+            result = append(result, r)
+        }
+    }
+    return result
+}
+
+// 触发告警，已触发的不重复记录。调用方需持有 e.mu
+func (e *alertEngine) fire(r *database.AlertRule, hostID int64, subject, message string) {
+    key := alertKey{r.ID, hostID, subject}
+    if _, ok := e.active[key]; ok {
+        return
+    }
+    now := time.Now()
+    event := &database.AlertEvent{
+        RuleID:    r.ID,
+        RuleName:  r.Name,
+        RuleType:  r.Type,
+        HostID:    hostID,
+        HostName:  e.hostName(hostID),
+        Subject:   subject,
+        Message:   message,
+        Silenced:  r.Silenced(now),
+        StartedAt: now.Format("2006-01-02 15:04:05"),
+    }
+    if err := database.InsertAlertEvent(event); err != nil {
+        log.Printf("记录告警失败: %v", err)
+        return
+    }
+    e.active[key] = event
+    if !event.Silenced {
+        go notifyAlert(r, *event)
+    }
+}
+
+// 恢复告警，r 为 nil 时只关闭记录不发送通知。调用方需持有 e.mu
+func (e *alertEngine) resolve(key alertKey, r *database.AlertRule) {
+    delete(e.pending, key)
+    event, ok := e.active[key]
+    if !ok {
+        return
+    }
+    delete(e.active, key)
+    event.Status = database.AlertResolved
+    event.ResolvedAt = time.Now().Format("2006-01-02 15:04:05")
+    if err := database.ResolveAlertEvent(event.ID, event.ResolvedAt); err != nil {
+        log.Printf("更新告警状态失败: %v", err)
+    }
+    if r != nil && !event.Silenced {
+        go notifyAlert(r, *event)
+    }
+}
+
+// 恢复某个容器在指定类型规则下的告警。调用方需持有 e.mu
+func (e *alertEngine) resolveSubject(ruleType string, hostID int64, subject string) {
+    for _, r := range e.rulesOf(ruleType, hostID) {
+        e.resolve(alertKey{r.ID, hostID, subject}, r)
+    }
+}
+
+// handleEvent 处理容器事件，由事件监听协程调用
+func (e *alertEngine) handleEvent(hostID int64, event events.Message) {
+    if event.Type != events.ContainerEventType {
+        return
+    }
+    attrs := event.Actor.Attributes
+    name := attrs["name"]
+    project := attrs["com.docker.compose.project"]
+    containerKey := fmt.Sprintf("%d/%s", hostID, event.Actor.ID)
+    now := time.Now()
+
+    e.mu.Lock()
+    defer e.mu.Unlock()
+
+    action := string(event.Action)
+    switch {
+    // 手动停止和重启时 kill 事件先于 die 到达
+    case action == "kill":
+        e.stopping[containerKey] = now
+
+    case action == "die":
+        stoppedAt, manual := e.stopping[containerKey]
+        delete(e.stopping, containerKey)
+        if manual && now.Sub(stoppedAt) < manualStopWindow {
+            return
+        }
+        exitCode := attrs["exitCode"]
+
+        exitKey := fmt.Sprintf("%d/%s", hostID, name)
+        delete(e.restarted, exitKey)
+        e.exits[exitKey] = append(e.exits[exitKey], now)
+        for _, r := range e.rulesOf(database.AlertRestartLoop, hostID) {
+            if !ruleMatches(r, name, project) {
+                continue
+            }
+            if count := e.exitCount(exitKey, r.Duration, now); float64(count) >= r.Threshold {
+                e.fire(r, hostID, name, fmt.Sprintf("容器 %s 在 %d 分钟内退出了 %d 次，可能处于重启循环，最近一次退出码 %s",
+                    name, r.Duration, count, exitCode))
+            }
+        }
+
+        if exitCode == "0" {
+            return
+        }
+        for _, r := range e.rulesOf(database.AlertContainerExited, hostID) {
+            if ruleMatches(r, name, project) {
+                e.fire(r, hostID, name, fmt.Sprintf("容器 %s 异常退出，退出码 %s", name, exitCode))
+            }
+        }
+
+    // 重新启动后不立即恢复，由 evaluate 在容器持续运行 exitResolveDelay 后恢复
+    case action == "start":
+        delete(e.stopping, containerKey)
+        e.restarted[fmt.Sprintf("%d/%s", hostID, name)] = now
+
+    case action == "destroy":
+        delete(e.restarted, fmt.Sprintf("%d/%s", hostID, name))
+        e.resolveSubject(database.AlertContainerExited, hostID, name)
+        e.resolveSubject(database.AlertUnhealthy, hostID, name)
+
+    case strings.HasPrefix(action, "health_status"):
+        status := strings.TrimSpace(strings.TrimPrefix(action, "health_status:"))
+        if status == "healthy" {
+            e.resolveSubject(database.AlertUnhealthy, hostID, name)
+            return
+        }
+        if status != "unhealthy" {
+            return
+        }
+        for _, r := range e.rulesOf(database.AlertUnhealthy, hostID) {
+            if ruleMatches(r, name, project) {
+                e.fire(r, hostID, name, fmt.Sprintf("容器 %s 健康检查失败", name))
+            }
+        }
+    }
+}
+
+// 最近 minutes 分钟内的退出次数。调用方需持有 e.mu
+func (e *alertEngine) exitCount(exitKey string, minutes int, now time.Time) int {
+    since := now.Add(-time.Duration(minutes) * time.Minute)
+    count := 0
+    for _, t := range e.exits[exitKey] {
+        if t.After(since) {
+            count++
+        }
+    }
+    return count
+}
+
+// 定期判断：阈值类规则、健康状态核对、重启循环恢复和镜像更新
+func (e *alertEngine) evaluate() {
+    e.reload()
+    now := time.Now()
+
+    e.mu.Lock()
+    var needMetrics bool
+    hostIDs := map[int64]bool{}
+    for _, r := range e.rules {
+        switch r.Type {
+        case database.AlertCPUHigh, database.AlertMemoryHigh, database.AlertDiskHigh, database.AlertHostUnreachable:
+            needMetrics = true
+        case database.AlertUnhealthy, database.AlertImageUpdate:
+            if r.HostID == database.AllHosts {
+                hostIDs[0] = true
+                for id := range e.hosts {
+                    hostIDs[id] = true
+                }
+            } else {
+                hostIDs[r.HostID] = true
+            }
+        }
+    }
+    e.mu.Unlock()
+
+    if needMetrics {
+        maxAge := 2 * alertEvalInterval
+        if interval, _ := metricsSettings(); interval > 0 && time.Duration(interval)*time.Second > alertEvalInterval {
+            maxAge = 2 * time.Duration(interval) * time.Second
+        }
+        e.evaluateThresholds(collector.snapshot(maxAge), now)
+    }
+
+    for hostID := range hostIDs {
+        e.evaluateHost(hostID, now)
+    }
+
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    e.notifyUnsilenced(now)
+    // 重新启动后一直没有再退出的容器，恢复异常退出告警
+    for exitKey, startedAt := range e.restarted {
+        if now.Sub(startedAt) < exitResolveDelay {
+            continue
+        }
+        delete(e.restarted, exitKey)
+        hostPart, name, _ := strings.Cut(exitKey, "/")
+        hostID, _ := strconv.ParseInt(hostPart, 10, 64)
+        e.resolveSubject(database.AlertContainerExited, hostID, name)
+    }
+    // 重启循环在退出次数回落到阈值以下时恢复
+    for key := range e.active {
+        r := e.ruleByID(key.ruleID)
+        if r == nil || r.Type != database.AlertRestartLoop {
+            continue
+        }
+        exitKey := fmt.Sprintf("%d/%s", key.hostID, key.subject)
+        if float64(e.exitCount(exitKey, r.Duration, now)) < r.Threshold {
+            e.resolve(key, r)
+        }
+    }
+    // 只保留一天内的退出记录和最近的停止记录
+    for exitKey, times := range e.exits {
+        kept := times[:0]
+        for _, t := range times {
+            if now.Sub(t) < 24*time.Hour {
+                kept = append(kept, t)
+            }
+        }
+        if len(kept) == 0 {
+            delete(e.exits, exitKey)
+        } else {
+            e.exits[exitKey] = kept
+        }
+    }
+    for containerKey, t := range e.stopping {
+        if now.Sub(t) > manualStopWindow {
+            delete(e.stopping, containerKey)
+        }
+    }
+}
+
+// 静默期间触发的告警在静默结束后仍未恢复时补发通知。调用方需持有 e.mu
+func (e *alertEngine) notifyUnsilenced(now time.Time) {
+    for key, event := range e.active {
+        if !event.Silenced {
+            continue
+        }
+        r := e.ruleByID(key.ruleID)
+        if r == nil || r.Silenced(now) {
+            continue
+        }
+        event.Silenced = false
+        if err := database.UnsilenceAlertEvent(event.ID); err != nil {
+            log.Printf("更新告警状态失败: %v", err)
+        }
+        go notifyAlert(r, *event)
+    }
+}
+
+// 调用方需持有 e.mu
+func (e *alertEngine) ruleByID(id int64) *database.AlertRule {
+    for _, r := range e.rules {
+        if r.ID == id {
+            return r
+        }
+    }
+    return nil
+}
+
+func subjectText(subject string) string {
+    if subject == hostSubject {
+        return "主机"
+    }
+    return "容器 " + subject
+}
+
+// 根据指标读数判断 CPU、内存和磁盘，条件持续满足 Duration 分钟后触发，回落后恢复。
+// 采集失败的主机没有读数，其告警保持原状态，并按 host_unreachable 规则告警
+func (e *alertEngine) evaluateThresholds(snapshot []*hostMetrics, now time.Time) {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+
+    seen := map[alertKey]bool{}
+    // 条件开始满足后已持续 Duration 分钟时返回 true
+    held := func(r *database.AlertRule, key alertKey) bool {
+        seen[key] = true
+        since, ok := e.pending[key]
+        if !ok {
+            since = now
+            e.pending[key] = now
+        }
+        return now.Sub(since) >= time.Duration(r.Duration)*time.Minute
+    }
+    check := func(r *database.AlertRule, hostID int64, subject string, value float64, metric string) {
+        key := alertKey{r.ID, hostID, subject}
+        if value <= r.Threshold {
+            seen[key] = true
+            e.resolve(key, r)
+            return
+        }
+        if held(r, key) {
+            e.fire(r, hostID, subject, fmt.Sprintf("%s的%s为 %.1f%%，已持续 %d 分钟高于 %.0f%%",
+                subjectText(subject), metric, value, r.Duration, r.Threshold))
+        }
+    }
+
+    sampled := make(map[int64]*hostMetrics, len(snapshot))
+    for _, h := range snapshot {
+        sampled[h.hostID] = h
+        for _, r := range e.rules {
+            if !ruleAppliesToHost(r, h.hostID) {
+                continue
+            }
+            switch r.Type {
+            case database.AlertHostUnreachable:
+                key := alertKey{r.ID, h.hostID, hostSubject}
+                if h.err == nil {
+                    seen[key] = true
+                    e.resolve(key, r)
+                } else if held(r, key) {
+                    e.fire(r, h.hostID, hostSubject, fmt.Sprintf("无法连接主机 %s: %v", h.hostName, h.err))
+                }
+            case database.AlertDiskHigh:
+                if h.host != nil && h.host.diskTotal > 0 {
+                    check(r, h.hostID, hostSubject, h.host.diskUsed/h.host.diskTotal*100, "磁盘使用率")
+                }
+            case database.AlertCPUHigh, database.AlertMemoryHigh:
+                metric := "CPU 使用率"
+                if r.Type == database.AlertMemoryHigh {
+                    metric = "内存使用率"
+                }
+                // Target 为 host 时判断主机本身
+                if r.Target == hostSubject {
+                    if h.host == nil {
+                        continue
+                    }
+                    value := h.host.cpu
+                    if r.Type == database.AlertMemoryHigh && h.host.memTotal > 0 {
+                        value = h.host.memUsed / h.host.memTotal * 100
+                    }
+                    check(r, h.hostID, hostSubject, value, metric)
+                    continue
+                }
+                for _, s := range h.containers {
+                    if !ruleMatches(r, s.name, s.project) {
+                        continue
+                    }
+                    value := s.cpu
+                    if r.Type == database.AlertMemoryHigh {
+                        if s.memLimit <= 0 {
+                            continue
+                        }
+                        value = s.memUsed / s.memLimit * 100
+                    }
+                    check(r, h.hostID, s.name, value, metric)
+                }
+            }
+        }
+    }
+
+    // 读数未知：主机不在本轮采集中（如读取主机列表失败）但仍存在，或对应指标采集失败
+    unknown := func(key alertKey) bool {
+        h, ok := sampled[key.hostID]
+        if !ok {
+            _, exists := e.hosts[key.hostID]
+            return key.hostID == 0 || exists
+        }
+        if key.subject == hostSubject {
+            return h.hostErr != nil
+        }
+        return h.err != nil
+    }
+
+    // 容器停止或删除后不再有读数，相关告警随之恢复
+    for key := range e.active {
+        r := e.ruleByID(key.ruleID)
+        if r != nil && isThresholdRule(r.Type) && !seen[key] && !unknown(key) {
+            e.resolve(key, r)
+        }
+    }
+    for key := range e.pending {
+        if !seen[key] && !unknown(key) {
+            delete(e.pending, key)
+        }
+    }
+}
+
+func isThresholdRule(ruleType string) bool {
+    return ruleType == database.AlertCPUHigh || ruleType == database.AlertMemoryHigh || ruleType == database.AlertDiskHigh ||
+        ruleType == database.AlertHostUnreachable
+}
+
+// 核对主机上的健康状态，并按间隔检查镜像更新。访问 Docker 时不持有锁
+func (e *alertEngine) evaluateHost(hostID int64, now time.Time) {
+    e.mu.Lock()
+    unhealthyRules := e.rulesOf(database.AlertUnhealthy, hostID)
+    updateRules := e.rulesOf(database.AlertImageUpdate, hostID)
+    checkUpdates := len(updateRules) > 0 && now.Sub(e.lastCheck[hostID]) >= imageUpdateInterval
+    var host *docker.Host
+    if h, ok := e.hosts[hostID]; ok {
+        host = toDockerHost(h)
+    } else if hostID != 0 {
+        e.mu.Unlock()
+        return
+    }
+    if checkUpdates {
+        e.lastCheck[hostID] = now
+    }
+    e.mu.Unlock()
+
+    cli, err := docker.SharedClient(host)
+    if err != nil {
+        log.Printf("告警检查连接主机 %d 失败: %v", hostID, err)
+        return
+    }
+
+    if len(unhealthyRules) > 0 {
+        ctx, cancel := context.WithTimeout(context.Background(), alertEvalInterval)
+        containers, err := cli.ContainerList(ctx, types.ContainerListOptions{
+            Filters: filters.NewArgs(filters.Arg("health", "unhealthy")),
+        })
+        cancel()
+        if err != nil {
+            log.Printf("告警检查获取容器列表失败: %v", err)
+        } else {
+            e.reconcileUnhealthy(hostID, unhealthyRules, containers)
+        }
+    }
+
+    if checkUpdates {
+        e.checkImageUpdates(cli, hostID, updateRules)
+    }
+}
+
+// 事件监听启动前已处于 unhealthy 的容器在这里触发，已恢复但错过事件的在这里恢复
+func (e *alertEngine) reconcileUnhealthy(hostID int64, rules []*database.AlertRule, containers []types.Container) {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    for _, r := range rules {
+        current := map[string]bool{}
+        for _, container := range containers {
+            if len(container.Names) == 0 {
+                continue
+            }
+            name := strings.TrimPrefix(container.Names[0], "/")
+            if !ruleMatches(r, name, container.Labels["com.docker.compose.project"]) {
+                continue
+            }
+            current[name] = true
+            e.fire(r, hostID, name, fmt.Sprintf("容器 %s 健康检查失败", name))
+        }
+        for key := range e.active {
+            if key.ruleID == r.ID && key.hostID == hostID && !current[key.subject] {
+                e.resolve(key, r)
+            }
+        }
+    }
+}
+
+// 检查运行中容器使用的镜像是否有更新，每个镜像一条告警
+func (e *alertEngine) checkImageUpdates(cli *docker.Client, hostID int64, rules []*database.AlertRule) {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+    defer cancel()
+    containers, err := cli.ContainerList(ctx, types.ContainerListOptions{})
+    if err != nil {
+        log.Printf("检查镜像更新获取容器列表失败: %v", err)
+        return
+    }
+
+    // 镜像 -> 使用该镜像的容器
+    users := map[string][]types.Container{}
+    for _, container := range containers {
+        if strings.HasPrefix(container.Image, "sha256:") || len(container.Names) == 0 {
+            continue
+        }
+        users[container.Image] = append(users[container.Image], container)
+    }
+    updates := map[string]bool{}
+    for image := range users {
+        available, err := cli.ImageUpdateAvailable(ctx, image, imageRegistryAuth(image))
+        if err != nil {
+            log.Printf("检查镜像更新失败: %v", err)
+            continue
+        }
+        updates[image] = available
+    }
+
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    for _, r := range rules {
+        for image, available := range updates {
+            var names []string
+            for _, container := range users[image] {
+                name := strings.TrimPrefix(container.Names[0], "/")
+                if ruleMatches(r, name, container.Labels["com.docker.compose.project"]) {
+                    names = append(names, name)
+                }
+            }
+            key := alertKey{r.ID, hostID, image}
+            if !available || len(names) == 0 {
+                e.resolve(key, r)
+                continue
+            }
+            sort.Strings(names)
+            e.fire(r, hostID, image, fmt.Sprintf("镜像 %s 有新版本可用，使用该镜像的容器: %s", image, strings.Join(names, ", ")))
+        }
+    }
+}
+
+// 按规则的通知渠道发送告警或恢复通知
+func notifyAlert(r *database.AlertRule, event database.AlertEvent) {
+    msg := notify.Message{
+        Status:  event.Status,
+        Title:   "[告警] " + r.Name,
+        Text:    event.Message,
+        Rule:    r.Name,
+        Type:    r.Type,
+        Host:    event.HostName,
+        Subject: event.Subject,
+        Time:    event.StartedAt,
+    }
+    if event.Status == database.AlertResolved {
+        msg.Title = "[已恢复] " + r.Name
+        msg.Text = "已恢复: " + event.Message
+        msg.Time = event.ResolvedAt
+    }
+    for _, id := range r.Channels {
+        ch, err := database.GetAlertChannel(id)
+        if err != nil {
+            log.Printf("读取通知渠道 %d 失败: %v", id, err)
+            continue
+        }
+        if !ch.Enabled {
+            continue
+        }
+        deliverAlert(ch, event.ID, event.Status, msg)
+    }
+}
+
+// 发送一条通知并记录结果，失败时按 1、5 秒间隔重试
+func deliverAlert(ch *database.AlertChannel, eventID int64, kind string, msg notify.Message) error {
+    start := time.Now()
+    var err error
+    for attempt := 1; attempt <= notifyAttempts; attempt++ {
+        ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+        err = notify.Send(ctx, notify.Channel{Type: ch.Type, Config: ch.Config}, msg)
+        cancel()
+        if err == nil || attempt == notifyAttempts {
+            break
+        }
+        time.Sleep(time.Duration(attempt*attempt) * time.Second)
+    }
+
+    delivery := &database.AlertDelivery{
+        EventID:     eventID,
+        ChannelID:   ch.ID,
+        ChannelName: ch.Name,
+        ChannelType: ch.Type,
+        Kind:        kind,
+        Status:      database.DeliverySuccess,
+        DurationMs:  time.Since(start).Milliseconds(),
+    }
+    if err != nil {
+        delivery.Status = database.DeliveryFailure
+        delivery.Error = err.Error()
+        log.Printf("通过渠道 %s 发送通知失败: %v", ch.Name, err)
+    }
+    if dbErr := database.InsertAlertDelivery(delivery); dbErr != nil {
+        log.Printf("记录通知发送结果失败: %v", dbErr)
+    }
+    return err
+}
+
+func pruneAlertHistory() {
+    value, _ := database.GetSetting(database.SettingAlertRetentionDays, strconv.Itoa(defaultAlertRetentionDays))
+    days, err := strconv.Atoi(value)
+    if err != nil || days <= 0 {
+        days = defaultAlertRetentionDays
+    }
+    if _, err := database.PruneAlertHistory(time.Now().AddDate(0, 0, -days)); err != nil {
+        log.Printf("清理告警记录失败: %v", err)
+    }
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+    "errors"
+    "testing"
+    "time"
+
+    "dockerpanel/backend/pkg/database"
+)
+
+func newTestAlertEngine(rules ...*database.AlertRule) *alertEngine {
+    return &alertEngine{
+        rules:   rules,
+        hosts:   map[int64]*database.DockerHost{2: {ID: 2, Name: "remote"}},
+        active:  make(map[alertKey]*database.AlertEvent),
+        pending: make(map[alertKey]time.Time),
+    }
+}
+
+// 采集失败时没有读数，已触发的阈值告警不能因此恢复，连接恢复后再按读数判断
+func TestEvaluateThresholdsKeepsAlertsWhenSampleFails(t *testing.T) {
+    openTestDB(t)
+    cpu := &database.AlertRule{ID: 1, Name: "cpu", Type: database.AlertCPUHigh, HostID: database.AllHosts, Threshold: 80}
+    disk := &database.AlertRule{ID: 2, Name: "disk", Type: database.AlertDiskHigh, HostID: 0, Threshold: 90}
+    e := newTestAlertEngine(cpu, disk)
+    now := time.Now()
+
+    healthy := []*hostMetrics{
+        {hostID: 0, host: &hostSnapshot{diskUsed: 95, diskTotal: 100}},
+        {hostID: 2, hostName: "remote", containers: []containerSnapshot{{name: "web", cpu: 150}}},
+    }
+    e.evaluateThresholds(healthy, now)
+    cpuKey := alertKey{cpu.ID, 2, "web"}
+    diskKey := alertKey{disk.ID, 0, hostSubject}
+    if e.active[cpuKey] == nil || e.active[diskKey] == nil {
+        t.Fatalf("超过阈值应触发告警: %v", e.active)
+    }
+
+    tests := []struct {
+        name     string
+        snapshot []*hostMetrics
+    }{
+        {"采集失败", []*hostMetrics{
+            {hostID: 0, hostErr: errors.New("读取磁盘失败")},
+            {hostID: 2, hostName: "remote", err: errors.New("connection refused")},
+        }},
+        // 读取主机列表失败时远程主机不在本轮采集中
+        {"主机缺失", []*hostMetrics{{hostID: 0, hostErr: errors.New("读取磁盘失败")}}},
+    }
+    for _, tt := range tests {
+        e.evaluateThresholds(tt.snapshot, now.Add(time.Minute))
+        if e.active[cpuKey] == nil || e.active[diskKey] == nil {
+            t.Errorf("%s: 告警不应恢复: %v", tt.name, e.active)
+        }
+    }
+
+    // 采集成功且容器已不在读数中时才恢复
+    e.evaluateThresholds([]*hostMetrics{{hostID: 0, host: &hostSnapshot{diskUsed: 10, diskTotal: 100}}, {hostID: 2}}, now.Add(2*time.Minute))
+    if len(e.active) != 0 {
+        t.Errorf("采集成功后告警应恢复: %v", e.active)
+    }
+}
+
+func TestEvaluateThresholdsHostUnreachable(t *testing.T) {
+    openTestDB(t)
+    rule := &database.AlertRule{ID: 1, Name: "down", Type: database.AlertHostUnreachable, HostID: database.AllHosts, Duration: 1}
+    e := newTestAlertEngine(rule)
+    key := alertKey{rule.ID, 2, hostSubject}
+    failed := []*hostMetrics{{hostID: 0}, {hostID: 2, hostName: "remote", err: errors.New("connection refused")}}
+    now := time.Now()
+
+    e.evaluateThresholds(failed, now)
+    if _, ok := e.active[key]; ok {
+        t.Fatal("未达到持续时间时不应触发")
+    }
+    e.evaluateThresholds(failed, now.Add(time.Minute))
+    event := e.active[key]
+    if event == nil || event.HostName != "remote" {
+        t.Fatalf("持续无法连接应触发告警: %+v", event)
+    }
+    if _, ok := e.active[alertKey{rule.ID, 0, hostSubject}]; ok {
+        t.Error("采集成功的主机不应告警")
+    }
+
+    e.evaluateThresholds([]*hostMetrics{{hostID: 0}, {hostID: 2}}, now.Add(2*time.Minute))
+    if _, ok := e.active[key]; ok {
+        t.Error("连接恢复后告警应恢复")
+    }
+    if _, ok := e.pending[key]; ok {
+        t.Error("连接恢复后应清除等待记录")
+    }
+}
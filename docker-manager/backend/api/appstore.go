@@ -2,7 +2,6 @@ package api
 
 import (
 	"context"
-	"dockerpanel/backend/pkg/docker"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -209,12 +208,11 @@ func deployApp(c *gin.Context) {
 	}
 
 	// 创建Docker客户端
-	cli, err := docker.NewDockerClient()
+	cli, err := dockerClient(c)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "连接Docker失败"})
 		return
 	}
-	defer cli.Close()
 
 	// 部署应用
 	if err := cli.DeployCompose(context.Background(), composeFile); err != nil {
@@ -233,12 +231,11 @@ func getAppStatus(c *gin.Context) {
 	id := c.Param("id")
 
 	// 创建Docker客户端
-	cli, err := docker.NewDockerClient()
+	cli, err := dockerClient(c)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "连接Docker失败"})
 		return
 	}
-	defer cli.Close()
 
 	// 查询与应用相关的容器
 	containers, err := cli.ContainerList(context.Background(), types.ContainerListOptions{
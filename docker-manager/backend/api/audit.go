@@ -0,0 +1,437 @@
+package api
+
+import (
+    "bytes"
+    "dockerpanel/backend/pkg/database"
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "io"
+    "log"
+    "net/http"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+const (
+    // 记录请求体的最大长度，超出部分不记录
+    maxAuditBodySize = 64 * 1024
+    // 失败时从响应中截取错误信息的最大长度
+    maxAuditErrorSize = 4 * 1024
+)
+
+// 有副作用的 GET 接口，同样需要审计
+var auditedGetRoutes = map[string]bool{
+    "/api/containers/:id/terminal": true,
+    "/api/containers/:id/exec":     true,
+}
+
+// 长连接接口在打开时先写入一条记录，结束后更新结果
+var auditedSessionRoutes = map[string]bool{
+    "/api/containers/:id/terminal": true,
+    "/api/containers/:id/exec":     true,
+}
+
+// 常用操作的名称，未列出的接口使用 "方法 路由模板"
+var auditActions = map[string]string{
+    "POST /api/auth/login":                          "auth.login",
+    "POST /api/auth/logout":                         "auth.logout",
+    "POST /api/auth/setup":                          "auth.setup",
+    "PUT /api/auth/password":                        "auth.password",
+    "POST /api/containers/create":                   "container.create",
+    "POST /api/containers/:id/recreate":             "container.recreate",
+    "POST /api/containers/:id/start":                "container.start",
+    "POST /api/containers/:id/stop":                 "container.stop",
+    "POST /api/containers/:id/restart":              "container.restart",
+    "POST /api/containers/:id/pause":                "container.pause",
+    "POST /api/containers/:id/unpause":              "container.unpause",
+    "DELETE /api/containers/:id":                    "container.remove",
+    "GET /api/containers/:id/terminal":              "container.terminal",
+    "GET /api/containers/:id/exec":                  "container.exec",
+    "POST /api/images/pull":                         "image.pull",
+    "POST /api/images/pull/progress":                "image.pull",
+    "POST /api/images/push":                         "image.push",
+    "POST /api/images/tag":                          "image.tag",
+    "DELETE /api/images/:id":                        "image.remove",
+    "POST /api/images/import":                       "image.import",
+    "POST /api/images/import/url":                   "image.import",
+    "POST /api/images/proxy":                        "daemon.proxy",
+    "POST /api/compose/deploy/events":               "compose.deploy",
+    "POST /api/compose/:name/start":                 "compose.start",
+    "POST /api/compose/:name/stop":                  "compose.stop",
+    "POST /api/compose/:name/yaml":                  "compose.yaml",
+    "DELETE /api/compose/remove/:name":              "compose.remove",
+    "PUT /api/daemon-config":                        "daemon.config",
+    "POST /api/daemon-config/backups/:name/restore": "daemon.restore",
+    "POST /api/daemon-config/restart":               "daemon.restart",
+    "POST /api/volumes":                             "volume.create",
+    "DELETE /api/volumes/:name":                     "volume.remove",
+    "POST /api/volumes/prune":                       "volume.prune",
+    "POST /api/networks":                            "network.create",
+    "DELETE /api/networks/:id":                      "network.remove",
+    "POST /api/users":                               "user.create",
+    "PUT /api/users/:id":                            "user.update",
+    "DELETE /api/users/:id":                         "user.remove",
+    "PUT /api/users/:id/permissions":                "user.permissions",
+    "POST /api/tokens":                              "token.create",
+    "DELETE /api/tokens/:id":                        "token.revoke",
+}
+
+// 参数名匹配时值会被替换为 ***
+var sensitiveKey = regexp.MustCompile(`(?i)pass|secret|token|auth|credential|private|(^|[_-])key$`)
+
+// 文本内容（例如 compose 文件、daemon.json）中形如 PASSWORD: xxx 的行
+var sensitiveLine = regexp.MustCompile(`(?im)^(\s*-?\s*"?[\w.-]*(?:pass|secret|token|credential|private_?key|api_?key)[\w.-]*"?\s*[:=]\s*)(.+)$`)
+
+// 请求体或查询参数中可能作为目标的字段
+var auditTargetFields = []string{"name", "image", "imageName", "repo", "username", "url"}
+
+func RegisterAuditRoutes(r *gin.Engine) {
+    r.GET("/api/audit", listAuditLogs)
+}
+
+// 包装 ResponseWriter，失败时截取响应中的错误信息
+type auditResponseWriter struct {
+    gin.ResponseWriter
+    body bytes.Buffer
+}
+
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+    if w.body.Len() < maxAuditErrorSize {
+        remaining := maxAuditErrorSize - w.body.Len()
+        if len(data) < remaining {
+            remaining = len(data)
+        }
+        w.body.Write(data[:remaining])
+    }
+    return w.ResponseWriter.Write(data)
+}
+
+// AuditMiddleware 记录所有修改类接口的调用，需放在 AuthMiddleware 之前，以便记录认证失败的请求
+func AuditMiddleware() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        route := c.FullPath()
+        method := c.Request.Method
+        if !strings.HasPrefix(c.Request.URL.Path, "/api/") || route == "" ||
+            method == http.MethodOptions || method == http.MethodHead ||
+            (method == http.MethodGet && !auditedGetRoutes[route]) {
+            c.Next()
+            return
+        }
+
+        start := time.Now()
+        params, target := auditParams(c)
+        entry := &database.AuditLog{
+            IP:     c.ClientIP(),
+            Method: method,
+            Path:   c.Request.URL.Path,
+            Action: auditAction(method, route),
+            Target: target,
+            Params: params,
+        }
+
+        writer := &auditResponseWriter{ResponseWriter: c.Writer}
+        c.Writer = writer
+
+        // 终端会话在打开时就写入记录，避免会话期间面板异常退出导致没有记录
+        var sessionID int64
+        if auditedSessionRoutes[route] {
+            entry.Actor = auditActor(c, params)
+            entry.Result = database.AuditRunning
+            id, err := database.InsertAuditLog(entry)
+            if err != nil {
+                log.Printf("写入审计日志失败: %v", err)
+            }
+            sessionID = id
+        }
+
+        c.Next()
+
+        status := writer.Status()
+        result := database.AuditSuccess
+        errMsg := ""
+        if status >= http.StatusBadRequest {
+            result = database.AuditFailure
+            errMsg = auditErrorMessage(writer.body.Bytes())
+        } else if len(c.Errors) > 0 {
+            result = database.AuditFailure
+            errMsg = c.Errors.String()
+        }
+        duration := time.Since(start).Milliseconds()
+
+        if sessionID > 0 {
+            if err := database.FinishAuditLog(sessionID, status, result, errMsg, duration); err != nil {
+                log.Printf("更新审计日志失败: %v", err)
+            }
+            return
+        }
+
+        entry.Actor = auditActor(c, params)
+        entry.Status = status
+        entry.Result = result
+        entry.Error = errMsg
+        entry.DurationMs = duration
+        if _, err := database.InsertAuditLog(entry); err != nil {
+            log.Printf("写入审计日志失败: %v", err)
+        }
+    }
+}
+
+func auditAction(method, route string) string {
+    if action, ok := auditActions[method+" "+route]; ok {
+        return action
+    }
+    return method + " " + route
+}
+
+// 操作者：登录用户名，使用 API 令牌时附带令牌名称；未登录时使用请求中的用户名
+func auditActor(c *gin.Context, params string) string {
+    user := currentUser(c)
+    if user == nil {
+        var body map[string]interface{}
+        if json.Unmarshal([]byte(params), &body) == nil {
+            if fields, ok := body["body"].(map[string]interface{}); ok {
+                if username, ok := fields["username"].(string); ok && username != "" {
+                    return username + " (未登录)"
+                }
+            }
+        }
+        return "匿名"
+    }
+    if token := currentAPIToken(c); token != nil && token.Type == database.TokenPersonal {
+        return fmt.Sprintf("%s (令牌: %s)", user.Username, token.Name)
+    }
+    return user.Username
+}
+
+// 收集路由参数、查询参数和请求体，敏感字段脱敏，同时找出操作目标
+func auditParams(c *gin.Context) (string, string) {
+    record := map[string]interface{}{}
+    target := ""
+
+    if len(c.Params) > 0 {
+        pathParams := map[string]string{}
+        for _, p := range c.Params {
+            pathParams[p.Key] = p.Value
+        }
+        record["path"] = pathParams
+        target = c.Params[0].Value
+    }
+
+    if len(c.Request.URL.Query()) > 0 {
+        query := map[string]interface{}{}
+        for key, values := range c.Request.URL.Query() {
+            if len(values) == 1 {
+                query[key] = values[0]
+            } else {
+                query[key] = values
+            }
+        }
+        redact(query)
+        record["query"] = query
+        if target == "" {
+            target = pickTarget(query)
+        }
+    }
+
+    // 通过请求头选择的远程主机，查询参数中的 host 已包含在 query 中
+    if host := c.GetHeader(hostHeader); host != "" && host != "0" {
+        record["host"] = host
+    }
+
+    contentType := c.ContentType()
+    switch {
+    case c.Request.Body == nil || c.Request.ContentLength == 0:
+    case strings.HasPrefix(contentType, "multipart/"):
+        // 上传文件不记录内容，只记录大小
+        record["body"] = fmt.Sprintf("<%s, %d 字节>", contentType, c.Request.ContentLength)
+    default:
+        data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxAuditBodySize+1))
+        // 读取的内容放回请求体，不影响后续处理函数
+        c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(data), c.Request.Body))
+        if err != nil {
+            break
+        }
+        if len(data) > maxAuditBodySize {
+            record["body"] = fmt.Sprintf("<超过 %d 字节，未记录>", maxAuditBodySize)
+            break
+        }
+        var body interface{}
+        if json.Unmarshal(data, &body) == nil {
+            redact(body)
+            record["body"] = body
+            if fields, ok := body.(map[string]interface{}); ok && target == "" {
+                target = pickTarget(fields)
+            }
+        } else {
+            record["body"] = fmt.Sprintf("<%s, %d 字节>", contentType, len(data))
+        }
+    }
+
+    if len(record) == 0 {
+        return "", target
+    }
+    encoded, _ := json.Marshal(record)
+    return string(encoded), target
+}
+
+// 递归替换敏感字段的值，多行文本按行替换
+func redact(value interface{}) {
+    switch v := value.(type) {
+    case map[string]interface{}:
+        for key, item := range v {
+            if sensitiveKey.MatchString(key) {
+                if item != nil && item != "" {
+                    v[key] = "***"
+                }
+                continue
+            }
+            if text, ok := item.(string); ok {
+                v[key] = redactText(text)
+                continue
+            }
+            redact(item)
+        }
+    case []interface{}:
+        for i, item := range v {
+            if text, ok := item.(string); ok {
+                v[i] = redactText(text)
+                continue
+            }
+            redact(item)
+        }
+    }
+}
+
+func redactText(text string) string {
+    if !strings.Contains(text, "\n") && !strings.ContainsAny(text, ":=") {
+        return text
+    }
+    return sensitiveLine.ReplaceAllString(text, "${1}***")
+}
+
+func pickTarget(fields map[string]interface{}) string {
+    for _, key := range auditTargetFields {
+        if value, ok := fields[key].(string); ok && value != "" {
+            return value
+        }
+    }
+    return ""
+}
+
+// 从 JSON 响应中取出 error 字段，不是 JSON 时直接截取文本
+func auditErrorMessage(body []byte) string {
+    var resp struct {
+        Error string `json:"error"`
+    }
+    if json.Unmarshal(body, &resp) == nil && resp.Error != "" {
+        return resp.Error
+    }
+    return strings.TrimSpace(string(body))
+}
+
+// 查询审计日志，format=csv 时导出全部符合条件的记录
+func listAuditLogs(c *gin.Context) {
+    filter := database.AuditFilter{
+        Actor:   c.Query("actor"),
+        Action:  c.Query("action"),
+        Target:  c.Query("target"),
+        Result:  c.Query("result"),
+        Keyword: c.Query("q"),
+        From:    c.Query("from"),
+        To:      c.Query("to"),
+    }
+
+    if c.Query("format") == "csv" {
+        logs, _, err := database.ListAuditLogs(filter)
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "查询审计日志失败: " + err.Error()})
+            return
+        }
+        writeAuditCSV(c, logs)
+        return
+    }
+
+    page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+    pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "50"))
+    if page < 1 {
+        page = 1
+    }
+    if pageSize < 1 || pageSize > 500 {
+        pageSize = 50
+    }
+    filter.Limit = pageSize
+    filter.Offset = (page - 1) * pageSize
+
+    logs, total, err := database.ListAuditLogs(filter)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "查询审计日志失败: " + err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{
+        "items": logs,
+        "total": total,
+        "page":  page,
+    })
+}
+
+func writeAuditCSV(c *gin.Context, logs []database.AuditLog) {
+    filename := fmt.Sprintf("audit-%s.csv", time.Now().Format("20060102-150405"))
+    c.Header("Content-Type", "text/csv; charset=utf-8")
+    c.Header("Content-Disposition", "attachment; filename="+filename)
+    c.Status(http.StatusOK)
+
+    // 写入 BOM，Excel 打开时才能正确识别中文
+    c.Writer.Write([]byte("\xEF\xBB\xBF"))
+    w := csv.NewWriter(c.Writer)
+    w.Write([]string{"时间", "操作者", "IP", "操作", "目标", "方法", "路径", "参数", "状态码", "结果", "错误", "耗时(ms)"})
+    for _, l := range logs {
+        w.Write([]string{
+            l.CreatedAt, csvCell(l.Actor), csvCell(l.IP), csvCell(l.Action), csvCell(l.Target), l.Method,
+            csvCell(l.Path), csvCell(l.Params), strconv.Itoa(l.Status), csvCell(l.Result), csvCell(l.Error),
+            strconv.FormatInt(l.DurationMs, 10),
+        })
+    }
+    w.Flush()
+}
+
+// csvCell 防止 CSV 公式注入：以 = + - @ 或制表符、回车开头的单元格会被 Excel 当作公式执行，
+// 在前面加上单引号使其按文本显示
+func csvCell(value string) string {
+    if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+        return "'" + value
+    }
+    return value
+}
+
+// StartAuditRetention 每天清理超过保留天数的审计日志
+func StartAuditRetention() {
+    prune := func() {
+        value, _ := database.GetSetting(database.SettingAuditRetentionDays, "180")
+        days, err := strconv.Atoi(value)
+        if err != nil || days <= 0 {
+            return
+        }
+        removed, err := database.PruneAuditLogs(time.Now().AddDate(0, 0, -days))
+        if err != nil {
+            log.Printf("清理审计日志失败: %v", err)
+            return
+        }
+        if removed > 0 {
+            log.Printf("已清理 %d 条超过 %d 天的审计日志", removed, days)
+        }
+    }
+
+    go func() {
+        prune()
+        ticker := time.NewTicker(24 * time.Hour)
+        defer ticker.Stop()
+        for range ticker.C {
+            prune()
+        }
+    }()
+}
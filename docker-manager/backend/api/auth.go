@@ -0,0 +1,415 @@
+package api
+
+import (
+    "crypto/rand"
+    "dockerpanel/backend/pkg/database"
+    "encoding/hex"
+    "log"
+    "net/http"
+    "net/url"
+    "os"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/gorilla/websocket"
+)
+
+const (
+    // 会话 Cookie 名称
+    sessionCookieName = "dockerpanel_session"
+    // 会话有效期
+    sessionTTL = 7 * 24 * time.Hour
+    // 允许跨域访问面板的来源，逗号分隔，例如 http://localhost:3000
+    allowedOriginsEnv = "DOCKERPANEL_ALLOWED_ORIGINS"
+    // 面板前的反向代理地址或网段，逗号分隔。只有来自这些地址的 X-Forwarded-For 才会被采用
+    trustedProxiesEnv = "DOCKERPANEL_TRUSTED_PROXIES"
+    // WebSocket 连接票据的有效期
+    wsTicketTTL = 30 * time.Second
+
+    // 上下文中保存当前用户的键
+    contextUserKey = "currentUser"
+    // 通过 API 令牌访问时，上下文中保存令牌的键
+    contextTokenKey = "apiToken"
+)
+
+// 无需登录即可访问的接口
+var publicPaths = map[string]bool{
+    "/api/auth/status": true,
+    "/api/auth/setup":  true,
+    "/api/auth/login":  true,
+}
+
+func RegisterAuthRoutes(r *gin.Engine) {
+    group := r.Group("/api/auth")
+    {
+        group.GET("/status", getAuthStatus)
+        group.POST("/setup", setupAdmin)
+        group.POST("/login", login)
+        group.POST("/logout", logout)
+        group.GET("/me", getCurrentUser)
+        group.PUT("/password", changePassword)
+        group.POST("/ws-ticket", createWSTicket)
+    }
+}
+
+// AuthMiddleware 校验 /api 下所有接口的登录状态，包括 WebSocket 终端
+func AuthMiddleware() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        path := c.Request.URL.Path
+        if !strings.HasPrefix(path, "/api/") || publicPaths[path] || c.Request.Method == http.MethodOptions {
+            c.Next()
+            return
+        }
+
+        token := requestToken(c)
+        if token == "" {
+            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "未登录或登录已过期"})
+            return
+        }
+        if strings.HasPrefix(token, database.APITokenPrefix) {
+            authenticateAPIToken(c, token)
+            return
+        }
+        user, err := database.GetSessionUser(token)
+        if err != nil {
+            if err != database.ErrSessionNotFound {
+                log.Printf("校验会话失败: %v", err)
+            }
+            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "未登录或登录已过期"})
+            return
+        }
+
+        c.Set(contextUserKey, user)
+        c.Next()
+    }
+}
+
+// 使用 API 令牌认证。个人令牌以所有者身份访问，服务令牌使用只带令牌名称的虚拟用户，
+// 具体能访问哪些接口由 RBACMiddleware 结合令牌范围判断
+func authenticateAPIToken(c *gin.Context, token string) {
+    apiToken, err := database.AuthenticateAPIToken(token, c.ClientIP())
+    if err != nil {
+        if err != database.ErrTokenNotFound {
+            log.Printf("校验 API 令牌失败: %v", err)
+        }
+        c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": database.ErrTokenNotFound.Error()})
+        return
+    }
+
+    user := &database.User{Username: "service:" + apiToken.Name}
+    if apiToken.Type == database.TokenPersonal {
+        user, err = database.GetUserByID(apiToken.UserID)
+        if err != nil {
+            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": database.ErrTokenNotFound.Error()})
+            return
+        }
+    }
+
+    c.Set(contextUserKey, user)
+    c.Set(contextTokenKey, apiToken)
+    c.Next()
+}
+
+// currentAPIToken 返回本次请求使用的 API 令牌，通过会话访问时返回 nil
+func currentAPIToken(c *gin.Context) *database.APIToken {
+    if value, ok := c.Get(contextTokenKey); ok {
+        if token, ok := value.(*database.APIToken); ok {
+            return token
+        }
+    }
+    return nil
+}
+
+// 依次从 Cookie、Authorization 头和 WebSocket 的 ticket 参数中读取令牌。
+// 浏览器的 WebSocket 无法设置请求头，跨站连接时先换取一次性票据再通过参数传递，
+// 避免会话令牌出现在 URL 和访问日志中
+func requestToken(c *gin.Context) string {
+    if token, err := c.Cookie(sessionCookieName); err == nil && token != "" {
+        return token
+    }
+    if header := c.GetHeader("Authorization"); strings.HasPrefix(header, "Bearer ") {
+        return strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+    }
+    if websocket.IsWebSocketUpgrade(c.Request) {
+        return consumeWSTicket(c.Query("ticket"))
+    }
+    return ""
+}
+
+type wsTicket struct {
+    token     string
+    expiresAt time.Time
+}
+
+var (
+    wsTickets   = make(map[string]wsTicket)
+    wsTicketsMu sync.Mutex
+)
+
+// 为当前会话或 API 令牌签发一次性的 WebSocket 票据，有效期 30 秒
+func createWSTicket(c *gin.Context) {
+    buf := make([]byte, 24)
+    if _, err := rand.Read(buf); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "生成票据失败: " + err.Error()})
+        return
+    }
+    ticket := hex.EncodeToString(buf)
+    now := time.Now()
+    wsTicketsMu.Lock()
+    for key, t := range wsTickets {
+        if now.After(t.expiresAt) {
+            delete(wsTickets, key)
+        }
+    }
+    wsTickets[ticket] = wsTicket{token: requestToken(c), expiresAt: now.Add(wsTicketTTL)}
+    wsTicketsMu.Unlock()
+    c.JSON(http.StatusOK, gin.H{"ticket": ticket, "expires_in": int(wsTicketTTL.Seconds())})
+}
+
+// 票据只能使用一次，返回其对应的令牌
+func consumeWSTicket(ticket string) string {
+    if ticket == "" {
+        return ""
+    }
+    wsTicketsMu.Lock()
+    defer wsTicketsMu.Unlock()
+    t, ok := wsTickets[ticket]
+    delete(wsTickets, ticket)
+    if !ok || time.Now().After(t.expiresAt) {
+        return ""
+    }
+    return t.token
+}
+
+// currentUser 返回当前登录用户，未经过 AuthMiddleware 时返回 nil
+func currentUser(c *gin.Context) *database.User {
+    if value, ok := c.Get(contextUserKey); ok {
+        if user, ok := value.(*database.User); ok {
+            return user
+        }
+    }
+    return nil
+}
+
+// AllowedOrigins 返回允许跨域访问的来源，未配置时只允许同源访问
+func AllowedOrigins() []string {
+    var origins []string
+    for _, origin := range strings.Split(os.Getenv(allowedOriginsEnv), ",") {
+        if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+            origins = append(origins, origin)
+        }
+    }
+    return origins
+}
+
+// TrustedProxies 返回可信的反向代理，未配置时不信任任何代理，客户端地址取 TCP 连接的对端地址
+func TrustedProxies() []string {
+    var proxies []string
+    for _, proxy := range strings.Split(os.Getenv(trustedProxiesEnv), ",") {
+        if proxy = strings.TrimSpace(proxy); proxy != "" {
+            proxies = append(proxies, proxy)
+        }
+    }
+    return proxies
+}
+
+// 校验 WebSocket 的 Origin，防止其他网站借用户的 Cookie 打开终端
+func checkWebSocketOrigin(r *http.Request) bool {
+    origin := r.Header.Get("Origin")
+    if origin == "" {
+        // 非浏览器客户端不会携带 Origin
+        return true
+    }
+    u, err := url.Parse(origin)
+    if err != nil {
+        return false
+    }
+    if strings.EqualFold(u.Host, r.Host) {
+        return true
+    }
+    for _, allowed := range AllowedOrigins() {
+        if strings.EqualFold(allowed, origin) {
+            return true
+        }
+    }
+    log.Printf("拒绝来源为 %s 的 WebSocket 连接", origin)
+    return false
+}
+
+// 登录失败次数限制，同一 IP 连续失败后暂时锁定
+const (
+    maxLoginFailures = 5
+    loginLockout     = 5 * time.Minute
+)
+
+type loginAttempt struct {
+    failures    int
+    lockedUntil time.Time
+}
+
+var (
+    loginAttempts   = make(map[string]*loginAttempt)
+    loginAttemptsMu sync.Mutex
+)
+
+func loginLocked(ip string) bool {
+    loginAttemptsMu.Lock()
+    defer loginAttemptsMu.Unlock()
+    attempt, ok := loginAttempts[ip]
+    return ok && time.Now().Before(attempt.lockedUntil)
+}
+
+func recordLoginResult(ip string, success bool) {
+    loginAttemptsMu.Lock()
+    defer loginAttemptsMu.Unlock()
+    if success {
+        delete(loginAttempts, ip)
+        return
+    }
+    attempt, ok := loginAttempts[ip]
+    if !ok {
+        attempt = &loginAttempt{}
+        loginAttempts[ip] = attempt
+    }
+    attempt.failures++
+    if attempt.failures >= maxLoginFailures {
+        attempt.failures = 0
+        attempt.lockedUntil = time.Now().Add(loginLockout)
+        log.Printf("IP %s 登录失败次数过多，锁定 %v", ip, loginLockout)
+    }
+}
+
+// 创建会话并写入 Cookie
+func startSession(c *gin.Context, user *database.User) error {
+    token, expiresAt, err := database.CreateSession(user.ID, sessionTTL, c.ClientIP(), c.Request.UserAgent())
+    if err != nil {
+        return err
+    }
+    secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+    // Strict 模式下其他站点的链接和表单不会携带会话 Cookie，防止跨站请求伪造
+    c.SetSameSite(http.SameSiteStrictMode)
+    c.SetCookie(sessionCookieName, token, int(time.Until(expiresAt).Seconds()), "/", "", secure, true)
+    return nil
+}
+
+// 登录状态，前端据此决定显示初始化页还是登录页
+func getAuthStatus(c *gin.Context) {
+    count, err := database.CountUsers()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户信息失败: " + err.Error()})
+        return
+    }
+
+    var user *database.User
+    if token := requestToken(c); token != "" {
+        user, _ = database.GetSessionUser(token)
+    }
+    c.JSON(http.StatusOK, gin.H{
+        "initialized":   count > 0,
+        "authenticated": user != nil,
+        "user":          user,
+    })
+}
+
+type credentialsRequest struct {
+    Username string `json:"username" binding:"required"`
+    Password string `json:"password" binding:"required"`
+}
+
+// 首次运行时创建管理员账号
+func setupAdmin(c *gin.Context) {
+    var req credentialsRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "请输入用户名和密码"})
+        return
+    }
+
+    user, err := database.CreateInitialAdmin(req.Username, req.Password)
+    if err != nil {
+        if err == database.ErrAlreadyInitialized {
+            c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+            return
+        }
+        c.JSON(http.StatusBadRequest, gin.H{"error": "创建管理员失败: " + err.Error()})
+        return
+    }
+    log.Printf("已创建管理员账号: %s", user.Username)
+
+    if err := startSession(c, user); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "创建会话失败: " + err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"message": "管理员账号已创建", "user": user})
+}
+
+func login(c *gin.Context) {
+    var req credentialsRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "请输入用户名和密码"})
+        return
+    }
+
+    ip := c.ClientIP()
+    if loginLocked(ip) {
+        c.JSON(http.StatusTooManyRequests, gin.H{"error": "登录失败次数过多，请稍后再试"})
+        return
+    }
+
+    user, err := database.Authenticate(req.Username, req.Password)
+    if err != nil {
+        if err == database.ErrInvalidPassword {
+            recordLoginResult(ip, false)
+            c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+            return
+        }
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "登录失败: " + err.Error()})
+        return
+    }
+    recordLoginResult(ip, true)
+
+    if err := startSession(c, user); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "创建会话失败: " + err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"message": "登录成功", "user": user})
+}
+
+func logout(c *gin.Context) {
+    if token := requestToken(c); token != "" {
+        if err := database.DeleteSession(token); err != nil {
+            log.Printf("注销会话失败: %v", err)
+        }
+    }
+    c.SetCookie(sessionCookieName, "", -1, "/", "", false, true)
+    c.JSON(http.StatusOK, gin.H{"message": "已退出登录"})
+}
+
+func getCurrentUser(c *gin.Context) {
+    c.JSON(http.StatusOK, currentUser(c))
+}
+
+// 修改当前用户的密码，其他设备上的会话会被注销
+func changePassword(c *gin.Context) {
+    var req struct {
+        OldPassword string `json:"oldPassword" binding:"required"`
+        NewPassword string `json:"newPassword" binding:"required"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "请输入原密码和新密码"})
+        return
+    }
+
+    if currentAPIToken(c) != nil {
+        c.JSON(http.StatusForbidden, gin.H{"error": "API 令牌不能修改密码"})
+        return
+    }
+    user := currentUser(c)
+    keep := database.HashToken(requestToken(c))
+    if err := database.ChangePassword(user.ID, req.OldPassword, req.NewPassword, keep); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "修改密码失败: " + err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"message": "密码已修改"})
+}
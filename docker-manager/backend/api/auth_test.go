@@ -0,0 +1,122 @@
+package api
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+
+    "dockerpanel/backend/pkg/database"
+    "github.com/gin-gonic/gin"
+)
+
+func TestLoginLockout(t *testing.T) {
+    const ip = "192.0.2.1"
+    t.Cleanup(func() { recordLoginResult(ip, true) })
+
+    for i := 1; i < maxLoginFailures; i++ {
+        recordLoginResult(ip, false)
+        if loginLocked(ip) {
+            t.Fatalf("失败 %d 次不应锁定", i)
+        }
+    }
+    recordLoginResult(ip, false)
+    if !loginLocked(ip) {
+        t.Fatalf("连续失败 %d 次后应锁定", maxLoginFailures)
+    }
+    if loginLocked("192.0.2.2") {
+        t.Error("锁定只针对失败的 IP")
+    }
+
+    // 锁定到期后解除
+    loginAttemptsMu.Lock()
+    loginAttempts[ip].lockedUntil = time.Now().Add(-time.Second)
+    loginAttemptsMu.Unlock()
+    if loginLocked(ip) {
+        t.Error("锁定到期后应解除")
+    }
+
+    // 登录成功清除失败记录
+    recordLoginResult(ip, false)
+    recordLoginResult(ip, true)
+    for i := 1; i < maxLoginFailures; i++ {
+        recordLoginResult(ip, false)
+    }
+    if loginLocked(ip) {
+        t.Error("登录成功后应重新计数")
+    }
+}
+
+func TestWSTicket(t *testing.T) {
+    wsTicketsMu.Lock()
+    wsTickets["valid"] = wsTicket{token: "session-token", expiresAt: time.Now().Add(wsTicketTTL)}
+    wsTickets["expired"] = wsTicket{token: "session-token", expiresAt: time.Now().Add(-time.Second)}
+    wsTicketsMu.Unlock()
+
+    if token := consumeWSTicket("valid"); token != "session-token" {
+        t.Errorf("有效票据应返回令牌, 得到 %q", token)
+    }
+    if token := consumeWSTicket("valid"); token != "" {
+        t.Error("票据只能使用一次")
+    }
+    if token := consumeWSTicket("expired"); token != "" {
+        t.Error("过期票据不应返回令牌")
+    }
+    if token := consumeWSTicket(""); token != "" {
+        t.Error("空票据不应返回令牌")
+    }
+}
+
+func TestAuthMiddlewareSession(t *testing.T) {
+    openTestDB(t)
+    user, err := database.CreateUser("alice", "password123", database.RoleViewer)
+    if err != nil {
+        t.Fatal(err)
+    }
+    valid, _, err := database.CreateSession(user.ID, time.Hour, "", "")
+    if err != nil {
+        t.Fatal(err)
+    }
+    expired, _, err := database.CreateSession(user.ID, -time.Second, "", "")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    gin.SetMode(gin.TestMode)
+    r := gin.New()
+    r.Use(AuthMiddleware())
+    r.GET("/api/auth/status", func(c *gin.Context) { c.Status(http.StatusOK) })
+    r.GET("/api/containers", func(c *gin.Context) { c.String(http.StatusOK, currentUser(c).Username) })
+
+    tests := []struct {
+        name   string
+        path   string
+        cookie string
+        bearer string
+        status int
+    }{
+        {"公开接口", "/api/auth/status", "", "", 200},
+        {"未登录", "/api/containers", "", "", 401},
+        {"会话 Cookie", "/api/containers", valid, "", 200},
+        {"Authorization 头", "/api/containers", "", valid, 200},
+        {"过期会话", "/api/containers", expired, "", 401},
+        {"无效会话", "/api/containers", "invalid", "", 401},
+    }
+    for _, tt := range tests {
+        w := httptest.NewRecorder()
+        req := httptest.NewRequest("GET", tt.path, nil)
+        if tt.cookie != "" {
+            req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: tt.cookie})
+        }
+        if tt.bearer != "" {
+            req.Header.Set("Authorization", "Bearer "+tt.bearer)
+        }
+        r.ServeHTTP(w, req)
+        if w.Code != tt.status {
+            t.Errorf("%s: 返回 %d, 期望 %d", tt.name, w.Code, tt.status)
+        }
+        if w.Code == 200 && tt.path == "/api/containers" && w.Body.String() != "alice" {
+            t.Errorf("%s: 当前用户 = %s", tt.name, w.Body.String())
+        }
+    }
+}
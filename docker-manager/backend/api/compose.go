@@ -12,10 +12,13 @@ import (
     "sort"
     "strings"
 	"bufio"
-    "github.com/docker/docker/client"
+    "log"
     "github.com/docker/docker/api/types"
     "github.com/docker/docker/api/types/filters"
+    "github.com/docker/go-units"
 	"github.com/gin-gonic/gin"
+    "gopkg.in/yaml.v3"
+    "dockerpanel/backend/pkg/docker"
 )
 
 // ComposeProject 定义项目结构
@@ -34,7 +37,7 @@ func RegisterComposeRoutes(r *gin.Engine) {
     group := r.Group("/api/compose")
     {
         group.GET("/list", listProjects)
-        group.GET("/deploy/events", deployEvents)
+        group.POST("/deploy/events", deployEvents)
         group.POST("/:name/start", startProject)
         group.POST("/:name/stop", stopProject)
         group.GET("/:name/status", getStackStatus)
@@ -48,11 +51,21 @@ func RegisterComposeRoutes(r *gin.Engine) {
 // startProject 启动项目
 func startProject(c *gin.Context) {
     name := c.Param("name")
-    projectDir := filepath.Join("data", "project", name)
     
     // 使用 docker compose up 命令启动项目
+    env, err := composeEnv(c)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "连接Docker失败: " + err.Error()})
+        return
+    }
+    projectDir, err := composeProjectDir(c, name)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
     cmd := exec.Command("docker", "compose", "up", "-d")
     cmd.Dir = projectDir
+    cmd.Env = env
     
     if output, err := cmd.CombinedOutput(); err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{
@@ -67,11 +80,21 @@ func startProject(c *gin.Context) {
 // stopProject 停止项目
 func stopProject(c *gin.Context) {
     name := c.Param("name")
-    projectDir := filepath.Join("data", "project", name)
     
     // 使用 docker compose stop 命令停止项目
+    env, err := composeEnv(c)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "连接Docker失败: " + err.Error()})
+        return
+    }
+    projectDir, err := composeProjectDir(c, name)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
     cmd := exec.Command("docker", "compose", "stop")
     cmd.Dir = projectDir
+    cmd.Env = env
     
     if output, err := cmd.CombinedOutput(); err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{
@@ -87,12 +110,11 @@ func stopProject(c *gin.Context) {
 // listProjects 获取项目列表
 func listProjects(c *gin.Context) {
     // 创建 Docker 客户端
-    cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+    cli, err := dockerClient(c)
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
     }
-    defer cli.Close()
 
     // 获取所有带有 compose 标签的容器
     containers, err := cli.ContainerList(context.Background(), types.ContainerListOptions{
@@ -106,6 +128,13 @@ func listProjects(c *gin.Context) {
         return
     }
 
+    host, err := selectedHost(c)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    root := projectRoot(host)
+
     // 用于存储项目信息的 map
     projects := make(map[string]*ComposeProject)
 
@@ -119,7 +148,7 @@ func listProjects(c *gin.Context) {
         if _, exists := projects[projectName]; !exists {
             projects[projectName] = &ComposeProject{
                 Name:       projectName,
-                Path:      filepath.Join("data", "project", projectName),
+                Path:      filepath.Join(root, projectName),
                 Containers: 0,
                 Status:    "已停止",
                 CreateTime: time.Unix(container.Created, 0),
@@ -153,20 +182,99 @@ func listProjects(c *gin.Context) {
 
     c.JSON(http.StatusOK, result)
 }
-// deployEvents 处理部署事件
+// 项目的 compose 文件按主机分开保存：本机沿用 data/project，其他主机保存在各自主机目录下的 project 中，
+// 不同主机上的同名项目互不影响
+func projectRoot(h *docker.Host) string {
+    if h == nil || h.ID == 0 {
+        return filepath.Join("data", "project")
+    }
+    return filepath.Join(docker.HostDir(h.ID), "project")
+}
+
+// composeProjectDir 返回当前请求所选主机上项目的目录
+func composeProjectDir(c *gin.Context, name string) (string, error) {
+    h, err := selectedHost(c)
+    if err != nil {
+        return "", err
+    }
+    return filepath.Join(projectRoot(h), name), nil
+}
+
+// composeProjectImages 读取主机上所有项目的 compose 文件，返回项目名到其引用镜像的映射
+func composeProjectImages(h *docker.Host) (map[string][]string, error) {
+    projectRoot := projectRoot(h)
+    entries, err := os.ReadDir(projectRoot)
+    if err != nil {
+        if os.IsNotExist(err) {
+            return map[string][]string{}, nil
+        }
+        return nil, err
+    }
+
+    result := make(map[string][]string)
+    for _, entry := range entries {
+        if !entry.IsDir() {
+            continue
+        }
+
+        composePath := filepath.Join(projectRoot, entry.Name(), "docker-compose.yml")
+        data, err := os.ReadFile(composePath)
+        if err != nil {
+            continue
+        }
+
+        var compose struct {
+            Services map[string]struct {
+                Image string `yaml:"image"`
+            } `yaml:"services"`
+        }
+        if err := yaml.Unmarshal(data, &compose); err != nil {
+            log.Printf("解析项目 %s 的 compose 文件失败: %v", entry.Name(), err)
+            continue
+        }
+
+        for _, service := range compose.Services {
+            if service.Image != "" {
+                result[entry.Name()] = append(result[entry.Name()], service.Image)
+            }
+        }
+    }
+
+    return result, nil
+}
+
+// deployEvents 部署项目并以 SSE 推送进度。
+// 项目名称在查询参数中，供权限检查使用；compose 内容在 JSON 请求体中
 func deployEvents(c *gin.Context) {
     projectName := c.Query("name")
-    compose := c.Query("compose")
-    
+    var req struct {
+        Compose string `json:"compose"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据: " + err.Error()})
+        return
+    }
+    compose := req.Compose
+
     if projectName == "" || compose == "" {
         c.JSON(http.StatusBadRequest, gin.H{"error": "项目名称和配置内容不能为空"})
         return
     }
+    if strings.ContainsAny(projectName, `/\`) || strings.HasPrefix(projectName, ".") {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "无效的项目名称: " + projectName})
+        return
+    }
+
+    // 部署在后台协程中执行，先解析目标主机
+    host, err := selectedHost(c)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "连接Docker失败: " + err.Error()})
+        return
+    }
 
     c.Header("Content-Type", "text/event-stream")
     c.Header("Cache-Control", "no-cache")
     c.Header("Connection", "keep-alive")
-    c.Header("Access-Control-Allow-Origin", "*")
 
     messageChan := make(chan map[string]interface{})
     doneChan := make(chan bool)
@@ -174,6 +282,14 @@ func deployEvents(c *gin.Context) {
     go func() {
         defer close(messageChan)
 
+        // 部署任务计数，未到达最终检查的都按失败统计
+        result := "failure"
+        deployJobsRunning.Inc()
+        defer func() {
+            deployJobsRunning.Dec()
+            deployJobsTotal.WithLabelValues(result).Inc()
+        }()
+
           sendMessage := func(msgType, msg string) {
             select {
             case <-doneChan: // 检查是否已完成
@@ -186,7 +302,7 @@ func deployEvents(c *gin.Context) {
             }
         }
 
-        projectDir := filepath.Join("data", "project", projectName)
+        projectDir := filepath.Join(projectRoot(host), projectName)
         composePath := filepath.Join(projectDir, "docker-compose.yml")
 		
         // 检查项目目录是否已存在
@@ -215,8 +331,14 @@ func deployEvents(c *gin.Context) {
         sendMessage("info", "正在启动服务...")
 
         // 使用 docker compose 命令
+        env, err := docker.ComposeEnvForHost(host)
+        if err != nil {
+            sendMessage("error", "连接Docker失败: "+err.Error())
+            return
+        }
         cmd := exec.Command("docker", "compose", "up", "-d")
         cmd.Dir = projectDir
+        cmd.Env = env
         
         // 获取命令的标准输出和错误输出管道
         stdout, err := cmd.StdoutPipe()
@@ -270,12 +392,11 @@ func deployEvents(c *gin.Context) {
         }
 
         // 检查容器状态
-        cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+        cli, err := docker.SharedClient(host)
         if err != nil {
             sendMessage("error", "Docker客户端初始化失败: "+err.Error())
             return
         }
-        defer cli.Close()
 
         containers, err := cli.ContainerList(context.Background(), types.ContainerListOptions{
             All: true,
@@ -298,8 +419,10 @@ func deployEvents(c *gin.Context) {
         }
 
         if allRunning {
+            result = "success"
             sendMessage("success", "所有服务已成功启动")
         } else {
+            result = "warning"
             sendMessage("warning", "部分服务可能未正常启动，请检查状态")
         }
     }()
@@ -322,12 +445,11 @@ func deployEvents(c *gin.Context) {
 // getStackStatus 获取堆栈状态
 func getStackStatus(c *gin.Context) {
     name := c.Param("name")
-    cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+    cli, err := dockerClient(c)
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
     }
-    defer cli.Close()
 
     // 获取项目的所有容器
     containers, err := cli.ContainerList(context.Background(), types.ContainerListOptions{
@@ -341,26 +463,37 @@ func getStackStatus(c *gin.Context) {
         return
     }
 	
-    // 转换容器信息为前端需要的格式
-    containerList := make([]map[string]interface{}, 0)
+    // 以有限并发获取运行中容器的资源统计
+    var running []string
     for _, container := range containers {
-        // 获取容器详细信息
-        stats, err := cli.ContainerStats(context.Background(), container.ID, false)
-        if err != nil {
-            continue
+        if container.State == "running" {
+            running = append(running, container.ID)
         }
-        defer stats.Body.Close()
+    }
+    ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+    defer cancel()
+    collected := cli.CollectStats(ctx, running)
 
+    // 转换容器信息为前端需要的格式
+    containerList := make([]map[string]interface{}, 0)
+    for _, container := range containers {
         containerInfo := map[string]interface{}{
             "name": strings.TrimPrefix(container.Names[0], "/"),
             "image": container.Image,
             "status": container.State,
             "state": container.State,
-            "cpu": "0%",  // 这里可以通过解析 stats 获取实际值
-            "memory": "0 MB",  // 这里可以通过解析 stats 获取实际值
+            "cpu": "0%",
+            "memory": "0 B",
             "networkRx": "0 B",
             "networkTx": "0 B",
         }
+        if stats, ok := collected[container.ID]; ok {
+            rx, tx := docker.NetworkIO(&stats)
+            containerInfo["cpu"] = fmt.Sprintf("%.2f%%", docker.CPUPercent(&stats))
+            containerInfo["memory"] = units.BytesSize(docker.MemoryUsage(&stats))
+            containerInfo["networkRx"] = units.BytesSize(float64(rx))
+            containerInfo["networkTx"] = units.BytesSize(float64(tx))
+        }
         containerList = append(containerList, containerInfo)
     }
 
@@ -372,11 +505,21 @@ func getStackStatus(c *gin.Context) {
 // removeStack 函数
 func removeProject(c *gin.Context) {
     name := c.Param("name")  // 修改这里，使用 name 而不是 stack
-    projectDir := filepath.Join("data", "project", name)
     
     // 使用 docker compose down 命令停止并删除容器
+    env, err := composeEnv(c)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "连接Docker失败: " + err.Error()})
+        return
+    }
+    projectDir, err := composeProjectDir(c, name)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
     cmd := exec.Command("docker", "compose", "down")
     cmd.Dir = projectDir
+    cmd.Env = env
     
     if output, err := cmd.CombinedOutput(); err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{
@@ -398,12 +541,11 @@ func removeProject(c *gin.Context) {
 // 添加获取 compose 日志的处理函数
 func getComposeLogs(c *gin.Context) {
     name := c.Param("name")
-    cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+    cli, err := dockerClient(c)
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
     }
-    defer cli.Close()
 
     // 获取项目的所有容器
     containers, err := cli.ContainerList(context.Background(), types.ContainerListOptions{
@@ -421,7 +563,6 @@ func getComposeLogs(c *gin.Context) {
     c.Writer.Header().Set("Content-Type", "text/event-stream")
     c.Writer.Header().Set("Cache-Control", "no-cache")
     c.Writer.Header().Set("Connection", "keep-alive")
-    c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 
     // 创建一个通道来接收所有容器的日志
     logsChan := make(chan string)
@@ -481,7 +622,11 @@ func getComposeLogs(c *gin.Context) {
 // 添加获取 YAML 配置的处理函数
 func getProjectYaml(c *gin.Context) {
     name := c.Param("name")
-    projectDir := filepath.Join("data", "project", name)
+    projectDir, err := composeProjectDir(c, name)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
     yamlPath := filepath.Join(projectDir, "docker-compose.yml")
     
     // 读取 YAML 文件
@@ -508,7 +653,11 @@ func saveProjectYaml(c *gin.Context) {
         return
     }
     
-    projectDir := filepath.Join("data", "project", name)
+    projectDir, err := composeProjectDir(c, name)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
     yamlPath := filepath.Join(projectDir, "docker-compose.yml")
     
     // 保存 YAML 文件
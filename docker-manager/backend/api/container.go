@@ -3,14 +3,18 @@ package api // 必须声明包名
 
 import (
     "context"
+    "dockerpanel/backend/pkg/database"
     "dockerpanel/backend/pkg/docker"
     "fmt"
+    "log"
     "net/http"
     "regexp"
     "strings"
     "time"
     "github.com/docker/docker/api/types"
     "github.com/docker/docker/api/types/container"
+    "github.com/docker/docker/api/types/network"
+    "github.com/docker/go-connections/nat"
     "github.com/gin-gonic/gin"
 )
 
@@ -19,6 +23,8 @@ func RegisterContainerRoutes(r *gin.Engine) {
     group := r.Group("/api/containers")
     {
         group.GET("", ListContainers)
+        group.POST("/create", createContainer)
+        group.POST("/:id/recreate", recreateContainer)
         group.POST("/:id/start", startContainer)
         group.POST("/:id/stop", stopContainer)
         group.POST("/:id/restart", restartContainer)
@@ -32,12 +38,11 @@ func RegisterContainerRoutes(r *gin.Engine) {
 
 // 容器列表
 func ListContainers(c *gin.Context) {
-    cli, err := docker.NewDockerClient()
+    cli, err := dockerClient(c)
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
     }
-    defer cli.Close()
 
     containers, err := cli.ContainerList(context.Background(), types.ContainerListOptions{All: true})
     if err != nil {
@@ -45,13 +50,32 @@ func ListContainers(c *gin.Context) {
         return
     }
 
-    // 获取每个容器的详细信息
+    // 日志大小提示阈值，可通过 logThreshold 参数临时指定
+    threshold := c.Query("logThreshold")
+    if threshold == "" {
+        threshold, _ = database.GetSetting(database.SettingLogSizeWarning, "100m")
+    }
+    logLimit, err := docker.ParseLogSize(threshold)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "无效的日志大小阈值: " + err.Error()})
+        return
+    }
+
+    // 日志文件在容器所在主机上，只有本机可以统计大小
+    host, _ := selectedHost(c)
+    countLogSize := host == nil
+
+    // 以有限并发获取每个容器的详细信息，共享客户端会缓存结果直到容器发生变化
+    ids := make([]string, len(containers))
+    for i, container := range containers {
+        ids[i] = container.ID
+    }
+    inspects := cli.InspectContainers(c.Request.Context(), ids)
+
     var containersWithDetails []gin.H
     for _, container := range containers {
-        inspect, err := cli.ContainerInspect(context.Background(), container.ID)
-        if err != nil {
-            continue
-        }
+        // 获取详情失败时（例如容器刚被删除或守护进程暂时出错）仍用列表中的信息返回该容器
+        inspect, inspected := inspects[container.ID]
 
         // 处理端口映射，添加 IP 地址
         formattedPorts := make([]gin.H, 0)
@@ -76,7 +100,9 @@ func ListContainers(c *gin.Context) {
 
         // 计算运行时间
         var runningTime string
-        if container.State == "running" {
+        if container.State == "running" && !inspected {
+            runningTime = container.Status
+        } else if container.State == "running" {
             startTime, err := time.Parse(time.RFC3339, inspect.State.StartedAt)
             if err != nil {
                 runningTime = "时间解析错误"
@@ -95,10 +121,24 @@ func ListContainers(c *gin.Context) {
             "Status":         container.Status,
             "Created":        container.Created,
             "Ports":          formattedPorts,
-            "NetworkSettings": inspect.NetworkSettings,  // 使用 inspect 中的网络设置
-            "HostConfig":     inspect.HostConfig,       // 添加 HostConfig
             "RunningTime":    runningTime,
         }
+        if inspected {
+            containerInfo["NetworkSettings"] = inspect.NetworkSettings // 使用 inspect 中的网络设置
+            containerInfo["HostConfig"] = inspect.HostConfig
+        } else {
+            containerInfo["NetworkSettings"] = container.NetworkSettings
+            containerInfo["HostConfig"] = gin.H{"NetworkMode": container.HostConfig.NetworkMode}
+            containerInfo["InspectFailed"] = true
+        }
+
+        // json-file 日志保存在宿主机上，可以直接统计大小
+        if countLogSize && inspected && inspect.HostConfig != nil && inspect.HostConfig.LogConfig.Type == "json-file" {
+            if size, err := docker.ContainerLogSize(inspect.LogPath); err == nil {
+                containerInfo["LogSize"] = size
+                containerInfo["LogTooLarge"] = size > logLimit
+            }
+        }
         containersWithDetails = append(containersWithDetails, containerInfo)
     }
 
@@ -108,12 +148,11 @@ func ListContainers(c *gin.Context) {
 
 // 重启容器
 func restartContainer(c *gin.Context) {
-    cli, err := docker.NewDockerClient()
+    cli, err := dockerClient(c)
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
     }
-    defer cli.Close()
 
     id := c.Param("id")
     if err := cli.ContainerRestart(context.Background(), id, container.StopOptions{}); err != nil {
@@ -126,12 +165,11 @@ func restartContainer(c *gin.Context) {
 
 // 暂停容器
 func pauseContainer(c *gin.Context) {
-    cli, err := docker.NewDockerClient()
+    cli, err := dockerClient(c)
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
     }
-    defer cli.Close()
 
     id := c.Param("id")
     if err := cli.ContainerPause(context.Background(), id); err != nil {
@@ -144,12 +182,11 @@ func pauseContainer(c *gin.Context) {
 
 // 恢复容器
 func unpauseContainer(c *gin.Context) {
-    cli, err := docker.NewDockerClient()
+    cli, err := dockerClient(c)
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
     }
-    defer cli.Close()
 
     id := c.Param("id")
     if err := cli.ContainerUnpause(context.Background(), id); err != nil {
@@ -162,12 +199,11 @@ func unpauseContainer(c *gin.Context) {
 
 // 启动容器
 func startContainer(c *gin.Context) {
-    cli, err := docker.NewDockerClient()
+    cli, err := dockerClient(c)
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": "无法连接到 Docker: " + err.Error()})
         return
     }
-    defer cli.Close()
 
     id := c.Param("id")
     // 先检查容器是否存在
@@ -240,12 +276,11 @@ func startContainer(c *gin.Context) {
 
 // 停止容器
 func stopContainer(c *gin.Context) {
-    cli, err := docker.NewDockerClient()
+    cli, err := dockerClient(c)
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
     }
-    defer cli.Close()
 
     id := c.Param("id")
     // 先检查容器是否存在
@@ -270,12 +305,11 @@ func stopContainer(c *gin.Context) {
 // 删除容器
 func removeContainer(c *gin.Context) {
 
-    cli, err := docker.NewDockerClient()
+    cli, err := dockerClient(c)
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
     }
-    defer cli.Close()
 
     id := c.Param("id")
     err = cli.ContainerStop(context.Background(), id, container.StopOptions{
@@ -295,7 +329,139 @@ func removeContainer(c *gin.Context) {
     c.JSON(http.StatusOK, gin.H{"message": "容器已删除"})
 }
 
+// 新建容器请求
+type createContainerRequest struct {
+    Name     string            `json:"name"`
+    Image    string            `json:"image" binding:"required"`
+    Cmd      []string          `json:"cmd"`
+    Env      []string          `json:"env"`      // KEY=VALUE
+    Ports    []string          `json:"ports"`    // 宿主机端口:容器端口[/协议]
+    Volumes  []string          `json:"volumes"`  // 宿主机路径:容器路径[:ro]
+    Networks []string          `json:"networks"`
+    Restart  string            `json:"restart"`  // no / always / unless-stopped / on-failure
+    Labels   map[string]string `json:"labels"`
+    Log      *docker.LogConfig `json:"log"`      // 为空时使用 daemon.json 中的默认日志配置
+    Start    *bool             `json:"start"`    // 创建后是否启动，默认 true
+}
+
 // 新建容器
 func createContainer(c *gin.Context) {
-    // 实现创建逻辑...
+    var req createContainerRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据: " + err.Error()})
+        return
+    }
+    if req.Log != nil {
+        if err := req.Log.Validate(); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "日志配置无效: " + err.Error()})
+            return
+        }
+    }
+
+    exposed, bindings, err := nat.ParsePortSpecs(req.Ports)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "端口映射格式错误: " + err.Error()})
+        return
+    }
+
+    cli, err := dockerClient(c)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+
+    ctx := context.Background()
+    config := &container.Config{
+        Image:        req.Image,
+        Cmd:          req.Cmd,
+        Env:          req.Env,
+        Labels:       req.Labels,
+        ExposedPorts: exposed,
+    }
+    hostConfig := &container.HostConfig{
+        Binds:         req.Volumes,
+        PortBindings:  bindings,
+        RestartPolicy: container.RestartPolicy{Name: req.Restart},
+    }
+    if req.Log != nil {
+        hostConfig.LogConfig = req.Log.HostLogConfig()
+    }
+    networkingConfig := &network.NetworkingConfig{
+        EndpointsConfig: make(map[string]*network.EndpointSettings),
+    }
+    for i, name := range req.Networks {
+        if i == 0 {
+            hostConfig.NetworkMode = container.NetworkMode(name)
+        }
+        networkingConfig.EndpointsConfig[name] = &network.EndpointSettings{}
+    }
+
+    resp, err := cli.ContainerCreate(ctx, config, hostConfig, networkingConfig, nil, req.Name)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "创建容器失败: " + err.Error()})
+        return
+    }
+
+    if req.Start == nil || *req.Start {
+        if err := cli.ContainerStart(ctx, resp.ID, types.ContainerStartOptions{}); err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "容器已创建但启动失败: " + err.Error(), "id": resp.ID})
+            return
+        }
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message":  "容器已创建",
+        "id":       resp.ID,
+        "warnings": resp.Warnings,
+    })
+}
+
+// 使用原配置重建容器，可覆盖日志配置。
+// 旧容器先重命名保留，新容器创建并启动成功后再删除，失败时恢复旧容器
+func recreateContainer(c *gin.Context) {
+    var req struct {
+        Log *docker.LogConfig `json:"log"` // 为空时沿用原容器的日志配置
+    }
+    if c.Request.ContentLength > 0 {
+        if err := c.ShouldBindJSON(&req); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据: " + err.Error()})
+            return
+        }
+    }
+    if req.Log != nil {
+        if err := req.Log.Validate(); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "日志配置无效: " + err.Error()})
+            return
+        }
+    }
+
+    cli, err := dockerClient(c)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+
+    ctx := context.Background()
+    old, err := cli.ContainerInspect(ctx, c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "容器不存在: " + err.Error()})
+        return
+    }
+
+    var logOverride *container.LogConfig
+    if req.Log != nil {
+        config := req.Log.HostLogConfig()
+        logOverride = &config
+    }
+    newID, err := cli.RecreateContainer(ctx, old, logOverride)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "重建容器失败: " + err.Error()})
+        return
+    }
+
+    log.Printf("容器 %s 已重建，新 ID: %s", strings.TrimPrefix(old.Name, "/"), newID)
+    c.JSON(http.StatusOK, gin.H{
+        "message": "容器已重建",
+        "id":      newID,
+    })
 }
@@ -0,0 +1,394 @@
+package api
+
+import (
+    "context"
+    "log"
+    "net/http"
+    "strings"
+    "sync"
+    "time"
+
+    "dockerpanel/backend/pkg/docker"
+    "github.com/docker/docker/api/types"
+    "github.com/gin-gonic/gin"
+)
+
+// RegisterDaemonRoutes 注册 daemon.json 编辑相关路由
+func RegisterDaemonRoutes(r *gin.Engine) {
+    group := r.Group("/api/daemon-config")
+    {
+        group.GET("", getDaemonConfig)
+        group.PUT("", updateDaemonConfig)
+        group.POST("/validate", validateDaemonConfig)
+        group.GET("/schema", getDaemonConfigSchema)
+        group.GET("/backups", listDaemonBackups)
+        group.GET("/backups/:name", getDaemonBackup)
+        group.POST("/backups/:name/restore", restoreDaemonBackup)
+        group.POST("/restart", restartDockerDaemon)
+    }
+}
+
+type daemonConfigRequest struct {
+    Config map[string]interface{} `json:"config"`
+    DryRun bool                   `json:"dryRun"`
+}
+
+// 获取当前 daemon.json 的内容
+func getDaemonConfig(c *gin.Context) {
+    path, err := docker.GetDaemonConfigPath()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+
+    config, raw, err := docker.ReadDaemonConfigRaw()
+    if err != nil {
+        // 文件内容损坏时仍返回原始内容，便于在编辑器中修复
+        c.JSON(http.StatusOK, gin.H{
+            "path":  path,
+            "raw":   string(raw),
+            "error": err.Error(),
+        })
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "path":       path,
+        "config":     config,
+        "raw":        string(raw),
+        "validation": docker.ValidateDaemonConfig(config),
+    })
+}
+
+// 解析请求中的配置，返回校验结果以及与当前文件的差异
+func prepareDaemonConfig(c *gin.Context) (*daemonConfigRequest, docker.DaemonValidation, []byte, string, bool) {
+    var req daemonConfigRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据: " + err.Error()})
+        return nil, docker.DaemonValidation{}, nil, "", false
+    }
+    if req.Config == nil {
+        req.Config = map[string]interface{}{}
+    }
+
+    // 经过一次序列化与解析，使数字类型与读取文件时一致
+    data, err := docker.MarshalDaemonConfig(req.Config)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "序列化配置失败: " + err.Error()})
+        return nil, docker.DaemonValidation{}, nil, "", false
+    }
+    if req.Config, err = docker.ParseDaemonConfig(data); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return nil, docker.DaemonValidation{}, nil, "", false
+    }
+
+    _, current, err := docker.ReadDaemonConfigRaw()
+    if err != nil && current == nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return nil, docker.DaemonValidation{}, nil, "", false
+    }
+
+    validation := docker.ValidateDaemonConfig(req.Config)
+    return &req, validation, data, docker.DiffDaemonConfig(current, data), true
+}
+
+// 校验配置并返回差异，不写入文件
+func validateDaemonConfig(c *gin.Context) {
+    _, validation, _, diff, ok := prepareDaemonConfig(c)
+    if !ok {
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{
+        "validation": validation,
+        "diff":       diff,
+        "changed":    diff != "",
+    })
+}
+
+// 写入完整的 daemon.json。dryRun 为 true 时只返回校验结果和差异
+func updateDaemonConfig(c *gin.Context) {
+    req, validation, _, diff, ok := prepareDaemonConfig(c)
+    if !ok {
+        return
+    }
+
+    if !validation.Valid {
+        c.JSON(http.StatusBadRequest, gin.H{
+            "error":      "配置校验失败",
+            "validation": validation,
+            "diff":       diff,
+        })
+        return
+    }
+    if req.DryRun || diff == "" {
+        c.JSON(http.StatusOK, gin.H{
+            "validation": validation,
+            "diff":       diff,
+            "changed":    diff != "",
+        })
+        return
+    }
+
+    backup, err := docker.WriteDaemonConfig(req.Config)
+    if err != nil {
+        log.Printf("写入 daemon.json 失败: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "写入配置失败: " + err.Error()})
+        return
+    }
+    log.Printf("daemon.json 已更新，备份: %s", backup)
+
+    c.JSON(http.StatusOK, gin.H{
+        "message":    "daemon.json 已更新，重启 Docker 服务后生效",
+        "validation": validation,
+        "diff":       diff,
+        "changed":    true,
+        "backup":     backup,
+    })
+}
+
+// 返回支持的配置项定义，供前端编辑器提示
+func getDaemonConfigSchema(c *gin.Context) {
+    c.JSON(http.StatusOK, docker.DaemonOptions)
+}
+
+// 列出 daemon.json 的备份
+func listDaemonBackups(c *gin.Context) {
+    backups, err := docker.ListDaemonBackups()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "获取备份列表失败: " + err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, backups)
+}
+
+// 查看备份内容及其与当前配置的差异
+func getDaemonBackup(c *gin.Context) {
+    data, err := docker.ReadDaemonBackup(c.Param("name"))
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+        return
+    }
+    _, current, _ := docker.ReadDaemonConfigRaw()
+    c.JSON(http.StatusOK, gin.H{
+        "name": c.Param("name"),
+        "raw":  string(data),
+        "diff": docker.DiffDaemonConfig(current, data),
+    })
+}
+
+// 用备份恢复 daemon.json
+func restoreDaemonBackup(c *gin.Context) {
+    name := c.Param("name")
+    data, err := docker.ReadDaemonBackup(name)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+        return
+    }
+    _, current, _ := docker.ReadDaemonConfigRaw()
+    diff := docker.DiffDaemonConfig(current, data)
+
+    backup, err := docker.RestoreDaemonBackup(name)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "恢复备份失败: " + err.Error()})
+        return
+    }
+    log.Printf("已从备份 %s 恢复 daemon.json，恢复前的配置备份为 %s", name, backup)
+
+    c.JSON(http.StatusOK, gin.H{
+        "message": "已恢复备份，重启 Docker 服务后生效",
+        "diff":    diff,
+        "backup":  backup,
+    })
+}
+
+// 同一时间只允许一个重启操作
+var daemonRestartMu sync.Mutex
+
+// 重启后的容器状态
+type restartedContainer struct {
+    ID            string `json:"id"`
+    Name          string `json:"name"`
+    WasRunning    bool   `json:"wasRunning"`
+    Running       bool   `json:"running"`
+    State         string `json:"state"`
+    RestartPolicy string `json:"restartPolicy"`
+}
+
+// 重启或重载 Docker 守护进程使 daemon.json 生效。
+// 守护进程未能恢复且 rollback 不为 false 时，恢复本次要生效的修改之前的 daemon.json 并再次重启
+func restartDockerDaemon(c *gin.Context) {
+    var req struct {
+        Mode     string `json:"mode"`     // restart 或 reload，默认 restart
+        Rollback *bool  `json:"rollback"` // 失败时是否回滚，默认 true
+        Timeout  int    `json:"timeout"`  // 等待守护进程恢复的秒数，默认 60
+    }
+    if c.Request.ContentLength > 0 {
+        if err := c.ShouldBindJSON(&req); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据: " + err.Error()})
+            return
+        }
+    }
+    if req.Mode == "" {
+        req.Mode = docker.DaemonRestart
+    }
+    if req.Mode != docker.DaemonRestart && req.Mode != docker.DaemonReload {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "mode 只能为 restart 或 reload"})
+        return
+    }
+    rollback := req.Rollback == nil || *req.Rollback
+    timeout := time.Duration(req.Timeout) * time.Second
+    if timeout <= 0 {
+        timeout = 60 * time.Second
+    }
+
+    if !daemonRestartMu.TryLock() {
+        c.JSON(http.StatusConflict, gin.H{"error": "Docker 正在重启中"})
+        return
+    }
+    defer daemonRestartMu.Unlock()
+
+    cli, err := docker.NewDockerClient()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    defer cli.Close()
+
+    // 记录重启前的容器状态
+    before, err := listContainerStates(cli, nil)
+    if err != nil {
+        log.Printf("获取重启前的容器状态失败: %v", err)
+    }
+
+    // 上次成功重启以来第一次修改前的 daemon.json，即守护进程当前使用的配置
+    previous := docker.PendingDaemonBackup()
+
+    start := time.Now()
+    if err := restartAndWait(req.Mode, timeout); err != nil {
+        log.Printf("Docker %s失败: %v", req.Mode, err)
+        response := gin.H{
+            "error":      "Docker 未能恢复: " + err.Error(),
+            "rolledBack": false,
+        }
+        if rollback && previous != "" {
+            response["rollbackBackup"] = previous
+            if _, restoreErr := docker.RestoreDaemonBackup(previous); restoreErr != nil {
+                response["rollbackError"] = "恢复备份失败: " + restoreErr.Error()
+            } else if restartErr := restartAndWait(docker.DaemonRestart, timeout); restartErr != nil {
+                response["rollbackError"] = "回滚后 Docker 仍未恢复: " + restartErr.Error()
+            } else {
+                log.Printf("已回滚 daemon.json 到 %s，Docker 已恢复", previous)
+                docker.MarkDaemonConfigApplied()
+                response["rolledBack"] = true
+            }
+        }
+        c.JSON(http.StatusInternalServerError, response)
+        return
+    }
+    downtime := time.Since(start)
+    // 重载只让部分配置生效，之后重启失败时仍要回滚到修改前的配置
+    if req.Mode == docker.DaemonRestart {
+        docker.MarkDaemonConfigApplied()
+    }
+
+    // 等待设置了重启策略的容器恢复运行
+    containers := waitForContainers(cli, before, 15*time.Second)
+    recovered, expected := 0, 0
+    for _, container := range containers {
+        if container.WasRunning {
+            expected++
+            if container.Running {
+                recovered++
+            }
+        }
+    }
+
+    message := "Docker 已重启"
+    if req.Mode == docker.DaemonReload {
+        message = "Docker 已重载配置"
+    }
+    c.JSON(http.StatusOK, gin.H{
+        "message":    message,
+        "mode":       req.Mode,
+        "durationMs": downtime.Milliseconds(),
+        "containers": containers,
+        "recovered":  recovered,
+        "expected":   expected,
+    })
+}
+
+func restartAndWait(mode string, timeout time.Duration) error {
+    ctx, cancel := context.WithTimeout(context.Background(), timeout)
+    defer cancel()
+
+    if err := docker.RestartDaemon(ctx, mode); err != nil {
+        return err
+    }
+    return docker.WaitForDaemon(ctx)
+}
+
+// 列出所有容器的状态，以容器 ID 为键。重启策略需要逐个 inspect 获取，
+// known 中已有且状态未变的容器直接沿用，轮询时只 inspect 新出现或状态变化的容器
+func listContainerStates(cli *docker.Client, known map[string]restartedContainer) (map[string]restartedContainer, error) {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+    list, err := cli.ContainerList(ctx, types.ContainerListOptions{All: true})
+    if err != nil {
+        return nil, err
+    }
+
+    states := make(map[string]restartedContainer, len(list))
+    for _, item := range list {
+        name := item.ID[:12]
+        if len(item.Names) > 0 {
+            name = strings.TrimPrefix(item.Names[0], "/")
+        }
+        state := restartedContainer{
+            ID:      item.ID,
+            Name:    name,
+            Running: item.State == "running",
+            State:   item.State,
+        }
+        if prev, ok := known[item.ID]; ok && prev.State == item.State {
+            state.RestartPolicy = prev.RestartPolicy
+        } else if inspect, err := cli.ContainerInspect(ctx, item.ID); err == nil && inspect.HostConfig != nil {
+            state.RestartPolicy = string(inspect.HostConfig.RestartPolicy.Name)
+        }
+        states[item.ID] = state
+    }
+    return states, nil
+}
+
+// 轮询容器状态，直到重启前运行的容器都已恢复或超时
+func waitForContainers(cli *docker.Client, before map[string]restartedContainer, timeout time.Duration) []restartedContainer {
+    deadline := time.Now().Add(timeout)
+    var after map[string]restartedContainer
+    known := before
+    for {
+        states, err := listContainerStates(cli, known)
+        if err == nil {
+            after, known = states, states
+            pending := false
+            for id, prev := range before {
+                if prev.Running && !after[id].Running {
+                    pending = true
+                    break
+                }
+            }
+            if !pending {
+                break
+            }
+        }
+        if time.Now().After(deadline) {
+            break
+        }
+        time.Sleep(time.Second)
+    }
+
+    result := make([]restartedContainer, 0, len(after))
+    for id, container := range after {
+        container.WasRunning = before[id].Running
+        result = append(result, container)
+    }
+    return result
+}
@@ -0,0 +1,18 @@
+package api
+
+import (
+    "path/filepath"
+    "testing"
+
+    "dockerpanel/backend/pkg/database"
+)
+
+// openTestDB 在临时目录中初始化数据库
+func openTestDB(t *testing.T) {
+    t.Helper()
+    dir := t.TempDir()
+    t.Setenv("DOCKERPANEL_SECRET_KEY", "test")
+    if err := database.InitDB(filepath.Join(dir, "test.db")); err != nil {
+        t.Fatalf("初始化数据库失败: %v", err)
+    }
+}
@@ -0,0 +1,288 @@
+package api
+
+import (
+    "log"
+    "net/http"
+    "os"
+    "strconv"
+
+    "dockerpanel/backend/pkg/database"
+    "dockerpanel/backend/pkg/docker"
+    "github.com/gin-gonic/gin"
+)
+
+// RegisterDockerConfigRoutes 注册 docker CLI config.json 凭据同步相关路由
+func RegisterDockerConfigRoutes(r *gin.Engine) {
+    group := r.Group("/api/docker-config")
+    {
+        group.GET("", getDockerConfigStatus)
+        group.PUT("", updateDockerConfigSettings)
+        group.POST("/export", exportDockerConfigCredentials)
+        group.POST("/import", importDockerConfigCredentials)
+    }
+}
+
+// 获取 config.json 状态及其中保存的凭据（不返回密码）
+func getDockerConfigStatus(c *gin.Context) {
+    config, err := docker.LoadDockerConfig(docker.DockerConfigDir())
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+
+    registries, err := database.GetAllRegistries()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "获取镜像注册表配置失败: " + err.Error()})
+        return
+    }
+    panelServers := make(map[string]*database.Registry)
+    for _, reg := range registries {
+        panelServers[docker.ConfigServerKey(reg.URL)] = reg
+    }
+
+    servers := make([]gin.H, 0)
+    for _, server := range config.Servers() {
+        item := gin.H{"server": server}
+        cred, err := config.GetCredential(server)
+        if err != nil {
+            item["error"] = err.Error()
+        } else if cred != nil {
+            item["username"] = cred.Username
+            item["helper"] = cred.Helper
+        }
+        if reg, ok := panelServers[server]; ok {
+            item["registry_id"] = reg.ID
+            item["in_sync"] = cred != nil && cred.Username == reg.Username && cred.Password == reg.Password
+        }
+        servers = append(servers, item)
+    }
+
+    _, statErr := os.Stat(config.Path())
+    c.JSON(http.StatusOK, gin.H{
+        "path":        config.Path(),
+        "exists":      statErr == nil,
+        "credsStore":  config.CredsStore,
+        "credHelpers": config.CredHelpers,
+        "sync":        database.GetBoolSetting(database.SettingDockerConfigSync, false),
+        "servers":     servers,
+    })
+}
+
+// 开启或关闭自动同步，开启时立即导出一次面板中的凭据
+func updateDockerConfigSettings(c *gin.Context) {
+    var req struct {
+        Sync bool `json:"sync"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据: " + err.Error()})
+        return
+    }
+
+    if err := database.SetSetting(database.SettingDockerConfigSync, strconv.FormatBool(req.Sync)); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "保存设置失败: " + err.Error()})
+        return
+    }
+
+    response := gin.H{"message": "设置已保存", "sync": req.Sync}
+    if req.Sync {
+        registries, err := database.GetAllRegistries()
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "获取镜像注册表配置失败: " + err.Error()})
+            return
+        }
+        exported, err := writeRegistriesToDockerConfig(registries)
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "写入 config.json 失败: " + err.Error()})
+            return
+        }
+        response["exported"] = exported
+    }
+    c.JSON(http.StatusOK, response)
+}
+
+// 将面板中的注册表凭据写入 config.json
+func exportDockerConfigCredentials(c *gin.Context) {
+    registries, err := database.GetAllRegistries()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "获取镜像注册表配置失败: " + err.Error()})
+        return
+    }
+
+    exported, err := writeRegistriesToDockerConfig(registries)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "写入 config.json 失败: " + err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{
+        "message":  "凭据已写入 config.json",
+        "exported": exported,
+    })
+}
+
+// 从 config.json 导入凭据，已存在的注册表更新账号密码，不存在的新建。
+// 请求体可选，servers 为空时导入全部
+func importDockerConfigCredentials(c *gin.Context) {
+    var req struct {
+        Servers []string `json:"servers"`
+    }
+    if c.Request.ContentLength > 0 {
+        if err := c.ShouldBindJSON(&req); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据: " + err.Error()})
+            return
+        }
+    }
+
+    config, err := docker.LoadDockerConfig(docker.DockerConfigDir())
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    servers := req.Servers
+    if len(servers) == 0 {
+        servers = config.Servers()
+    }
+
+    registries, err := database.GetAllRegistries()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "获取镜像注册表配置失败: " + err.Error()})
+        return
+    }
+    byServer := make(map[string]*database.Registry)
+    for _, reg := range registries {
+        byServer[docker.ConfigServerKey(reg.URL)] = reg
+    }
+
+    imported := make([]string, 0)
+    failed := make(map[string]string)
+    for _, server := range servers {
+        key := docker.ConfigServerKey(server)
+        cred, err := config.GetCredential(key)
+        if err != nil {
+            failed[key] = err.Error()
+            continue
+        }
+        if cred == nil {
+            continue
+        }
+
+        // GetAllRegistries 会补一条未保存的 Docker Hub 默认项（ID 为 0），需要新建
+        if reg, ok := byServer[key]; ok && reg.ID != 0 {
+            reg.Username = cred.Username
+            reg.Password = cred.Password
+            err = database.UpdateRegistry(reg)
+        } else {
+            url := key
+            if key == docker.ConfigServerKey("docker.io") {
+                url = "docker.io"
+            }
+            err = database.CreateRegistry(&database.Registry{
+                Name:      url,
+                URL:       url,
+                Username:  cred.Username,
+                Password:  cred.Password,
+                IsDefault: url == "docker.io",
+            })
+        }
+        if err != nil {
+            failed[key] = err.Error()
+            continue
+        }
+        imported = append(imported, key)
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message":  "导入完成",
+        "imported": imported,
+        "failed":   failed,
+    })
+}
+
+// 将带凭据的注册表写入 config.json，返回写入的服务器列表
+func writeRegistriesToDockerConfig(registries map[string]*database.Registry) ([]string, error) {
+    config, err := docker.LoadDockerConfig(docker.DockerConfigDir())
+    if err != nil {
+        return nil, err
+    }
+
+    exported := make([]string, 0)
+    for _, reg := range registries {
+        if reg.Username == "" || reg.Password == "" {
+            continue
+        }
+        if err := config.SetCredential(reg.URL, reg.Username, reg.Password); err != nil {
+            return nil, err
+        }
+        exported = append(exported, docker.ConfigServerKey(reg.URL))
+    }
+    if err := config.Save(); err != nil {
+        return nil, err
+    }
+    return exported, nil
+}
+
+// 开启自动同步时，在注册表保存后把凭据写入 config.json。
+// 同步失败不影响面板中的保存结果，只记录日志
+func syncRegistriesToDockerConfig(registries ...*database.Registry) {
+    if !database.GetBoolSetting(database.SettingDockerConfigSync, false) {
+        return
+    }
+
+    byURL := make(map[string]*database.Registry, len(registries))
+    for _, reg := range registries {
+        // 接口中传入的密码可能为空（保持不变），以数据库中的为准
+        if saved, err := database.GetRegistry(reg.ID); err == nil {
+            reg = saved
+        }
+        byURL[reg.URL] = reg
+    }
+    if _, err := writeRegistriesToDockerConfig(byURL); err != nil {
+        log.Printf("同步注册表凭据到 config.json 失败: %v", err)
+    }
+}
+
+// 开启自动同步时，从 config.json 中删除已在面板中删除或改了地址的注册表凭据。
+// 与保存时一样，失败只记录日志
+func removeRegistriesFromDockerConfig(urls ...string) {
+    if len(urls) == 0 || !database.GetBoolSetting(database.SettingDockerConfigSync, false) {
+        return
+    }
+
+    config, err := docker.LoadDockerConfig(docker.DockerConfigDir())
+    if err != nil {
+        log.Printf("读取 config.json 失败: %v", err)
+        return
+    }
+    for _, url := range urls {
+        if err := config.RemoveCredential(url); err != nil {
+            log.Printf("从 config.json 删除 %s 的凭据失败: %v", url, err)
+        }
+    }
+    if err := config.Save(); err != nil {
+        log.Printf("保存 config.json 失败: %v", err)
+    }
+}
+
+// 查找注册表凭据：优先使用面板中保存的，没有时回退到 config.json
+func lookupRegistryCredential(registryURL string) (string, string) {
+    registries, err := database.GetAllRegistries()
+    if err == nil {
+        if reg, ok := registries[registryURL]; ok && reg.Username != "" && reg.Password != "" {
+            return reg.Username, reg.Password
+        }
+    }
+
+    config, err := docker.LoadDockerConfig(docker.DockerConfigDir())
+    if err != nil {
+        log.Printf("读取 config.json 失败: %v", err)
+        return "", ""
+    }
+    cred, err := config.GetCredential(registryURL)
+    if err != nil {
+        log.Printf("从 config.json 读取 %s 的凭据失败: %v", registryURL, err)
+        return "", ""
+    }
+    if cred == nil {
+        return "", ""
+    }
+    return cred.Username, cred.Password
+}
@@ -0,0 +1,268 @@
+package api
+
+import (
+    "dockerpanel/backend/pkg/database"
+    "dockerpanel/backend/pkg/docker"
+    "io"
+    "log"
+    "net/http"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/docker/docker/api/types/events"
+    "github.com/gin-gonic/gin"
+    "github.com/gorilla/websocket"
+)
+
+// 实时 Docker 事件，通过 SSE（/api/events）或 WebSocket（/api/events/ws）推送。
+// 查询参数：type 事件类型（逗号分隔，如 container,image），container 容器 ID 或名称，
+// project compose 项目名称，host 主机（与其他接口相同）
+func RegisterEventRoutes(r *gin.Engine) {
+    group := r.Group("/api/events")
+    {
+        group.GET("", streamEvents)
+        group.GET("/ws", streamEventsWebSocket)
+    }
+}
+
+const (
+    // 每个订阅者缓存的事件数，浏览器跟不上时断开连接，由客户端重连后重新加载
+    eventBufferSize = 256
+    // 心跳间隔，避免代理因连接空闲而断开
+    eventHeartbeat = 25 * time.Second
+)
+
+type eventSubscriber struct {
+    hostID    int64
+    types     map[string]bool
+    container string
+    project   string
+    allowed   func(action string, resource *database.Resource) bool
+    ch        chan gin.H
+}
+
+// 按订阅条件和访问权限判断是否推送该事件
+func (s *eventSubscriber) matches(event events.Message) bool {
+    if len(s.types) > 0 && !s.types[string(event.Type)] {
+        return false
+    }
+    attrs := event.Actor.Attributes
+    if s.container != "" {
+        if event.Type != events.ContainerEventType {
+            return false
+        }
+        if !strings.HasPrefix(event.Actor.ID, s.container) && attrs["name"] != s.container {
+            return false
+        }
+    }
+    project := attrs["com.docker.compose.project"]
+    if s.project != "" && project != s.project {
+        return false
+    }
+
+    // 容器事件的属性中带有容器标签，按项目和标签授权判断；其他事件需要全局查看权限
+    var resource *database.Resource
+    if event.Type == events.ContainerEventType {
+        resource = &database.Resource{Project: project, Labels: attrs}
+    }
+    return s.allowed(database.ActionRead, resource)
+}
+
+type eventHub struct {
+    mu          sync.Mutex
+    subscribers map[*eventSubscriber]struct{}
+}
+
+var hub = &eventHub{subscribers: make(map[*eventSubscriber]struct{})}
+
+// StartEventHub 接收所有主机的 Docker 事件并分发给订阅者和告警引擎。
+// 本机的事件监听随即启动，远程主机在首次访问时启动，事件流中断后自动重连
+func StartEventHub() {
+    docker.SetBroadcastHandler(func(hostID int64, event events.Message) {
+        hub.broadcast(hostID, event)
+        alerts.handleEvent(hostID, event)
+    })
+    docker.SetStreamStatusHandler(hub.streamStatus)
+    if _, err := docker.SharedClient(nil); err != nil {
+        log.Printf("启动事件监听失败: %v", err)
+    }
+}
+
+func (h *eventHub) subscribe(s *eventSubscriber) {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    h.subscribers[s] = struct{}{}
+}
+
+func (h *eventHub) unsubscribe(s *eventSubscriber) {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    if _, ok := h.subscribers[s]; ok {
+        delete(h.subscribers, s)
+        close(s.ch)
+    }
+}
+
+// 向订阅者发送消息，缓冲区已满的订阅者被断开
+func (h *eventHub) send(hostID int64, payload gin.H, match func(*eventSubscriber) bool) {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    for s := range h.subscribers {
+        if s.hostID != hostID || !match(s) {
+            continue
+        }
+        select {
+        case s.ch <- payload:
+        default:
+            log.Printf("事件订阅者处理过慢，断开连接")
+            delete(h.subscribers, s)
+            close(s.ch)
+        }
+    }
+}
+
+func (h *eventHub) broadcast(hostID int64, event events.Message) {
+    attrs := event.Actor.Attributes
+    payload := gin.H{
+        "host":       hostID,
+        "type":       event.Type,
+        "action":     event.Action,
+        "id":         event.Actor.ID,
+        "name":       attrs["name"],
+        "image":      attrs["image"],
+        "project":    attrs["com.docker.compose.project"],
+        "time":       event.TimeNano / int64(time.Millisecond),
+        "attributes": attrs,
+    }
+    h.send(hostID, payload, func(s *eventSubscriber) bool { return s.matches(event) })
+}
+
+// 事件流连接状态也推送给订阅者，重新连接后客户端应重新加载列表
+func (h *eventHub) streamStatus(hostID int64, connected bool, err error) {
+    payload := gin.H{"host": hostID, "type": "stream", "action": "disconnected"}
+    if connected {
+        payload["action"] = "connected"
+    } else if err != nil {
+        payload["error"] = err.Error()
+    }
+    h.send(hostID, payload, func(*eventSubscriber) bool { return true })
+}
+
+// 按请求参数创建订阅者，同时确保所选主机的事件监听已启动
+func newEventSubscriber(c *gin.Context) (*eventSubscriber, int, error) {
+    host, err := selectedHost(c)
+    if err != nil {
+        return nil, http.StatusBadRequest, err
+    }
+    if _, err := docker.SharedClient(host); err != nil {
+        return nil, http.StatusInternalServerError, err
+    }
+    allowed, err := accessChecker(c)
+    if err != nil {
+        return nil, http.StatusInternalServerError, err
+    }
+
+    s := &eventSubscriber{
+        types:     map[string]bool{},
+        container: strings.TrimSpace(c.Query("container")),
+        project:   strings.TrimSpace(c.Query("project")),
+        allowed:   allowed,
+        ch:        make(chan gin.H, eventBufferSize),
+    }
+    if host != nil {
+        s.hostID = host.ID
+    }
+    for _, t := range strings.Split(c.Query("type"), ",") {
+        if t = strings.TrimSpace(t); t != "" {
+            s.types[t] = true
+        }
+    }
+    return s, 0, nil
+}
+
+func streamEvents(c *gin.Context) {
+    s, status, err := newEventSubscriber(c)
+    if err != nil {
+        c.JSON(status, gin.H{"error": "订阅事件失败: " + err.Error()})
+        return
+    }
+    hub.subscribe(s)
+    defer hub.unsubscribe(s)
+
+    c.Header("Content-Type", "text/event-stream")
+    c.Header("Cache-Control", "no-cache")
+    c.Header("Connection", "keep-alive")
+    c.Header("X-Accel-Buffering", "no")
+
+    heartbeat := time.NewTicker(eventHeartbeat)
+    defer heartbeat.Stop()
+
+    c.Stream(func(w io.Writer) bool {
+        select {
+        case <-c.Request.Context().Done():
+            return false
+        case payload, ok := <-s.ch:
+            if !ok {
+                return false
+            }
+            c.SSEvent("message", payload)
+            return true
+        case <-heartbeat.C:
+            io.WriteString(w, ": ping\n\n")
+            return true
+        }
+    })
+}
+
+func streamEventsWebSocket(c *gin.Context) {
+    s, status, err := newEventSubscriber(c)
+    if err != nil {
+        c.JSON(status, gin.H{"error": "订阅事件失败: " + err.Error()})
+        return
+    }
+
+    ws, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+    if err != nil {
+        log.Printf("WebSocket 升级失败: %v", err)
+        return
+    }
+    defer ws.Close()
+
+    hub.subscribe(s)
+    defer hub.unsubscribe(s)
+
+    // 客户端不发送数据，读取只用于发现连接关闭
+    closed := make(chan struct{})
+    go func() {
+        defer close(closed)
+        for {
+            if _, _, err := ws.ReadMessage(); err != nil {
+                return
+            }
+        }
+    }()
+
+    heartbeat := time.NewTicker(eventHeartbeat)
+    defer heartbeat.Stop()
+    for {
+        select {
+        case <-closed:
+            return
+        case payload, ok := <-s.ch:
+            if !ok {
+                ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "处理过慢"))
+                return
+            }
+            ws.SetWriteDeadline(time.Now().Add(10 * time.Second))
+            if err := ws.WriteJSON(payload); err != nil {
+                return
+            }
+        case <-heartbeat.C:
+            ws.SetWriteDeadline(time.Now().Add(10 * time.Second))
+            if err := ws.WriteMessage(websocket.PingMessage, nil); err != nil {
+                return
+            }
+        }
+    }
+}
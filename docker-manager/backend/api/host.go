@@ -0,0 +1,337 @@
+package api
+
+import (
+    "context"
+    "dockerpanel/backend/pkg/database"
+    "dockerpanel/backend/pkg/docker"
+    "errors"
+    "fmt"
+    "log"
+    "net/http"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+// 请求通过该请求头选择目标主机，WebSocket 和 EventSource 无法设置请求头时使用 ?host= 查询参数。
+// 未指定或为 0 时操作面板所在的本机
+const (
+    hostHeader     = "X-Docker-Host"
+    contextHostKey = "dockerHost"
+)
+
+// 主机管理接口，守护进程配置（daemon.json、重启）始终只针对本机
+func RegisterHostRoutes(r *gin.Engine) {
+    group := r.Group("/api/hosts")
+    {
+        group.GET("", listHosts)
+        group.GET("/health", getHostsHealth)
+        group.POST("", createHost)
+        group.PUT("/:id", updateHost)
+        group.DELETE("/:id", deleteHost)
+        group.POST("/:id/test", testHost)
+        group.POST("/:id/host-key", trustHostKey)
+    }
+}
+
+// 转换为 docker 包使用的连接信息
+func toDockerHost(h *database.DockerHost) *docker.Host {
+    return &docker.Host{
+        ID:          h.ID,
+        Name:        h.Name,
+        Type:        h.Type,
+        Address:     h.Address,
+        TLSCA:       h.TLSCA,
+        TLSCert:     h.TLSCert,
+        TLSKey:      h.TLSKey,
+        TLSVerify:   h.TLSVerify,
+        SSHKey:      h.SSHKey,
+        SSHPassword: h.SSHPassword,
+        SSHHostKey:  h.SSHHostKey,
+    }
+}
+
+// selectedHost 返回当前请求选择的主机，本机返回 nil
+func selectedHost(c *gin.Context) (*docker.Host, error) {
+    if v, ok := c.Get(contextHostKey); ok {
+        h, _ := v.(*docker.Host)
+        return h, nil
+    }
+
+    value := strings.TrimSpace(c.GetHeader(hostHeader))
+    if value == "" {
+        value = strings.TrimSpace(c.Query("host"))
+    }
+    if value == "" || value == "0" {
+        c.Set(contextHostKey, (*docker.Host)(nil))
+        return nil, nil
+    }
+
+    id, err := strconv.ParseInt(value, 10, 64)
+    if err != nil {
+        return nil, fmt.Errorf("无效的主机: %s", value)
+    }
+    record, err := database.GetHost(id)
+    if err != nil {
+        return nil, err
+    }
+    h := toDockerHost(record)
+    c.Set(contextHostKey, h)
+    return h, nil
+}
+
+// dockerClient 返回当前请求所选主机的共享客户端，调用方不需要关闭
+func dockerClient(c *gin.Context) (*docker.Client, error) {
+    h, err := selectedHost(c)
+    if err != nil {
+        return nil, err
+    }
+    return docker.SharedClient(h)
+}
+
+// composeEnv 返回对当前请求所选主机执行 docker compose 时的环境变量
+func composeEnv(c *gin.Context) ([]string, error) {
+    h, err := selectedHost(c)
+    if err != nil {
+        return nil, err
+    }
+    return docker.ComposeEnvForHost(h)
+}
+
+// 主机列表中的本机条目
+func localHostEntry() gin.H {
+    local := docker.LocalHost()
+    return gin.H{
+        "id":      0,
+        "name":    local.Name,
+        "type":    local.Type,
+        "address": local.Address,
+        "local":   true,
+    }
+}
+
+func listHosts(c *gin.Context) {
+    hosts, err := database.ListHosts()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "获取主机列表失败: " + err.Error()})
+        return
+    }
+    result := []interface{}{localHostEntry()}
+    for _, h := range hosts {
+        result = append(result, h.Redacted())
+    }
+    c.JSON(http.StatusOK, result)
+}
+
+// 并发检查所有主机的连通性，单个主机最多等待 10 秒
+func getHostsHealth(c *gin.Context) {
+    hosts, err := database.ListHosts()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "获取主机列表失败: " + err.Error()})
+        return
+    }
+
+    targets := []*docker.Host{nil}
+    for _, h := range hosts {
+        targets = append(targets, toDockerHost(h))
+    }
+
+    results := make([]gin.H, len(targets))
+    var wg sync.WaitGroup
+    for i, h := range targets {
+        wg.Add(1)
+        go func(i int, h *docker.Host) {
+            defer wg.Done()
+            ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+            defer cancel()
+            var id int64
+            if h != nil {
+                id = h.ID
+            }
+            results[i] = gin.H{"id": id, "health": docker.CheckHost(ctx, h)}
+        }(i, h)
+    }
+    wg.Wait()
+    c.JSON(http.StatusOK, results)
+}
+
+type hostRequest struct {
+    Name        string `json:"name" binding:"required"`
+    Type        string `json:"type" binding:"required"`
+    Address     string `json:"address" binding:"required"`
+    TLSCA       string `json:"tls_ca"`
+    TLSCert     string `json:"tls_cert"`
+    TLSKey      string `json:"tls_key"`
+    TLSVerify   bool   `json:"tls_verify"`
+    SSHKey      string `json:"ssh_key"`
+    SSHPassword string `json:"ssh_password"`
+    // 清除已信任的公钥，远端重装系统后需要重新信任
+    ResetHostKey bool `json:"reset_host_key"`
+}
+
+// 按请求生成主机记录，existing 不为空时未提供的私钥和密码沿用原值
+func (req *hostRequest) toHost(existing *database.DockerHost) *database.DockerHost {
+    h := &database.DockerHost{
+        Name:        strings.TrimSpace(req.Name),
+        Type:        req.Type,
+        Address:     strings.TrimSpace(req.Address),
+        TLSCA:       strings.TrimSpace(req.TLSCA),
+        TLSCert:     strings.TrimSpace(req.TLSCert),
+        TLSKey:      strings.TrimSpace(req.TLSKey),
+        TLSVerify:   req.TLSVerify,
+        SSHKey:      strings.TrimSpace(req.SSHKey),
+        SSHPassword: req.SSHPassword,
+    }
+    if existing != nil {
+        h.ID = existing.ID
+        if h.TLSKey == "" {
+            h.TLSKey = existing.TLSKey
+        }
+        if h.SSHKey == "" {
+            h.SSHKey = existing.SSHKey
+        }
+        if h.SSHPassword == "" {
+            h.SSHPassword = existing.SSHPassword
+        }
+        // 地址变化后原公钥不再适用
+        if !req.ResetHostKey && existing.Address == h.Address {
+            h.SSHHostKey = existing.SSHHostKey
+        }
+    }
+    return h
+}
+
+func createHost(c *gin.Context) {
+    var req hostRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据: " + err.Error()})
+        return
+    }
+    h := req.toHost(nil)
+    if err := docker.ValidateHost(toDockerHost(h)); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if err := database.CreateHost(h); err != nil {
+        status := http.StatusInternalServerError
+        if errors.Is(err, database.ErrHostExists) {
+            status = http.StatusConflict
+        }
+        c.JSON(status, gin.H{"error": "添加主机失败: " + err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, h.Redacted())
+}
+
+func updateHost(c *gin.Context) {
+    existing, ok := hostFromParam(c)
+    if !ok {
+        return
+    }
+    var req hostRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据: " + err.Error()})
+        return
+    }
+    h := req.toHost(existing)
+    if err := docker.ValidateHost(toDockerHost(h)); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if err := database.UpdateHost(h); err != nil {
+        status := http.StatusInternalServerError
+        if errors.Is(err, database.ErrHostExists) {
+            status = http.StatusConflict
+        }
+        c.JSON(status, gin.H{"error": "修改主机失败: " + err.Error()})
+        return
+    }
+    docker.CloseHost(h.ID)
+    c.JSON(http.StatusOK, h.Redacted())
+}
+
+func deleteHost(c *gin.Context) {
+    h, ok := hostFromParam(c)
+    if !ok {
+        return
+    }
+    if err := database.DeleteHost(h.ID); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "删除主机失败: " + err.Error()})
+        return
+    }
+    docker.CloseHost(h.ID)
+    c.JSON(http.StatusOK, gin.H{"message": "主机已删除"})
+}
+
+// 测试连接，返回版本、容器数量与延迟
+func testHost(c *gin.Context) {
+    var target *docker.Host
+    if c.Param("id") != "0" {
+        h, ok := hostFromParam(c)
+        if !ok {
+            return
+        }
+        target = toDockerHost(h)
+    }
+    ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+    defer cancel()
+    c.JSON(http.StatusOK, docker.CheckHost(ctx, target))
+}
+
+// 信任 SSH 主机的公钥。公钥由测试连接返回，管理员核对指纹后提交；
+// 提交时重新读取一次，确认与服务端当前的公钥一致
+func trustHostKey(c *gin.Context) {
+    h, ok := hostFromParam(c)
+    if !ok {
+        return
+    }
+    var req struct {
+        Key string `json:"key" binding:"required"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据: " + err.Error()})
+        return
+    }
+    if h.Type != docker.HostSSH {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "只有 SSH 主机需要信任公钥"})
+        return
+    }
+
+    key, fingerprint, err := docker.ScanSSHHostKey(toDockerHost(h))
+    if err != nil {
+        c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+        return
+    }
+    if key != strings.TrimSpace(req.Key) {
+        c.JSON(http.StatusConflict, gin.H{"error": "主机公钥已变化，请重新测试连接后确认", "hostKeyFingerprint": fingerprint})
+        return
+    }
+    if err := database.SetHostKey(h.ID, key); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "保存主机公钥失败: " + err.Error()})
+        return
+    }
+    docker.CloseHost(h.ID)
+    log.Printf("已信任主机 %s 的公钥 %s", h.Name, fingerprint)
+    c.JSON(http.StatusOK, gin.H{"message": "已信任主机公钥", "hostKeyFingerprint": fingerprint})
+}
+
+func hostFromParam(c *gin.Context) (*database.DockerHost, bool) {
+    id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "无效的主机 ID"})
+        return nil, false
+    }
+    h, err := database.GetHost(id)
+    if err != nil {
+        status := http.StatusInternalServerError
+        if errors.Is(err, database.ErrHostNotFound) {
+            status = http.StatusNotFound
+        }
+        c.JSON(status, gin.H{"error": "获取主机失败: " + err.Error()})
+        return nil, false
+    }
+    return h, true
+}
@@ -10,10 +10,16 @@ import (
 	"fmt"
     "log"
 	"strings"
-	"os"
-	"archive/tar"
-    "path/filepath"
+	"compress/gzip"
+    "net"
+    "net/url"
+    "sort"
+    "syscall"
+    "time"
+    "github.com/docker/distribution/reference"
     "github.com/docker/docker/api/types"
+    "github.com/docker/docker/errdefs"
+    "github.com/docker/docker/pkg/jsonmessage"
     "github.com/gin-gonic/gin"
     "dockerpanel/backend/pkg/database"
 )
@@ -23,169 +29,342 @@ func RegisterImageRoutes(r *gin.Engine) {
     {
         group.GET("", listImages)
         group.DELETE("/:id", removeImage)
+        group.GET("/:id/history", getImageHistory)
         group.POST("/pull", pullImage)
-		group.GET("/pull/progress", pullImageProgress)
+		group.POST("/pull/progress", pullImageProgress)
+        group.POST("/push", pushImage)
         group.GET("/proxy", getDockerProxy)
         group.POST("/proxy", updateDockerProxy)
+        group.POST("/proxy/test", testDockerProxy)
         group.POST("/tag", tagImage)
+        group.GET("/export", exportImages)
         group.GET("/export/:id", exportImage)
         group.POST("/import", importImage)
+        group.POST("/import/url", importImageFromURL)
+        group.GET("/import/settings", getImportSettings)
+        group.PUT("/import/settings", updateImportSettings)
     }
 }
 
-// 导入镜像
+// 导入镜像，上传的文件以流的方式直接送入 ImageLoad，支持 tar / tar.gz / tar.zst。
+// 请求带 progress=true 时以 SSE 事件返回进度，否则等待导入完成后返回结果
 func importImage(c *gin.Context) {
-    // 获取上传的文件
-    file, err := c.FormFile("file")
+    reader, err := c.Request.MultipartReader()
     if err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": "获取上传文件失败: " + err.Error()})
         return
     }
 
-    // 创建临时文件
-    tempFile, err := os.CreateTemp("", "docker-image-*.tar")
+    // 找到文件字段后直接读取，不落盘
+    for {
+        part, err := reader.NextPart()
+        if err == io.EOF {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "获取上传文件失败: 未找到 file 字段"})
+            return
+        }
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "获取上传文件失败: " + err.Error()})
+            return
+        }
+
+        if part.FormName() == "file" {
+            log.Printf("开始导入上传的镜像文件: %s", part.FileName())
+            runImageImport(c, part, c.Request.ContentLength)
+            part.Close()
+            return
+        }
+        part.Close()
+    }
+}
+
+// 从 HTTP(S) 地址下载并导入镜像
+func importImageFromURL(c *gin.Context) {
+    var req struct {
+        URL string `json:"url" binding:"required"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    u, err := url.Parse(req.URL)
+    if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "仅支持 http/https 地址"})
+        return
+    }
+
+    httpReq, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, u.String(), nil)
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "创建临时文件失败: " + err.Error()})
+        c.JSON(http.StatusBadRequest, gin.H{"error": "无效的下载地址: " + err.Error()})
         return
     }
-    defer os.Remove(tempFile.Name())
-    defer tempFile.Close()
 
-	// 保存上传的文件到临时文件
-    src, err := file.Open()
+    log.Printf("开始从地址导入镜像: %s", u.Redacted())
+    resp, err := importHTTPClient.Do(httpReq)
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "打开上传文件失败: " + err.Error()})
+        c.JSON(http.StatusBadGateway, gin.H{"error": "下载镜像归档失败: " + err.Error()})
         return
     }
-    defer src.Close()
+    defer resp.Body.Close()
 
-    if _, err = io.Copy(tempFile, src); err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "保存上传文件失败: " + err.Error()})
+    if resp.StatusCode != http.StatusOK {
+        c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("下载镜像归档失败: 服务器返回 %s", resp.Status)})
+        return
+    }
+    if resp.ContentLength > importURLMaxSize {
+        c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("镜像归档超过 %d GB 上限", importURLMaxSize>>30)})
         return
     }
 
-    // 关闭临时文件
-    tempFile.Close()
-	
-	// 从tar文件中解析镜像信息
-    imageInfo, err := extractImageInfoFromTar(tempFile.Name())
+    runImageImport(c, &sizeLimitedReader{r: resp.Body, remaining: importURLMaxSize}, resp.ContentLength)
+}
+
+const (
+    // 从地址导入时下载的归档大小上限
+    importURLMaxSize = 20 << 30
+    // 从地址导入的整体超时，包括下载和导入
+    importURLTimeout = 2 * time.Hour
+)
+
+// 从地址导入镜像使用的客户端：默认只允许连接公网地址，避免被用来访问面板所在主机或内网的服务，
+// 内网文件服务器需要管理员在导入设置中加入允许的地址段。
+// 在建立连接时检查解析后的 IP，重定向和 DNS 重绑定同样受限
+var importHTTPClient = &http.Client{
+    Timeout: importURLTimeout,
+    Transport: &http.Transport{
+        // 不经过代理，否则检查的是代理的地址而不是下载地址
+        Proxy: nil,
+        DialContext: (&net.Dialer{
+            Timeout: 30 * time.Second,
+            Control: func(network, address string, _ syscall.RawConn) error {
+                host, _, err := net.SplitHostPort(address)
+                if err != nil {
+                    return err
+                }
+                ip := net.ParseIP(host)
+                if ip == nil {
+                    return fmt.Errorf("无效的地址: %s", host)
+                }
+                if !isPublicIP(ip) && !importAddressAllowed(ip) {
+                    return fmt.Errorf("不允许从内网或本机地址导入: %s，可由管理员在导入设置中添加允许的地址段", host)
+                }
+                return nil
+            },
+        }).DialContext,
+        TLSHandshakeTimeout:   30 * time.Second,
+        ResponseHeaderTimeout: time.Minute,
+    },
+}
+
+func isPublicIP(ip net.IP) bool {
+    return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+        ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified())
+}
+
+// importAddressAllowed 检查内网地址是否在管理员允许的地址段中
+func importAddressAllowed(ip net.IP) bool {
+    value, err := database.GetSetting(database.SettingImportAllowedNetworks, "")
+    if err != nil || value == "" {
+        return false
+    }
+    networks, err := parseNetworks(value)
     if err != nil {
-        log.Printf("从tar文件解析镜像信息失败: %v", err)
-    } else {
-        log.Printf("从tar文件解析的镜像信息: %+v", imageInfo)
+        log.Printf("导入允许的地址段配置无效: %v", err)
+        return false
     }
-	
-    // 创建Docker客户端
-    cli, err := docker.NewDockerClient()
+    for _, network := range networks {
+        if network.Contains(ip) {
+            return true
+        }
+    }
+    return false
+}
+
+// parseNetworks 解析逗号或空白分隔的 CIDR 列表，单个 IP 视为只包含该地址的地址段
+func parseNetworks(value string) ([]*net.IPNet, error) {
+    networks := make([]*net.IPNet, 0)
+    for _, item := range strings.Fields(strings.ReplaceAll(value, ",", " ")) {
+        if !strings.Contains(item, "/") {
+            ip := net.ParseIP(item)
+            if ip == nil {
+                return nil, fmt.Errorf("%q 不是有效的 IP 或 CIDR", item)
+            }
+            bits := 128
+            if ip.To4() != nil {
+                ip, bits = ip.To4(), 32
+            }
+            networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+            continue
+        }
+        _, network, err := net.ParseCIDR(item)
+        if err != nil {
+            return nil, fmt.Errorf("%q 不是有效的 IP 或 CIDR", item)
+        }
+        networks = append(networks, network)
+    }
+    return networks, nil
+}
+
+// 获取从地址导入镜像的设置
+func getImportSettings(c *gin.Context) {
+    value, err := database.GetSetting(database.SettingImportAllowedNetworks, "")
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "连接Docker失败: " + err.Error()})
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "读取设置失败: " + err.Error()})
         return
     }
-    defer cli.Close()
+    networks := make([]string, 0)
+    if parsed, err := parseNetworks(value); err == nil {
+        for _, network := range parsed {
+            networks = append(networks, network.String())
+        }
+    }
+    c.JSON(http.StatusOK, gin.H{"allowedNetworks": networks})
+}
 
-    // 打开临时文件用于导入
-    importFile, err := os.Open(tempFile.Name())
+// 更新从地址导入镜像时允许访问的内网地址段，仅管理员可修改
+func updateImportSettings(c *gin.Context) {
+    var req struct {
+        AllowedNetworks []string `json:"allowedNetworks"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据: " + err.Error()})
+        return
+    }
+    networks, err := parseNetworks(strings.Join(req.AllowedNetworks, ","))
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "读取临时文件失败: " + err.Error()})
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
         return
     }
-    defer importFile.Close()
+    values := make([]string, 0, len(networks))
+    for _, network := range networks {
+        values = append(values, network.String())
+    }
+    if err := database.SetSetting(database.SettingImportAllowedNetworks, strings.Join(values, ",")); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "保存设置失败: " + err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"message": "设置已保存", "allowedNetworks": values})
+}
+
+// sizeLimitedReader 超过大小上限时返回错误，而不是像 io.LimitReader 那样静默截断
+type sizeLimitedReader struct {
+    r         io.Reader
+    remaining int64
+}
+
+func (l *sizeLimitedReader) Read(p []byte) (int, error) {
+    if l.remaining <= 0 {
+        // 恰好读到上限时再探测一个字节，区分正常结束和超出上限
+        if len(p) == 0 {
+            return 0, nil
+        }
+        n, err := l.r.Read(p[:1])
+        if n > 0 {
+            return 0, fmt.Errorf("镜像归档超过 %d GB 上限", importURLMaxSize>>30)
+        }
+        return 0, err
+    }
+    if int64(len(p)) > l.remaining {
+        p = p[:l.remaining]
+    }
+    n, err := l.r.Read(p)
+    l.remaining -= int64(n)
+    return n, err
+}
 
-    // 导入镜像
-    response, err := cli.ImageLoad(context.Background(), importFile, true)
+// 解压并导入镜像归档，total 为归档大小（未知时为 -1）
+func runImageImport(c *gin.Context, src io.Reader, total int64) {
+    counter := docker.NewCountingReader(src)
+    input, format, err := docker.DecompressStream(counter)
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "导入镜像失败: " + err.Error()})
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
         return
     }
-    defer response.Body.Close()
+    defer input.Close()
 
-    // 读取响应
-    body, err := io.ReadAll(response.Body)
+    cli, err := dockerClient(c)
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "读取导入响应失败: " + err.Error()})
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "连接Docker失败: " + err.Error()})
         return
     }
 
-    // 返回结果，优先使用从tar文件解析的信息
-    if imageInfo != nil {
-        c.JSON(http.StatusOK, gin.H{
-            "message": "镜像导入成功",
-            "details": string(body),
-            "imageInfo": imageInfo,
-        })
-    } else {
-        // 如果无法从tar文件解析，则返回基本信息
+    ctx := c.Request.Context()
+
+    if c.Query("progress") != "true" {
+        images, err := cli.LoadImages(ctx, input, nil)
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "导入镜像失败: " + err.Error()})
+            return
+        }
+        log.Printf("镜像导入成功: %+v", images)
         c.JSON(http.StatusOK, gin.H{
             "message": "镜像导入成功",
-            "details": string(body),
+            "format":  format,
+            "bytes":   counter.BytesRead(),
+            "images":  images,
         })
+        return
     }
-}
-// 从tar文件中提取镜像信息
-func extractImageInfoFromTar(tarPath string) (map[string]interface{}, error) {
-    // 打开tar文件
-    f, err := os.Open(tarPath)
-    if err != nil {
-        return nil, fmt.Errorf("打开tar文件失败: %v", err)
-    }
-    defer f.Close()
-
-    // 创建tar读取器
-    tr := tar.NewReader(f)
 
-    // 查找manifest.json文件
-    for {
-        header, err := tr.Next()
-        if err == io.EOF {
-            break
-        }
-        if err != nil {
-            return nil, fmt.Errorf("读取tar文件条目失败: %v", err)
-        }
+    c.Header("Content-Type", "text/event-stream")
+    c.Header("Cache-Control", "no-cache")
+    c.Header("Connection", "keep-alive")
+    c.Header("X-Accel-Buffering", "no")
 
-        // 检查是否是manifest.json文件
-        if filepath.Base(header.Name) == "manifest.json" {
-            // 读取manifest.json内容
-            manifestData, err := io.ReadAll(tr)
-            if err != nil {
-                return nil, fmt.Errorf("读取manifest.json失败: %v", err)
+    type loadResult struct {
+        images []docker.LoadedImage
+        err    error
+    }
+    messages := make(chan string, 16)
+    done := make(chan loadResult, 1)
+    go func() {
+        images, err := cli.LoadImages(ctx, input, func(msg jsonmessage.JSONMessage) {
+            text := strings.TrimSpace(msg.Stream)
+            if text == "" {
+                text = strings.TrimSpace(msg.Status + " " + msg.ID)
             }
-
-            // 解析manifest.json
-            var manifests []struct {
-                Config   string   `json:"Config"`
-                RepoTags []string `json:"RepoTags"`
-                Layers   []string `json:"Layers"`
+            if text == "" {
+                return
             }
-            if err := json.Unmarshal(manifestData, &manifests); err != nil {
-                return nil, fmt.Errorf("解析manifest.json失败: %v", err)
+            select {
+            case messages <- text:
+            default:
             }
+        })
+        done <- loadResult{images: images, err: err}
+    }()
 
-            // 如果找到了manifest信息
-            if len(manifests) > 0 {
-                imageID := ""
-                if manifests[0].Config != "" {
-                    // 从Config文件名中提取镜像ID
-                    imageID = strings.TrimSuffix(manifests[0].Config, ".json")
-                }
-                
-                repoTags := manifests[0].RepoTags
-                if len(repoTags) == 0 {
-                    repoTags = []string{"<none>:<none>"}
-                }
-                
-                return map[string]interface{}{
-                    "id":       imageID,
-                    "repoTags": repoTags,
-                }, nil
+    ticker := time.NewTicker(500 * time.Millisecond)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ticker.C:
+            c.SSEvent("progress", gin.H{
+                "format":     format,
+                "bytesRead":  counter.BytesRead(),
+                "totalBytes": total,
+            })
+        case msg := <-messages:
+            c.SSEvent("message", gin.H{"message": msg})
+        case result := <-done:
+            if result.err != nil {
+                log.Printf("导入镜像失败: %v", result.err)
+                c.SSEvent("error", gin.H{"error": "导入镜像失败: " + result.err.Error()})
+            } else {
+                c.SSEvent("done", gin.H{
+                    "message": "镜像导入成功",
+                    "format":  format,
+                    "bytes":   counter.BytesRead(),
+                    "images":  result.images,
+                })
             }
+            c.Writer.Flush()
+            return
         }
+        c.Writer.Flush()
     }
-
-    return nil, fmt.Errorf("未在tar文件中找到manifest.json或有效的镜像信息")
 }
 
-
 // Docker代理配置结构
 type DockerConfig struct {
     Enabled         bool                       `json:"enabled"`
@@ -194,15 +373,17 @@ type DockerConfig struct {
     NoProxy         string                     `json:"No Proxy"`
     RegistryMirrors []string                   `json:"registry-mirrors"`
     Registries      map[string]docker.Registry `json:"registries"`
+    LogDriver       string                     `json:"log-driver"`
+    LogOpts         map[string]string          `json:"log-opts"`
 }
 
-// 类型转换函数
+// 类型转换函数，密码为只写字段，仅返回是否已设置
 func convertRegistryToDocker(r *database.Registry) docker.Registry {
     return docker.Registry{
-        Name:     r.Name,
-        URL:      r.URL,
-        Username: r.Username,
-        Password: r.Password,
+        Name:        r.Name,
+        URL:         r.URL,
+        Username:    r.Username,
+        HasPassword: r.Password != "",
     }
 }
 
@@ -249,6 +430,8 @@ func getDockerProxy(c *gin.Context) {
         Enabled:         daemonConfig.Proxies != nil,
         RegistryMirrors: daemonConfig.RegistryMirrors,
         Registries:      registries,
+        LogDriver:       daemonConfig.LogDriver,
+        LogOpts:         daemonConfig.LogOpts,
     }
     
     // 如果 daemon.json 中有代理配置，使用它
@@ -293,6 +476,8 @@ func updateDockerProxy(c *gin.Context) {
 	// 更新 daemon.json 配置
     daemonConfig := &docker.DaemonConfig{
         RegistryMirrors: config.RegistryMirrors,
+        LogDriver:       config.LogDriver,
+        LogOpts:         config.LogOpts,
     }
     
     if config.Enabled {
@@ -350,90 +535,322 @@ func updateDockerProxy(c *gin.Context) {
     }
     
     c.JSON(http.StatusOK, gin.H{
-        "message": "Docker配置已更新，请运行以下命令重启 Docker 服务：\nsudo systemctl restart docker",
+        "message": "Docker配置已更新，重启 Docker 服务后生效",
+        "restart": "/api/daemon-config/restart",
     })
 }
 
-// 拉取进度监听
-func pullImageProgress(c *gin.Context) {
-    // 从查询参数获取镜像名称和注册表
-    imageName := c.Query("name")
-    registry := c.Query("registry")
-    
-    if imageName == "" {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "镜像名称不能为空"})
+// 测试镜像加速地址与代理配置，请求体与 updateDockerProxy 相同，不写入任何配置。
+// 启用代理时通过代理访问各加速地址的 /v2/，并附带 Docker Hub 作为对照
+func testDockerProxy(c *gin.Context) {
+    var config DockerConfig
+    if err := c.ShouldBindJSON(&config); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "无效的配置格式: " + err.Error()})
         return
     }
-    
-    cli, err := docker.NewDockerClient()
+
+    var transport *http.Transport
+    var err error
+    if config.Enabled {
+        transport, err = docker.ProxyTransport(config.HTTPProxy, config.HTTPSProxy, config.NoProxy)
+    } else {
+        transport, err = docker.ProxyTransport("", "", "")
+    }
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
         return
     }
-    defer cli.Close()
 
+    mirrors := make([]string, 0, len(config.RegistryMirrors))
+    seen := make(map[string]bool)
+    for _, mirror := range config.RegistryMirrors {
+        mirror = strings.TrimRight(strings.TrimSpace(mirror), "/")
+        if mirror == "" || seen[mirror] {
+            continue
+        }
+        seen[mirror] = true
+        mirrors = append(mirrors, mirror)
+    }
+
+    ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+    defer cancel()
+
+    results := docker.ProbeMirrors(ctx, mirrors, transport)
+    dockerHub := docker.ProbeRegistry(ctx, "docker.io", transport)
+
+    usable := 0
+    for _, result := range results {
+        if result.Usable {
+            usable++
+        }
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "proxy":     config.Enabled,
+        "mirrors":   results,
+        "usable":    usable,
+        "dockerHub": dockerHub,
+    })
+}
+
+// 解析待拉取的完整镜像名称、目标平台及认证信息
+func resolvePullImage(imageName, registry, platform string) (string, types.ImagePullOptions, error) {
     var options types.ImagePullOptions
-    
+
+    if platform != "" {
+        p, err := docker.ParsePlatform(platform)
+        if err != nil {
+            return "", options, err
+        }
+        options.Platform = p.String()
+    }
+
     // 如果指定了仓库，使用仓库配置
     if registry != "" && registry != "docker.io" {
-        registries, err := database.GetAllRegistries()
+        reg, auth, err := getRegistryAuth(registry)
         if err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "获取注册表配置失败: " + err.Error()})
-            return
+            return "", options, fmt.Errorf("获取注册表配置失败: %v", err)
         }
 
-        if reg, ok := registries[registry]; ok {
+        if reg != nil {
             imageName = reg.URL + "/" + imageName
-            
-            if reg.Username != "" && reg.Password != "" {
-                authConfig := types.AuthConfig{
-                    Username: reg.Username,
-                    Password: reg.Password,
-                }
-                encodedJSON, err := json.Marshal(authConfig)
-                if err == nil {
-                    options.RegistryAuth = base64.URLEncoding.EncodeToString(encodedJSON)
-                }
-            }
+            options.RegistryAuth = auth
+            log.Printf("使用注册表 %s 拉取镜像，完整镜像名: %s", reg.Name, imageName)
+        } else {
+            log.Printf("未找到注册表配置: %s", registry)
+        }
+    } else if username, password := lookupRegistryCredential("docker.io"); username != "" && password != "" {
+        // Docker Hub 登录后可避免匿名拉取的频率限制
+        encoded, err := json.Marshal(types.AuthConfig{
+            Username:      username,
+            Password:      password,
+            ServerAddress: "docker.io",
+        })
+        if err != nil {
+            return "", options, fmt.Errorf("编码认证信息失败: %v", err)
         }
+        options.RegistryAuth = base64.URLEncoding.EncodeToString(encoded)
     }
 
-    reader, err := cli.ImagePull(context.Background(), imageName, options)
+    return imageName, options, nil
+}
+
+// 拉取镜像并以 SSE 事件推送汇总后的进度：
+// progress 为进行中的进度，done 表示拉取完成，error 表示拉取失败
+func pullImageProgress(c *gin.Context) {
+    var req struct {
+        Image    string `json:"name" binding:"required"`
+        Registry string `json:"registry"`
+        Platform string `json:"platform"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "镜像名称不能为空"})
+        return
+    }
+
+    imageName, options, err := resolvePullImage(req.Image, req.Registry, req.Platform)
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
         return
     }
-    defer reader.Close()
+
+    host, err := selectedHost(c)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "连接Docker失败: " + err.Error()})
+        return
+    }
+
+    // 订阅拉取任务，客户端断开后退出订阅，无订阅者时拉取自动取消
+    updates, unsubscribe := docker.SubscribePull(host, imageName, options)
+    defer unsubscribe()
 
     c.Header("Content-Type", "text/event-stream")
     c.Header("Cache-Control", "no-cache")
     c.Header("Connection", "keep-alive")
-    c.Header("Transfer-Encoding", "chunked")
-    c.Header("Access-Control-Allow-Origin", "*")
 
-    // 读取并发送进度信息
-    buf := make([]byte, 32*1024)
-    for {
-        n, err := reader.Read(buf)
-        if err != nil {
-            if err == io.EOF {
-                break
+    c.Stream(func(w io.Writer) bool {
+        select {
+        case <-c.Request.Context().Done():
+            return false
+        case progress, ok := <-updates:
+            if !ok {
+                return false
+            }
+            if !progress.Done {
+                c.SSEvent("progress", progress)
+                return true
+            }
+            if progress.Error != "" {
+                c.SSEvent("error", progress)
+            } else {
+                c.SSEvent("done", progress)
+            }
+            return false
+        }
+    })
+}
+
+// 获取注册表配置及编码后的认证信息，未找到注册表时返回 nil
+func getRegistryAuth(registry string) (*database.Registry, string, error) {
+    registries, err := database.GetAllRegistries()
+    if err != nil {
+        return nil, "", err
+    }
+
+    reg, ok := registries[registry]
+    if !ok {
+        return nil, "", nil
+    }
+
+    // 面板中未保存凭据时回退到 config.json，与 docker compose 使用同一份凭据
+    username, password := lookupRegistryCredential(reg.URL)
+    if username == "" || password == "" {
+        return reg, "", nil
+    }
+
+    authConfig := types.AuthConfig{
+        Username:      username,
+        Password:      password,
+        ServerAddress: reg.URL,
+    }
+    encodedJSON, err := json.Marshal(authConfig)
+    if err != nil {
+        return nil, "", fmt.Errorf("编码认证信息失败: %v", err)
+    }
+    return reg, base64.URLEncoding.EncodeToString(encodedJSON), nil
+}
+
+// 按镜像所在仓库查找凭据并编码，用于查询摘要和后台拉取，没有凭据时返回空字符串
+func imageRegistryAuth(ref string) string {
+    registry := docker.ImageRegistry(ref)
+    if registry == "" {
+        return ""
+    }
+    username, password := lookupRegistryCredential(registry)
+    if username == "" || password == "" {
+        return ""
+    }
+    encoded, err := json.Marshal(types.AuthConfig{
+        Username:      username,
+        Password:      password,
+        ServerAddress: registry,
+    })
+    if err != nil {
+        return ""
+    }
+    return base64.URLEncoding.EncodeToString(encoded)
+}
+
+// 推送镜像到指定注册表
+func pushImage(c *gin.Context) {
+    var req struct {
+        Image    string `json:"image" binding:"required"`    // 本地镜像 ID 或名称
+        Registry string `json:"registry" binding:"required"` // 目标注册表，对应 registries 表中的 URL
+        Repo     string `json:"repo"`                        // 目标仓库路径，默认沿用镜像原仓库名
+        Tag      string `json:"tag"`                         // 目标标签，默认沿用镜像原标签
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    reg, auth, err := getRegistryAuth(req.Registry)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "获取注册表配置失败: " + err.Error()})
+        return
+    }
+    if reg == nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "未找到注册表配置: " + req.Registry})
+        return
+    }
+
+    cli, err := dockerClient(c)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+
+    inspect, _, err := cli.ImageInspectWithRaw(context.Background(), req.Image)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "镜像不存在: " + err.Error()})
+        return
+    }
+
+    // 未指定仓库或标签时，从镜像的第一个标签中推导
+    repo, tag := req.Repo, req.Tag
+    if len(inspect.RepoTags) > 0 {
+        if named, err := reference.ParseNormalizedNamed(inspect.RepoTags[0]); err == nil {
+            if repo == "" {
+                repo = reference.Path(named)
+            }
+            if tagged, ok := named.(reference.Tagged); ok && tag == "" {
+                tag = tagged.Tag()
             }
-            log.Printf("读取进度失败: %v", err)
-            continue
         }
+    }
+    if repo == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "镜像没有标签，请指定目标仓库"})
+        return
+    }
+    if tag == "" {
+        tag = "latest"
+    }
 
-        // 发送原始进度数据
-        c.Writer.Write(buf[:n])
+    target := fmt.Sprintf("%s:%s", repo, tag)
+    if reg.URL != "docker.io" {
+        target = reg.URL + "/" + target
+    }
+    if _, err := reference.ParseNormalizedNamed(target); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "无效的目标镜像名称: " + err.Error()})
+        return
+    }
+
+    log.Printf("推送镜像: %s -> %s", req.Image, target)
+
+    if err := cli.ImageTag(context.Background(), req.Image, target); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("标记镜像失败: %v", err)})
+        return
+    }
+
+    reader, err := cli.ImagePush(c.Request.Context(), target, types.ImagePushOptions{
+        RegistryAuth: auth,
+    })
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("推送镜像失败: %v", err)})
+        return
+    }
+    defer reader.Close()
+
+    c.Header("Content-Type", "text/event-stream")
+    c.Header("Cache-Control", "no-cache")
+    c.Header("Connection", "keep-alive")
+
+    // 按层汇总推送进度，以 SSE 事件推送
+    aggregator := docker.NewProgressAggregator(target)
+    err = docker.ConsumeProgress(reader, aggregator, func(progress docker.Progress) {
+        c.SSEvent("progress", progress)
         c.Writer.Flush()
+    })
+
+    final := aggregator.Snapshot()
+    final.Done = true
+    if err != nil {
+        log.Printf("推送镜像 %s 失败: %v", target, err)
+        final.Error = err.Error()
+        c.SSEvent("error", final)
+    } else {
+        final.Percent = 100
+        c.SSEvent("done", final)
     }
+    c.Writer.Flush()
 }
 
-// 拉取镜像
+// 拉取镜像，等待拉取完成后返回结果
 func pullImage(c *gin.Context) {
     var req struct {
         Image    string `json:"name" binding:"required"`
         Registry string `json:"registry"`
+        Platform string `json:"platform"` // 目标平台，例如 linux/arm64，默认为守护进程所在平台
     }
     if err := c.ShouldBindJSON(&req); err != nil {
         log.Printf("解析请求参数失败: %v", err)
@@ -441,84 +858,158 @@ func pullImage(c *gin.Context) {
         return
     }
 
-    log.Printf("开始拉取镜像: %s, 注册表: %s", req.Image, req.Registry)
+    log.Printf("开始拉取镜像: %s, 注册表: %s, 平台: %s", req.Image, req.Registry, req.Platform)
 
-    cli, err := docker.NewDockerClient()
+    imageName, options, err := resolvePullImage(req.Image, req.Registry, req.Platform)
     if err != nil {
-        log.Printf("创建 Docker 客户端失败: %v", err)
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
         return
     }
-    defer cli.Close()
 
-    var options types.ImagePullOptions
-    imageName := req.Image
+    host, err := selectedHost(c)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "连接Docker失败: " + err.Error()})
+        return
+    }
 
-    // 如果指定了仓库，使用仓库配置
-    if req.Registry != "" && req.Registry != "docker.io" {
-        registries, err := database.GetAllRegistries()
-        if err != nil {
-            log.Printf("获取注册表配置失败: %v", err)
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "获取注册表配置失败: " + err.Error()})
-            return
-        }
+    // 与同一主机上同一镜像的其他拉取请求（包括进度监听）共享同一个拉取任务
+    updates, unsubscribe := docker.SubscribePull(host, imageName, options)
+    defer unsubscribe()
 
-        if registry, ok := registries[req.Registry]; ok {
-            imageName = registry.URL + "/" + req.Image
-            log.Printf("使用注册表 %s 拉取镜像，完整镜像名: %s", registry.Name, imageName)
-            
-            if registry.Username != "" && registry.Password != "" {
-                authConfig := types.AuthConfig{
-                    Username: registry.Username,
-                    Password: registry.Password,
-                }
-                encodedJSON, err := json.Marshal(authConfig)
-                if err == nil {
-                    options.RegistryAuth = base64.URLEncoding.EncodeToString(encodedJSON)
-                    log.Printf("使用认证信息拉取镜像")
+    var result docker.Progress
+    for {
+        select {
+        case <-c.Request.Context().Done():
+            log.Printf("客户端已断开，停止等待拉取: %s", imageName)
+            return
+        case progress, ok := <-updates:
+            if !ok {
+                if result.Error != "" {
+                    c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error, "progress": result})
+                    return
                 }
+                c.JSON(http.StatusOK, gin.H{"message": "镜像拉取成功", "progress": result})
+                return
             }
-        } else {
-            log.Printf("未找到注册表配置: %s", req.Registry)
+            result = progress
         }
-    } else {
-        log.Printf("使用默认注册表拉取镜像: %s", imageName)
     }
+}
+
+// 使用镜像的容器信息
+type imageContainerRef struct {
+    ID    string `json:"id"`
+    Name  string `json:"name"`
+    State string `json:"state"`
+}
 
-    // 获取 Docker 信息，检查代理设置
-    info, err := cli.Info(context.Background())
-    if err == nil && (info.HTTPProxy != "" || info.HTTPSProxy != "") {
-        log.Printf("Docker 代理设置: HTTP=%s, HTTPS=%s, NoProxy=%s", 
-            info.HTTPProxy, info.HTTPSProxy, info.NoProxy)
+// 附带平台及使用情况的镜像信息
+type imageWithUsage struct {
+    types.ImageSummary
+    Os               string              `json:"Os"`
+    Architecture     string              `json:"Architecture"`
+    Variant          string              `json:"Variant,omitempty"`
+    UsedByContainers []imageContainerRef `json:"UsedByContainers"`
+    UsedByProjects   []string            `json:"UsedByProjects"`
+}
+
+// 镜像使用情况索引
+type imageUsage struct {
+    containers map[string][]imageContainerRef // 镜像 ID -> 容器
+    projects   map[string][]string            // 规范化镜像名 / 镜像 ID -> compose 项目
+}
+
+// 规范化镜像引用，使 nginx、nginx:latest、docker.io/library/nginx:latest 可以互相匹配
+func normalizeImageRef(ref string) string {
+    named, err := reference.ParseNormalizedNamed(ref)
+    if err != nil {
+        return ref
     }
+    return reference.TagNameOnly(named).String()
+}
 
-    log.Printf("开始拉取镜像: %s", imageName)
-    reader, err := cli.ImagePull(context.Background(), imageName, options)
+// 收集主机上容器和 compose 项目对镜像的引用情况
+func collectImageUsage(cli *docker.Client, host *docker.Host) (*imageUsage, error) {
+    containers, err := cli.ContainerList(context.Background(), types.ContainerListOptions{All: true})
     if err != nil {
-        log.Printf("拉取镜像失败: %v", err)
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-        return
+        return nil, err
+    }
+
+    usage := &imageUsage{
+        containers: make(map[string][]imageContainerRef),
+        projects:   make(map[string][]string),
+    }
+    addProject := func(key, project string) {
+        for _, p := range usage.projects[key] {
+            if p == project {
+                return
+            }
+        }
+        usage.projects[key] = append(usage.projects[key], project)
+    }
+
+    for _, container := range containers {
+        name := ""
+        if len(container.Names) > 0 {
+            name = strings.TrimPrefix(container.Names[0], "/")
+        }
+        usage.containers[container.ImageID] = append(usage.containers[container.ImageID], imageContainerRef{
+            ID:    container.ID[:12],
+            Name:  name,
+            State: container.State,
+        })
+        // 已部署的 compose 容器同样算作项目引用
+        if project := container.Labels["com.docker.compose.project"]; project != "" {
+            addProject(container.ImageID, project)
+        }
     }
-    defer reader.Close()
 
-	response, err := io.ReadAll(reader)
+    projectImages, err := composeProjectImages(host)
     if err != nil {
-        log.Printf("读取响应失败: %v", err)
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "读取响应失败: " + err.Error()})
-        return
+        log.Printf("读取 compose 项目失败: %v", err)
+    }
+    for project, images := range projectImages {
+        for _, image := range images {
+            addProject(normalizeImageRef(image), project)
+        }
+    }
+
+    return usage, nil
+}
+
+// 获取引用指定镜像的容器和项目
+func (u *imageUsage) dependents(id string, repoTags []string) ([]imageContainerRef, []string) {
+    containers := u.containers[id]
+    if containers == nil {
+        containers = []imageContainerRef{}
+    }
+
+    projects := []string{}
+    seen := make(map[string]bool)
+    keys := []string{id}
+    for _, tag := range repoTags {
+        keys = append(keys, normalizeImageRef(tag))
+    }
+    for _, key := range keys {
+        for _, project := range u.projects[key] {
+            if !seen[project] {
+                seen[project] = true
+                projects = append(projects, project)
+            }
+        }
     }
-	log.Printf("镜像拉取成功: %s", imageName)
-    c.JSON(http.StatusOK, gin.H{"message": "镜像拉取成功", "details": string(response)})
+    sort.Strings(projects)
+
+    return containers, projects
 }
 
 // 展示镜像
 func listImages(c *gin.Context) {
-	cli, err := docker.NewDockerClient()
+	cli, err := dockerClient(c)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	defer cli.Close()
 
 	images, err := cli.ImageList(context.Background(), types.ImageListOptions{})
 	if err != nil {
@@ -526,31 +1017,183 @@ func listImages(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, images)
+	// dockerClient 已解析过所选主机，这里不会再出错
+	host, _ := selectedHost(c)
+	usage, err := collectImageUsage(cli, host)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取镜像使用情况失败: " + err.Error()})
+		return
+	}
+
+	// 镜像列表接口不包含平台信息，需要逐个查询，以有限并发进行
+	ids := make([]string, len(images))
+	for i, image := range images {
+		ids[i] = image.ID
+	}
+	inspects := cli.InspectImages(context.Background(), ids)
+
+	result := make([]imageWithUsage, 0, len(images))
+	for _, image := range images {
+		containers, projects := usage.dependents(image.ID, image.RepoTags)
+		item := imageWithUsage{
+			ImageSummary:     image,
+			UsedByContainers: containers,
+			UsedByProjects:   projects,
+		}
+		if inspect, ok := inspects[image.ID]; ok {
+			item.Os = inspect.Os
+			item.Architecture = inspect.Architecture
+			item.Variant = inspect.Variant
+		}
+		result = append(result, item)
+	}
+
+	c.JSON(http.StatusOK, result)
 }
 
-// 删除镜像
+// 删除镜像，存在依赖的容器或项目时需显式指定 force=true
 func removeImage(c *gin.Context) {
-	cli, err := docker.NewDockerClient()
+	cli, err := dockerClient(c)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	defer cli.Close()
 
 	id := c.Param("id")
+	force := c.Query("force") == "true"
+
+	inspect, _, err := cli.ImageInspectWithRaw(context.Background(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "镜像不存在: " + err.Error()})
+		return
+	}
+
+	// dockerClient 已解析过所选主机，这里不会再出错
+	host, _ := selectedHost(c)
+	usage, err := collectImageUsage(cli, host)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取镜像使用情况失败: " + err.Error()})
+		return
+	}
+
+	containers, projects := usage.dependents(inspect.ID, inspect.RepoTags)
+	hasDependents := len(containers) > 0 || len(projects) > 0
+	if hasDependents && !force {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":      fmt.Sprintf("镜像正在被 %d 个容器和 %d 个项目使用，如需删除请使用强制删除", len(containers), len(projects)),
+			"containers": containers,
+			"projects":   projects,
+		})
+		return
+	}
+
+	// 只有用户确认强制删除时才传 Force，否则由 Docker 拒绝删除带有多个标签或被其他镜像引用的镜像
 	_, err = cli.ImageRemove(context.Background(), id, types.ImageRemoveOptions{
-		Force:         true,
+		Force:         force,
 		PruneChildren: true,
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		status := http.StatusInternalServerError
+		if errdefs.IsConflict(err) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": "删除镜像失败: " + err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "镜像已删除"})
 }
 
+// 获取镜像分层历史及运行配置
+func getImageHistory(c *gin.Context) {
+    cli, err := dockerClient(c)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+
+    id := c.Param("id")
+    inspect, _, err := cli.ImageInspectWithRaw(context.Background(), id)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "镜像不存在: " + err.Error()})
+        return
+    }
+
+    history, err := cli.ImageHistory(context.Background(), id)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "获取镜像历史失败: " + err.Error()})
+        return
+    }
+
+    // 按构建顺序（从最早到最新）整理各层信息
+    layers := make([]gin.H, 0, len(history))
+    for i := len(history) - 1; i >= 0; i-- {
+        item := history[i]
+        percent := 0.0
+        if inspect.Size > 0 {
+            percent = float64(item.Size) / float64(inspect.Size) * 100
+        }
+        layers = append(layers, gin.H{
+            "id":          item.ID,
+            "instruction": formatHistoryInstruction(item.CreatedBy),
+            "createdBy":   item.CreatedBy,
+            "size":        item.Size,
+            "percent":     percent,
+            "created":     item.Created,
+            "comment":     item.Comment,
+            "tags":        item.Tags,
+            "empty":       item.Size == 0,
+        })
+    }
+
+    config := gin.H{}
+    if inspect.Config != nil {
+        exposedPorts := make([]string, 0, len(inspect.Config.ExposedPorts))
+        for port := range inspect.Config.ExposedPorts {
+            exposedPorts = append(exposedPorts, string(port))
+        }
+        sort.Strings(exposedPorts)
+
+        volumes := make([]string, 0, len(inspect.Config.Volumes))
+        for volume := range inspect.Config.Volumes {
+            volumes = append(volumes, volume)
+        }
+        sort.Strings(volumes)
+
+        config = gin.H{
+            "entrypoint":   inspect.Config.Entrypoint,
+            "cmd":          inspect.Config.Cmd,
+            "env":          inspect.Config.Env,
+            "exposedPorts": exposedPorts,
+            "labels":       inspect.Config.Labels,
+            "workingDir":   inspect.Config.WorkingDir,
+            "user":         inspect.Config.User,
+            "volumes":      volumes,
+        }
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "id":           inspect.ID,
+        "repoTags":     inspect.RepoTags,
+        "size":         inspect.Size,
+        "created":      inspect.Created,
+        "os":           inspect.Os,
+        "architecture": inspect.Architecture,
+        "layers":       layers,
+        "config":       config,
+    })
+}
+
+// 去掉构建历史中 shell 包装前缀，只保留 Dockerfile 指令
+func formatHistoryInstruction(createdBy string) string {
+    instruction := strings.TrimSpace(createdBy)
+    instruction = strings.TrimPrefix(instruction, "/bin/sh -c #(nop)")
+    if strings.HasPrefix(instruction, "/bin/sh -c ") {
+        instruction = "RUN " + strings.TrimPrefix(instruction, "/bin/sh -c ")
+    }
+    return strings.TrimSpace(instruction)
+}
+
 // 标签处理
 func tagImage(c *gin.Context) {
     var req struct {
@@ -564,12 +1207,11 @@ func tagImage(c *gin.Context) {
         return
     }
 
-    cli, err := docker.NewDockerClient()
+    cli, err := dockerClient(c)
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
     }
-    defer cli.Close()
 
     newTag := fmt.Sprintf("%s:%s", req.Repo, req.Tag)
     
@@ -582,39 +1224,90 @@ func tagImage(c *gin.Context) {
     c.JSON(http.StatusOK, gin.H{"message": "标签修改成功"})
 }
 
-// 导出镜像
+// 导出单个镜像，支持 platform 和 compress=gzip 查询参数，platform 只用于校验本地镜像的平台
 func exportImage(c *gin.Context) {
-    imageID := c.Param("id")
-    
-    cli, err := docker.NewDockerClient()
-    if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+    writeImageArchive(c, []string{c.Param("id")}, c.Query("platform"), c.Query("compress") == "gzip")
+}
+
+// 将多个镜像导出到同一个 tar 包，用于离线传输：
+// GET /api/images/export?id=nginx:latest&id=redis:7&platform=linux/arm64&compress=gzip
+// platform 不会选择或转换平台，导出的始终是本地存储中的镜像；指定时要求每个镜像都是该平台，
+// 用于在传输前确认拿到的是目标机器能运行的镜像
+func exportImages(c *gin.Context) {
+    ids := c.QueryArray("id")
+    if len(ids) == 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "请至少指定一个镜像"})
         return
     }
-    defer cli.Close()
-    
-    inspect, _, err := cli.ImageInspectWithRaw(context.Background(), imageID)
+    writeImageArchive(c, ids, c.Query("platform"), c.Query("compress") == "gzip")
+}
+
+// 导出镜像归档。docker save 只能导出本地存储中的镜像，无法按平台选择，
+// requirePlatform 非空时只检查每个镜像的平台与之相同，不符时返回 400
+func writeImageArchive(c *gin.Context, ids []string, requirePlatform string, compress bool) {
+    var target *docker.Platform
+    if requirePlatform != "" {
+        p, err := docker.ParsePlatform(requirePlatform)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        target = &p
+    }
+
+    cli, err := dockerClient(c)
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取镜像信息失败: %v", err)})
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
     }
     
-    fileName := strings.TrimPrefix(imageID, "sha256:")[:12]
-    if len(inspect.RepoTags) > 0 {
-        fileName = strings.Replace(inspect.RepoTags[0], "/", "_", -1)
-        fileName = strings.Replace(fileName, ":", "_", -1)
-    }
-    c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.tar", fileName))
-    c.Header("Content-Type", "application/x-tar")
-    
     var names []string
-    if len(inspect.RepoTags) > 0 {
-        names = inspect.RepoTags
-    } else {
-        names = []string{imageID}
+    seen := make(map[string]bool)
+    fileName := ""
+    for _, imageID := range ids {
+        inspect, _, err := cli.ImageInspectWithRaw(context.Background(), imageID)
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取镜像 %s 信息失败: %v", imageID, err)})
+            return
+        }
+
+        if target != nil && !target.Matches(inspect.Os, inspect.Architecture, inspect.Variant) {
+            actual := docker.Platform{OS: inspect.Os, Architecture: inspect.Architecture, Variant: inspect.Variant}
+            c.JSON(http.StatusBadRequest, gin.H{
+                "error": fmt.Sprintf("本地镜像 %s 的平台为 %s，与请求的 %s 不符。导出内容为本地存储中的镜像，不会按平台转换，请先按 %s 平台拉取该镜像后再导出",
+                    imageID, actual.String(), target.String(), target.String()),
+            })
+            return
+        }
+
+        // 优先按标签导出，以便导入后保留镜像名称
+        imageNames := inspect.RepoTags
+        if len(imageNames) == 0 {
+            imageNames = []string{imageID}
+        }
+        for _, name := range imageNames {
+            if !seen[name] {
+                seen[name] = true
+                names = append(names, name)
+            }
+        }
+
+        if fileName == "" {
+            fileName = strings.TrimPrefix(inspect.ID, "sha256:")[:12]
+            if len(inspect.RepoTags) > 0 {
+                fileName = strings.Replace(inspect.RepoTags[0], "/", "_", -1)
+                fileName = strings.Replace(fileName, ":", "_", -1)
+            }
+        }
+    }
+    if len(ids) > 1 {
+        fileName = fmt.Sprintf("images-%d", len(ids))
+    }
+    if target != nil {
+        fileName += "-" + strings.Replace(target.String(), "/", "_", -1)
     }
     
-    log.Printf("导出镜像: %v", names)
+    log.Printf("导出镜像: %v, 校验平台: %s, 压缩: %v", names, requirePlatform, compress)
     
     reader, err := cli.ImageSave(context.Background(), names)
     if err != nil {
@@ -622,10 +1315,28 @@ func exportImage(c *gin.Context) {
         return
     }
     defer reader.Close()
+
+    if compress {
+        c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.tar.gz", fileName))
+        c.Header("Content-Type", "application/gzip")
+
+        gz := gzip.NewWriter(c.Writer)
+        if _, err := io.Copy(gz, reader); err != nil {
+            log.Printf("写入压缩归档失败: %v", err)
+            return
+        }
+        if err := gz.Close(); err != nil {
+            log.Printf("关闭压缩归档失败: %v", err)
+        }
+        return
+    }
+
+    c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.tar", fileName))
+    c.Header("Content-Type", "application/x-tar")
     
     _, err = io.Copy(c.Writer, reader)
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("写入响应失败: %v", err)})
+        log.Printf("写入响应失败: %v", err)
         return
     }
-}
\ No newline at end of file
+}
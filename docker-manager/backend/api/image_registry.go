@@ -1,9 +1,16 @@
 package api
 
 import (
+    "context"
+    "errors"
     "net/http"
 	"log"
+    "strconv"
+    "strings"
+    "time"
     "dockerpanel/backend/pkg/database"
+    "dockerpanel/backend/pkg/docker"
+    registrytypes "github.com/docker/docker/api/types/registry"
     "github.com/gin-gonic/gin"
 )
 
@@ -11,7 +18,16 @@ func RegisterImageRegistryRoutes(r *gin.Engine) {
     group := r.Group("/api/image-registry")  // 修改路由路径
     {
         group.GET("", getImageRegistries)
-        group.POST("", updateImageRegistries)
+        group.POST("", createImageRegistry)
+        group.PUT("", updateImageRegistries)
+        group.GET("/:id", getImageRegistry)
+        group.PUT("/:id", updateImageRegistry)
+        group.DELETE("/:id", deleteImageRegistry)
+        group.POST("/:id/test", testImageRegistry)
+        group.GET("/:id/repositories", listRegistryRepositories)
+        group.GET("/:id/tags", listRegistryTags)
+        group.GET("/:id/manifest", getRegistryManifest)
+        group.DELETE("/:id/tags", deleteRegistryTag)
     }
 }
 
@@ -22,10 +38,16 @@ func getImageRegistries(c *gin.Context) {
         c.JSON(http.StatusInternalServerError, gin.H{"error": "获取镜像注册表配置失败: " + err.Error()})
         return
     }
-    c.JSON(http.StatusOK, registries)
+
+    // 密码为只写字段，不返回给前端
+    result := make(map[string]*database.Registry, len(registries))
+    for key, registry := range registries {
+        result[key] = registry.Redacted()
+    }
+    c.JSON(http.StatusOK, result)
 }
 
-// 更新镜像注册表配置
+// 批量替换镜像注册表配置，所有写入在同一事务中完成
 func updateImageRegistries(c *gin.Context) {
     var registries map[string]database.Registry
     if err := c.ShouldBindJSON(&registries); err != nil {
@@ -35,34 +57,402 @@ func updateImageRegistries(c *gin.Context) {
     
     // 打印接收到的注册表配置
     log.Printf("接收到 %d 个注册表配置", len(registries))
-    for key, registry := range registries {
-        log.Printf("接收到注册表配置: key=%s, name=%s, url=%s", key, registry.Name, registry.URL)
-    }
 
-    // 清除现有配置
-    if err := database.ClearRegistries(); err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "清除镜像注册表配置失败: " + err.Error()})
+    // 前端拿不到已保存的密码，未提交新密码时沿用原密码
+    existing, err := database.GetAllRegistries()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "获取镜像注册表配置失败: " + err.Error()})
         return
     }
 
-    // 保存新配置
+    list := make([]*database.Registry, 0, len(registries))
     for key, registry := range registries {
+        registry := registry
         // 确保 URL 不为空
         if registry.URL == "" {
             registry.URL = key // 如果 URL 为空，使用键作为 URL
             log.Printf("注册表 URL 为空，使用键作为 URL: %s", key)
         }
-        
+        if old, ok := existing[registry.URL]; ok && registry.Password == "" && registry.Username == old.Username {
+            if old.PasswordUndecryptable {
+                c.JSON(http.StatusConflict, gin.H{"error": "保存镜像注册表配置失败: " + registry.URL + " " + database.ErrRegistryPasswordUndecryptable.Error()})
+                return
+            }
+            registry.Password = old.Password
+        }
         registry.IsDefault = (key == "docker.io")
         log.Printf("保存注册表: key=%s, name=%s, url=%s, isDefault=%v", 
                   key, registry.Name, registry.URL, registry.IsDefault)
-        
-        if err := database.SaveRegistry(&registry); err != nil {
-            log.Printf("保存注册表失败: %v", err)
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "保存镜像注册表配置失败: " + err.Error()})
-            return
+        list = append(list, &registry)
+    }
+
+    if err := database.ReplaceRegistries(list); err != nil {
+        log.Printf("保存注册表失败: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "保存镜像注册表配置失败: " + err.Error()})
+        return
+    }
+    // 不再保留的注册表，其凭据也要从 config.json 中移除
+    kept := make(map[string]bool, len(list))
+    for _, registry := range list {
+        kept[docker.ConfigServerKey(registry.URL)] = true
+    }
+    removed := make([]string, 0)
+    for _, old := range existing {
+        if old.ID != 0 && !kept[docker.ConfigServerKey(old.URL)] {
+            removed = append(removed, old.URL)
         }
     }
+    removeRegistriesFromDockerConfig(removed...)
+    syncRegistriesToDockerConfig(list...)
 
     c.JSON(http.StatusOK, gin.H{"message": "镜像注册表配置已更新"})
-}
\ No newline at end of file
+}
+
+// 解析路径中的注册表 ID 并读取配置，失败时已写入响应
+func loadRegistryParam(c *gin.Context) (*database.Registry, bool) {
+    id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "无效的注册表 ID"})
+        return nil, false
+    }
+
+    registry, err := database.GetRegistry(id)
+    if err != nil {
+        if errors.Is(err, database.ErrRegistryNotFound) {
+            c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+        } else {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "获取注册表配置失败: " + err.Error()})
+        }
+        return nil, false
+    }
+    return registry, true
+}
+
+// 注册表写入错误对应的状态码
+func registryErrorStatus(err error) int {
+    switch {
+    case errors.Is(err, database.ErrRegistryNotFound):
+        return http.StatusNotFound
+    case errors.Is(err, database.ErrRegistryExists), errors.Is(err, database.ErrRegistryPasswordUndecryptable):
+        return http.StatusConflict
+    case errors.Is(err, database.ErrRegistryPasswordRequired):
+        return http.StatusBadRequest
+    }
+    return http.StatusInternalServerError
+}
+
+// 获取单个注册表配置
+func getImageRegistry(c *gin.Context) {
+    registry, ok := loadRegistryParam(c)
+    if !ok {
+        return
+    }
+    c.JSON(http.StatusOK, registry.Redacted())
+}
+
+// 新建注册表配置
+func createImageRegistry(c *gin.Context) {
+    var registry database.Registry
+    if err := c.ShouldBindJSON(&registry); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据: " + err.Error()})
+        return
+    }
+
+    registry.URL = strings.TrimSpace(registry.URL)
+    if registry.URL == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "注册表 URL 不能为空"})
+        return
+    }
+    if registry.Name == "" {
+        registry.Name = registry.URL
+    }
+    registry.IsDefault = registry.URL == "docker.io"
+
+    if err := database.CreateRegistry(&registry); err != nil {
+        c.JSON(registryErrorStatus(err), gin.H{"error": "保存注册表配置失败: " + err.Error()})
+        return
+    }
+
+    log.Printf("已新建注册表: ID=%d, URL=%s", registry.ID, registry.URL)
+    saved, err := database.GetRegistry(registry.ID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "获取注册表配置失败: " + err.Error()})
+        return
+    }
+    syncRegistriesToDockerConfig(saved)
+    c.JSON(http.StatusCreated, saved.Redacted())
+}
+
+// 更新单个注册表配置，密码留空时保留原密码
+func updateImageRegistry(c *gin.Context) {
+    existing, ok := loadRegistryParam(c)
+    if !ok {
+        return
+    }
+
+    var req struct {
+        Name     *string `json:"name"`
+        URL      *string `json:"url"`
+        Username *string `json:"username"`
+        Password string  `json:"password"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据: " + err.Error()})
+        return
+    }
+
+    registry := *existing
+    registry.Password = req.Password
+    if req.Name != nil {
+        registry.Name = *req.Name
+    }
+    if req.URL != nil {
+        registry.URL = strings.TrimSpace(*req.URL)
+    }
+    if req.Username != nil {
+        registry.Username = *req.Username
+    }
+    registry.IsDefault = registry.URL == "docker.io"
+
+    // 沿用原密码时地址和用户名都不能变，否则可以把只写的密码发往调用方指定的主机
+    if registry.Password == "" && existing.HasPassword {
+        if docker.ConfigServerKey(registry.URL) != docker.ConfigServerKey(existing.URL) ||
+            docker.RegistryEndpoint(registry.URL) != docker.RegistryEndpoint(existing.URL) ||
+            registry.Username != existing.Username {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "更新注册表配置失败: " + database.ErrRegistryPasswordRequired.Error()})
+            return
+        }
+        // 只是地址写法不同（如末尾多了斜杠）时显式带上原密码
+        if registry.URL != existing.URL {
+            if existing.PasswordUndecryptable {
+                c.JSON(http.StatusConflict, gin.H{"error": "更新注册表配置失败: " + database.ErrRegistryPasswordUndecryptable.Error()})
+                return
+            }
+            registry.Password = existing.Password
+        }
+    }
+
+    if err := database.UpdateRegistry(&registry); err != nil {
+        c.JSON(registryErrorStatus(err), gin.H{"error": "更新注册表配置失败: " + err.Error()})
+        return
+    }
+
+    saved, err := database.GetRegistry(registry.ID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "获取注册表配置失败: " + err.Error()})
+        return
+    }
+    if docker.ConfigServerKey(existing.URL) != docker.ConfigServerKey(saved.URL) {
+        removeRegistriesFromDockerConfig(existing.URL)
+    }
+    syncRegistriesToDockerConfig(saved)
+    c.JSON(http.StatusOK, saved.Redacted())
+}
+
+// 删除注册表配置
+func deleteImageRegistry(c *gin.Context) {
+    registry, ok := loadRegistryParam(c)
+    if !ok {
+        return
+    }
+
+    if err := database.DeleteRegistry(registry.ID); err != nil {
+        c.JSON(registryErrorStatus(err), gin.H{"error": "删除注册表配置失败: " + err.Error()})
+        return
+    }
+    removeRegistriesFromDockerConfig(registry.URL)
+    c.JSON(http.StatusOK, gin.H{"message": "注册表已删除"})
+}
+
+// 测试注册表连通性、证书及登录凭据。
+// 请求体可选，提供 username/password 时使用其代替已保存的凭据，便于保存前验证
+func testImageRegistry(c *gin.Context) {
+    registry, ok := loadRegistryParam(c)
+    if !ok {
+        return
+    }
+
+    var req struct {
+        Username string `json:"username"`
+        Password string `json:"password"`
+    }
+    if c.Request.ContentLength > 0 {
+        if err := c.ShouldBindJSON(&req); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据: " + err.Error()})
+            return
+        }
+    }
+    username, password := registry.Username, registry.Password
+    if req.Username != "" {
+        username = req.Username
+        password = req.Password
+    }
+
+    ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+    defer cancel()
+
+    // 先直接访问 /v2/ 检查网络和证书
+    probe := docker.ProbeRegistry(ctx, registry.URL, nil)
+
+    // 再由 Docker 守护进程执行登录，与实际拉取/推送使用相同的网络和证书配置
+    login := gin.H{"attempted": false}
+    if username != "" && password != "" {
+        login["attempted"] = true
+        cli, err := dockerClient(c)
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "连接Docker失败: " + err.Error()})
+            return
+        }
+
+        result, err := cli.RegistryLogin(ctx, registrytypes.AuthConfig{
+            Username:      username,
+            Password:      password,
+            ServerAddress: registry.URL,
+        })
+        if err != nil {
+            login["success"] = false
+            login["error"] = err.Error()
+        } else {
+            login["success"] = true
+            login["status"] = result.Status
+        }
+    }
+
+    success := probe.Reachable && probe.Error == ""
+    if attempted, _ := login["attempted"].(bool); attempted {
+        loginOK, _ := login["success"].(bool)
+        success = success && loginOK
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "registry": registry.Redacted(),
+        "success":  success,
+        "probe":    probe,
+        "login":    login,
+    })
+}
+
+// 根据路径中的注册表 ID 创建 v2 API 客户端，失败时已写入响应
+func registryClientParam(c *gin.Context) (*docker.RegistryClient, bool) {
+    registry, ok := loadRegistryParam(c)
+    if !ok {
+        return nil, false
+    }
+    return docker.NewRegistryClient(registry.URL, registry.Username, registry.Password), true
+}
+
+// 返回注册表 API 错误，响应中附带上游状态码。注册表返回的 4xx 除认证错误外原样透传，其余视为网关错误
+func registryAPIError(c *gin.Context, message string, err error) {
+    if errors.Is(err, docker.ErrRegistryDeleteDisabled) {
+        c.JSON(http.StatusMethodNotAllowed, gin.H{"error": message + ": " + err.Error()})
+        return
+    }
+
+    status := http.StatusBadGateway
+    body := gin.H{"error": message + ": " + err.Error()}
+    var regErr *docker.RegistryError
+    if errors.As(err, &regErr) {
+        body["upstream_status"] = regErr.StatusCode
+        if regErr.Code != "" {
+            body["upstream_code"] = regErr.Code
+        }
+        // 上游的 401/403 是注册表凭据问题，原样返回会被前端当作面板登录失效
+        switch regErr.StatusCode {
+        case http.StatusUnauthorized, http.StatusForbidden:
+            status = http.StatusBadGateway
+        default:
+            if regErr.StatusCode >= 400 && regErr.StatusCode < 500 {
+                status = regErr.StatusCode
+            }
+        }
+    }
+    c.JSON(status, body)
+}
+
+// 通过 _catalog 接口列出仓库，支持 n/last 分页参数
+func listRegistryRepositories(c *gin.Context) {
+    client, ok := registryClientParam(c)
+    if !ok {
+        return
+    }
+
+    n, _ := strconv.Atoi(c.DefaultQuery("n", "100"))
+    repositories, next, err := client.Catalog(c.Request.Context(), c.Query("last"), n)
+    if err != nil {
+        registryAPIError(c, "获取仓库列表失败", err)
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{
+        "repositories": repositories,
+        "next":         next,
+    })
+}
+
+// 列出仓库的标签
+func listRegistryTags(c *gin.Context) {
+    repo := c.Query("repo")
+    if repo == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "仓库名称不能为空"})
+        return
+    }
+    client, ok := registryClientParam(c)
+    if !ok {
+        return
+    }
+
+    tags, err := client.Tags(c.Request.Context(), repo)
+    if err != nil {
+        registryAPIError(c, "获取标签列表失败", err)
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{
+        "repository": repo,
+        "tags":       tags,
+    })
+}
+
+// 获取标签的清单，包括各平台、层大小
+func getRegistryManifest(c *gin.Context) {
+    repo := c.Query("repo")
+    tag := c.DefaultQuery("tag", "latest")
+    if repo == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "仓库名称不能为空"})
+        return
+    }
+    client, ok := registryClientParam(c)
+    if !ok {
+        return
+    }
+
+    manifest, err := client.Manifest(c.Request.Context(), repo, tag)
+    if err != nil {
+        registryAPIError(c, "获取镜像清单失败", err)
+        return
+    }
+    c.JSON(http.StatusOK, manifest)
+}
+
+// 删除注册表中的标签，需要注册表开启删除功能
+func deleteRegistryTag(c *gin.Context) {
+    repo := c.Query("repo")
+    tag := c.Query("tag")
+    if repo == "" || tag == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "仓库名称和标签不能为空"})
+        return
+    }
+    client, ok := registryClientParam(c)
+    if !ok {
+        return
+    }
+
+    digest, err := client.DeleteTag(c.Request.Context(), repo, tag)
+    if err != nil {
+        registryAPIError(c, "删除标签失败", err)
+        return
+    }
+    log.Printf("已删除注册表标签: %s:%s (%s)", repo, tag, digest)
+    c.JSON(http.StatusOK, gin.H{
+        "message": "标签已删除",
+        "digest":  digest,
+    })
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strconv"
+    "strings"
+    "testing"
+
+    "dockerpanel/backend/pkg/database"
+    "dockerpanel/backend/pkg/docker"
+
+    "github.com/gin-gonic/gin"
+)
+
+func TestRegistryAPIError(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+
+    tests := []struct {
+        name     string
+        err      error
+        status   int
+        upstream int
+    }{
+        {"上游 401 不能透传", &docker.RegistryError{StatusCode: 401, Code: "UNAUTHORIZED"}, http.StatusBadGateway, 401},
+        {"上游 403 不能透传", &docker.RegistryError{StatusCode: 403, Code: "DENIED"}, http.StatusBadGateway, 403},
+        {"上游 404 原样返回", &docker.RegistryError{StatusCode: 404, Code: "NAME_UNKNOWN"}, http.StatusNotFound, 404},
+        {"上游 5xx 返回 502", &docker.RegistryError{StatusCode: 503}, http.StatusBadGateway, 503},
+        {"包装后的错误", fmt.Errorf("获取标签: %w", &docker.RegistryError{StatusCode: 429}), http.StatusTooManyRequests, 429},
+        {"网络错误", errors.New("connection refused"), http.StatusBadGateway, 0},
+        {"未开启删除", docker.ErrRegistryDeleteDisabled, http.StatusMethodNotAllowed, 0},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            w := httptest.NewRecorder()
+            c, _ := gin.CreateTestContext(w)
+            registryAPIError(c, "获取标签失败", tt.err)
+
+            if w.Code != tt.status {
+                t.Errorf("状态码 = %d, 期望 %d", w.Code, tt.status)
+            }
+            var body struct {
+                Error          string `json:"error"`
+                UpstreamStatus int    `json:"upstream_status"`
+            }
+            if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+                t.Fatalf("解析响应失败: %v", err)
+            }
+            if body.UpstreamStatus != tt.upstream {
+                t.Errorf("upstream_status = %d, 期望 %d", body.UpstreamStatus, tt.upstream)
+            }
+            if body.Error == "" {
+                t.Error("响应缺少 error 字段")
+            }
+        })
+    }
+}
+
+func TestUpdateImageRegistryRequiresPassword(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    openTestDB(t)
+    t.Setenv("DOCKER_CONFIG", t.TempDir())
+
+    registry := &database.Registry{Name: "harbor", URL: "https://harbor.example.com", Username: "alice", Password: "secret"}
+    if err := database.CreateRegistry(registry); err != nil {
+        t.Fatal(err)
+    }
+
+    tests := []struct {
+        name   string
+        body   string
+        status int
+    }{
+        {"改地址不带密码", `{"url":"evil.example.com"}`, http.StatusBadRequest},
+        {"改用户名不带密码", `{"username":"mallory"}`, http.StatusBadRequest},
+        {"改为明文 HTTP 不带密码", `{"url":"http://harbor.example.com"}`, http.StatusBadRequest},
+        {"只是写法不同", `{"url":"https://harbor.example.com/"}`, http.StatusOK},
+        {"只改名称", `{"name":"Harbor"}`, http.StatusOK},
+        {"改地址并提供新密码", `{"url":"registry.example.com","password":"new"}`, http.StatusOK},
+    }
+    for _, tt := range tests {
+        w := httptest.NewRecorder()
+        c, _ := gin.CreateTestContext(w)
+        c.Params = gin.Params{{Key: "id", Value: strconv.FormatInt(registry.ID, 10)}}
+        c.Request = httptest.NewRequest(http.MethodPut, "/api/image-registry/1", strings.NewReader(tt.body))
+        c.Request.Header.Set("Content-Type", "application/json")
+        updateImageRegistry(c)
+        if w.Code != tt.status {
+            t.Errorf("%s: 状态码 = %d, 期望 %d: %s", tt.name, w.Code, tt.status, w.Body.String())
+        }
+    }
+
+    saved, _ := database.GetRegistry(registry.ID)
+    if saved.URL != "registry.example.com" || saved.Password != "new" {
+        t.Errorf("最终配置 = %+v", saved)
+    }
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+    "bytes"
+    "io"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "dockerpanel/backend/pkg/database"
+)
+
+func TestIsPublicIP(t *testing.T) {
+    tests := []struct {
+        ip     string
+        public bool
+    }{
+        {"8.8.8.8", true},
+        {"2606:4700:4700::1111", true},
+        {"127.0.0.1", false},
+        {"::1", false},
+        {"10.1.2.3", false},
+        {"172.16.0.1", false},
+        {"192.168.1.1", false},
+        {"169.254.169.254", false},
+        {"fe80::1", false},
+        {"fd00::1", false},
+        {"0.0.0.0", false},
+        {"::ffff:127.0.0.1", false},
+        {"224.0.0.1", false},
+    }
+    for _, tt := range tests {
+        if got := isPublicIP(net.ParseIP(tt.ip)); got != tt.public {
+            t.Errorf("isPublicIP(%s) = %v, 期望 %v", tt.ip, got, tt.public)
+        }
+    }
+}
+
+// httptest 服务监听在 127.0.0.1，未配置允许的地址段时导入客户端必须拒绝连接
+func TestImportHTTPClientRejectsLoopback(t *testing.T) {
+    openTestDB(t)
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Write([]byte("should not be reached"))
+    }))
+    defer server.Close()
+
+    for _, allowed := range []string{"", "10.0.0.0/8"} {
+        database.SetSetting(database.SettingImportAllowedNetworks, allowed)
+        resp, err := importHTTPClient.Get(server.URL)
+        if err == nil {
+            resp.Body.Close()
+            t.Fatalf("允许的地址段为 %q 时连接本机地址应被拒绝", allowed)
+        }
+        if !strings.Contains(err.Error(), "不允许从内网或本机地址导入") {
+            t.Errorf("错误信息 = %v", err)
+        }
+    }
+}
+
+// 管理员允许的地址段内的文件服务器可以导入
+func TestImportHTTPClientAllowedNetwork(t *testing.T) {
+    openTestDB(t)
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Write([]byte("archive"))
+    }))
+    defer server.Close()
+
+    database.SetSetting(database.SettingImportAllowedNetworks, "192.168.0.0/16,127.0.0.1")
+    resp, err := importHTTPClient.Get(server.URL)
+    if err != nil {
+        t.Fatalf("允许的地址应可以连接: %v", err)
+    }
+    defer resp.Body.Close()
+    if body, _ := io.ReadAll(resp.Body); string(body) != "archive" {
+        t.Errorf("响应内容 = %q", body)
+    }
+}
+
+func TestParseNetworks(t *testing.T) {
+    tests := []struct {
+        value   string
+        want    []string
+        wantErr bool
+    }{
+        {"", []string{}, false},
+        {"10.0.0.0/8, 192.168.1.10", []string{"10.0.0.0/8", "192.168.1.10/32"}, false},
+        {"fd00::/8\n::1", []string{"fd00::/8", "::1/128"}, false},
+        {"10.0.0.1/33", nil, true},
+        {"fileserver.lan", nil, true},
+    }
+    for _, tt := range tests {
+        networks, err := parseNetworks(tt.value)
+        if (err != nil) != tt.wantErr {
+            t.Errorf("parseNetworks(%q) err = %v, 期望出错: %v", tt.value, err, tt.wantErr)
+            continue
+        }
+        got := make([]string, 0)
+        for _, n := range networks {
+            got = append(got, n.String())
+        }
+        if !tt.wantErr && strings.Join(got, ",") != strings.Join(tt.want, ",") {
+            t.Errorf("parseNetworks(%q) = %v, 期望 %v", tt.value, got, tt.want)
+        }
+    }
+}
+
+func TestSizeLimitedReader(t *testing.T) {
+    tests := []struct {
+        name    string
+        size    int
+        limit   int64
+        wantErr bool
+    }{
+        {"小于上限", 10, 20, false},
+        {"恰好等于上限", 20, 20, false},
+        {"超过上限", 21, 20, true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            r := &sizeLimitedReader{r: bytes.NewReader(make([]byte, tt.size)), remaining: tt.limit}
+            data, err := io.ReadAll(r)
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("err = %v, 期望出错: %v", err, tt.wantErr)
+            }
+            if !tt.wantErr && len(data) != tt.size {
+                t.Errorf("读取 %d 字节, 期望 %d", len(data), tt.size)
+            }
+        })
+    }
+}
@@ -2,7 +2,6 @@ package api
 
 import (
     "context"
-    "dockerpanel/backend/pkg/docker"
     "net/http"
     "github.com/docker/docker/api/types"
     "github.com/gin-gonic/gin"
@@ -11,12 +10,11 @@ import (
 
 // 修改日志接口，支持实时日志
 func getContainerLogs(c *gin.Context) {
-    cli, err := docker.NewDockerClient()
+    cli, err := dockerClient(c)
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
     }
-    defer cli.Close()
 
     id := c.Param("id")
     
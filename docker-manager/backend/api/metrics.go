@@ -0,0 +1,461 @@
+package api
+
+import (
+    "context"
+    "dockerpanel/backend/pkg/database"
+    "dockerpanel/backend/pkg/docker"
+    "fmt"
+    "log"
+    "net/http"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/docker/docker/api/types"
+    "github.com/gin-gonic/gin"
+    "github.com/shirou/gopsutil/v3/cpu"
+    "github.com/shirou/gopsutil/v3/disk"
+    "github.com/shirou/gopsutil/v3/mem"
+    psnet "github.com/shirou/gopsutil/v3/net"
+)
+
+// 历史指标，由后台采集器定期写入，图表按时间范围查询
+func RegisterMetricsRoutes(r *gin.Engine) {
+    group := r.Group("/api/metrics")
+    {
+        group.GET("/history", getMetricsHistory)
+        group.GET("/targets", getMetricsTargets)
+        group.GET("/settings", getMetricsSettings)
+        group.PUT("/settings", updateMetricsSettings)
+    }
+}
+
+const (
+    defaultMetricsInterval      = 15
+    defaultMetricsRetentionDays = 30
+
+    // 指标目标名称
+    metricsHostTarget      = "host"
+    metricsContainerPrefix = "container:"
+
+    // Prometheus 标签中本机的主机名称
+    metricsLocalHostName = "local"
+    // 采集停止或读数过旧时，抓取请求触发采集的超时时间
+    metricsScrapeTimeout = 15 * time.Second
+)
+
+// 查询范围与使用的数据精度，点数控制在几百个以内
+var metricsRanges = map[string]struct {
+    Duration   time.Duration
+    Resolution int
+}{
+    "1h":  {time.Hour, database.MetricsRaw},
+    "6h":  {6 * time.Hour, database.MetricsRaw},
+    "24h": {24 * time.Hour, database.Metrics5Min},
+    "7d":  {7 * 24 * time.Hour, database.Metrics1Hour},
+    "30d": {30 * 24 * time.Hour, database.Metrics1Hour},
+}
+
+// GET /api/metrics/history?target=host|container:<名称>&range=1h
+func getMetricsHistory(c *gin.Context) {
+    target := c.DefaultQuery("target", metricsHostTarget)
+    rangeName := c.DefaultQuery("range", "1h")
+    r, ok := metricsRanges[rangeName]
+    if !ok {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的时间范围: " + rangeName})
+        return
+    }
+    host, err := selectedHost(c)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    var hostID int64
+    if host != nil {
+        hostID = host.ID
+    }
+
+    since := time.Now().Add(-r.Duration).Unix()
+    points, err := database.QueryMetrics(hostID, target, r.Resolution, since)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "查询指标失败: " + err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{
+        "target":     target,
+        "range":      rangeName,
+        "resolution": r.Resolution,
+        "points":     points,
+    })
+}
+
+// 返回所选主机最近 30 天内有数据的目标
+func getMetricsTargets(c *gin.Context) {
+    host, err := selectedHost(c)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    var hostID int64
+    if host != nil {
+        hostID = host.ID
+    }
+    targets, err := database.ListMetricTargets(hostID, time.Now().AddDate(0, 0, -30).Unix())
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "获取指标目标失败: " + err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, targets)
+}
+
+func metricsSettings() (interval, retentionDays int) {
+    value, _ := database.GetSetting(database.SettingMetricsInterval, strconv.Itoa(defaultMetricsInterval))
+    interval, err := strconv.Atoi(value)
+    if err != nil || interval < 0 {
+        interval = defaultMetricsInterval
+    }
+    value, _ = database.GetSetting(database.SettingMetricsRetentionDays, strconv.Itoa(defaultMetricsRetentionDays))
+    retentionDays, err = strconv.Atoi(value)
+    if err != nil || retentionDays <= 0 {
+        retentionDays = defaultMetricsRetentionDays
+    }
+    return interval, retentionDays
+}
+
+func getMetricsSettings(c *gin.Context) {
+    interval, retentionDays := metricsSettings()
+    c.JSON(http.StatusOK, gin.H{"interval": interval, "retentionDays": retentionDays})
+}
+
+func updateMetricsSettings(c *gin.Context) {
+    var req struct {
+        Interval      int `json:"interval"`      // 秒，0 表示停止采集
+        RetentionDays int `json:"retentionDays"` // 小时级数据保留天数
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据: " + err.Error()})
+        return
+    }
+    if req.Interval != 0 && (req.Interval < 5 || req.Interval > 3600) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "采集间隔应在 5 到 3600 秒之间"})
+        return
+    }
+    if req.RetentionDays < 1 || req.RetentionDays > 365 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "保留天数应在 1 到 365 之间"})
+        return
+    }
+    if err := database.SetSetting(database.SettingMetricsInterval, strconv.Itoa(req.Interval)); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "保存设置失败: " + err.Error()})
+        return
+    }
+    if err := database.SetSetting(database.SettingMetricsRetentionDays, strconv.Itoa(req.RetentionDays)); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "保存设置失败: " + err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"message": "设置已保存，将在下一次采集时生效"})
+}
+
+// 累计计数器的上一次读数，用于计算每秒速率
+type counterReading struct {
+    time                       time.Time
+    netRx, netTx, blkRd, blkWr uint64
+}
+
+// 最近一轮采集到的原始读数，Prometheus 接口直接导出，不再重复访问 Docker
+type hostSnapshot struct {
+    cpu, memUsed, memTotal, diskUsed, diskTotal float64
+    counters                                    counterReading
+}
+
+type containerSnapshot struct {
+    id, name, image, project string
+    cpu, memUsed, memLimit   float64
+    counters                 counterReading
+}
+
+type hostMetrics struct {
+    hostID     int64
+    hostName   string
+    time       time.Time
+    host       *hostSnapshot // 只有面板所在主机有主机指标
+    containers []containerSnapshot
+    // 本轮采集失败的原因，失败时对应读数为空，不能当作指标已回落
+    hostErr error // 主机指标
+    err     error // 容器指标，通常是无法连接主机
+}
+
+type metricsCollector struct {
+    mu       sync.Mutex
+    previous map[string]counterReading
+    latest   map[int64]*hostMetrics
+
+    // 采集停止时由抓取请求触发采集，同一时间只进行一轮
+    collectMu sync.Mutex
+}
+
+var collector = &metricsCollector{
+    previous: make(map[string]counterReading),
+    latest:   make(map[int64]*hostMetrics),
+}
+
+// 计算相对上一次读数的每秒速率，首次读数或计数器回绕（容器重启）时返回 0
+func (m *metricsCollector) rates(key string, now counterReading) (netRx, netTx, blkRd, blkWr float64) {
+    m.mu.Lock()
+    prev, ok := m.previous[key]
+    m.previous[key] = now
+    m.mu.Unlock()
+
+    seconds := now.time.Sub(prev.time).Seconds()
+    if !ok || seconds <= 0 || now.netRx < prev.netRx || now.netTx < prev.netTx ||
+        now.blkRd < prev.blkRd || now.blkWr < prev.blkWr {
+        return 0, 0, 0, 0
+    }
+    return float64(now.netRx-prev.netRx) / seconds, float64(now.netTx-prev.netTx) / seconds,
+        float64(now.blkRd-prev.blkRd) / seconds, float64(now.blkWr-prev.blkWr) / seconds
+}
+
+// 采集面板所在主机的资源使用情况
+func (m *metricsCollector) sampleHost(now time.Time) (database.MetricSample, *hostSnapshot, error) {
+    sample := database.MetricSample{Target: metricsHostTarget, Time: now.Unix()}
+    snapshot := &hostSnapshot{}
+
+    // 距上一次调用的平均使用率
+    percents, err := cpu.Percent(0, false)
+    if err != nil {
+        return sample, nil, fmt.Errorf("获取CPU信息失败: %v", err)
+    }
+    if len(percents) > 0 {
+        sample.CPU = percents[0]
+    }
+    memInfo, err := mem.VirtualMemory()
+    if err != nil {
+        return sample, nil, fmt.Errorf("获取内存信息失败: %v", err)
+    }
+    sample.MemUsed = float64(memInfo.Used)
+    sample.MemPercent = memInfo.UsedPercent
+    snapshot.memTotal = float64(memInfo.Total)
+    if diskInfo, err := disk.Usage("/"); err == nil {
+        sample.DiskPercent = diskInfo.UsedPercent
+        snapshot.diskUsed = float64(diskInfo.Used)
+        snapshot.diskTotal = float64(diskInfo.Total)
+    }
+
+    reading := counterReading{time: now}
+    if counters, err := psnet.IOCounters(false); err == nil && len(counters) > 0 {
+        reading.netRx = counters[0].BytesRecv
+        reading.netTx = counters[0].BytesSent
+    }
+    if counters, err := disk.IOCounters(); err == nil {
+        for _, d := range counters {
+            reading.blkRd += d.ReadBytes
+            reading.blkWr += d.WriteBytes
+        }
+    }
+    sample.NetRx, sample.NetTx, sample.BlkRead, sample.BlkWrite = m.rates(metricsHostTarget, reading)
+    snapshot.cpu, snapshot.memUsed, snapshot.counters = sample.CPU, sample.MemUsed, reading
+    return sample, snapshot, nil
+}
+
+// 采集一台主机上所有运行中容器的资源使用情况
+func (m *metricsCollector) sampleContainers(ctx context.Context, host *docker.Host, now time.Time) ([]database.MetricSample, []containerSnapshot, error) {
+    cli, err := docker.SharedClient(host)
+    if err != nil {
+        return nil, nil, err
+    }
+    containers, err := cli.ContainerList(ctx, types.ContainerListOptions{})
+    if err != nil {
+        return nil, nil, err
+    }
+    ids := make([]string, len(containers))
+    for i, container := range containers {
+        ids[i] = container.ID
+    }
+    collected := cli.CollectStats(ctx, ids)
+
+    var hostID int64
+    if host != nil {
+        hostID = host.ID
+    }
+    samples := make([]database.MetricSample, 0, len(collected))
+    snapshots := make([]containerSnapshot, 0, len(collected))
+    for _, container := range containers {
+        stats, ok := collected[container.ID]
+        if !ok || len(container.Names) == 0 {
+            continue
+        }
+        // 以名称为目标，重建容器后历史数据可以延续
+        name := strings.TrimPrefix(container.Names[0], "/")
+        target := metricsContainerPrefix + name
+        sample := database.MetricSample{
+            HostID:     hostID,
+            Target:     target,
+            Time:       now.Unix(),
+            CPU:        docker.CPUPercent(&stats),
+            MemUsed:    docker.MemoryUsage(&stats),
+            MemPercent: docker.MemoryPercent(&stats),
+        }
+        reading := counterReading{time: now}
+        reading.netRx, reading.netTx = docker.NetworkIO(&stats)
+        reading.blkRd, reading.blkWr = docker.BlockIO(&stats)
+        sample.NetRx, sample.NetTx, sample.BlkRead, sample.BlkWrite = m.rates(fmt.Sprintf("%d/%s", hostID, target), reading)
+        samples = append(samples, sample)
+        snapshots = append(snapshots, containerSnapshot{
+            id:       container.ID,
+            name:     name,
+            image:    container.Image,
+            project:  container.Labels["com.docker.compose.project"],
+            cpu:      sample.CPU,
+            memUsed:  sample.MemUsed,
+            memLimit: float64(stats.MemoryStats.Limit),
+            counters: reading,
+        })
+    }
+    return samples, snapshots, nil
+}
+
+// 采集一轮：本机主机指标和所有主机的容器指标，远程主机并发采集，互不影响。
+// persist 为 false 时只更新最近读数，用于采集停止时响应 Prometheus 抓取
+func (m *metricsCollector) collect(timeout time.Duration, persist bool) {
+    m.collectMu.Lock()
+    defer m.collectMu.Unlock()
+
+    now := time.Now()
+    var mu sync.Mutex
+    var samples []database.MetricSample
+    latest := make(map[int64]*hostMetrics)
+
+    local := &hostMetrics{hostName: metricsLocalHostName, time: now}
+    latest[0] = local
+    if sample, snapshot, err := m.sampleHost(now); err != nil {
+        log.Printf("采集主机指标失败: %v", err)
+        local.hostErr = err
+    } else {
+        samples = append(samples, sample)
+        local.host = snapshot
+    }
+
+    targets := []*docker.Host{nil}
+    if hosts, err := database.ListHosts(); err == nil {
+        for _, h := range hosts {
+            targets = append(targets, toDockerHost(h))
+            latest[h.ID] = &hostMetrics{hostID: h.ID, hostName: h.Name, time: now}
+        }
+    }
+
+    var wg sync.WaitGroup
+    for _, h := range targets {
+        wg.Add(1)
+        go func(h *docker.Host) {
+            defer wg.Done()
+            ctx, cancel := context.WithTimeout(context.Background(), timeout)
+            defer cancel()
+            result, snapshots, err := m.sampleContainers(ctx, h, now)
+            if err != nil {
+                name := "本机"
+                if h != nil {
+                    name = h.Name
+                }
+                log.Printf("采集主机 %s 的容器指标失败: %v", name, err)
+                mu.Lock()
+                if h == nil {
+                    local.err = err
+                } else {
+                    latest[h.ID].err = err
+                }
+                mu.Unlock()
+                return
+            }
+            mu.Lock()
+            samples = append(samples, result...)
+            if h == nil {
+                local.containers = snapshots
+            } else {
+                latest[h.ID].containers = snapshots
+            }
+            mu.Unlock()
+        }(h)
+    }
+    wg.Wait()
+
+    m.mu.Lock()
+    m.latest = latest
+    m.mu.Unlock()
+
+    if !persist {
+        return
+    }
+    if err := database.InsertMetricSamples(samples); err != nil {
+        log.Printf("保存指标失败: %v", err)
+    }
+}
+
+// snapshot 返回最近一轮采集的读数，超过 maxAge 时先重新采集
+func (m *metricsCollector) snapshot(maxAge time.Duration) []*hostMetrics {
+    m.mu.Lock()
+    local, ok := m.latest[0]
+    m.mu.Unlock()
+    if !ok || time.Since(local.time) > maxAge {
+        m.collect(metricsScrapeTimeout, false)
+    }
+
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    result := make([]*hostMetrics, 0, len(m.latest))
+    for _, h := range m.latest {
+        result = append(result, h)
+    }
+    return result
+}
+
+// 汇总与清理：原始数据保留 1 天，5 分钟数据保留 7 天，小时数据按设置保留
+func (m *metricsCollector) maintain(retentionDays int) {
+    now := time.Now()
+    if err := database.RollupMetrics(database.MetricsRaw, database.Metrics5Min, now.Unix()); err != nil {
+        log.Printf("汇总指标失败: %v", err)
+    }
+    if err := database.RollupMetrics(database.Metrics5Min, database.Metrics1Hour, now.Unix()); err != nil {
+        log.Printf("汇总指标失败: %v", err)
+    }
+    retention := map[int]time.Time{
+        database.MetricsRaw:   now.Add(-24 * time.Hour),
+        database.Metrics5Min:  now.AddDate(0, 0, -7),
+        database.Metrics1Hour: now.AddDate(0, 0, -retentionDays),
+    }
+    for resolution, before := range retention {
+        if _, err := database.PruneMetrics(resolution, before.Unix()); err != nil {
+            log.Printf("清理指标失败: %v", err)
+        }
+    }
+
+    // 已删除容器的计数器读数不再需要
+    m.mu.Lock()
+    for key, reading := range m.previous {
+        if now.Sub(reading.time) > 10*time.Minute {
+            delete(m.previous, key)
+        }
+    }
+    m.mu.Unlock()
+}
+
+// StartMetricsCollector 启动后台指标采集，采集间隔和保留天数每轮重新读取设置
+func StartMetricsCollector() {
+    m := collector
+    go func() {
+        lastMaintain := time.Now()
+        for {
+            interval, retentionDays := metricsSettings()
+            if interval == 0 {
+                // 已停止采集，定期检查设置是否重新开启
+                time.Sleep(time.Minute)
+                continue
+            }
+            m.collect(time.Duration(interval)*time.Second, true)
+            if time.Since(lastMaintain) >= 5*time.Minute {
+                m.maintain(retentionDays)
+                lastMaintain = time.Now()
+            }
+            time.Sleep(time.Duration(interval) * time.Second)
+        }
+    }()
+}
@@ -2,7 +2,6 @@ package api
 
 import (
     "context"
-    "dockerpanel/backend/pkg/docker"
     "net/http"
 
     "github.com/docker/docker/api/types"  // 保留需要的导入
@@ -19,12 +18,11 @@ func RegisterNetworkRoutes(r *gin.Engine) {
 }
 
 func listNetworks(c *gin.Context) {
-    cli, err := docker.NewDockerClient()
+    cli, err := dockerClient(c)
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
     }
-    defer cli.Close()
 
     // 获取详细的网络信息
     networks, err := cli.NetworkList(context.Background(), types.NetworkListOptions{})
@@ -55,12 +53,11 @@ func createNetwork(c *gin.Context) {
 		return
 	}
 
-	cli, err := docker.NewDockerClient()
+	cli, err := dockerClient(c)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	defer cli.Close()
 
 	resp, err := cli.NetworkCreate(context.Background(), req.Name, types.NetworkCreate{
 		Driver: req.Driver,
@@ -74,12 +71,11 @@ func createNetwork(c *gin.Context) {
 }
 
 func removeNetwork(c *gin.Context) {
-    cli, err := docker.NewDockerClient()
+    cli, err := dockerClient(c)
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
     }
-    defer cli.Close()
 
     id := c.Param("id")
 
@@ -0,0 +1,241 @@
+package api
+
+import (
+    "crypto/subtle"
+    "dockerpanel/backend/pkg/database"
+    "net/http"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/prometheus/client_golang/prometheus"
+    "github.com/prometheus/client_golang/prometheus/collectors"
+    "github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Prometheus 指标接口。/metrics 不在 /api 下，不使用登录会话，
+// 通过 Authorization: Bearer <抓取令牌> 访问，令牌由管理员在面板中生成，
+// 也可以通过环境变量 DOCKERPANEL_METRICS_TOKEN 指定
+func RegisterPrometheusRoutes(r *gin.Engine) {
+    r.GET("/metrics", scrapeMetrics)
+
+    group := r.Group("/api/metrics/prometheus")
+    {
+        group.GET("", getPrometheusSettings)
+        group.POST("/token", createScrapeToken)
+        group.DELETE("/token", deleteScrapeToken)
+    }
+}
+
+const scrapeTokenEnv = "DOCKERPANEL_METRICS_TOKEN"
+
+const metricsNamespace = "dockerpanel"
+
+// 面板自身的指标
+var (
+    httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+        Namespace: metricsNamespace,
+        Name:      "http_request_duration_seconds",
+        Help:      "API 请求处理耗时",
+        Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+    }, []string{"method", "route", "status"})
+
+    terminalSessions = prometheus.NewGauge(prometheus.GaugeOpts{
+        Namespace: metricsNamespace,
+        Name:      "terminal_sessions",
+        Help:      "当前打开的容器终端数",
+    })
+
+    deployJobsRunning = prometheus.NewGauge(prometheus.GaugeOpts{
+        Namespace: metricsNamespace,
+        Name:      "deploy_jobs_running",
+        Help:      "正在执行的 compose 部署任务数",
+    })
+
+    deployJobsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+        Namespace: metricsNamespace,
+        Name:      "deploy_jobs_total",
+        Help:      "已完成的 compose 部署任务数，result 为 success、warning 或 failure",
+    }, []string{"result"})
+)
+
+var metricsRegistry = prometheus.NewRegistry()
+
+func init() {
+    metricsRegistry.MustRegister(
+        collectors.NewGoCollector(),
+        collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+        httpRequestDuration,
+        terminalSessions,
+        deployJobsRunning,
+        deployJobsTotal,
+        &resourceCollector{},
+    )
+}
+
+// PrometheusMiddleware 记录 API 请求耗时。
+// 按路由模板统计，避免容器 ID 等参数导致标签过多；WebSocket 和 SSE 长连接不计入
+func PrometheusMiddleware() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        if !strings.HasPrefix(c.Request.URL.Path, "/api/") ||
+            c.GetHeader("Upgrade") != "" ||
+            strings.Contains(c.GetHeader("Accept"), "text/event-stream") {
+            c.Next()
+            return
+        }
+        start := time.Now()
+        c.Next()
+
+        route := c.FullPath()
+        if route == "" {
+            route = "unmatched"
+        }
+        httpRequestDuration.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+            Observe(time.Since(start).Seconds())
+    }
+}
+
+var (
+    hostLabels      = []string{"host"}
+    containerLabels = []string{"host", "container", "id", "image", "project"}
+)
+
+func metricDesc(name, help string, labels []string) *prometheus.Desc {
+    return prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", name), help, labels, nil)
+}
+
+// 主机和容器的资源指标，来自后台采集器最近一轮的读数
+var (
+    hostCPUDesc       = metricDesc("host_cpu_percent", "主机 CPU 使用率", hostLabels)
+    hostMemUsedDesc   = metricDesc("host_memory_used_bytes", "主机已用内存", hostLabels)
+    hostMemTotalDesc  = metricDesc("host_memory_total_bytes", "主机总内存", hostLabels)
+    hostDiskUsedDesc  = metricDesc("host_disk_used_bytes", "根分区已用空间", hostLabels)
+    hostDiskTotalDesc = metricDesc("host_disk_total_bytes", "根分区总空间", hostLabels)
+    hostNetRxDesc     = metricDesc("host_network_receive_bytes_total", "主机网络累计接收字节数", hostLabels)
+    hostNetTxDesc     = metricDesc("host_network_transmit_bytes_total", "主机网络累计发送字节数", hostLabels)
+    hostBlkReadDesc   = metricDesc("host_disk_read_bytes_total", "主机磁盘累计读取字节数", hostLabels)
+    hostBlkWriteDesc  = metricDesc("host_disk_write_bytes_total", "主机磁盘累计写入字节数", hostLabels)
+
+    containerCPUDesc      = metricDesc("container_cpu_percent", "容器 CPU 使用率，多核时可超过 100", containerLabels)
+    containerMemUsedDesc  = metricDesc("container_memory_usage_bytes", "容器内存使用量，不含页缓存", containerLabels)
+    containerMemLimitDesc = metricDesc("container_memory_limit_bytes", "容器内存限制", containerLabels)
+    containerNetRxDesc    = metricDesc("container_network_receive_bytes_total", "容器网络累计接收字节数", containerLabels)
+    containerNetTxDesc    = metricDesc("container_network_transmit_bytes_total", "容器网络累计发送字节数", containerLabels)
+    containerBlkReadDesc  = metricDesc("container_blkio_read_bytes_total", "容器块设备累计读取字节数", containerLabels)
+    containerBlkWriteDesc = metricDesc("container_blkio_write_bytes_total", "容器块设备累计写入字节数", containerLabels)
+    containersRunningDesc = metricDesc("containers_running", "运行中的容器数", hostLabels)
+    scrapeAgeDesc         = metricDesc("metrics_age_seconds", "导出的资源指标距采集时的秒数", hostLabels)
+)
+
+type resourceCollector struct{}
+
+func (r *resourceCollector) Describe(ch chan<- *prometheus.Desc) {
+    for _, desc := range []*prometheus.Desc{
+        hostCPUDesc, hostMemUsedDesc, hostMemTotalDesc, hostDiskUsedDesc, hostDiskTotalDesc,
+        hostNetRxDesc, hostNetTxDesc, hostBlkReadDesc, hostBlkWriteDesc,
+        containerCPUDesc, containerMemUsedDesc, containerMemLimitDesc, containerNetRxDesc,
+        containerNetTxDesc, containerBlkReadDesc, containerBlkWriteDesc, containersRunningDesc, scrapeAgeDesc,
+    } {
+        ch <- desc
+    }
+}
+
+// 正常情况下直接使用后台采集的读数；采集已停止或读数过旧时由本次抓取触发采集
+func (r *resourceCollector) Collect(ch chan<- prometheus.Metric) {
+    maxAge := 2 * time.Minute
+    if interval, _ := metricsSettings(); interval > 0 {
+        maxAge = 3 * time.Duration(interval) * time.Second
+    }
+
+    for _, h := range collector.snapshot(maxAge) {
+        gauge := func(desc *prometheus.Desc, value float64, labels ...string) {
+            ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labels...)
+        }
+        counter := func(desc *prometheus.Desc, value uint64, labels ...string) {
+            ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(value), labels...)
+        }
+
+        gauge(scrapeAgeDesc, time.Since(h.time).Seconds(), h.hostName)
+        gauge(containersRunningDesc, float64(len(h.containers)), h.hostName)
+        if s := h.host; s != nil {
+            gauge(hostCPUDesc, s.cpu, h.hostName)
+            gauge(hostMemUsedDesc, s.memUsed, h.hostName)
+            gauge(hostMemTotalDesc, s.memTotal, h.hostName)
+            gauge(hostDiskUsedDesc, s.diskUsed, h.hostName)
+            gauge(hostDiskTotalDesc, s.diskTotal, h.hostName)
+            counter(hostNetRxDesc, s.counters.netRx, h.hostName)
+            counter(hostNetTxDesc, s.counters.netTx, h.hostName)
+            counter(hostBlkReadDesc, s.counters.blkRd, h.hostName)
+            counter(hostBlkWriteDesc, s.counters.blkWr, h.hostName)
+        }
+        for _, s := range h.containers {
+            labels := []string{h.hostName, s.name, shortID(s.id), s.image, s.project}
+            gauge(containerCPUDesc, s.cpu, labels...)
+            gauge(containerMemUsedDesc, s.memUsed, labels...)
+            gauge(containerMemLimitDesc, s.memLimit, labels...)
+            counter(containerNetRxDesc, s.counters.netRx, labels...)
+            counter(containerNetTxDesc, s.counters.netTx, labels...)
+            counter(containerBlkReadDesc, s.counters.blkRd, labels...)
+            counter(containerBlkWriteDesc, s.counters.blkWr, labels...)
+        }
+    }
+}
+
+func shortID(id string) string {
+    if len(id) > 12 {
+        return id[:12]
+    }
+    return id
+}
+
+var metricsHandler = promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+
+// GET /metrics，未生成令牌时返回 404，避免暴露接口存在
+func scrapeMetrics(c *gin.Context) {
+    envToken := os.Getenv(scrapeTokenEnv)
+    if envToken == "" && !database.HasScrapeToken() {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Prometheus 接口未启用，请先在面板中生成抓取令牌"})
+        return
+    }
+
+    token := strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+    valid := database.CheckScrapeToken(token)
+    if !valid && envToken != "" {
+        valid = subtle.ConstantTimeCompare([]byte(token), []byte(envToken)) == 1
+    }
+    if !valid {
+        c.Header("WWW-Authenticate", `Bearer realm="metrics"`)
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "抓取令牌无效"})
+        return
+    }
+    metricsHandler.ServeHTTP(c.Writer, c.Request)
+}
+
+func getPrometheusSettings(c *gin.Context) {
+    c.JSON(http.StatusOK, gin.H{
+        "enabled":  database.HasScrapeToken() || os.Getenv(scrapeTokenEnv) != "",
+        "hasToken": database.HasScrapeToken(),
+        "envToken": os.Getenv(scrapeTokenEnv) != "",
+        "path":     "/metrics",
+    })
+}
+
+// 生成新令牌，旧令牌立即失效
+func createScrapeToken(c *gin.Context) {
+    token, err := database.CreateScrapeToken()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "生成抓取令牌失败: " + err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"token": token, "message": "令牌只显示一次，请立即保存"})
+}
+
+func deleteScrapeToken(c *gin.Context) {
+    if err := database.DeleteScrapeToken(); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "删除抓取令牌失败: " + err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"message": "抓取令牌已删除"})
+}
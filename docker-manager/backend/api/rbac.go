@@ -0,0 +1,226 @@
+package api
+
+import (
+    "context"
+    "dockerpanel/backend/pkg/database"
+    "log"
+    "net/http"
+    "strings"
+
+    "github.com/docker/docker/errdefs"
+    "github.com/gin-gonic/gin"
+)
+
+// 资源类型，决定如何从请求参数中解析出被访问的资源
+const (
+    resourceContainer = "container"
+    resourceProject   = "project"
+)
+
+type accessRule struct {
+    Action    string
+    Resource  string
+    Param     string // 路由中资源名称所在的参数
+    Query     string // 资源名称在查询参数中时使用
+    AdminOnly bool   // 只有管理员角色可以访问，不受额外授权影响
+}
+
+// 路由与所需权限的对应关系，键为 "方法 路由模板"。
+// 未列出的路由 GET 视为 read，其他方法视为 write
+var accessRules = map[string]accessRule{
+    "POST /api/containers/:id/start":     {Action: database.ActionOperate, Resource: resourceContainer, Param: "id"},
+    "POST /api/containers/:id/stop":      {Action: database.ActionOperate, Resource: resourceContainer, Param: "id"},
+    "POST /api/containers/:id/restart":   {Action: database.ActionOperate, Resource: resourceContainer, Param: "id"},
+    "POST /api/containers/:id/pause":     {Action: database.ActionOperate, Resource: resourceContainer, Param: "id"},
+    "POST /api/containers/:id/unpause":   {Action: database.ActionOperate, Resource: resourceContainer, Param: "id"},
+    "POST /api/containers/:id/recreate":  {Action: database.ActionWrite, Resource: resourceContainer, Param: "id"},
+    "DELETE /api/containers/:id":         {Action: database.ActionWrite, Resource: resourceContainer, Param: "id"},
+    "GET /api/containers/:id/logs":       {Action: database.ActionLogs, Resource: resourceContainer, Param: "id"},
+    "GET /api/containers/:id/terminal":   {Action: database.ActionExec, Resource: resourceContainer, Param: "id"},
+    "GET /api/containers/:id/exec":       {Action: database.ActionExec, Resource: resourceContainer, Param: "id"},
+
+    "POST /api/compose/:name/start":      {Action: database.ActionOperate, Resource: resourceProject, Param: "name"},
+    "POST /api/compose/:name/stop":       {Action: database.ActionOperate, Resource: resourceProject, Param: "name"},
+    "GET /api/compose/:name/status":      {Action: database.ActionRead, Resource: resourceProject, Param: "name"},
+    "GET /api/compose/:name/logs":        {Action: database.ActionLogs, Resource: resourceProject, Param: "name"},
+    "GET /api/compose/:name/yaml":        {Action: database.ActionRead, Resource: resourceProject, Param: "name"},
+    "POST /api/compose/:name/yaml":       {Action: database.ActionWrite, Resource: resourceProject, Param: "name"},
+    "DELETE /api/compose/remove/:name":   {Action: database.ActionWrite, Resource: resourceProject, Param: "name"},
+
+    // 部署通过返回 SSE 的 POST 请求完成，项目名称在查询参数中
+    "POST /api/compose/deploy/events":    {Action: database.ActionWrite, Resource: resourceProject, Query: "name"},
+
+    // 拉取镜像不修改已有资源，运维角色和 CI 令牌只需 operate 即可
+    "POST /api/images/pull":              {Action: database.ActionOperate},
+    "POST /api/images/pull/progress":     {Action: database.ActionOperate},
+
+    // 导出会下载完整的镜像内容，浏览注册表会使用面板保存的凭据，只读角色不能访问
+    "GET /api/images/export":                  {Action: database.ActionOperate},
+    "GET /api/images/export/:id":              {Action: database.ActionOperate},
+    "GET /api/image-registry/:id/repositories": {Action: database.ActionOperate},
+    "GET /api/image-registry/:id/tags":         {Action: database.ActionOperate},
+    "GET /api/image-registry/:id/manifest":     {Action: database.ActionOperate},
+
+    // 只有项目授权的用户需要通过 project 参数订阅该项目的事件，推送时还会逐条检查
+    "GET /api/events":                    {Action: database.ActionRead, Resource: resourceProject, Query: "project"},
+    "GET /api/events/ws":                 {Action: database.ActionRead, Resource: resourceProject, Query: "project"},
+
+    // 所有用户都可以查看主机列表并切换主机，添加和修改连接只对管理员开放
+    "POST /api/hosts":                    {Action: database.ActionWrite, AdminOnly: true},
+    "PUT /api/hosts/:id":                 {Action: database.ActionWrite, AdminOnly: true},
+    "DELETE /api/hosts/:id":              {Action: database.ActionWrite, AdminOnly: true},
+    "POST /api/hosts/:id/test":           {Action: database.ActionWrite, AdminOnly: true},
+    "POST /api/hosts/:id/host-key":       {Action: database.ActionWrite, AdminOnly: true},
+
+    "PUT /api/metrics/settings":          {Action: database.ActionWrite, AdminOnly: true},
+    // 允许从内网地址导入会放开对面板所在网络的访问
+    "PUT /api/images/import/settings":    {Action: database.ActionWrite, AdminOnly: true},
+}
+
+// 用户管理、审计日志、告警与 Prometheus 抓取令牌接口只对管理员开放
+var adminOnlyPrefixes = []string{"/api/users", "/api/audit", "/api/alerts", "/api/tasks", "/api/metrics/prometheus"}
+
+// 令牌管理接口由处理函数按所有者校验，只允许通过登录会话访问，避免令牌自我扩权
+var sessionOnlyPrefixes = []string{"/api/tokens"}
+
+func hasPathPrefix(path string, prefixes []string) bool {
+    for _, prefix := range prefixes {
+        if path == prefix || strings.HasPrefix(path, prefix+"/") {
+            return true
+        }
+    }
+    return false
+}
+
+// RBACMiddleware 按角色和额外授权检查当前用户能否访问接口，需放在 AuthMiddleware 之后。
+// 通过 API 令牌访问时还需要令牌范围允许该操作
+func RBACMiddleware() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        user := currentUser(c)
+        path := c.Request.URL.Path
+        // 未登录的请求已由 AuthMiddleware 处理，能走到这里的只有公开接口
+        if user == nil || strings.HasPrefix(path, "/api/auth/") {
+            c.Next()
+            return
+        }
+
+        token := currentAPIToken(c)
+        if hasPathPrefix(path, sessionOnlyPrefixes) {
+            if token != nil {
+                c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API 令牌不能管理令牌"})
+                return
+            }
+            c.Next()
+            return
+        }
+        if user.Role == database.RoleAdmin && token == nil {
+            c.Next()
+            return
+        }
+
+        rule := lookupAccessRule(c)
+        if rule.AdminOnly && (user.Role != database.RoleAdmin || token != nil) {
+            c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "只有管理员可以执行该操作"})
+            return
+        }
+
+        // 无法确定资源时不能按未授权处理，否则容器不存在或 Docker 连接失败都会显示为没有权限
+        resource, err := resolveResource(c, rule)
+        if err != nil {
+            log.Printf("解析资源失败: %v", err)
+            if errdefs.IsNotFound(err) {
+                c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "资源不存在: " + err.Error()})
+            } else {
+                c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"error": "获取资源信息失败: " + err.Error()})
+            }
+            return
+        }
+
+        allowed, err := accessChecker(c)
+        if err != nil {
+            c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "读取权限失败: " + err.Error()})
+            return
+        }
+        if !allowed(rule.Action, resource) {
+            c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+                "error":  "没有权限执行该操作",
+                "action": rule.Action,
+            })
+            return
+        }
+        c.Next()
+    }
+}
+
+// accessChecker 返回判断当前用户能否对资源执行操作的函数，
+// 事件推送等需要逐条判断的场景在订阅时调用一次，之后复用
+func accessChecker(c *gin.Context) (func(action string, resource *database.Resource) bool, error) {
+    user := currentUser(c)
+    token := currentAPIToken(c)
+    if user == nil {
+        return func(string, *database.Resource) bool { return false }, nil
+    }
+    if user.Role == database.RoleAdmin && token == nil {
+        return func(string, *database.Resource) bool { return true }, nil
+    }
+
+    // 服务令牌不属于任何用户，只按令牌范围判断
+    var permissions []database.Permission
+    checkUser := token == nil || token.Type == database.TokenPersonal
+    if checkUser {
+        var err error
+        if permissions, err = database.ListUserPermissions(user.ID); err != nil {
+            return nil, err
+        }
+    }
+    return func(action string, resource *database.Resource) bool {
+        if checkUser && !database.Allowed(user.Role, permissions, action, resource) {
+            return false
+        }
+        return token == nil || database.Allowed("", token.Scopes, action, resource)
+    }, nil
+}
+
+func lookupAccessRule(c *gin.Context) accessRule {
+    if rule, ok := accessRules[c.Request.Method+" "+c.FullPath()]; ok {
+        return rule
+    }
+    if hasPathPrefix(c.Request.URL.Path, adminOnlyPrefixes) {
+        return accessRule{Action: database.ActionWrite, AdminOnly: true}
+    }
+    if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+        return accessRule{Action: database.ActionRead}
+    }
+    return accessRule{Action: database.ActionWrite}
+}
+
+// 解析被访问的资源，容器通过 compose 标签确定所属项目
+func resolveResource(c *gin.Context, rule accessRule) (*database.Resource, error) {
+    name := c.Param(rule.Param)
+    if rule.Query != "" {
+        name = c.Query(rule.Query)
+    }
+    switch rule.Resource {
+    case resourceProject:
+        return &database.Resource{Project: name}, nil
+    case resourceContainer:
+        cli, err := dockerClient(c)
+        if err != nil {
+            return nil, err
+        }
+
+        inspect, err := cli.InspectContainer(context.Background(), name)
+        if err != nil {
+            return nil, err
+        }
+        labels := map[string]string{}
+        if inspect.Config != nil && inspect.Config.Labels != nil {
+            labels = inspect.Config.Labels
+        }
+        return &database.Resource{
+            Project: labels["com.docker.compose.project"],
+            Labels:  labels,
+        }, nil
+    }
+    return nil, nil
+}
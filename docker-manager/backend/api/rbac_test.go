@@ -0,0 +1,156 @@
+package api
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "dockerpanel/backend/pkg/database"
+    "github.com/gin-gonic/gin"
+)
+
+// newRBACTestRouter 在 RBACMiddleware 前直接放入当前用户和令牌，路由只返回 200
+func newRBACTestRouter(user *database.User, token *database.APIToken) *gin.Engine {
+    gin.SetMode(gin.TestMode)
+    r := gin.New()
+    r.Use(func(c *gin.Context) {
+        c.Set(contextUserKey, user)
+        if token != nil {
+            c.Set(contextTokenKey, token)
+        }
+    }, RBACMiddleware())
+    ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+    r.GET("/api/containers", ok)
+    r.DELETE("/api/volumes/:name", ok)
+    r.POST("/api/compose/:name/start", ok)
+    r.POST("/api/compose/:name/yaml", ok)
+    r.GET("/api/images/export", ok)
+    r.POST("/api/hosts", ok)
+    r.GET("/api/users", ok)
+    r.PUT("/api/alerts/rules/:id", ok)
+    r.GET("/api/tokens", ok)
+    return r
+}
+
+func createTestUser(t *testing.T, username, role string, permissions ...database.Permission) *database.User {
+    t.Helper()
+    user, err := database.CreateUser(username, "password123", role)
+    if err != nil {
+        t.Fatalf("创建用户失败: %v", err)
+    }
+    if err := database.ReplaceUserPermissions(user.ID, permissions); err != nil {
+        t.Fatalf("保存授权失败: %v", err)
+    }
+    return user
+}
+
+func TestRBACMiddleware(t *testing.T) {
+    openTestDB(t)
+    admin := createTestUser(t, "admin", database.RoleAdmin)
+    operator := createTestUser(t, "operator", database.RoleOperator)
+    viewer := createTestUser(t, "viewer", database.RoleViewer)
+    // 只读用户在 web 项目上额外拥有 operate 和 write
+    scoped := createTestUser(t, "scoped", database.RoleViewer,
+        database.Permission{Scope: "project:web", Actions: []string{database.ActionOperate, database.ActionWrite}})
+
+    personal := func(scopes ...database.Permission) *database.APIToken {
+        return &database.APIToken{Type: database.TokenPersonal, Scopes: scopes}
+    }
+    service := &database.APIToken{Type: database.TokenService, Scopes: []database.Permission{
+        {Scope: "project:web", Actions: []string{database.ActionOperate}},
+    }}
+    serviceUser := &database.User{Username: "service:ci"}
+    readAll := database.Permission{Scope: "*", Actions: []string{database.ActionRead}}
+    everything := database.Permission{Scope: "*", Actions: []string{
+        database.ActionRead, database.ActionLogs, database.ActionOperate, database.ActionExec, database.ActionWrite,
+    }}
+
+    tests := []struct {
+        name   string
+        user   *database.User
+        token  *database.APIToken
+        method string
+        path   string
+        status int
+    }{
+        // 未列出的路由：GET 为 read，其他方法为 write，非管理员角色默认没有 write
+        {"只读用户查看列表", viewer, nil, "GET", "/api/containers", 200},
+        {"只读用户删除卷", viewer, nil, "DELETE", "/api/volumes/data", 403},
+        {"运维删除卷", operator, nil, "DELETE", "/api/volumes/data", 403},
+        {"管理员删除卷", admin, nil, "DELETE", "/api/volumes/data", 200},
+
+        {"运维启动项目", operator, nil, "POST", "/api/compose/web/start", 200},
+        {"只读用户启动项目", viewer, nil, "POST", "/api/compose/web/start", 403},
+        {"只读用户导出镜像", viewer, nil, "GET", "/api/images/export", 403},
+        {"运维导出镜像", operator, nil, "GET", "/api/images/export", 200},
+
+        // 项目授权只覆盖该项目，不覆盖其他项目和不针对具体资源的操作
+        {"项目授权启动本项目", scoped, nil, "POST", "/api/compose/web/start", 200},
+        {"项目授权修改本项目", scoped, nil, "POST", "/api/compose/web/yaml", 200},
+        {"项目授权启动其他项目", scoped, nil, "POST", "/api/compose/db/start", 403},
+        {"项目授权删除卷", scoped, nil, "DELETE", "/api/volumes/data", 403},
+
+        // 管理员专属接口，通过令牌访问时即使是管理员也不允许
+        {"运维添加主机", operator, nil, "POST", "/api/hosts", 403},
+        {"运维查看用户", operator, nil, "GET", "/api/users", 403},
+        {"运维修改告警规则", operator, nil, "PUT", "/api/alerts/rules/1", 403},
+        {"管理员查看用户", admin, nil, "GET", "/api/users", 200},
+        {"管理员令牌查看用户", admin, personal(everything), "GET", "/api/users", 403},
+
+        // 个人令牌的权限为所有者权限与令牌范围的交集
+        {"只读令牌查看列表", operator, personal(readAll), "GET", "/api/containers", 200},
+        {"只读令牌启动项目", operator, personal(readAll), "POST", "/api/compose/web/start", 403},
+        {"全部范围不超过所有者权限", viewer, personal(everything), "POST", "/api/compose/web/start", 403},
+        {"管理员令牌按范围判断", admin, personal(readAll), "DELETE", "/api/volumes/data", 403},
+
+        // 服务令牌只按令牌范围判断
+        {"服务令牌启动授权项目", serviceUser, service, "POST", "/api/compose/web/start", 200},
+        {"服务令牌启动其他项目", serviceUser, service, "POST", "/api/compose/db/start", 403},
+        {"服务令牌查看列表", serviceUser, service, "GET", "/api/containers", 403},
+
+        // 令牌管理只允许通过登录会话访问
+        {"会话管理令牌", viewer, nil, "GET", "/api/tokens", 200},
+        {"令牌管理令牌", admin, personal(everything), "GET", "/api/tokens", 403},
+    }
+    for _, tt := range tests {
+        w := httptest.NewRecorder()
+        req := httptest.NewRequest(tt.method, tt.path, nil)
+        newRBACTestRouter(tt.user, tt.token).ServeHTTP(w, req)
+        if w.Code != tt.status {
+            t.Errorf("%s: %s %s 返回 %d, 期望 %d: %s", tt.name, tt.method, tt.path, w.Code, tt.status, w.Body.String())
+        }
+    }
+}
+
+func TestLookupAccessRule(t *testing.T) {
+    tests := []struct {
+        method    string
+        route     string
+        path      string
+        action    string
+        adminOnly bool
+    }{
+        {"GET", "/api/volumes", "/api/volumes", database.ActionRead, false},
+        {"HEAD", "/api/volumes", "/api/volumes", database.ActionRead, false},
+        {"POST", "/api/volumes", "/api/volumes", database.ActionWrite, false},
+        {"PUT", "/api/networks/:id", "/api/networks/n1", database.ActionWrite, false},
+        {"GET", "/api/containers/:id/logs", "/api/containers/c1/logs", database.ActionLogs, false},
+        {"GET", "/api/containers/:id/terminal", "/api/containers/c1/terminal", database.ActionExec, false},
+        {"POST", "/api/images/pull", "/api/images/pull", database.ActionOperate, false},
+        {"GET", "/api/audit", "/api/audit", database.ActionWrite, true},
+        {"GET", "/api/metrics/prometheus", "/api/metrics/prometheus", database.ActionWrite, true},
+        {"PUT", "/api/images/import/settings", "/api/images/import/settings", database.ActionWrite, true},
+        // 前缀按路径段匹配，/api/usersettings 不属于 /api/users
+        {"GET", "/api/usersettings", "/api/usersettings", database.ActionRead, false},
+    }
+    gin.SetMode(gin.TestMode)
+    for _, tt := range tests {
+        var rule accessRule
+        r := gin.New()
+        r.Handle(tt.method, tt.route, func(c *gin.Context) { rule = lookupAccessRule(c) })
+        r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+        if rule.Action != tt.action || rule.AdminOnly != tt.adminOnly {
+            t.Errorf("%s %s = %+v, 期望 action=%s adminOnly=%v", tt.method, tt.path, rule, tt.action, tt.adminOnly)
+        }
+    }
+}
@@ -2,7 +2,6 @@ package api
 
 import (
 	"context"
-	"dockerpanel/backend/pkg/docker"
 	"fmt"
 	"net/http"
 	"os/exec"
@@ -10,7 +9,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
-	"encoding/json"
+	"dockerpanel/backend/pkg/docker"
 	"github.com/docker/docker/api/types"
 	"github.com/gin-gonic/gin"
 	"github.com/shirou/gopsutil/v3/cpu"
@@ -30,12 +29,11 @@ func RegisterSystemRoutes(r *gin.Engine) {
 // 获取系统信息
 func getSystemInfo(c *gin.Context) {
 	// 创建Docker客户端
-	cli, err := docker.NewDockerClient()
+	cli, err := dockerClient(c)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "连接Docker失败: " + err.Error()})
 		return
 	}
-	defer cli.Close()
 
 	// 获取Docker信息
 	info, err := cli.Info(context.Background())
@@ -118,7 +116,7 @@ func getSystemStats(c *gin.Context) {
 	}
 
 	// 获取Docker容器资源使用情况
-	containerStats, err := getContainersStats()
+	containerStats, err := getContainersStats(c)
 	if err != nil {
 		fmt.Printf("获取容器统计信息失败: %v\n", err)
 		// 继续执行，不返回错误
@@ -136,61 +134,43 @@ func getSystemStats(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
-// 获取所有容器的资源使用情况
-func getContainersStats() ([]gin.H, error) {
-	cli, err := docker.NewDockerClient()
+// 获取所有容器的资源使用情况，以有限并发采集，单次最多等待 15 秒
+func getContainersStats(c *gin.Context) ([]gin.H, error) {
+	cli, err := dockerClient(c)
 	if err != nil {
 		return nil, err
 	}
-	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
 
 	// 获取所有运行中的容器
-	containers, err := cli.ContainerList(context.Background(), types.ContainerListOptions{
+	containers, err := cli.ContainerList(ctx, types.ContainerListOptions{
 		All: false, // 只获取运行中的容器
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	var stats []gin.H
-	for _, container := range containers {
-		// 获取容器统计信息
-		containerStats, err := cli.ContainerStats(context.Background(), container.ID, false)
-		if err != nil {
-			continue
-		}
-		defer containerStats.Body.Close()
+	ids := make([]string, len(containers))
+	for i, container := range containers {
+		ids[i] = container.ID
+	}
+	collected := cli.CollectStats(ctx, ids)
 
-		// 解析统计信息
-		var statsJSON types.StatsJSON
-		if err := json.NewDecoder(containerStats.Body).Decode(&statsJSON); err != nil {
+	stats := []gin.H{}
+	for _, container := range containers {
+		statsJSON, ok := collected[container.ID]
+		if !ok {
 			continue
 		}
-
-		// 计算CPU使用率
-		cpuDelta := float64(statsJSON.CPUStats.CPUUsage.TotalUsage - statsJSON.PreCPUStats.CPUUsage.TotalUsage)
-		systemDelta := float64(statsJSON.CPUStats.SystemUsage - statsJSON.PreCPUStats.SystemUsage)
-		cpuPercent := 0.0
-		if systemDelta > 0 && cpuDelta > 0 {
-			cpuPercent = (cpuDelta / systemDelta) * float64(len(statsJSON.CPUStats.CPUUsage.PercpuUsage)) * 100.0
-		}
-
-		// 计算内存使用率
-		memoryUsage := float64(statsJSON.MemoryStats.Usage)
-		memoryLimit := float64(statsJSON.MemoryStats.Limit)
-		memoryPercent := 0.0
-		if memoryLimit > 0 {
-			memoryPercent = (memoryUsage / memoryLimit) * 100.0
-		}
-
-		// 添加到结果
 		stats = append(stats, gin.H{
 			"id":             container.ID[:12],
 			"name":           strings.TrimPrefix(container.Names[0], "/"),
-			"cpu_percent":    cpuPercent,
-			"memory_percent": memoryPercent,
-			"memory_usage":   memoryUsage,
-			"memory_limit":   memoryLimit,
+			"cpu_percent":    docker.CPUPercent(&statsJSON),
+			"memory_percent": docker.MemoryPercent(&statsJSON),
+			"memory_usage":   docker.MemoryUsage(&statsJSON),
+			"memory_limit":   float64(statsJSON.MemoryStats.Limit),
 		})
 	}
 
@@ -0,0 +1,237 @@
+package api
+
+import (
+    "dockerpanel/backend/pkg/database"
+    "errors"
+    "fmt"
+    "net/http"
+    "strconv"
+    "strings"
+
+    "github.com/gin-gonic/gin"
+    "github.com/robfig/cron/v3"
+)
+
+// 计划任务与执行记录，任务可以在容器中执行任意命令，只对管理员开放
+func RegisterTaskRoutes(r *gin.Engine) {
+    group := r.Group("/api/tasks")
+    {
+        group.GET("", listScheduledTasks)
+        group.POST("", createScheduledTask)
+        group.PUT("/:id", updateScheduledTask)
+        group.DELETE("/:id", deleteScheduledTask)
+        group.POST("/:id/run", runScheduledTask)
+        group.POST("/:id/cancel", cancelScheduledTask)
+
+        group.GET("/runs", listTaskRuns)
+        group.GET("/runs/:id", getTaskRun)
+    }
+}
+
+// 列表中附带下次执行时间和是否正在执行
+func listScheduledTasks(c *gin.Context) {
+    tasks, err := database.ListScheduledTasks()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "获取计划任务失败: " + err.Error()})
+        return
+    }
+    type taskStatus struct {
+        *database.ScheduledTask
+        NextRun string `json:"next_run"`
+        Running bool   `json:"running"`
+    }
+    result := make([]taskStatus, 0, len(tasks))
+    for _, t := range tasks {
+        result = append(result, taskStatus{t, scheduler.nextRun(t.ID), scheduler.isRunning(t.ID)})
+    }
+    c.JSON(http.StatusOK, result)
+}
+
+// 检查任务类型、执行计划和各类型需要的参数，并填充默认的超时和保留份数
+func validateScheduledTask(t *database.ScheduledTask) error {
+    t.Name = strings.TrimSpace(t.Name)
+    t.Target = strings.TrimSpace(t.Target)
+    t.Schedule = strings.TrimSpace(t.Schedule)
+    if t.Name == "" {
+        return fmt.Errorf("任务名称不能为空")
+    }
+    if _, err := cron.ParseStandard(t.Schedule); err != nil {
+        return fmt.Errorf("无效的执行计划 %q: %v", t.Schedule, err)
+    }
+    if t.Timeout == 0 {
+        t.Timeout = taskDefaultTimeout
+    }
+    if t.Timeout < 1 || t.Timeout > taskMaxTimeout {
+        return fmt.Errorf("超时时间应在 1 到 %d 秒之间", taskMaxTimeout)
+    }
+
+    switch t.Type {
+    case database.TaskRestartContainer, database.TaskUpdateContainer:
+        if t.Target == "" {
+            return fmt.Errorf("请指定容器")
+        }
+    case database.TaskRestartProject, database.TaskUpdateProject:
+        if t.Target == "" || strings.ContainsAny(t.Target, `/\`) || strings.HasPrefix(t.Target, ".") {
+            return fmt.Errorf("无效的项目名称: %s", t.Target)
+        }
+    case database.TaskPruneImages, database.TaskPruneNetworks, database.TaskPruneVolumes:
+        t.Target = ""
+    case database.TaskBackupVolume:
+        if t.Target == "" || strings.ContainsAny(t.Target, `/\`) || strings.HasPrefix(t.Target, ".") {
+            return fmt.Errorf("无效的卷名称: %s", t.Target)
+        }
+        if t.Keep == 0 {
+            t.Keep = taskDefaultKeep
+        }
+        if t.Keep < 1 || t.Keep > 365 {
+            return fmt.Errorf("备份保留份数应在 1 到 365 之间")
+        }
+    case database.TaskExec:
+        if t.Target == "" {
+            return fmt.Errorf("请指定容器")
+        }
+        if strings.TrimSpace(t.Command) == "" {
+            return fmt.Errorf("请填写要执行的命令")
+        }
+    default:
+        return fmt.Errorf("不支持的任务类型: %s", t.Type)
+    }
+    if t.Type != database.TaskExec {
+        t.Command = ""
+    }
+    if t.Type != database.TaskBackupVolume {
+        t.Keep = 0
+    }
+
+    if t.HostID < 0 {
+        return fmt.Errorf("无效的主机: %d", t.HostID)
+    }
+    if t.HostID != 0 {
+        if _, err := database.GetHost(t.HostID); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func scheduledTaskError(c *gin.Context, message string, err error) {
+    status := http.StatusInternalServerError
+    switch {
+    case errors.Is(err, database.ErrTaskNotFound), errors.Is(err, database.ErrTaskRunNotFound):
+        status = http.StatusNotFound
+    case errors.Is(err, database.ErrTaskExists), errors.Is(err, errTaskRunning):
+        status = http.StatusConflict
+    }
+    c.JSON(status, gin.H{"error": message + ": " + err.Error()})
+}
+
+func createScheduledTask(c *gin.Context) {
+    var task database.ScheduledTask
+    if err := c.ShouldBindJSON(&task); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据: " + err.Error()})
+        return
+    }
+    if err := validateScheduledTask(&task); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if err := database.CreateScheduledTask(&task); err != nil {
+        scheduledTaskError(c, "添加计划任务失败", err)
+        return
+    }
+    scheduler.reload()
+    c.JSON(http.StatusOK, task)
+}
+
+func updateScheduledTask(c *gin.Context) {
+    id, ok := idParam(c)
+    if !ok {
+        return
+    }
+    var task database.ScheduledTask
+    if err := c.ShouldBindJSON(&task); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据: " + err.Error()})
+        return
+    }
+    task.ID = id
+    if err := validateScheduledTask(&task); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if err := database.UpdateScheduledTask(&task); err != nil {
+        scheduledTaskError(c, "修改计划任务失败", err)
+        return
+    }
+    scheduler.reload()
+    c.JSON(http.StatusOK, gin.H{"message": "计划任务已保存"})
+}
+
+// 正在执行的任务需要先取消，避免执行记录失去对应的任务
+func deleteScheduledTask(c *gin.Context) {
+    id, ok := idParam(c)
+    if !ok {
+        return
+    }
+    if scheduler.isRunning(id) {
+        scheduledTaskError(c, "删除计划任务失败", errTaskRunning)
+        return
+    }
+    if err := database.DeleteScheduledTask(id); err != nil {
+        scheduledTaskError(c, "删除计划任务失败", err)
+        return
+    }
+    scheduler.reload()
+    c.JSON(http.StatusOK, gin.H{"message": "计划任务已删除"})
+}
+
+// 立即执行一次，不等待执行结束，返回执行记录供前端查询结果
+func runScheduledTask(c *gin.Context) {
+    id, ok := idParam(c)
+    if !ok {
+        return
+    }
+    run, err := scheduler.trigger(id, database.TaskTriggerManual)
+    if err != nil {
+        scheduledTaskError(c, "执行计划任务失败", err)
+        return
+    }
+    c.JSON(http.StatusOK, run)
+}
+
+func cancelScheduledTask(c *gin.Context) {
+    id, ok := idParam(c)
+    if !ok {
+        return
+    }
+    if !scheduler.cancel(id) {
+        c.JSON(http.StatusConflict, gin.H{"error": "任务没有正在进行的执行"})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"message": "已取消执行"})
+}
+
+// GET /api/tasks/runs?task_id=&page=&pageSize=
+func listTaskRuns(c *gin.Context) {
+    page, pageSize := pageParams(c)
+    taskID, _ := strconv.ParseInt(c.Query("task_id"), 10, 64)
+    runs, total, err := database.ListTaskRuns(taskID, pageSize, (page-1)*pageSize)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "查询执行记录失败: " + err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"items": runs, "total": total, "page": page, "pageSize": pageSize})
+}
+
+// 单次执行记录，包含输出
+func getTaskRun(c *gin.Context) {
+    id, ok := idParam(c)
+    if !ok {
+        return
+    }
+    run, err := database.GetTaskRun(id)
+    if err != nil {
+        scheduledTaskError(c, "查询执行记录失败", err)
+        return
+    }
+    c.JSON(http.StatusOK, run)
+}
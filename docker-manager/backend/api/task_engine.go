@@ -0,0 +1,517 @@
+package api
+
+import (
+    "context"
+    "dockerpanel/backend/pkg/database"
+    "dockerpanel/backend/pkg/docker"
+    "errors"
+    "fmt"
+    "io"
+    "log"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/docker/docker/api/types"
+    "github.com/docker/docker/api/types/container"
+    "github.com/docker/docker/api/types/filters"
+    "github.com/docker/docker/api/types/versions"
+    "github.com/docker/docker/pkg/stdcopy"
+    "github.com/docker/go-units"
+    "github.com/robfig/cron/v3"
+)
+
+const (
+    taskDefaultTimeout = 600          // 秒
+    taskMaxTimeout     = 24 * 60 * 60 // 秒
+    taskOutputLimit    = 64 << 10     // 每次执行保留的输出，超出时只保留末尾
+    taskDefaultKeep    = 7            // 卷备份默认保留份数
+)
+
+var errTaskRunning = errors.New("任务正在执行中")
+
+// 卷备份保存在面板数据目录下，按主机分目录
+var volumeBackupDir = filepath.Join("data", "backups", "volumes")
+
+// taskExecution 正在进行的一次执行，用于防止同一任务并发执行和手动取消
+type taskExecution struct {
+    runID  int64
+    cancel context.CancelFunc
+}
+
+type taskScheduler struct {
+    mu      sync.Mutex
+    cron    *cron.Cron
+    entries map[int64]cron.EntryID
+    running map[int64]*taskExecution
+}
+
+var scheduler = &taskScheduler{
+    cron:    cron.New(),
+    entries: make(map[int64]cron.EntryID),
+    running: make(map[int64]*taskExecution),
+}
+
+// StartTaskScheduler 按计划执行任务。上次运行中断的执行记录在启动时标记为失败
+func StartTaskScheduler() {
+    if err := database.FailInterruptedTaskRuns(); err != nil {
+        log.Printf("更新中断的任务记录失败: %v", err)
+    }
+    scheduler.reload()
+    scheduler.cron.Start()
+}
+
+// 重新读取任务并注册到调度器，任务修改后立即调用
+func (s *taskScheduler) reload() {
+    tasks, err := database.ListScheduledTasks()
+    if err != nil {
+        log.Printf("读取计划任务失败: %v", err)
+        return
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for id, entry := range s.entries {
+        s.cron.Remove(entry)
+        delete(s.entries, id)
+    }
+    for _, t := range tasks {
+        if !t.Enabled {
+            continue
+        }
+        schedule, err := cron.ParseStandard(t.Schedule)
+        if err != nil {
+            log.Printf("计划任务 %s 的执行计划无效: %v", t.Name, err)
+            continue
+        }
+        id := t.ID
+        s.entries[id] = s.cron.Schedule(schedule, cron.FuncJob(func() {
+            if _, err := s.trigger(id, database.TaskTriggerSchedule); err != nil && !errors.Is(err, errTaskRunning) {
+                log.Printf("执行计划任务 %d 失败: %v", id, err)
+            }
+        }))
+    }
+}
+
+// 下次计划执行时间，任务未启用时返回空
+func (s *taskScheduler) nextRun(id int64) string {
+    s.mu.Lock()
+    entry, ok := s.entries[id]
+    s.mu.Unlock()
+    if !ok {
+        return ""
+    }
+    next := s.cron.Entry(entry).Next
+    if next.IsZero() {
+        return ""
+    }
+    return next.Format("2006-01-02 15:04:05")
+}
+
+func (s *taskScheduler) isRunning(id int64) bool {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    _, ok := s.running[id]
+    return ok
+}
+
+// 取消正在进行的执行，没有执行时返回 false
+func (s *taskScheduler) cancel(id int64) bool {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    execution, ok := s.running[id]
+    if ok {
+        execution.cancel()
+    }
+    return ok
+}
+
+// trigger 在后台开始一次执行并返回执行记录。
+// 上一次执行尚未结束时不再执行：计划触发记录为跳过，手动触发返回 errTaskRunning
+func (s *taskScheduler) trigger(id int64, trigger string) (*database.TaskRun, error) {
+    t, err := database.GetScheduledTask(id)
+    if err != nil {
+        return nil, err
+    }
+    now := time.Now()
+    run := &database.TaskRun{
+        TaskID:    t.ID,
+        TaskName:  t.Name,
+        TaskType:  t.Type,
+        HostID:    t.HostID,
+        Trigger:   trigger,
+        Status:    database.TaskRunning,
+        StartedAt: now.Format("2006-01-02 15:04:05"),
+    }
+
+    s.mu.Lock()
+    if execution, ok := s.running[id]; ok {
+        s.mu.Unlock()
+        if trigger == database.TaskTriggerSchedule {
+            run.Status = database.TaskSkipped
+            run.Error = fmt.Sprintf("上一次执行（记录 #%d）尚未结束", execution.runID)
+            run.FinishedAt = run.StartedAt
+            if err := database.InsertTaskRun(run); err != nil {
+                log.Printf("保存任务执行记录失败: %v", err)
+            }
+        }
+        return nil, errTaskRunning
+    }
+    if err := database.InsertTaskRun(run); err != nil {
+        s.mu.Unlock()
+        return nil, err
+    }
+    ctx, cancel := context.WithTimeout(context.Background(), time.Duration(t.Timeout)*time.Second)
+    s.running[id] = &taskExecution{runID: run.ID, cancel: cancel}
+    s.mu.Unlock()
+
+    go func() {
+        defer func() {
+            cancel()
+            s.mu.Lock()
+            delete(s.running, id)
+            s.mu.Unlock()
+        }()
+        s.execute(ctx, t, run, now)
+    }()
+    return run, nil
+}
+
+func (s *taskScheduler) execute(ctx context.Context, t *database.ScheduledTask, run *database.TaskRun, started time.Time) {
+    output := &taskOutput{}
+    err := runTask(ctx, t, output)
+
+    run.Status = database.TaskSuccess
+    switch {
+    case errors.Is(ctx.Err(), context.DeadlineExceeded):
+        run.Status = database.TaskTimeout
+        run.Error = fmt.Sprintf("执行超时（%d 秒）", t.Timeout)
+    case errors.Is(ctx.Err(), context.Canceled):
+        run.Status = database.TaskFailure
+        run.Error = "已手动取消"
+    case err != nil:
+        run.Status = database.TaskFailure
+        run.Error = err.Error()
+    }
+    run.Output = output.String()
+    finished := time.Now()
+    run.FinishedAt = finished.Format("2006-01-02 15:04:05")
+    run.DurationMs = finished.Sub(started).Milliseconds()
+    if err := database.FinishTaskRun(run); err != nil {
+        log.Printf("保存任务执行记录失败: %v", err)
+    }
+    if run.Status != database.TaskSuccess {
+        log.Printf("计划任务 %s 执行失败: %s", t.Name, run.Error)
+    }
+}
+
+// taskOutput 收集执行输出，超出 taskOutputLimit 时丢弃开头部分。
+// exec 超时后读取输出的协程可能仍在写入，所以需要加锁
+type taskOutput struct {
+    mu      sync.Mutex
+    buf     []byte
+    dropped int
+}
+
+func (o *taskOutput) Write(p []byte) (int, error) {
+    o.mu.Lock()
+    defer o.mu.Unlock()
+    o.buf = append(o.buf, p...)
+    if over := len(o.buf) - taskOutputLimit; over > 0 {
+        o.dropped += over
+        o.buf = append(o.buf[:0:0], o.buf[over:]...)
+    }
+    return len(p), nil
+}
+
+func (o *taskOutput) Printf(format string, args ...interface{}) {
+    fmt.Fprintf(o, format+"\n", args...)
+}
+
+func (o *taskOutput) String() string {
+    o.mu.Lock()
+    defer o.mu.Unlock()
+    if o.dropped > 0 {
+        return fmt.Sprintf("...（输出过长，省略前 %d 字节）\n%s", o.dropped, o.buf)
+    }
+    return string(o.buf)
+}
+
+// 任务所在主机的连接信息，本机返回 nil
+func taskHost(hostID int64) (*docker.Host, error) {
+    if hostID == 0 {
+        return nil, nil
+    }
+    h, err := database.GetHost(hostID)
+    if err != nil {
+        return nil, err
+    }
+    return toDockerHost(h), nil
+}
+
+func runTask(ctx context.Context, t *database.ScheduledTask, out *taskOutput) error {
+    host, err := taskHost(t.HostID)
+    if err != nil {
+        return err
+    }
+
+    // compose 项目的任务通过 docker compose 命令执行
+    switch t.Type {
+    case database.TaskRestartProject:
+        return runCompose(ctx, host, t.Target, out, "restart")
+    case database.TaskUpdateProject:
+        if err := runCompose(ctx, host, t.Target, out, "pull"); err != nil {
+            return err
+        }
+        return runCompose(ctx, host, t.Target, out, "up", "-d")
+    }
+
+    cli, err := docker.SharedClient(host)
+    if err != nil {
+        return fmt.Errorf("连接Docker失败: %v", err)
+    }
+    switch t.Type {
+    case database.TaskRestartContainer:
+        if err := cli.ContainerRestart(ctx, t.Target, container.StopOptions{}); err != nil {
+            return err
+        }
+        out.Printf("容器 %s 已重启", t.Target)
+        return nil
+    case database.TaskUpdateContainer:
+        return updateContainerImage(ctx, cli, t.Target, out)
+    case database.TaskPruneImages:
+        report, err := cli.ImagesPrune(ctx, filters.NewArgs(filters.Arg("dangling", strconv.FormatBool(!t.All))))
+        if err != nil {
+            return err
+        }
+        for _, item := range report.ImagesDeleted {
+            if item.Untagged != "" {
+                out.Printf("Untagged: %s", item.Untagged)
+            }
+            if item.Deleted != "" {
+                out.Printf("Deleted: %s", item.Deleted)
+            }
+        }
+        out.Printf("共删除 %d 项，释放 %s", len(report.ImagesDeleted), units.HumanSize(float64(report.SpaceReclaimed)))
+        return nil
+    case database.TaskPruneNetworks:
+        report, err := cli.NetworksPrune(ctx, filters.NewArgs())
+        if err != nil {
+            return err
+        }
+        for _, name := range report.NetworksDeleted {
+            out.Printf("Deleted: %s", name)
+        }
+        out.Printf("共删除 %d 个网络", len(report.NetworksDeleted))
+        return nil
+    case database.TaskPruneVolumes:
+        // API 1.42 起默认只清理匿名卷，更早的版本总是清理所有未使用的卷
+        args := filters.NewArgs()
+        if t.All && versions.GreaterThanOrEqualTo(cli.ClientVersion(), "1.42") {
+            args.Add("all", "true")
+        }
+        report, err := cli.VolumesPrune(ctx, args)
+        if err != nil {
+            return err
+        }
+        for _, name := range report.VolumesDeleted {
+            out.Printf("Deleted: %s", name)
+        }
+        out.Printf("共删除 %d 个卷，释放 %s", len(report.VolumesDeleted), units.HumanSize(float64(report.SpaceReclaimed)))
+        return nil
+    case database.TaskBackupVolume:
+        return backupVolume(ctx, cli, t, out)
+    case database.TaskExec:
+        return execInContainer(ctx, cli, t.Target, t.Command, out)
+    }
+    return fmt.Errorf("不支持的任务类型: %s", t.Type)
+}
+
+// 在项目目录下执行 docker compose 子命令，超时或取消时结束命令
+func runCompose(ctx context.Context, host *docker.Host, project string, out io.Writer, args ...string) error {
+    projectDir := filepath.Join(projectRoot(host), project)
+    if _, err := os.Stat(projectDir); err != nil {
+        return fmt.Errorf("项目 %s 不存在", project)
+    }
+    env, err := docker.ComposeEnvForHost(host)
+    if err != nil {
+        return fmt.Errorf("连接Docker失败: %v", err)
+    }
+    cmd := exec.CommandContext(ctx, "docker", append([]string{"compose"}, args...)...)
+    cmd.Dir = projectDir
+    cmd.Env = env
+    cmd.Stdout = out
+    cmd.Stderr = out
+    cmd.WaitDelay = 5 * time.Second
+    fmt.Fprintf(out, "$ docker compose %s\n", strings.Join(args, " "))
+    if err := cmd.Run(); err != nil {
+        return fmt.Errorf("docker compose %s 失败: %v", args[0], err)
+    }
+    return nil
+}
+
+// 镜像在仓库中有更新时拉取并按原配置重建容器，没有更新时不做任何操作
+func updateContainerImage(ctx context.Context, cli *docker.Client, name string, out *taskOutput) error {
+    old, err := cli.ContainerInspect(ctx, name)
+    if err != nil {
+        return fmt.Errorf("容器不存在: %v", err)
+    }
+    image := old.Config.Image
+    if strings.HasPrefix(image, "sha256:") {
+        return fmt.Errorf("容器 %s 使用镜像 ID 创建，无法检查更新", name)
+    }
+
+    auth := imageRegistryAuth(image)
+    available, err := cli.ImageUpdateAvailable(ctx, image, auth)
+    if err != nil {
+        return err
+    }
+    if !available {
+        out.Printf("镜像 %s 没有更新", image)
+        return nil
+    }
+
+    out.Printf("镜像 %s 有更新，开始拉取", image)
+    reader, err := cli.ImagePull(ctx, image, types.ImagePullOptions{RegistryAuth: auth})
+    if err != nil {
+        return fmt.Errorf("拉取镜像失败: %v", err)
+    }
+    aggregator := docker.NewProgressAggregator(image)
+    err = docker.ConsumeProgress(reader, aggregator, func(docker.Progress) {})
+    reader.Close()
+    if err != nil {
+        return fmt.Errorf("拉取镜像失败: %v", err)
+    }
+    out.Printf("%s", aggregator.Snapshot().Status)
+
+    // 重建过程中途取消会留下改名后的旧容器，所以重建不受任务超时限制
+    newID, err := cli.RecreateContainer(context.Background(), old, nil)
+    if err != nil {
+        return fmt.Errorf("重建容器失败: %v", err)
+    }
+    out.Printf("容器 %s 已重建，新 ID: %s", name, shortID(newID))
+    return nil
+}
+
+// 备份卷到 data/backups/volumes/<主机 ID>/<卷名>-<时间>.tar.gz，并删除超出保留份数的旧备份
+func backupVolume(ctx context.Context, cli *docker.Client, t *database.ScheduledTask, out *taskOutput) error {
+    dir := filepath.Join(volumeBackupDir, strconv.FormatInt(t.HostID, 10))
+    if err := os.MkdirAll(dir, 0700); err != nil {
+        return fmt.Errorf("创建备份目录失败: %v", err)
+    }
+    path := filepath.Join(dir, fmt.Sprintf("%s-%s.tar.gz", t.Target, time.Now().Format("20060102-150405")))
+    partial := path + ".partial"
+    file, err := os.OpenFile(partial, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+    if err != nil {
+        return fmt.Errorf("创建备份文件失败: %v", err)
+    }
+
+    size, err := cli.BackupVolume(ctx, t.Target, file)
+    if closeErr := file.Close(); err == nil && closeErr != nil {
+        err = fmt.Errorf("写入备份失败: %v", closeErr)
+    }
+    if err != nil {
+        os.Remove(partial)
+        return err
+    }
+    if err := os.Rename(partial, path); err != nil {
+        os.Remove(partial)
+        return fmt.Errorf("保存备份文件失败: %v", err)
+    }
+    if info, err := os.Stat(path); err == nil {
+        out.Printf("卷 %s 已备份到 %s（原始 %s，压缩后 %s）", t.Target, path,
+            units.HumanSize(float64(size)), units.HumanSize(float64(info.Size())))
+    }
+
+    for _, old := range expiredVolumeBackups(dir, t.Target, t.Keep) {
+        if err := os.Remove(old); err != nil {
+            out.Printf("删除旧备份 %s 失败: %v", old, err)
+            continue
+        }
+        out.Printf("已删除旧备份 %s", old)
+    }
+    return nil
+}
+
+// 返回卷的备份中超出保留份数的旧文件。按文件名中的时间判断，避免把同前缀的其他卷的备份算进来
+func expiredVolumeBackups(dir, volume string, keep int) []string {
+    entries, err := os.ReadDir(dir)
+    if err != nil {
+        return nil
+    }
+    var names []string
+    for _, entry := range entries {
+        stamp, ok := strings.CutPrefix(entry.Name(), volume+"-")
+        if !ok || !strings.HasSuffix(stamp, ".tar.gz") {
+            continue
+        }
+        if _, err := time.Parse("20060102-150405", strings.TrimSuffix(stamp, ".tar.gz")); err != nil {
+            continue
+        }
+        names = append(names, entry.Name())
+    }
+    if len(names) <= keep {
+        return nil
+    }
+    sort.Strings(names)
+    var expired []string
+    for _, name := range names[:len(names)-keep] {
+        expired = append(expired, filepath.Join(dir, name))
+    }
+    return expired
+}
+
+// 通过 /bin/sh -c 在运行中的容器内执行命令，退出码不为 0 时视为失败。
+// 超时后停止读取输出，但 Docker 无法结束 exec 进程，命令可能仍在容器中运行
+func execInContainer(ctx context.Context, cli *docker.Client, name, command string, out *taskOutput) error {
+    info, err := cli.ContainerInspect(ctx, name)
+    if err != nil {
+        return fmt.Errorf("容器不存在: %v", err)
+    }
+    if info.State == nil || !info.State.Running {
+        return fmt.Errorf("容器未运行，无法执行命令")
+    }
+
+    execResp, err := cli.ContainerExecCreate(ctx, info.ID, types.ExecConfig{
+        Cmd:          []string{"/bin/sh", "-c", command},
+        AttachStdout: true,
+        AttachStderr: true,
+    })
+    if err != nil {
+        return fmt.Errorf("创建exec命令失败: %v", err)
+    }
+    resp, err := cli.ContainerExecAttach(ctx, execResp.ID, types.ExecStartCheck{})
+    if err != nil {
+        return fmt.Errorf("附加到exec命令失败: %v", err)
+    }
+    defer resp.Close()
+
+    done := make(chan error, 1)
+    go func() {
+        _, err := stdcopy.StdCopy(out, out, resp.Reader)
+        done <- err
+    }()
+    select {
+    case err := <-done:
+        if err != nil {
+            return fmt.Errorf("读取命令输出失败: %v", err)
+        }
+    case <-ctx.Done():
+        out.Printf("\n命令未在时限内结束，可能仍在容器中运行")
+        return ctx.Err()
+    }
+
+    inspect, err := cli.ContainerExecInspect(ctx, execResp.ID)
+    if err != nil {
+        return fmt.Errorf("获取命令退出码失败: %v", err)
+    }
+    if inspect.ExitCode != 0 {
+        return fmt.Errorf("命令退出码 %d", inspect.ExitCode)
+    }
+    return nil
+}
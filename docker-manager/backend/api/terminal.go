@@ -7,11 +7,9 @@ import (
     "io"
 	"strings"
     "net/http"
-    "os"
     "sync"
     "github.com/docker/docker/api/types"
     "github.com/gorilla/websocket"
-    "dockerpanel/backend/pkg/docker"
     "github.com/gin-gonic/gin"
 )
 
@@ -122,12 +120,11 @@ func containerExec(c *gin.Context) {
     
     fmt.Printf("执行容器命令: %s, 容器ID: %s\n", command, containerId)
     
-    cli, err := docker.NewDockerClient()
+    cli, err := dockerClient(c)
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Docker客户端创建失败: %v", err)})
         return
     }
-    defer cli.Close()
     
     // 检查容器是否存在并运行
     containerInfo, err := cli.ContainerInspect(context.Background(), containerId)
@@ -194,16 +191,17 @@ func containerInteractiveExec(c *gin.Context) {
         return
     }
     defer ws.Close()
+    terminalSessions.Inc()
+    defer terminalSessions.Dec()
     
     // 发送连接成功消息
     ws.WriteMessage(websocket.TextMessage, []byte("WebSocket连接成功，正在连接到容器...\n"))
     
-    cli, err := docker.NewDockerClient()
+    cli, err := dockerClient(c)
     if err != nil {
         sendErrorMessage(ws, fmt.Sprintf("Docker客户端创建失败: %v", err))
         return
     }
-    defer cli.Close()
     
     // 检查容器是否存在并运行
     containerInfo, err := cli.ContainerInspect(context.Background(), containerId)
@@ -380,9 +378,7 @@ func containerInteractiveExec(c *gin.Context) {
 var upgrader = websocket.Upgrader{
     ReadBufferSize:  1024,
     WriteBufferSize: 1024,
-    CheckOrigin: func(r *http.Request) bool {
-        return true // 允许所有来源的WebSocket连接
-    },
+    CheckOrigin:     checkWebSocketOrigin, // 只允许同源或已配置的来源，见 auth.go
 }
 
 
@@ -400,18 +396,19 @@ func containerTerminal(c *gin.Context) {
         return
     }
     defer ws.Close()
+    terminalSessions.Inc()
+    defer terminalSessions.Dec()
      
     // 发送连接成功消息
     ws.WriteMessage(websocket.TextMessage, []byte("WebSocket连接成功，正在连接到容器...\n"))
 	
-    cli, err := docker.NewDockerClient()
+    cli, err := dockerClient(c)
     if err != nil {
         errMsg := fmt.Sprintf("Docker客户端创建失败: %v\n", err)
         fmt.Println(errMsg)
         ws.WriteMessage(websocket.TextMessage, []byte(errMsg))
         return
     }
-    defer cli.Close()
 	
     // 检查容器是否存在
     _, err = cli.ContainerInspect(context.Background(), containerId)
@@ -585,13 +582,14 @@ func containerTerminalAlternative(c *gin.Context) {
         return
     }
     defer ws.Close()
+    terminalSessions.Inc()
+    defer terminalSessions.Dec()
     
-    cli, err := docker.NewDockerClient()
+    cli, err := dockerClient(c)
     if err != nil {
         ws.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("错误: %v\n", err)))
         return
     }
-    defer cli.Close()
     
     // 检查容器是否存在并运行
     containerInfo, err := cli.ContainerInspect(context.Background(), containerId)
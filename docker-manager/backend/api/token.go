@@ -0,0 +1,101 @@
+package api
+
+import (
+    "dockerpanel/backend/pkg/database"
+    "net/http"
+    "strconv"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+// API 令牌管理，只能通过登录会话访问（见 rbac.go）。
+// 普通用户只能管理自己的个人令牌，管理员可以查看和吊销全部令牌并创建服务令牌
+func RegisterTokenRoutes(r *gin.Engine) {
+    group := r.Group("/api/tokens")
+    {
+        group.GET("", listAPITokens)
+        group.POST("", createAPIToken)
+        group.DELETE("/:id", revokeAPIToken)
+    }
+}
+
+func listAPITokens(c *gin.Context) {
+    user := currentUser(c)
+    owner := user.ID
+    if user.Role == database.RoleAdmin {
+        owner = 0
+    }
+    tokens, err := database.ListAPITokens(owner)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "获取令牌列表失败: " + err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, tokens)
+}
+
+func createAPIToken(c *gin.Context) {
+    var req struct {
+        Name          string                `json:"name" binding:"required"`
+        Type          string                `json:"type"`
+        Scopes        []database.Permission `json:"scopes"`
+        ExpiresInDays int                   `json:"expiresInDays"` // 0 表示永不过期
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据: " + err.Error()})
+        return
+    }
+    if req.Type == "" {
+        req.Type = database.TokenPersonal
+    }
+
+    user := currentUser(c)
+    if req.Type == database.TokenService && user.Role != database.RoleAdmin {
+        c.JSON(http.StatusForbidden, gin.H{"error": "只有管理员可以创建服务令牌"})
+        return
+    }
+    if req.ExpiresInDays < 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "有效天数不能为负数"})
+        return
+    }
+    var expiresAt time.Time
+    if req.ExpiresInDays > 0 {
+        expiresAt = time.Now().AddDate(0, 0, req.ExpiresInDays)
+    }
+
+    token, plaintext, err := database.CreateAPIToken(user.ID, req.Name, req.Type, req.Scopes, expiresAt)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "创建令牌失败: " + err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{
+        "message": "令牌已创建，请立即复制，关闭后将无法再次查看",
+        "token":   plaintext,
+        "info":    token,
+    })
+}
+
+func revokeAPIToken(c *gin.Context) {
+    id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "无效的令牌 ID"})
+        return
+    }
+
+    token, err := database.GetAPIToken(id)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+        return
+    }
+    user := currentUser(c)
+    if user.Role != database.RoleAdmin && token.UserID != user.ID {
+        c.JSON(http.StatusNotFound, gin.H{"error": database.ErrTokenNotFound.Error()})
+        return
+    }
+
+    if err := database.RevokeAPIToken(id); err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "吊销令牌失败: " + err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"message": "令牌已吊销"})
+}
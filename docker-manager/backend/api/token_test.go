@@ -0,0 +1,67 @@
+package api
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+
+    "dockerpanel/backend/pkg/database"
+    "github.com/gin-gonic/gin"
+)
+
+// 通过 AuthMiddleware 和 RBACMiddleware 访问，吊销或过期的令牌返回 401
+func TestAPITokenAuthentication(t *testing.T) {
+    openTestDB(t)
+    user, err := database.CreateUser("ci", "password123", database.RoleOperator)
+    if err != nil {
+        t.Fatal(err)
+    }
+    scopes := []database.Permission{{Scope: "*", Actions: []string{database.ActionRead}}}
+    personal, personalToken, err := database.CreateAPIToken(user.ID, "personal", database.TokenPersonal, scopes, time.Time{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    _, serviceToken, err := database.CreateAPIToken(0, "service", database.TokenService, scopes, time.Now().Add(time.Hour))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    gin.SetMode(gin.TestMode)
+    r := gin.New()
+    r.Use(AuthMiddleware(), RBACMiddleware())
+    r.GET("/api/containers", func(c *gin.Context) { c.String(http.StatusOK, currentUser(c).Username) })
+    r.GET("/api/tokens", func(c *gin.Context) { c.Status(http.StatusOK) })
+    request := func(path, token string) *httptest.ResponseRecorder {
+        w := httptest.NewRecorder()
+        req := httptest.NewRequest("GET", path, nil)
+        if token != "" {
+            req.Header.Set("Authorization", "Bearer "+token)
+        }
+        r.ServeHTTP(w, req)
+        return w
+    }
+
+    if w := request("/api/containers", personalToken); w.Code != 200 || w.Body.String() != "ci" {
+        t.Errorf("个人令牌应以所有者身份访问: %d %s", w.Code, w.Body.String())
+    }
+    if w := request("/api/containers", serviceToken); w.Code != 200 || w.Body.String() != "service:service" {
+        t.Errorf("服务令牌应以虚拟用户访问: %d %s", w.Code, w.Body.String())
+    }
+    if w := request("/api/tokens", personalToken); w.Code != http.StatusForbidden {
+        t.Errorf("令牌不能管理令牌, 得到 %d", w.Code)
+    }
+    if w := request("/api/containers", ""); w.Code != http.StatusUnauthorized {
+        t.Errorf("未携带令牌应返回 401, 得到 %d", w.Code)
+    }
+    if w := request("/api/containers", database.APITokenPrefix+"unknown"); w.Code != http.StatusUnauthorized {
+        t.Errorf("不存在的令牌应返回 401, 得到 %d", w.Code)
+    }
+
+    if err := database.RevokeAPIToken(personal.ID); err != nil {
+        t.Fatal(err)
+    }
+    if w := request("/api/containers", personalToken); w.Code != http.StatusUnauthorized {
+        t.Errorf("吊销的令牌应返回 401, 得到 %d", w.Code)
+    }
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+    "dockerpanel/backend/pkg/database"
+    "net/http"
+    "strconv"
+
+    "github.com/gin-gonic/gin"
+)
+
+// 用户与权限管理，仅管理员可访问（见 rbac.go）
+func RegisterUserRoutes(r *gin.Engine) {
+    group := r.Group("/api/users")
+    {
+        group.GET("", listUsers)
+        group.POST("", createUser)
+        group.PUT("/:id", updateUser)
+        group.DELETE("/:id", deleteUser)
+        group.GET("/:id/permissions", getUserPermissions)
+        group.PUT("/:id/permissions", updateUserPermissions)
+    }
+}
+
+// 解析路由中的用户 ID
+func userIDParam(c *gin.Context) (int64, bool) {
+    id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户 ID"})
+        return 0, false
+    }
+    return id, true
+}
+
+func userErrorStatus(err error) int {
+    switch err {
+    case database.ErrUserNotFound:
+        return http.StatusNotFound
+    case database.ErrUserExists, database.ErrLastAdmin:
+        return http.StatusConflict
+    }
+    return http.StatusBadRequest
+}
+
+func listUsers(c *gin.Context) {
+    users, err := database.ListUsers()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户列表失败: " + err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, users)
+}
+
+func createUser(c *gin.Context) {
+    var req struct {
+        Username string `json:"username" binding:"required"`
+        Password string `json:"password" binding:"required"`
+        Role     string `json:"role" binding:"required"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据: " + err.Error()})
+        return
+    }
+
+    user, err := database.CreateUser(req.Username, req.Password, req.Role)
+    if err != nil {
+        c.JSON(userErrorStatus(err), gin.H{"error": "创建用户失败: " + err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, user)
+}
+
+// 修改角色或重置密码，字段为空时不修改
+func updateUser(c *gin.Context) {
+    id, ok := userIDParam(c)
+    if !ok {
+        return
+    }
+    var req struct {
+        Role     string `json:"role"`
+        Password string `json:"password"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据: " + err.Error()})
+        return
+    }
+
+    if req.Role != "" {
+        if err := database.UpdateUserRole(id, req.Role); err != nil {
+            c.JSON(userErrorStatus(err), gin.H{"error": "修改角色失败: " + err.Error()})
+            return
+        }
+    }
+    if req.Password != "" {
+        if err := database.ResetPassword(id, req.Password); err != nil {
+            c.JSON(userErrorStatus(err), gin.H{"error": "重置密码失败: " + err.Error()})
+            return
+        }
+    }
+
+    user, err := database.GetUserByID(id)
+    if err != nil {
+        c.JSON(userErrorStatus(err), gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, user)
+}
+
+func deleteUser(c *gin.Context) {
+    id, ok := userIDParam(c)
+    if !ok {
+        return
+    }
+    if self := currentUser(c); self != nil && self.ID == id {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "不能删除当前登录的用户"})
+        return
+    }
+    if err := database.DeleteUser(id); err != nil {
+        c.JSON(userErrorStatus(err), gin.H{"error": "删除用户失败: " + err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"message": "用户已删除"})
+}
+
+func getUserPermissions(c *gin.Context) {
+    id, ok := userIDParam(c)
+    if !ok {
+        return
+    }
+    permissions, err := database.ListUserPermissions(id)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "获取权限失败: " + err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, permissions)
+}
+
+// 整体替换用户的额外授权
+func updateUserPermissions(c *gin.Context) {
+    id, ok := userIDParam(c)
+    if !ok {
+        return
+    }
+    if _, err := database.GetUserByID(id); err != nil {
+        c.JSON(userErrorStatus(err), gin.H{"error": err.Error()})
+        return
+    }
+    var permissions []database.Permission
+    if err := c.ShouldBindJSON(&permissions); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据: " + err.Error()})
+        return
+    }
+    if err := database.ReplaceUserPermissions(id, permissions); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "保存权限失败: " + err.Error()})
+        return
+    }
+    updated, _ := database.ListUserPermissions(id)
+    c.JSON(http.StatusOK, updated)
+}
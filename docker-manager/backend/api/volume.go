@@ -2,7 +2,6 @@ package api
 
 import (
 	"context"
-	"dockerpanel/backend/pkg/docker"
 	"fmt"
 	"net/http"
 	"log"
@@ -23,13 +22,12 @@ func RegisterVolumeRoutes(r *gin.Engine) {
 
 // 添加清除无用卷的处理函数
 func pruneVolumes(c *gin.Context) {
-    cli, err := docker.NewDockerClient()
+    cli, err := dockerClient(c)
     if err != nil {
         log.Printf("创建 Docker 客户端失败: %v", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
     }
-    defer cli.Close()
 
     log.Printf("开始清理无用卷")
 
@@ -133,12 +131,11 @@ type ContainerRef struct {
 }
 
 func listVolumes(c *gin.Context) {
-	cli, err := docker.NewDockerClient()
+	cli, err := dockerClient(c)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	defer cli.Close()
 
 	// 获取所有容器信息
 	containers, err := cli.ContainerList(context.Background(), types.ContainerListOptions{All: true})
@@ -196,12 +193,11 @@ func createVolume(c *gin.Context) {
 		return
 	}
 
-	cli, err := docker.NewDockerClient()
+	cli, err := dockerClient(c)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	defer cli.Close()
 
 	vol, err := cli.VolumeCreate(context.Background(), volume.CreateOptions{
 		Name: req.Name,
@@ -215,12 +211,11 @@ func createVolume(c *gin.Context) {
 }
 
 func removeVolume(c *gin.Context) {
-	cli, err := docker.NewDockerClient()
+	cli, err := dockerClient(c)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	defer cli.Close()
 
 	name := c.Param("name")
 	if err := cli.VolumeRemove(context.Background(), name, true); err != nil {
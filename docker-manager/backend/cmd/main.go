@@ -4,6 +4,7 @@ package main
 import (
     "dockerpanel/backend/api"
     "dockerpanel/backend/pkg/database"
+    "dockerpanel/backend/pkg/docker"
     "log"
     "path/filepath"
     "github.com/gin-contrib/cors"
@@ -26,20 +27,69 @@ func main() {
     log.Println("数据库初始化成功")
     defer database.Close()
 
+    // 远程主机的证书和 SSH 转发套接字
+    docker.SetHostsDir(filepath.Join(dataDir, "hosts"))
+
     r := gin.Default()
 
+    // 只信任配置的反向代理转发的客户端地址，否则任何人都能通过 X-Forwarded-For 伪造来源 IP，
+    // 绕过登录失败锁定并污染审计日志
+    if err := r.SetTrustedProxies(api.TrustedProxies()); err != nil {
+        log.Fatalf("可信代理配置无效: %v", err)
+    }
+
     // Configure CORS
-    config := cors.DefaultConfig()
-    config.AllowAllOrigins = true
-    r.Use(cors.New(config))
+    // 默认只允许同源访问，前后端分开部署时通过 DOCKERPANEL_ALLOWED_ORIGINS 指定前端地址
+    if origins := api.AllowedOrigins(); len(origins) > 0 {
+        config := cors.DefaultConfig()
+        config.AllowOrigins = origins
+        config.AllowCredentials = true
+        config.AddAllowHeaders("Authorization")
+        r.Use(cors.New(config))
+    }
+
+    // 将各主机的 Docker 事件实时推送给浏览器
+    api.StartEventHub()
+
+    // 定期采集主机和容器指标，供历史图表使用
+    api.StartMetricsCollector()
+
+    // 按告警规则判断容器与主机状态并发送通知
+    api.StartAlertEngine()
+
+    // 按 cron 表达式执行计划任务
+    api.StartTaskScheduler()
+
+    // 记录 API 请求耗时，通过 /metrics 导出
+    r.Use(api.PrometheusMiddleware())
+
+    // 审计放在认证之前，登录失败和未授权的请求也会被记录
+    r.Use(api.AuditMiddleware())
+    api.StartAuditRetention()
+
+    // /api 下除登录相关接口外都需要登录，并按角色与授权范围检查权限
+    r.Use(api.AuthMiddleware())
+    r.Use(api.RBACMiddleware())
 
     // Register API routes
+    api.RegisterAuthRoutes(r)
+    api.RegisterUserRoutes(r)
+    api.RegisterTokenRoutes(r)
+    api.RegisterAuditRoutes(r)
+    api.RegisterHostRoutes(r)
+    api.RegisterEventRoutes(r)
+    api.RegisterMetricsRoutes(r)
+    api.RegisterPrometheusRoutes(r)
+    api.RegisterAlertRoutes(r)
+    api.RegisterTaskRoutes(r)
     api.RegisterContainerRoutes(r)
     api.RegisterImageRoutes(r)
     api.RegisterVolumeRoutes(r)
     api.RegisterNetworkRoutes(r)
     api.RegisterComposeRoutes(r)
     api.RegisterImageRegistryRoutes(r)
+    api.RegisterDockerConfigRoutes(r)
+    api.RegisterDaemonRoutes(r)
 	api.RegisterSystemRoutes(r)
 	//api.RegisterTerminalRoutes(r)
     // 使用特定前缀处理静态文件
@@ -0,0 +1,717 @@
+package database
+
+import (
+    "database/sql"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "strings"
+    "time"
+)
+
+// 告警规则类型
+const (
+    AlertContainerExited = "container_exited" // 容器非正常退出（退出码不为 0 且不是手动停止）
+    AlertRestartLoop     = "restart_loop"     // 在 Duration 分钟内退出次数达到 Threshold
+    AlertUnhealthy       = "unhealthy"        // 健康检查失败
+    AlertCPUHigh         = "cpu_high"         // CPU 使用率持续 Duration 分钟高于 Threshold
+    AlertMemoryHigh      = "memory_high"      // 内存使用率持续 Duration 分钟高于 Threshold
+    AlertDiskHigh        = "disk_high"        // 面板所在主机根分区使用率高于 Threshold
+    AlertImageUpdate     = "image_update"     // 运行中容器的镜像在仓库中有更新
+    AlertHostUnreachable = "host_unreachable" // 持续 Duration 分钟无法从主机采集容器指标
+)
+
+// 告警状态与发送结果
+const (
+    AlertFiring   = "firing"
+    AlertResolved = "resolved"
+
+    DeliverySuccess = "success"
+    DeliveryFailure = "failure"
+)
+
+// 所有主机，规则的 HostID 为该值时对本机和所有远程主机生效
+const AllHosts = -1
+
+// 告警记录与发送记录的保留天数，默认 90 天
+const SettingAlertRetentionDays = "alert_retention_days"
+
+var (
+    ErrAlertRuleNotFound    = errors.New("告警规则不存在")
+    ErrAlertRuleExists      = errors.New("同名告警规则已存在")
+    ErrAlertChannelNotFound = errors.New("通知渠道不存在")
+    ErrAlertChannelExists   = errors.New("同名通知渠道已存在")
+)
+
+// SilenceWindow 每周重复的静默时段，结束时间早于开始时间时跨越午夜
+type SilenceWindow struct {
+    Weekdays []int  `json:"weekdays"` // 0 为周日，为空表示每天
+    Start    string `json:"start"`    // 15:04
+    End      string `json:"end"`
+}
+
+// Validate 检查时间格式
+func (w SilenceWindow) Validate() error {
+    if _, err := time.Parse("15:04", w.Start); err != nil {
+        return fmt.Errorf("静默开始时间格式错误: %s", w.Start)
+    }
+    if _, err := time.Parse("15:04", w.End); err != nil {
+        return fmt.Errorf("静默结束时间格式错误: %s", w.End)
+    }
+    for _, d := range w.Weekdays {
+        if d < 0 || d > 6 {
+            return fmt.Errorf("无效的星期: %d", d)
+        }
+    }
+    return nil
+}
+
+func (w SilenceWindow) onDay(day time.Weekday) bool {
+    if len(w.Weekdays) == 0 {
+        return true
+    }
+    for _, d := range w.Weekdays {
+        if time.Weekday(d) == day {
+            return true
+        }
+    }
+    return false
+}
+
+// Contains 判断时刻是否在静默时段内，跨越午夜的时段按开始那天的星期判断
+func (w SilenceWindow) Contains(t time.Time) bool {
+    now := t.Format("15:04")
+    if w.Start <= w.End {
+        return w.onDay(t.Weekday()) && now >= w.Start && now < w.End
+    }
+    if now >= w.Start {
+        return w.onDay(t.Weekday())
+    }
+    return now < w.End && w.onDay(t.AddDate(0, 0, -1).Weekday())
+}
+
+// AlertRule 告警规则。Target 为空时匹配所有容器，否则按容器名称或 compose 项目名称匹配
+type AlertRule struct {
+    ID         int64           `json:"id"`
+    Name       string          `json:"name"`
+    Type       string          `json:"type"`
+    HostID     int64           `json:"host_id"` // 0 为本机，-1 为所有主机
+    Target     string          `json:"target"`
+    Threshold  float64         `json:"threshold"`
+    Duration   int             `json:"duration"` // 分钟
+    Channels   []int64         `json:"channels"`
+    Silences   []SilenceWindow `json:"silences"`
+    MutedUntil string          `json:"muted_until"` // 临时静默截止时间
+    Enabled    bool            `json:"enabled"`
+    CreatedAt  string          `json:"created_at"`
+    UpdatedAt  string          `json:"updated_at"`
+}
+
+// Silenced 判断规则在该时刻是否处于静默中
+func (r *AlertRule) Silenced(t time.Time) bool {
+    if r.MutedUntil != "" {
+        if until, err := time.ParseInLocation("2006-01-02 15:04:05", r.MutedUntil, time.Local); err == nil && t.Before(until) {
+            return true
+        }
+    }
+    for _, w := range r.Silences {
+        if w.Contains(t) {
+            return true
+        }
+    }
+    return false
+}
+
+// AlertChannel 通知渠道，配置项因类型而异，整体加密保存。
+// 密码和签名密钥为只写字段，接口响应通过 Redacted 去掉
+type AlertChannel struct {
+    ID         int64             `json:"id"`
+    Name       string            `json:"name"`
+    Type       string            `json:"type"`
+    Config     map[string]string `json:"config"`
+    HasSecrets []string          `json:"has_secrets,omitempty"`
+    Enabled    bool              `json:"enabled"`
+    CreatedAt  string            `json:"created_at"`
+    UpdatedAt  string            `json:"updated_at"`
+}
+
+// 通知渠道配置中的敏感字段
+var channelSecretKeys = []string{"password", "secret"}
+
+// Redacted 返回去掉密码和密钥的副本，用于接口响应
+func (ch *AlertChannel) Redacted() *AlertChannel {
+    copied := *ch
+    copied.Config = make(map[string]string, len(ch.Config))
+    copied.HasSecrets = nil
+    for key, value := range ch.Config {
+        copied.Config[key] = value
+    }
+    for _, key := range channelSecretKeys {
+        if copied.Config[key] != "" {
+            copied.HasSecrets = append(copied.HasSecrets, key)
+        }
+        delete(copied.Config, key)
+    }
+    return &copied
+}
+
+// AlertEvent 一次告警从触发到恢复的记录
+type AlertEvent struct {
+    ID         int64  `json:"id"`
+    RuleID     int64  `json:"rule_id"`
+    RuleName   string `json:"rule_name"`
+    RuleType   string `json:"rule_type"`
+    HostID     int64  `json:"host_id"`
+    HostName   string `json:"host_name"`
+    Subject    string `json:"subject"` // 容器名称、镜像或 host
+    Status     string `json:"status"`
+    Message    string `json:"message"`
+    Silenced   bool   `json:"silenced"` // 处于静默中，未发送通知；静默结束时仍未恢复会补发
+    StartedAt  string `json:"started_at"`
+    ResolvedAt string `json:"resolved_at"`
+}
+
+// AlertDelivery 一次通知发送的结果
+type AlertDelivery struct {
+    ID          int64  `json:"id"`
+    EventID     int64  `json:"event_id"` // 测试消息为 0
+    ChannelID   int64  `json:"channel_id"`
+    ChannelName string `json:"channel_name"`
+    ChannelType string `json:"channel_type"`
+    Kind        string `json:"kind"` // firing、resolved、test
+    Status      string `json:"status"`
+    Error       string `json:"error"`
+    DurationMs  int64  `json:"duration_ms"`
+    CreatedAt   string `json:"created_at"`
+}
+
+// createAlertTables 创建告警规则、通知渠道、告警记录与发送记录表
+func createAlertTables() error {
+    tables := map[string]string{
+        "alert_rules": `
+        CREATE TABLE IF NOT EXISTS alert_rules (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            name TEXT NOT NULL UNIQUE,
+            type TEXT NOT NULL,
+            host_id INTEGER NOT NULL DEFAULT 0,
+            target TEXT,
+            threshold REAL DEFAULT 0,
+            duration INTEGER DEFAULT 0,
+            channels TEXT,
+            silences TEXT,
+            muted_until TEXT,
+            enabled INTEGER DEFAULT 1,
+            created_at DATETIME,
+            updated_at DATETIME
+        )`,
+        "alert_channels": `
+        CREATE TABLE IF NOT EXISTS alert_channels (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            name TEXT NOT NULL UNIQUE,
+            type TEXT NOT NULL,
+            config TEXT,
+            enabled INTEGER DEFAULT 1,
+            created_at DATETIME,
+            updated_at DATETIME
+        )`,
+        "alert_events": `
+        CREATE TABLE IF NOT EXISTS alert_events (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            rule_id INTEGER NOT NULL,
+            rule_name TEXT NOT NULL,
+            rule_type TEXT NOT NULL,
+            host_id INTEGER NOT NULL,
+            host_name TEXT,
+            subject TEXT NOT NULL,
+            status TEXT NOT NULL,
+            message TEXT,
+            silenced INTEGER DEFAULT 0,
+            started_at DATETIME NOT NULL,
+            resolved_at DATETIME
+        )`,
+        "alert_deliveries": `
+        CREATE TABLE IF NOT EXISTS alert_deliveries (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            event_id INTEGER NOT NULL DEFAULT 0,
+            channel_id INTEGER NOT NULL,
+            channel_name TEXT,
+            channel_type TEXT,
+            kind TEXT NOT NULL,
+            status TEXT NOT NULL,
+            error TEXT,
+            duration_ms INTEGER DEFAULT 0,
+            created_at DATETIME NOT NULL
+        )`,
+    }
+    for _, name := range []string{"alert_rules", "alert_channels", "alert_events", "alert_deliveries"} {
+        if _, err := db.Exec(tables[name]); err != nil {
+            return fmt.Errorf("创建 %s 表失败: %v", name, err)
+        }
+    }
+    for _, index := range []string{
+        `CREATE INDEX IF NOT EXISTS idx_alert_events_started_at ON alert_events(started_at)`,
+        `CREATE INDEX IF NOT EXISTS idx_alert_deliveries_event_id ON alert_deliveries(event_id)`,
+    } {
+        if _, err := db.Exec(index); err != nil {
+            return fmt.Errorf("创建告警索引失败: %v", err)
+        }
+    }
+    return nil
+}
+
+const alertRuleColumns = `id, name, type, host_id, COALESCE(target, ''), threshold, duration, COALESCE(channels, ''),
+    COALESCE(silences, ''), COALESCE(muted_until, ''), enabled, created_at, updated_at`
+
+func scanAlertRule(row rowScanner) (*AlertRule, error) {
+    var r AlertRule
+    var channels, silences string
+    var enabled int
+    var createdAt, updatedAt sql.NullString
+    if err := row.Scan(&r.ID, &r.Name, &r.Type, &r.HostID, &r.Target, &r.Threshold, &r.Duration, &channels,
+        &silences, &r.MutedUntil, &enabled, &createdAt, &updatedAt); err != nil {
+        return nil, err
+    }
+    r.Enabled = enabled == 1
+    r.CreatedAt = createdAt.String
+    r.UpdatedAt = updatedAt.String
+    r.Channels = []int64{}
+    r.Silences = []SilenceWindow{}
+    if channels != "" {
+        if err := json.Unmarshal([]byte(channels), &r.Channels); err != nil {
+            return nil, fmt.Errorf("解析告警规则 %s 的通知渠道失败: %v", r.Name, err)
+        }
+    }
+    if silences != "" {
+        if err := json.Unmarshal([]byte(silences), &r.Silences); err != nil {
+            return nil, fmt.Errorf("解析告警规则 %s 的静默时段失败: %v", r.Name, err)
+        }
+    }
+    return &r, nil
+}
+
+// ListAlertRules 返回所有告警规则
+func ListAlertRules() ([]*AlertRule, error) {
+    rows, err := db.Query("SELECT " + alertRuleColumns + " FROM alert_rules ORDER BY id")
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    rules := []*AlertRule{}
+    for rows.Next() {
+        r, err := scanAlertRule(rows)
+        if err != nil {
+            return nil, err
+        }
+        rules = append(rules, r)
+    }
+    return rules, rows.Err()
+}
+
+// GetAlertRule 按 ID 查询告警规则
+func GetAlertRule(id int64) (*AlertRule, error) {
+    r, err := scanAlertRule(db.QueryRow("SELECT "+alertRuleColumns+" FROM alert_rules WHERE id = ?", id))
+    if err == sql.ErrNoRows {
+        return nil, ErrAlertRuleNotFound
+    }
+    return r, err
+}
+
+func marshalRuleLists(r *AlertRule) (string, string, error) {
+    if r.Channels == nil {
+        r.Channels = []int64{}
+    }
+    if r.Silences == nil {
+        r.Silences = []SilenceWindow{}
+    }
+    channels, err := json.Marshal(r.Channels)
+    if err != nil {
+        return "", "", err
+    }
+    silences, err := json.Marshal(r.Silences)
+    if err != nil {
+        return "", "", err
+    }
+    return string(channels), string(silences), nil
+}
+
+// CreateAlertRule 添加告警规则
+func CreateAlertRule(r *AlertRule) error {
+    channels, silences, err := marshalRuleLists(r)
+    if err != nil {
+        return err
+    }
+    now := time.Now().Format("2006-01-02 15:04:05")
+    result, err := db.Exec(`
+        INSERT INTO alert_rules
+        (name, type, host_id, target, threshold, duration, channels, silences, muted_until, enabled, created_at, updated_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    `, r.Name, r.Type, r.HostID, r.Target, r.Threshold, r.Duration, channels, silences, r.MutedUntil,
+        boolToInt(r.Enabled), now, now)
+    if err != nil {
+        if strings.Contains(err.Error(), "UNIQUE") {
+            return ErrAlertRuleExists
+        }
+        return err
+    }
+    r.ID, _ = result.LastInsertId()
+    r.CreatedAt = now
+    r.UpdatedAt = now
+    return nil
+}
+
+// UpdateAlertRule 修改告警规则，临时静默由 MuteAlertRule 单独设置
+func UpdateAlertRule(r *AlertRule) error {
+    channels, silences, err := marshalRuleLists(r)
+    if err != nil {
+        return err
+    }
+    now := time.Now().Format("2006-01-02 15:04:05")
+    result, err := db.Exec(`
+        UPDATE alert_rules
+        SET name = ?, type = ?, host_id = ?, target = ?, threshold = ?, duration = ?,
+            channels = ?, silences = ?, enabled = ?, updated_at = ?
+        WHERE id = ?
+    `, r.Name, r.Type, r.HostID, r.Target, r.Threshold, r.Duration, channels, silences,
+        boolToInt(r.Enabled), now, r.ID)
+    if err != nil {
+        if strings.Contains(err.Error(), "UNIQUE") {
+            return ErrAlertRuleExists
+        }
+        return err
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        return ErrAlertRuleNotFound
+    }
+    return nil
+}
+
+// MuteAlertRule 临时静默告警规则，until 为空时取消静默
+func MuteAlertRule(id int64, until string) error {
+    result, err := db.Exec("UPDATE alert_rules SET muted_until = ? WHERE id = ?", until, id)
+    if err != nil {
+        return err
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        return ErrAlertRuleNotFound
+    }
+    return nil
+}
+
+// DeleteAlertRule 删除告警规则，历史记录保留
+func DeleteAlertRule(id int64) error {
+    result, err := db.Exec("DELETE FROM alert_rules WHERE id = ?", id)
+    if err != nil {
+        return err
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        return ErrAlertRuleNotFound
+    }
+    return nil
+}
+
+const alertChannelColumns = `id, name, type, COALESCE(config, ''), enabled, created_at, updated_at`
+
+func scanAlertChannel(row rowScanner) (*AlertChannel, error) {
+    var ch AlertChannel
+    var config string
+    var enabled int
+    var createdAt, updatedAt sql.NullString
+    if err := row.Scan(&ch.ID, &ch.Name, &ch.Type, &config, &enabled, &createdAt, &updatedAt); err != nil {
+        return nil, err
+    }
+    ch.Enabled = enabled == 1
+    ch.CreatedAt = createdAt.String
+    ch.UpdatedAt = updatedAt.String
+    ch.Config = map[string]string{}
+
+    plain, err := decryptSecret(config)
+    if err != nil {
+        return nil, fmt.Errorf("解密通知渠道 %s 的配置失败: %v", ch.Name, err)
+    }
+    if plain != "" {
+        if err := json.Unmarshal([]byte(plain), &ch.Config); err != nil {
+            return nil, fmt.Errorf("解析通知渠道 %s 的配置失败: %v", ch.Name, err)
+        }
+    }
+    return &ch, nil
+}
+
+func encryptChannelConfig(config map[string]string) (string, error) {
+    if config == nil {
+        config = map[string]string{}
+    }
+    data, err := json.Marshal(config)
+    if err != nil {
+        return "", err
+    }
+    encrypted, err := encryptSecret(string(data))
+    if err != nil {
+        return "", fmt.Errorf("加密通知渠道配置失败: %v", err)
+    }
+    return encrypted, nil
+}
+
+// ListAlertChannels 返回所有通知渠道
+func ListAlertChannels() ([]*AlertChannel, error) {
+    rows, err := db.Query("SELECT " + alertChannelColumns + " FROM alert_channels ORDER BY id")
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    channels := []*AlertChannel{}
+    for rows.Next() {
+        ch, err := scanAlertChannel(rows)
+        if err != nil {
+            return nil, err
+        }
+        channels = append(channels, ch)
+    }
+    return channels, rows.Err()
+}
+
+// GetAlertChannel 按 ID 查询通知渠道
+func GetAlertChannel(id int64) (*AlertChannel, error) {
+    ch, err := scanAlertChannel(db.QueryRow("SELECT "+alertChannelColumns+" FROM alert_channels WHERE id = ?", id))
+    if err == sql.ErrNoRows {
+        return nil, ErrAlertChannelNotFound
+    }
+    return ch, err
+}
+
+// CreateAlertChannel 添加通知渠道
+func CreateAlertChannel(ch *AlertChannel) error {
+    config, err := encryptChannelConfig(ch.Config)
+    if err != nil {
+        return err
+    }
+    now := time.Now().Format("2006-01-02 15:04:05")
+    result, err := db.Exec(`
+        INSERT INTO alert_channels (name, type, config, enabled, created_at, updated_at)
+        VALUES (?, ?, ?, ?, ?, ?)
+    `, ch.Name, ch.Type, config, boolToInt(ch.Enabled), now, now)
+    if err != nil {
+        if strings.Contains(err.Error(), "UNIQUE") {
+            return ErrAlertChannelExists
+        }
+        return err
+    }
+    ch.ID, _ = result.LastInsertId()
+    ch.CreatedAt = now
+    ch.UpdatedAt = now
+    return nil
+}
+
+// UpdateAlertChannel 修改通知渠道，密码和密钥为空时保留原值
+func UpdateAlertChannel(ch *AlertChannel) error {
+    old, err := GetAlertChannel(ch.ID)
+    if err != nil {
+        return err
+    }
+    if ch.Config == nil {
+        ch.Config = map[string]string{}
+    }
+    for _, key := range channelSecretKeys {
+        if ch.Config[key] == "" && old.Config[key] != "" {
+            ch.Config[key] = old.Config[key]
+        }
+    }
+    config, err := encryptChannelConfig(ch.Config)
+    if err != nil {
+        return err
+    }
+    now := time.Now().Format("2006-01-02 15:04:05")
+    _, err = db.Exec(`
+        UPDATE alert_channels SET name = ?, type = ?, config = ?, enabled = ?, updated_at = ?
+        WHERE id = ?
+    `, ch.Name, ch.Type, config, boolToInt(ch.Enabled), now, ch.ID)
+    if err != nil && strings.Contains(err.Error(), "UNIQUE") {
+        return ErrAlertChannelExists
+    }
+    return err
+}
+
+// DeleteAlertChannel 删除通知渠道，发送记录保留
+func DeleteAlertChannel(id int64) error {
+    result, err := db.Exec("DELETE FROM alert_channels WHERE id = ?", id)
+    if err != nil {
+        return err
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        return ErrAlertChannelNotFound
+    }
+    return nil
+}
+
+// InsertAlertEvent 记录触发的告警
+func InsertAlertEvent(e *AlertEvent) error {
+    if e.StartedAt == "" {
+        e.StartedAt = time.Now().Format("2006-01-02 15:04:05")
+    }
+    e.Status = AlertFiring
+    result, err := db.Exec(`
+        INSERT INTO alert_events
+        (rule_id, rule_name, rule_type, host_id, host_name, subject, status, message, silenced, started_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    `, e.RuleID, e.RuleName, e.RuleType, e.HostID, e.HostName, e.Subject, e.Status, e.Message,
+        boolToInt(e.Silenced), e.StartedAt)
+    if err != nil {
+        return err
+    }
+    e.ID, _ = result.LastInsertId()
+    return nil
+}
+
+// ResolveAlertEvent 将告警标记为已恢复
+func ResolveAlertEvent(id int64, resolvedAt string) error {
+    _, err := db.Exec(`UPDATE alert_events SET status = ?, resolved_at = ? WHERE id = ?`, AlertResolved, resolvedAt, id)
+    return err
+}
+
+// UnsilenceAlertEvent 静默结束后补发通知时清除静默标记
+func UnsilenceAlertEvent(id int64) error {
+    _, err := db.Exec(`UPDATE alert_events SET silenced = 0 WHERE id = ?`, id)
+    return err
+}
+
+// ResolveStaleAlertEvents 面板启动时关闭上次运行遗留的未恢复告警，恢复状态无法再跟踪
+func ResolveStaleAlertEvents() error {
+    _, err := db.Exec(`UPDATE alert_events SET status = ?, resolved_at = ? WHERE status = ?`,
+        AlertResolved, time.Now().Format("2006-01-02 15:04:05"), AlertFiring)
+    return err
+}
+
+// AlertEventFilter 告警记录查询条件，空字段不参与过滤
+type AlertEventFilter struct {
+    RuleID int64
+    Status string
+    Limit  int
+    Offset int
+}
+
+// ListAlertEvents 按时间倒序查询告警记录，返回当前页与总数
+func ListAlertEvents(filter AlertEventFilter) ([]AlertEvent, int, error) {
+    var conditions []string
+    var args []interface{}
+    if filter.RuleID > 0 {
+        conditions = append(conditions, "rule_id = ?")
+        args = append(args, filter.RuleID)
+    }
+    if filter.Status != "" {
+        conditions = append(conditions, "status = ?")
+        args = append(args, filter.Status)
+    }
+    where := ""
+    if len(conditions) > 0 {
+        where = " WHERE " + strings.Join(conditions, " AND ")
+    }
+
+    var total int
+    if err := db.QueryRow(`SELECT COUNT(*) FROM alert_events`+where, args...).Scan(&total); err != nil {
+        return nil, 0, err
+    }
+
+    query := `SELECT id, rule_id, rule_name, rule_type, host_id, COALESCE(host_name, ''), subject, status,
+        COALESCE(message, ''), silenced, started_at, COALESCE(resolved_at, '')
+        FROM alert_events` + where + ` ORDER BY id DESC`
+    if filter.Limit > 0 {
+        query += ` LIMIT ? OFFSET ?`
+        args = append(args, filter.Limit, filter.Offset)
+    }
+    rows, err := db.Query(query, args...)
+    if err != nil {
+        return nil, 0, err
+    }
+    defer rows.Close()
+
+    events := []AlertEvent{}
+    for rows.Next() {
+        var e AlertEvent
+        var silenced int
+        if err := rows.Scan(&e.ID, &e.RuleID, &e.RuleName, &e.RuleType, &e.HostID, &e.HostName, &e.Subject,
+            &e.Status, &e.Message, &silenced, &e.StartedAt, &e.ResolvedAt); err != nil {
+            return nil, 0, err
+        }
+        e.Silenced = silenced == 1
+        events = append(events, e)
+    }
+    return events, total, rows.Err()
+}
+
+// InsertAlertDelivery 记录一次通知发送
+func InsertAlertDelivery(d *AlertDelivery) error {
+    if d.CreatedAt == "" {
+        d.CreatedAt = time.Now().Format("2006-01-02 15:04:05")
+    }
+    result, err := db.Exec(`
+        INSERT INTO alert_deliveries
+        (event_id, channel_id, channel_name, channel_type, kind, status, error, duration_ms, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+    `, d.EventID, d.ChannelID, d.ChannelName, d.ChannelType, d.Kind, d.Status, d.Error, d.DurationMs, d.CreatedAt)
+    if err != nil {
+        return err
+    }
+    d.ID, _ = result.LastInsertId()
+    return nil
+}
+
+// ListAlertDeliveries 按时间倒序查询发送记录，eventID 或 channelID 大于 0 时按其过滤
+func ListAlertDeliveries(eventID, channelID int64, limit, offset int) ([]AlertDelivery, int, error) {
+    var conditions []string
+    var args []interface{}
+    if eventID > 0 {
+        conditions = append(conditions, "event_id = ?")
+        args = append(args, eventID)
+    }
+    if channelID > 0 {
+        conditions = append(conditions, "channel_id = ?")
+        args = append(args, channelID)
+    }
+    where := ""
+    if len(conditions) > 0 {
+        where = " WHERE " + strings.Join(conditions, " AND ")
+    }
+
+    var total int
+    if err := db.QueryRow(`SELECT COUNT(*) FROM alert_deliveries`+where, args...).Scan(&total); err != nil {
+        return nil, 0, err
+    }
+
+    query := `SELECT id, event_id, channel_id, COALESCE(channel_name, ''), COALESCE(channel_type, ''), kind, status,
+        COALESCE(error, ''), duration_ms, created_at
+        FROM alert_deliveries` + where + ` ORDER BY id DESC`
+    if limit > 0 {
+        query += ` LIMIT ? OFFSET ?`
+        args = append(args, limit, offset)
+    }
+    rows, err := db.Query(query, args...)
+    if err != nil {
+        return nil, 0, err
+    }
+    defer rows.Close()
+
+    deliveries := []AlertDelivery{}
+    for rows.Next() {
+        var d AlertDelivery
+        if err := rows.Scan(&d.ID, &d.EventID, &d.ChannelID, &d.ChannelName, &d.ChannelType, &d.Kind, &d.Status,
+            &d.Error, &d.DurationMs, &d.CreatedAt); err != nil {
+            return nil, 0, err
+        }
+        deliveries = append(deliveries, d)
+    }
+    return deliveries, total, rows.Err()
+}
+
+// PruneAlertHistory 删除早于指定时间且已恢复的告警记录及早于该时间的发送记录
+func PruneAlertHistory(before time.Time) (int64, error) {
+    cutoff := before.Format("2006-01-02 15:04:05")
+    result, err := db.Exec(`DELETE FROM alert_events WHERE status = ? AND started_at < ?`, AlertResolved, cutoff)
+    if err != nil {
+        return 0, err
+    }
+    if _, err := db.Exec(`DELETE FROM alert_deliveries WHERE created_at < ?`, cutoff); err != nil {
+        return 0, err
+    }
+    return result.RowsAffected()
+}
@@ -0,0 +1,180 @@
+package database
+
+import (
+    "fmt"
+    "strings"
+    "time"
+)
+
+// 审计结果
+const (
+    AuditSuccess = "success"
+    AuditFailure = "failure"
+    // 终端等长连接操作在打开时先记录，结束后更新为成功或失败
+    AuditRunning = "running"
+)
+
+// 审计日志保留天数设置，默认 180 天
+const SettingAuditRetentionDays = "audit_retention_days"
+
+type AuditLog struct {
+    ID         int64  `json:"id"`
+    CreatedAt  string `json:"created_at"`
+    Actor      string `json:"actor"`
+    IP         string `json:"ip"`
+    Method     string `json:"method"`
+    Path       string `json:"path"`
+    Action     string `json:"action"`
+    Target     string `json:"target"`
+    Params     string `json:"params"` // 已脱敏的请求参数，JSON 格式
+    Status     int    `json:"status"`
+    Result     string `json:"result"`
+    Error      string `json:"error,omitempty"`
+    DurationMs int64  `json:"duration_ms"`
+}
+
+// AuditFilter 审计日志查询条件，空字段不参与过滤
+type AuditFilter struct {
+    Actor   string
+    Action  string
+    Target  string
+    Result  string
+    Keyword string // 在路径、目标、参数和错误信息中模糊匹配
+    From    string // 2006-01-02 15:04:05
+    To      string
+    Limit   int
+    Offset  int
+}
+
+// createAuditTables 创建审计日志表
+func createAuditTables() error {
+    _, err := db.Exec(`
+    CREATE TABLE IF NOT EXISTS audit_logs (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        created_at DATETIME NOT NULL,
+        actor TEXT NOT NULL,
+        ip TEXT,
+        method TEXT NOT NULL,
+        path TEXT NOT NULL,
+        action TEXT NOT NULL,
+        target TEXT,
+        params TEXT,
+        status INTEGER,
+        result TEXT NOT NULL,
+        error TEXT,
+        duration_ms INTEGER DEFAULT 0
+    )`)
+    if err != nil {
+        return fmt.Errorf("创建 audit_logs 表失败: %v", err)
+    }
+    for _, index := range []string{
+        `CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs(created_at)`,
+        `CREATE INDEX IF NOT EXISTS idx_audit_logs_actor ON audit_logs(actor)`,
+    } {
+        if _, err := db.Exec(index); err != nil {
+            return fmt.Errorf("创建 audit_logs 索引失败: %v", err)
+        }
+    }
+    return nil
+}
+
+// InsertAuditLog 写入审计日志并返回 ID
+func InsertAuditLog(entry *AuditLog) (int64, error) {
+    if entry.CreatedAt == "" {
+        entry.CreatedAt = time.Now().Format("2006-01-02 15:04:05")
+    }
+    result, err := db.Exec(`
+        INSERT INTO audit_logs (created_at, actor, ip, method, path, action, target, params, status, result, error, duration_ms)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+        entry.CreatedAt, entry.Actor, entry.IP, entry.Method, entry.Path, entry.Action, entry.Target,
+        entry.Params, entry.Status, entry.Result, entry.Error, entry.DurationMs)
+    if err != nil {
+        return 0, err
+    }
+    return result.LastInsertId()
+}
+
+// FinishAuditLog 更新先行记录的长连接操作的结果
+func FinishAuditLog(id int64, status int, result, errMsg string, durationMs int64) error {
+    _, err := db.Exec(`UPDATE audit_logs SET status = ?, result = ?, error = ?, duration_ms = ? WHERE id = ?`,
+        status, result, errMsg, durationMs, id)
+    return err
+}
+
+// ListAuditLogs 按条件查询审计日志，按时间倒序，返回当前页与总数
+func ListAuditLogs(filter AuditFilter) ([]AuditLog, int, error) {
+    var conditions []string
+    var args []interface{}
+    if filter.Actor != "" {
+        conditions = append(conditions, "actor = ?")
+        args = append(args, filter.Actor)
+    }
+    if filter.Action != "" {
+        conditions = append(conditions, "action LIKE ?")
+        args = append(args, filter.Action+"%")
+    }
+    if filter.Target != "" {
+        conditions = append(conditions, "target LIKE ?")
+        args = append(args, "%"+filter.Target+"%")
+    }
+    if filter.Result != "" {
+        conditions = append(conditions, "result = ?")
+        args = append(args, filter.Result)
+    }
+    if filter.Keyword != "" {
+        conditions = append(conditions, "(path LIKE ? OR target LIKE ? OR params LIKE ? OR error LIKE ?)")
+        keyword := "%" + filter.Keyword + "%"
+        args = append(args, keyword, keyword, keyword, keyword)
+    }
+    if filter.From != "" {
+        conditions = append(conditions, "created_at >= ?")
+        args = append(args, filter.From)
+    }
+    if filter.To != "" {
+        conditions = append(conditions, "created_at <= ?")
+        args = append(args, filter.To)
+    }
+
+    where := ""
+    if len(conditions) > 0 {
+        where = " WHERE " + strings.Join(conditions, " AND ")
+    }
+
+    var total int
+    if err := db.QueryRow(`SELECT COUNT(*) FROM audit_logs`+where, args...).Scan(&total); err != nil {
+        return nil, 0, err
+    }
+
+    query := `SELECT id, created_at, actor, COALESCE(ip, ''), method, path, action, COALESCE(target, ''),
+        COALESCE(params, ''), COALESCE(status, 0), result, COALESCE(error, ''), duration_ms
+        FROM audit_logs` + where + ` ORDER BY id DESC`
+    if filter.Limit > 0 {
+        query += ` LIMIT ? OFFSET ?`
+        args = append(args, filter.Limit, filter.Offset)
+    }
+    rows, err := db.Query(query, args...)
+    if err != nil {
+        return nil, 0, err
+    }
+    defer rows.Close()
+
+    logs := []AuditLog{}
+    for rows.Next() {
+        var l AuditLog
+        if err := rows.Scan(&l.ID, &l.CreatedAt, &l.Actor, &l.IP, &l.Method, &l.Path, &l.Action, &l.Target,
+            &l.Params, &l.Status, &l.Result, &l.Error, &l.DurationMs); err != nil {
+            return nil, 0, err
+        }
+        logs = append(logs, l)
+    }
+    return logs, total, rows.Err()
+}
+
+// PruneAuditLogs 删除早于指定时间的审计日志
+func PruneAuditLogs(before time.Time) (int64, error) {
+    result, err := db.Exec(`DELETE FROM audit_logs WHERE created_at < ?`, before.Format("2006-01-02 15:04:05"))
+    if err != nil {
+        return 0, err
+    }
+    return result.RowsAffected()
+}
@@ -0,0 +1,181 @@
+package database
+
+import (
+    "crypto/aes"
+    "crypto/cipher"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/hex"
+    "fmt"
+    "io"
+    "log"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+const (
+    // 密钥环境变量，可为 64 位十六进制、32 字节的 base64，或任意口令（取 SHA-256）
+    secretKeyEnv = "DOCKERPANEL_SECRET_KEY"
+    // 密钥文件路径环境变量，未设置时使用数据目录下的 secret.key
+    secretKeyFileEnv = "DOCKERPANEL_SECRET_KEY_FILE"
+
+    // 加密后的值带有该前缀，没有前缀的视为旧版明文
+    encryptedPrefix = "enc:v1:"
+)
+
+var secretKey []byte
+
+// initSecretKey 加载加密密钥，优先使用环境变量，其次读取密钥文件，文件不存在时自动生成
+func initSecretKey(dataDir string) error {
+    if value := strings.TrimSpace(os.Getenv(secretKeyEnv)); value != "" {
+        secretKey = parseSecretKey(value)
+        log.Printf("使用环境变量 %s 中的加密密钥", secretKeyEnv)
+        return nil
+    }
+
+    keyPath := os.Getenv(secretKeyFileEnv)
+    if keyPath == "" {
+        keyPath = filepath.Join(dataDir, "secret.key")
+    }
+
+    data, err := os.ReadFile(keyPath)
+    if err == nil {
+        secretKey = parseSecretKey(strings.TrimSpace(string(data)))
+        log.Printf("已加载加密密钥文件: %s", keyPath)
+        return nil
+    }
+    if !os.IsNotExist(err) {
+        return fmt.Errorf("读取密钥文件失败: %v", err)
+    }
+
+    // 首次运行时生成随机密钥
+    key := make([]byte, 32)
+    if _, err := io.ReadFull(rand.Reader, key); err != nil {
+        return fmt.Errorf("生成加密密钥失败: %v", err)
+    }
+    if err := os.MkdirAll(filepath.Dir(keyPath), 0700); err != nil {
+        return fmt.Errorf("创建密钥目录失败: %v", err)
+    }
+    if err := os.WriteFile(keyPath, []byte(hex.EncodeToString(key)), 0600); err != nil {
+        return fmt.Errorf("保存密钥文件失败: %v", err)
+    }
+    secretKey = key
+    log.Printf("已生成新的加密密钥文件: %s", keyPath)
+    return nil
+}
+
+// parseSecretKey 将配置的密钥转换为 32 字节的 AES-256 密钥
+func parseSecretKey(value string) []byte {
+    if key, err := hex.DecodeString(value); err == nil && len(key) == 32 {
+        return key
+    }
+    if key, err := base64.StdEncoding.DecodeString(value); err == nil && len(key) == 32 {
+        return key
+    }
+    sum := sha256.Sum256([]byte(value))
+    return sum[:]
+}
+
+// encryptSecret 使用 AES-GCM 加密敏感字段，空字符串保持为空
+func encryptSecret(plain string) (string, error) {
+    if plain == "" {
+        return plain, nil
+    }
+    // 已加密的值原样保存前先确认能用当前密钥解密，避免写入无法使用的密文
+    if strings.HasPrefix(plain, encryptedPrefix) {
+        if _, err := decryptSecret(plain); err != nil {
+            return "", err
+        }
+        return plain, nil
+    }
+    if secretKey == nil {
+        return "", fmt.Errorf("加密密钥未初始化")
+    }
+
+    gcm, err := newGCM()
+    if err != nil {
+        return "", err
+    }
+    nonce := make([]byte, gcm.NonceSize())
+    if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+        return "", err
+    }
+    sealed := gcm.Seal(nonce, nonce, []byte(plain), nil)
+    return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptSecret 解密敏感字段，旧版明文原样返回
+func decryptSecret(value string) (string, error) {
+    if !strings.HasPrefix(value, encryptedPrefix) {
+        return value, nil
+    }
+    if secretKey == nil {
+        return "", fmt.Errorf("加密密钥未初始化")
+    }
+
+    data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+    if err != nil {
+        return "", fmt.Errorf("密文格式错误: %v", err)
+    }
+    gcm, err := newGCM()
+    if err != nil {
+        return "", err
+    }
+    if len(data) < gcm.NonceSize() {
+        return "", fmt.Errorf("密文长度错误")
+    }
+    plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+    if err != nil {
+        return "", fmt.Errorf("解密失败，密钥可能已变更: %v", err)
+    }
+    return string(plain), nil
+}
+
+func newGCM() (cipher.AEAD, error) {
+    block, err := aes.NewCipher(secretKey)
+    if err != nil {
+        return nil, err
+    }
+    return cipher.NewGCM(block)
+}
+
+// migratePlaintextSecrets 将旧版本明文保存的注册表密码加密
+func migratePlaintextSecrets() error {
+    rows, err := db.Query(`SELECT id, password FROM registries WHERE password IS NOT NULL AND password != ''`)
+    if err != nil {
+        return err
+    }
+
+    plain := make(map[int64]string)
+    for rows.Next() {
+        var id int64
+        var password string
+        if err := rows.Scan(&id, &password); err != nil {
+            rows.Close()
+            return err
+        }
+        if !strings.HasPrefix(password, encryptedPrefix) {
+            plain[id] = password
+        }
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return err
+    }
+
+    for id, password := range plain {
+        encrypted, err := encryptSecret(password)
+        if err != nil {
+            return err
+        }
+        if _, err := db.Exec(`UPDATE registries SET password = ? WHERE id = ?`, encrypted, id); err != nil {
+            return err
+        }
+    }
+    if len(plain) > 0 {
+        log.Printf("已加密 %d 个明文保存的注册表密码", len(plain))
+    }
+    return nil
+}
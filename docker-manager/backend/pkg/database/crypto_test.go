@@ -0,0 +1,203 @@
+package database
+
+import (
+    "bytes"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/hex"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+// useSecretKey 临时替换加密密钥
+func useSecretKey(t *testing.T, key []byte) {
+    old := secretKey
+    secretKey = key
+    t.Cleanup(func() { secretKey = old })
+}
+
+func TestParseSecretKey(t *testing.T) {
+    raw := bytes.Repeat([]byte{0xab}, 32)
+    passphrase := sha256.Sum256([]byte("correct horse battery staple"))
+
+    tests := []struct {
+        name  string
+        value string
+        want  []byte
+    }{
+        {"十六进制", hex.EncodeToString(raw), raw},
+        {"base64", base64.StdEncoding.EncodeToString(raw), raw},
+        {"口令", "correct horse battery staple", passphrase[:]},
+        {"长度不足的十六进制按口令处理", "abcd", func() []byte { s := sha256.Sum256([]byte("abcd")); return s[:] }()},
+    }
+    for _, tt := range tests {
+        if got := parseSecretKey(tt.value); !bytes.Equal(got, tt.want) {
+            t.Errorf("%s: parseSecretKey(%q) = %x, 期望 %x", tt.name, tt.value, got, tt.want)
+        }
+    }
+}
+
+func TestEncryptDecryptSecret(t *testing.T) {
+    useSecretKey(t, bytes.Repeat([]byte{1}, 32))
+
+    tests := []string{"secret", "密码 with spaces", strings.Repeat("x", 4096)}
+    for _, plain := range tests {
+        encrypted, err := encryptSecret(plain)
+        if err != nil {
+            t.Fatalf("encryptSecret 返回错误: %v", err)
+        }
+        if !strings.HasPrefix(encrypted, encryptedPrefix) || strings.Contains(encrypted, plain) {
+            t.Errorf("密文格式不正确: %s", encrypted)
+        }
+        again, _ := encryptSecret(plain)
+        if again == encrypted {
+            t.Error("每次加密应使用不同的随机数")
+        }
+        decrypted, err := decryptSecret(encrypted)
+        if err != nil || decrypted != plain {
+            t.Errorf("decryptSecret = %q, %v, 期望 %q", decrypted, err, plain)
+        }
+    }
+
+    if encrypted, err := encryptSecret(""); err != nil || encrypted != "" {
+        t.Errorf("空字符串应保持为空, 得到 %q, %v", encrypted, err)
+    }
+    // 旧版明文原样返回，由迁移负责加密
+    if plain, err := decryptSecret("legacy-plaintext"); err != nil || plain != "legacy-plaintext" {
+        t.Errorf("明文应原样返回, 得到 %q, %v", plain, err)
+    }
+}
+
+func TestEncryptSecretPreEncrypted(t *testing.T) {
+    useSecretKey(t, bytes.Repeat([]byte{1}, 32))
+    valid, _ := encryptSecret("secret")
+
+    secretKey = bytes.Repeat([]byte{2}, 32)
+    otherKey, _ := encryptSecret("secret")
+    secretKey = bytes.Repeat([]byte{1}, 32)
+
+    tests := []struct {
+        name    string
+        value   string
+        wantErr bool
+    }{
+        {"当前密钥的密文原样保存", valid, false},
+        {"其他密钥的密文", otherKey, true},
+        {"不是 base64", encryptedPrefix + "!!!", true},
+        {"长度不足", encryptedPrefix + base64.StdEncoding.EncodeToString([]byte("short")), true},
+    }
+    for _, tt := range tests {
+        got, err := encryptSecret(tt.value)
+        if (err != nil) != tt.wantErr {
+            t.Errorf("%s: err = %v, 期望出错: %v", tt.name, err, tt.wantErr)
+        }
+        if !tt.wantErr && got != tt.value {
+            t.Errorf("%s: 密文应原样保存", tt.name)
+        }
+    }
+}
+
+func TestDecryptSecretUndecryptable(t *testing.T) {
+    useSecretKey(t, bytes.Repeat([]byte{1}, 32))
+    encrypted, _ := encryptSecret("secret")
+
+    secretKey = bytes.Repeat([]byte{2}, 32)
+    if _, err := decryptSecret(encrypted); err == nil {
+        t.Error("密钥变更后解密应返回错误")
+    }
+    secretKey = nil
+    if _, err := decryptSecret(encrypted); err == nil {
+        t.Error("密钥未初始化时解密应返回错误")
+    }
+    if _, err := encryptSecret("secret"); err == nil {
+        t.Error("密钥未初始化时加密应返回错误")
+    }
+}
+
+func TestInitSecretKeyFile(t *testing.T) {
+    dir := t.TempDir()
+    t.Setenv(secretKeyEnv, "")
+    t.Setenv(secretKeyFileEnv, "")
+    useSecretKey(t, nil)
+
+    if err := initSecretKey(dir); err != nil {
+        t.Fatalf("initSecretKey 返回错误: %v", err)
+    }
+    generated := secretKey
+    info, err := os.Stat(filepath.Join(dir, "secret.key"))
+    if err != nil {
+        t.Fatalf("应生成密钥文件: %v", err)
+    }
+    if info.Mode().Perm() != 0600 {
+        t.Errorf("密钥文件权限 = %o, 期望 600", info.Mode().Perm())
+    }
+
+    secretKey = nil
+    if err := initSecretKey(dir); err != nil || !bytes.Equal(secretKey, generated) {
+        t.Errorf("再次启动应读取同一个密钥, err = %v", err)
+    }
+
+    t.Setenv(secretKeyEnv, "from-env")
+    initSecretKey(dir)
+    if want := sha256.Sum256([]byte("from-env")); !bytes.Equal(secretKey, want[:]) {
+        t.Error("环境变量中的密钥应优先于密钥文件")
+    }
+}
+
+func TestMigratePlaintextSecrets(t *testing.T) {
+    openTestDB(t)
+
+    if _, err := db.Exec(`INSERT INTO registries (name, url, username, password) VALUES ('old', 'old.example.com', 'u', 'plain-pass')`); err != nil {
+        t.Fatal(err)
+    }
+    if err := migratePlaintextSecrets(); err != nil {
+        t.Fatalf("migratePlaintextSecrets 返回错误: %v", err)
+    }
+
+    var stored string
+    db.QueryRow(`SELECT password FROM registries WHERE url = 'old.example.com'`).Scan(&stored)
+    if !strings.HasPrefix(stored, encryptedPrefix) {
+        t.Errorf("迁移后应加密保存, 得到 %q", stored)
+    }
+    registries, err := GetAllRegistries()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if r := registries["old.example.com"]; r == nil || r.Password != "plain-pass" || r.PasswordUndecryptable {
+        t.Errorf("读取的密码应为原明文: %+v", r)
+    }
+
+    // 再次迁移不应重复加密
+    migratePlaintextSecrets()
+    var again string
+    db.QueryRow(`SELECT password FROM registries WHERE url = 'old.example.com'`).Scan(&again)
+    if again != stored {
+        t.Error("已加密的密码不应再次加密")
+    }
+}
+
+func TestScanRegistryUndecryptable(t *testing.T) {
+    openTestDB(t)
+
+    key := secretKey
+    secretKey = bytes.Repeat([]byte{9}, 32)
+    foreign, _ := encryptSecret("secret")
+    secretKey = key
+
+    if _, err := db.Exec(`INSERT INTO registries (name, url, username, password) VALUES ('r', 'r.example.com', 'u', ?)`, foreign); err != nil {
+        t.Fatal(err)
+    }
+    registries, err := GetAllRegistries()
+    if err != nil {
+        t.Fatalf("无法解密的密码不应导致列表失败: %v", err)
+    }
+    r := registries["r.example.com"]
+    if r == nil || r.Password != "" || !r.PasswordUndecryptable || !r.HasPassword {
+        t.Errorf("应标记为无法解密: %+v", r)
+    }
+    if redacted := r.Redacted(); !redacted.HasPassword || redacted.Password != "" {
+        t.Errorf("Redacted = %+v", redacted)
+    }
+}
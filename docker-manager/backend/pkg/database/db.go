@@ -31,8 +31,18 @@ func InitDB(dbPath string) error {
         return err
     }
 
+    // 加载敏感字段的加密密钥
+    if err := initSecretKey(dir); err != nil {
+        return err
+    }
+
     // 创建表
-    return createTables()
+    if err := createTables(); err != nil {
+        return err
+    }
+
+    // 加密旧版本中以明文保存的密码
+    return migratePlaintextSecrets()
 }
 
 // createTables 创建必要的数据库表
@@ -71,6 +81,62 @@ func createTables() error {
         return err
     }
 
+    // 创建面板设置表
+    _, err = db.Exec(`
+    CREATE TABLE IF NOT EXISTS settings (
+        key TEXT PRIMARY KEY,
+        value TEXT NOT NULL,
+        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+    )`)
+    if err != nil {
+        log.Printf("创建 settings 表失败: %v", err)
+        return err
+    }
+
+    // 创建用户、会话、授权与 API 令牌表
+    if err = createUserTables(); err != nil {
+        log.Printf("%v", err)
+        return err
+    }
+    if err = createPermissionTables(); err != nil {
+        log.Printf("%v", err)
+        return err
+    }
+    if err = createTokenTables(); err != nil {
+        log.Printf("%v", err)
+        return err
+    }
+
+    // 创建审计日志表
+    if err = createAuditTables(); err != nil {
+        log.Printf("%v", err)
+        return err
+    }
+
+    // 创建远程主机表
+    if err = createHostTables(); err != nil {
+        log.Printf("%v", err)
+        return err
+    }
+
+    // 创建指标采样表
+    if err = createMetricsTables(); err != nil {
+        log.Printf("%v", err)
+        return err
+    }
+
+    // 创建告警规则与通知表
+    if err = createAlertTables(); err != nil {
+        log.Printf("%v", err)
+        return err
+    }
+
+    // 创建计划任务与执行记录表
+    if err = createTaskTables(); err != nil {
+        log.Printf("%v", err)
+        return err
+    }
+
     // 创建应用商店表
     _, err = db.Exec(`
     CREATE TABLE IF NOT EXISTS applications (
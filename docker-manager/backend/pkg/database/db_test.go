@@ -0,0 +1,23 @@
+package database
+
+import (
+    "path/filepath"
+    "testing"
+)
+
+// openTestDB 在临时目录中初始化数据库，密钥文件同样生成在临时目录
+func openTestDB(t *testing.T) string {
+    t.Helper()
+    dir := t.TempDir()
+    t.Setenv(secretKeyEnv, "")
+    t.Setenv(secretKeyFileEnv, "")
+    if err := InitDB(filepath.Join(dir, "test.db")); err != nil {
+        t.Fatalf("初始化数据库失败: %v", err)
+    }
+    t.Cleanup(func() {
+        db.Close()
+        db = nil
+        secretKey = nil
+    })
+    return dir
+}
@@ -0,0 +1,217 @@
+package database
+
+import (
+    "database/sql"
+    "errors"
+    "fmt"
+    "strings"
+    "time"
+)
+
+// ErrHostNotFound 指定的主机不存在
+var ErrHostNotFound = errors.New("主机不存在")
+
+// ErrHostExists 同名主机已存在
+var ErrHostExists = errors.New("同名主机已存在")
+
+// DockerHost 面板管理的远程 Docker 主机。证书私钥、SSH 私钥和密码为只写字段，
+// 数据库中加密保存，接口响应通过 Redacted 去掉
+type DockerHost struct {
+    ID          int64  `json:"id"`
+    Name        string `json:"name"`
+    Type        string `json:"type"` // unix、tcp、ssh
+    Address     string `json:"address"`
+    TLSCA       string `json:"tls_ca,omitempty"`
+    TLSCert     string `json:"tls_cert,omitempty"`
+    TLSKey      string `json:"tls_key,omitempty"`
+    TLSVerify   bool   `json:"tls_verify"`
+    SSHKey      string `json:"ssh_key,omitempty"`
+    SSHPassword string `json:"ssh_password,omitempty"`
+    SSHHostKey  string `json:"ssh_host_key,omitempty"`
+    HasTLSKey   bool   `json:"has_tls_key"`
+    HasSSHKey   bool   `json:"has_ssh_key"`
+    HasPassword bool   `json:"has_password"`
+    CreatedAt   string `json:"created_at"`
+    UpdatedAt   string `json:"updated_at"`
+}
+
+// Redacted 返回去掉私钥和密码的副本，用于接口响应
+func (h *DockerHost) Redacted() *DockerHost {
+    copied := *h
+    copied.HasTLSKey = h.TLSKey != ""
+    copied.HasSSHKey = h.SSHKey != ""
+    copied.HasPassword = h.SSHPassword != ""
+    copied.TLSKey = ""
+    copied.SSHKey = ""
+    copied.SSHPassword = ""
+    return &copied
+}
+
+// createHostTables 创建远程主机表
+func createHostTables() error {
+    _, err := db.Exec(`
+    CREATE TABLE IF NOT EXISTS docker_hosts (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        name TEXT NOT NULL UNIQUE,
+        type TEXT NOT NULL,
+        address TEXT NOT NULL,
+        tls_ca TEXT,
+        tls_cert TEXT,
+        tls_key TEXT,
+        tls_verify INTEGER DEFAULT 0,
+        ssh_key TEXT,
+        ssh_password TEXT,
+        ssh_host_key TEXT,
+        created_at DATETIME,
+        updated_at DATETIME
+    )`)
+    if err != nil {
+        return fmt.Errorf("创建 docker_hosts 表失败: %v", err)
+    }
+    return nil
+}
+
+const hostColumns = `id, name, type, address, tls_ca, tls_cert, tls_key, tls_verify, ssh_key, ssh_password, ssh_host_key, created_at, updated_at`
+
+// scanHost 扫描一行主机记录并解密敏感字段
+func scanHost(row rowScanner) (*DockerHost, error) {
+    var h DockerHost
+    var tlsVerify int
+    var tlsCA, tlsCert, tlsKey, sshKey, sshPassword, sshHostKey, createdAt, updatedAt sql.NullString
+    if err := row.Scan(&h.ID, &h.Name, &h.Type, &h.Address, &tlsCA, &tlsCert, &tlsKey, &tlsVerify,
+        &sshKey, &sshPassword, &sshHostKey, &createdAt, &updatedAt); err != nil {
+        return nil, err
+    }
+    h.TLSCA = tlsCA.String
+    h.TLSCert = tlsCert.String
+    h.TLSVerify = tlsVerify == 1
+    h.SSHHostKey = sshHostKey.String
+    h.CreatedAt = createdAt.String
+    h.UpdatedAt = updatedAt.String
+
+    var err error
+    if h.TLSKey, err = decryptSecret(tlsKey.String); err != nil {
+        return nil, fmt.Errorf("解密主机 %s 的证书私钥失败: %v", h.Name, err)
+    }
+    if h.SSHKey, err = decryptSecret(sshKey.String); err != nil {
+        return nil, fmt.Errorf("解密主机 %s 的 ssh 私钥失败: %v", h.Name, err)
+    }
+    if h.SSHPassword, err = decryptSecret(sshPassword.String); err != nil {
+        return nil, fmt.Errorf("解密主机 %s 的 ssh 密码失败: %v", h.Name, err)
+    }
+    return &h, nil
+}
+
+// ListHosts 返回所有远程主机
+func ListHosts() ([]*DockerHost, error) {
+    rows, err := db.Query("SELECT " + hostColumns + " FROM docker_hosts ORDER BY id")
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    hosts := []*DockerHost{}
+    for rows.Next() {
+        h, err := scanHost(rows)
+        if err != nil {
+            return nil, err
+        }
+        hosts = append(hosts, h)
+    }
+    return hosts, rows.Err()
+}
+
+// GetHost 按 ID 查询主机
+func GetHost(id int64) (*DockerHost, error) {
+    h, err := scanHost(db.QueryRow("SELECT "+hostColumns+" FROM docker_hosts WHERE id = ?", id))
+    if err == sql.ErrNoRows {
+        return nil, ErrHostNotFound
+    }
+    return h, err
+}
+
+// CreateHost 添加主机
+func CreateHost(h *DockerHost) error {
+    tlsKey, sshKey, sshPassword, err := encryptHostSecrets(h)
+    if err != nil {
+        return err
+    }
+    now := time.Now().Format("2006-01-02 15:04:05")
+    result, err := db.Exec(`
+        INSERT INTO docker_hosts
+        (name, type, address, tls_ca, tls_cert, tls_key, tls_verify, ssh_key, ssh_password, ssh_host_key, created_at, updated_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    `, h.Name, h.Type, h.Address, h.TLSCA, h.TLSCert, tlsKey, boolToInt(h.TLSVerify),
+        sshKey, sshPassword, h.SSHHostKey, now, now)
+    if err != nil {
+        if strings.Contains(err.Error(), "UNIQUE") {
+            return ErrHostExists
+        }
+        return err
+    }
+    h.ID, _ = result.LastInsertId()
+    h.CreatedAt = now
+    h.UpdatedAt = now
+    return nil
+}
+
+// UpdateHost 修改主机，私钥和密码为空时保留原值
+func UpdateHost(h *DockerHost) error {
+    tlsKey, sshKey, sshPassword, err := encryptHostSecrets(h)
+    if err != nil {
+        return err
+    }
+    now := time.Now().Format("2006-01-02 15:04:05")
+    result, err := db.Exec(`
+        UPDATE docker_hosts
+        SET name = ?, type = ?, address = ?, tls_ca = ?, tls_cert = ?,
+            tls_key = CASE WHEN ? = '' THEN tls_key ELSE ? END,
+            tls_verify = ?,
+            ssh_key = CASE WHEN ? = '' THEN ssh_key ELSE ? END,
+            ssh_password = CASE WHEN ? = '' THEN ssh_password ELSE ? END,
+            ssh_host_key = ?, updated_at = ?
+        WHERE id = ?
+    `, h.Name, h.Type, h.Address, h.TLSCA, h.TLSCert, tlsKey, tlsKey, boolToInt(h.TLSVerify),
+        sshKey, sshKey, sshPassword, sshPassword, h.SSHHostKey, now, h.ID)
+    if err != nil {
+        if strings.Contains(err.Error(), "UNIQUE") {
+            return ErrHostExists
+        }
+        return err
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        return ErrHostNotFound
+    }
+    return nil
+}
+
+// SetHostKey 保存首次连接时信任的 SSH 主机公钥
+func SetHostKey(id int64, key string) error {
+    _, err := db.Exec("UPDATE docker_hosts SET ssh_host_key = ? WHERE id = ?", key, id)
+    return err
+}
+
+// DeleteHost 删除主机
+func DeleteHost(id int64) error {
+    result, err := db.Exec("DELETE FROM docker_hosts WHERE id = ?", id)
+    if err != nil {
+        return err
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        return ErrHostNotFound
+    }
+    return nil
+}
+
+func encryptHostSecrets(h *DockerHost) (tlsKey, sshKey, sshPassword string, err error) {
+    if tlsKey, err = encryptSecret(h.TLSKey); err != nil {
+        return "", "", "", fmt.Errorf("加密证书私钥失败: %v", err)
+    }
+    if sshKey, err = encryptSecret(h.SSHKey); err != nil {
+        return "", "", "", fmt.Errorf("加密 ssh 私钥失败: %v", err)
+    }
+    if sshPassword, err = encryptSecret(h.SSHPassword); err != nil {
+        return "", "", "", fmt.Errorf("加密 ssh 密码失败: %v", err)
+    }
+    return tlsKey, sshKey, sshPassword, nil
+}
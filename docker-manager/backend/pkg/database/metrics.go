@@ -0,0 +1,194 @@
+package database
+
+import (
+    "crypto/subtle"
+    "fmt"
+)
+
+// 指标采样的存储精度（秒），原始采样按采集间隔保存，定期汇总为 5 分钟和 1 小时的平均值
+const (
+    MetricsRaw   = 0
+    Metrics5Min  = 300
+    Metrics1Hour = 3600
+
+    // 采集间隔（秒），0 表示停止采集
+    SettingMetricsInterval = "metrics_interval"
+    // 小时级汇总数据的保留天数，原始数据保留 1 天，5 分钟数据保留 7 天
+    SettingMetricsRetentionDays = "metrics_retention_days"
+    // Prometheus 抓取令牌的哈希，为空时 /metrics 不可访问
+    SettingScrapeTokenHash = "metrics_scrape_token_hash"
+)
+
+// MetricSample 一个目标在某一时刻的资源使用情况，网络和磁盘为每秒字节数。
+// 目标为 host（面板所在主机）或 container:<容器名称>
+type MetricSample struct {
+    HostID      int64   `json:"-"`
+    Target      string  `json:"-"`
+    Time        int64   `json:"t"` // unix 秒
+    CPU         float64 `json:"cpu"`
+    MemUsed     float64 `json:"memUsed"`
+    MemPercent  float64 `json:"memPercent"`
+    DiskPercent float64 `json:"diskPercent,omitempty"`
+    NetRx       float64 `json:"netRx"`
+    NetTx       float64 `json:"netTx"`
+    BlkRead     float64 `json:"blkRead"`
+    BlkWrite    float64 `json:"blkWrite"`
+}
+
+// createMetricsTables 创建指标采样表
+func createMetricsTables() error {
+    _, err := db.Exec(`
+    CREATE TABLE IF NOT EXISTS metric_samples (
+        resolution INTEGER NOT NULL,
+        host_id INTEGER NOT NULL,
+        target TEXT NOT NULL,
+        ts INTEGER NOT NULL,
+        cpu REAL,
+        mem_used REAL,
+        mem_percent REAL,
+        disk_percent REAL,
+        net_rx REAL,
+        net_tx REAL,
+        blk_read REAL,
+        blk_write REAL,
+        PRIMARY KEY (resolution, host_id, target, ts)
+    ) WITHOUT ROWID`)
+    if err != nil {
+        return fmt.Errorf("创建 metric_samples 表失败: %v", err)
+    }
+    return nil
+}
+
+// InsertMetricSamples 在一个事务中保存一轮原始采样
+func InsertMetricSamples(samples []MetricSample) error {
+    if len(samples) == 0 {
+        return nil
+    }
+    tx, err := db.Begin()
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
+    stmt, err := tx.Prepare(`
+        INSERT OR REPLACE INTO metric_samples
+        (resolution, host_id, target, ts, cpu, mem_used, mem_percent, disk_percent, net_rx, net_tx, blk_read, blk_write)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+    if err != nil {
+        return err
+    }
+    defer stmt.Close()
+
+    for _, s := range samples {
+        if _, err := stmt.Exec(MetricsRaw, s.HostID, s.Target, s.Time, s.CPU, s.MemUsed, s.MemPercent,
+            s.DiskPercent, s.NetRx, s.NetTx, s.BlkRead, s.BlkWrite); err != nil {
+            return err
+        }
+    }
+    return tx.Commit()
+}
+
+// RollupMetrics 将 from 精度的数据按 to 精度求平均，只汇总 until 之前已经结束的时间段。
+// 最近两个时间段会被重新计算，重复执行不会产生重复数据
+func RollupMetrics(from, to int, until int64) error {
+    end := until / int64(to) * int64(to)
+    start := end - 2*int64(to)
+    _, err := db.Exec(`
+        INSERT OR REPLACE INTO metric_samples
+        (resolution, host_id, target, ts, cpu, mem_used, mem_percent, disk_percent, net_rx, net_tx, blk_read, blk_write)
+        SELECT ?, host_id, target, ts / ? * ?,
+            AVG(cpu), AVG(mem_used), AVG(mem_percent), AVG(disk_percent),
+            AVG(net_rx), AVG(net_tx), AVG(blk_read), AVG(blk_write)
+        FROM metric_samples
+        WHERE resolution = ? AND ts >= ? AND ts < ?
+        GROUP BY host_id, target, ts / ?`,
+        to, to, to, from, start, end, to)
+    return err
+}
+
+// PruneMetrics 删除指定精度中早于 before 的数据
+func PruneMetrics(resolution int, before int64) (int64, error) {
+    result, err := db.Exec(`DELETE FROM metric_samples WHERE resolution = ? AND ts < ?`, resolution, before)
+    if err != nil {
+        return 0, err
+    }
+    return result.RowsAffected()
+}
+
+// QueryMetrics 按时间顺序返回目标在 since 之后的数据
+func QueryMetrics(hostID int64, target string, resolution int, since int64) ([]MetricSample, error) {
+    rows, err := db.Query(`
+        SELECT ts, cpu, mem_used, mem_percent, disk_percent, net_rx, net_tx, blk_read, blk_write
+        FROM metric_samples
+        WHERE resolution = ? AND host_id = ? AND target = ? AND ts >= ?
+        ORDER BY ts`, resolution, hostID, target, since)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    samples := []MetricSample{}
+    for rows.Next() {
+        s := MetricSample{HostID: hostID, Target: target}
+        if err := rows.Scan(&s.Time, &s.CPU, &s.MemUsed, &s.MemPercent, &s.DiskPercent,
+            &s.NetRx, &s.NetTx, &s.BlkRead, &s.BlkWrite); err != nil {
+            return nil, err
+        }
+        samples = append(samples, s)
+    }
+    return samples, rows.Err()
+}
+
+// ListMetricTargets 返回主机在 since 之后有数据的目标
+func ListMetricTargets(hostID int64, since int64) ([]string, error) {
+    rows, err := db.Query(`
+        SELECT DISTINCT target FROM metric_samples
+        WHERE host_id = ? AND ts >= ? AND resolution IN (?, ?)
+        ORDER BY target`, hostID, since, MetricsRaw, Metrics1Hour)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    targets := []string{}
+    for rows.Next() {
+        var target string
+        if err := rows.Scan(&target); err != nil {
+            return nil, err
+        }
+        targets = append(targets, target)
+    }
+    return targets, rows.Err()
+}
+
+// CreateScrapeToken 生成新的 Prometheus 抓取令牌并替换旧令牌，明文只在此时返回一次
+func CreateScrapeToken() (string, error) {
+    token, err := newToken()
+    if err != nil {
+        return "", fmt.Errorf("生成抓取令牌失败: %v", err)
+    }
+    if err := SetSetting(SettingScrapeTokenHash, HashToken(token)); err != nil {
+        return "", err
+    }
+    return token, nil
+}
+
+// DeleteScrapeToken 删除抓取令牌，之后 /metrics 不可访问
+func DeleteScrapeToken() error {
+    return SetSetting(SettingScrapeTokenHash, "")
+}
+
+// HasScrapeToken 是否已生成抓取令牌
+func HasScrapeToken() bool {
+    hash, err := GetSetting(SettingScrapeTokenHash, "")
+    return err == nil && hash != ""
+}
+
+// CheckScrapeToken 校验抓取令牌
+func CheckScrapeToken(token string) bool {
+    hash, err := GetSetting(SettingScrapeTokenHash, "")
+    if err != nil || hash == "" || token == "" {
+        return false
+    }
+    return subtle.ConstantTimeCompare([]byte(hash), []byte(HashToken(token))) == 1
+}
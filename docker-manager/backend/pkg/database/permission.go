@@ -0,0 +1,194 @@
+package database
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+    "time"
+)
+
+// 可授权的操作
+const (
+    ActionRead    = "read"    // 查看列表、详情、配置
+    ActionLogs    = "logs"    // 查看容器与项目日志
+    ActionOperate = "operate" // 启动、停止、重启、暂停、恢复
+    ActionExec    = "exec"    // 容器终端与命令执行，只能显式授予
+    ActionWrite   = "write"   // 创建、修改、删除
+)
+
+// 用户角色，admin 拥有全部权限
+const (
+    RoleAdmin    = "admin"
+    RoleOperator = "operator"
+    RoleViewer   = "viewer"
+)
+
+// 各角色在所有资源上默认拥有的操作，exec 不属于任何非管理员角色
+var roleActions = map[string][]string{
+    RoleAdmin:    {ActionRead, ActionLogs, ActionOperate, ActionExec, ActionWrite},
+    RoleOperator: {ActionRead, ActionLogs, ActionOperate},
+    RoleViewer:   {ActionRead},
+}
+
+// ValidRole 判断角色是否存在
+func ValidRole(role string) bool {
+    _, ok := roleActions[role]
+    return ok
+}
+
+func validAction(action string) bool {
+    switch action {
+    case ActionRead, ActionLogs, ActionOperate, ActionExec, ActionWrite:
+        return true
+    }
+    return false
+}
+
+// Permission 在指定范围内额外授予的操作。
+// Scope 格式: "*" 所有资源；"project:<名称>" compose 项目；
+// "label:<键>" 或 "label:<键>=<值>" 带有该标签的容器
+type Permission struct {
+    ID        int64    `json:"id"`
+    Scope     string   `json:"scope"`
+    Actions   []string `json:"actions"`
+    CreatedAt string   `json:"created_at,omitempty"`
+}
+
+// Resource 被访问的资源，为 nil 表示不针对具体资源的操作（例如列表、创建）
+type Resource struct {
+    Project string
+    Labels  map[string]string
+}
+
+// Validate 校验授权范围与操作
+func (p Permission) Validate() error {
+    switch {
+    case p.Scope == "*":
+    case strings.HasPrefix(p.Scope, "project:"):
+        if strings.TrimPrefix(p.Scope, "project:") == "" {
+            return fmt.Errorf("项目名称不能为空")
+        }
+    case strings.HasPrefix(p.Scope, "label:"):
+        key := strings.SplitN(strings.TrimPrefix(p.Scope, "label:"), "=", 2)[0]
+        if key == "" {
+            return fmt.Errorf("标签名称不能为空")
+        }
+    default:
+        return fmt.Errorf("无效的授权范围: %s", p.Scope)
+    }
+    if len(p.Actions) == 0 {
+        return fmt.Errorf("授权范围 %s 未指定操作", p.Scope)
+    }
+    for _, action := range p.Actions {
+        if !validAction(action) {
+            return fmt.Errorf("无效的操作: %s", action)
+        }
+    }
+    return nil
+}
+
+// Matches 判断授权范围是否覆盖资源，project/label 范围不匹配非具体资源
+func (p Permission) Matches(resource *Resource) bool {
+    if p.Scope == "*" {
+        return true
+    }
+    if resource == nil {
+        return false
+    }
+    if name, ok := strings.CutPrefix(p.Scope, "project:"); ok {
+        return resource.Project != "" && resource.Project == name
+    }
+    if label, ok := strings.CutPrefix(p.Scope, "label:"); ok {
+        key, value, hasValue := strings.Cut(label, "=")
+        actual, exists := resource.Labels[key]
+        return exists && (!hasValue || actual == value)
+    }
+    return false
+}
+
+// Allowed 判断角色与额外授权是否允许对资源执行操作
+func Allowed(role string, permissions []Permission, action string, resource *Resource) bool {
+    for _, a := range roleActions[role] {
+        if a == action {
+            return true
+        }
+    }
+    for _, p := range permissions {
+        if !p.Matches(resource) {
+            continue
+        }
+        for _, a := range p.Actions {
+            if a == action {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// createPermissionTables 创建用户授权表
+func createPermissionTables() error {
+    _, err := db.Exec(`
+    CREATE TABLE IF NOT EXISTS user_permissions (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        user_id INTEGER NOT NULL,
+        scope TEXT NOT NULL,
+        actions TEXT NOT NULL,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        FOREIGN KEY (user_id) REFERENCES users(id)
+    )`)
+    if err != nil {
+        return fmt.Errorf("创建 user_permissions 表失败: %v", err)
+    }
+    return nil
+}
+
+// ListUserPermissions 获取用户的额外授权
+func ListUserPermissions(userID int64) ([]Permission, error) {
+    rows, err := db.Query(`SELECT id, scope, actions, created_at FROM user_permissions WHERE user_id = ? ORDER BY id`, userID)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    permissions := []Permission{}
+    for rows.Next() {
+        var p Permission
+        var actions string
+        if err := rows.Scan(&p.ID, &p.Scope, &actions, &p.CreatedAt); err != nil {
+            return nil, err
+        }
+        p.Actions = strings.Split(actions, ",")
+        permissions = append(permissions, p)
+    }
+    return permissions, rows.Err()
+}
+
+// ReplaceUserPermissions 用新的授权列表替换用户的全部额外授权
+func ReplaceUserPermissions(userID int64, permissions []Permission) error {
+    for _, p := range permissions {
+        if err := p.Validate(); err != nil {
+            return err
+        }
+    }
+
+    tx, err := db.Begin()
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
+    if _, err := tx.Exec(`DELETE FROM user_permissions WHERE user_id = ?`, userID); err != nil {
+        return err
+    }
+    now := time.Now().Format("2006-01-02 15:04:05")
+    for _, p := range permissions {
+        actions := append([]string(nil), p.Actions...)
+        sort.Strings(actions)
+        if _, err := tx.Exec(`INSERT INTO user_permissions (user_id, scope, actions, created_at) VALUES (?, ?, ?, ?)`,
+            userID, p.Scope, strings.Join(actions, ","), now); err != nil {
+            return err
+        }
+    }
+    return tx.Commit()
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+    "errors"
     "time"
     "fmt"
     "database/sql"
@@ -8,14 +9,25 @@ import (
 )
 
 type Registry struct {
-    ID        int64  `json:"id"`
-    Name      string `json:"name"`
-    URL       string `json:"url"`
-    Username  string `json:"username,omitempty"`
-    Password  string `json:"password,omitempty"`
-    IsDefault bool   `json:"is_default"`
-    CreatedAt string `json:"created_at"` // 改为 string 类型
-    UpdatedAt string `json:"updated_at"` // 改为 string 类型
+    ID          int64  `json:"id"`
+    Name        string `json:"name"`
+    URL         string `json:"url"`
+    Username    string `json:"username,omitempty"`
+    Password    string `json:"password,omitempty"` // 只写字段，数据库中加密保存，不在接口响应中返回
+    HasPassword bool   `json:"has_password"`
+    // 已保存的密码无法解密（如密钥变更），需要重新填写，不能沿用
+    PasswordUndecryptable bool `json:"password_undecryptable,omitempty"`
+    IsDefault   bool   `json:"is_default"`
+    CreatedAt   string `json:"created_at"` // 改为 string 类型
+    UpdatedAt   string `json:"updated_at"` // 改为 string 类型
+}
+
+// Redacted 返回去掉密码的副本，用于接口响应
+func (r *Registry) Redacted() *Registry {
+    copied := *r
+    copied.HasPassword = r.Password != "" || r.PasswordUndecryptable
+    copied.Password = ""
+    return &copied
 }
 
 // 添加清除注册表的函数
@@ -39,15 +51,22 @@ func SaveRegistry(registry *Registry) error {
     err := db.QueryRow("SELECT id FROM registries WHERE url = ?", registry.URL).Scan(&id)
     
     now := time.Now().Format("2006-01-02 15:04:05")
+
+    // 密码加密后保存
+    password, encErr := encryptSecret(registry.Password)
+    if encErr != nil {
+        return fmt.Errorf("加密密码失败: %v", encErr)
+    }
     
     if err == nil {
-        // 更新现有注册表
+        // 更新现有注册表，密码为只写字段，未提供新密码时保留原密码
         log.Printf("更新现有注册表: %s (ID: %d, URL: %s)", registry.Name, id, registry.URL)
         _, err = db.Exec(`
             UPDATE registries 
-            SET name = ?, username = ?, password = ?, is_default = ?, updated_at = ? 
+            SET name = ?, username = ?, password = CASE WHEN ? = '' THEN password ELSE ? END,
+                is_default = ?, updated_at = ? 
             WHERE id = ?
-        `, registry.Name, registry.Username, registry.Password, 
+        `, registry.Name, registry.Username, password, password,
            boolToInt(registry.IsDefault), now, id)
         return err
     } else if err == sql.ErrNoRows {
@@ -64,7 +83,7 @@ func SaveRegistry(registry *Registry) error {
             INSERT INTO registries 
             (name, url, username, password, is_default, created_at, updated_at) 
             VALUES (?, ?, ?, ?, ?, ?, ?)
-        `, registry.Name, registry.URL, registry.Username, registry.Password, 
+        `, registry.Name, registry.URL, registry.Username, password, 
            boolToInt(registry.IsDefault), now, now)
         
         if err != nil {
@@ -85,12 +104,195 @@ func SaveRegistry(registry *Registry) error {
     return err
 }
 
+// 注册表查询的列，与 scanRegistry 的扫描顺序一致
+const registryColumns = `id, name, url, username, password, is_default, created_at, updated_at`
+
+// ErrRegistryNotFound 指定的注册表不存在
+var ErrRegistryNotFound = errors.New("注册表不存在")
+
+// ErrRegistryExists 相同 URL 的注册表已存在
+var ErrRegistryExists = errors.New("相同 URL 的注册表已存在")
+
+// ErrRegistryPasswordUndecryptable 已保存的密码无法解密，沿用原密码会写入空密码
+var ErrRegistryPasswordUndecryptable = errors.New("已保存的密码无法解密，请重新填写密码")
+
+// ErrRegistryPasswordRequired 修改地址或用户名时未提供新密码，原密码不能发往新的目标
+var ErrRegistryPasswordRequired = errors.New("修改地址或用户名时需要重新填写密码")
+
+type rowScanner interface {
+    Scan(dest ...interface{}) error
+}
+
+// scanRegistry 扫描一行注册表记录并解密密码
+func scanRegistry(row rowScanner) (*Registry, error) {
+    var r Registry
+    var isDefault int
+    var createdAt, updatedAt string
+    var username, password sql.NullString
+
+    if err := row.Scan(&r.ID, &r.Name, &r.URL, &username, &password, &isDefault, &createdAt, &updatedAt); err != nil {
+        return nil, err
+    }
+
+    r.IsDefault = isDefault == 1
+    r.Username = username.String
+    r.CreatedAt = createdAt
+    r.UpdatedAt = updatedAt
+
+    // 解密密码，解密失败时标记出来而不是报错，避免整个列表不可用；
+    // 写回时调用方需检查该标记，不能把空密码当作原密码保存
+    plain, err := decryptSecret(password.String)
+    if err != nil {
+        log.Printf("解密注册表 %s 的密码失败: %v", r.URL, err)
+        plain = ""
+        r.PasswordUndecryptable = true
+    }
+    r.Password = plain
+    r.HasPassword = r.Password != "" || r.PasswordUndecryptable
+    return &r, nil
+}
+
+// GetRegistry 按 ID 获取注册表配置
+func GetRegistry(id int64) (*Registry, error) {
+    row := db.QueryRow(`SELECT `+registryColumns+` FROM registries WHERE id = ?`, id)
+    r, err := scanRegistry(row)
+    if err == sql.ErrNoRows {
+        return nil, ErrRegistryNotFound
+    }
+    return r, err
+}
+
+// CreateRegistry 新建注册表配置，URL 不能与已有注册表重复
+func CreateRegistry(registry *Registry) error {
+    if registry.URL == "" {
+        return fmt.Errorf("注册表 URL 不能为空")
+    }
+
+    return withTx(func(tx *sql.Tx) error {
+        var exists int
+        if err := tx.QueryRow(`SELECT COUNT(*) FROM registries WHERE url = ?`, registry.URL).Scan(&exists); err != nil {
+            return err
+        }
+        if exists > 0 {
+            return ErrRegistryExists
+        }
+
+        id, err := insertRegistry(tx, registry)
+        if err != nil {
+            return err
+        }
+        registry.ID = id
+        return nil
+    })
+}
+
+// UpdateRegistry 更新注册表配置，未提供新密码时保留原密码。
+// 保留原密码时地址和用户名都不能变，否则返回 ErrRegistryPasswordRequired
+func UpdateRegistry(registry *Registry) error {
+    if registry.URL == "" {
+        return fmt.Errorf("注册表 URL 不能为空")
+    }
+
+    password, err := encryptSecret(registry.Password)
+    if err != nil {
+        return fmt.Errorf("加密密码失败: %v", err)
+    }
+
+    return withTx(func(tx *sql.Tx) error {
+        var exists int
+        if err := tx.QueryRow(`SELECT COUNT(*) FROM registries WHERE url = ? AND id != ?`, registry.URL, registry.ID).Scan(&exists); err != nil {
+            return err
+        }
+        if exists > 0 {
+            return ErrRegistryExists
+        }
+
+        if password == "" {
+            var oldURL string
+            var oldUsername, oldPassword sql.NullString
+            err := tx.QueryRow(`SELECT url, username, password FROM registries WHERE id = ?`, registry.ID).
+                Scan(&oldURL, &oldUsername, &oldPassword)
+            if err == sql.ErrNoRows {
+                return ErrRegistryNotFound
+            }
+            if err != nil {
+                return err
+            }
+            if oldPassword.String != "" && (oldURL != registry.URL || oldUsername.String != registry.Username) {
+                return ErrRegistryPasswordRequired
+            }
+        }
+
+        result, err := tx.Exec(`
+            UPDATE registries
+            SET name = ?, url = ?, username = ?, password = CASE WHEN ? = '' THEN password ELSE ? END,
+                is_default = ?, updated_at = ?
+            WHERE id = ?
+        `, registry.Name, registry.URL, registry.Username, password, password,
+           boolToInt(registry.IsDefault), time.Now().Format("2006-01-02 15:04:05"), registry.ID)
+        if err != nil {
+            return err
+        }
+        if affected, _ := result.RowsAffected(); affected == 0 {
+            return ErrRegistryNotFound
+        }
+        return nil
+    })
+}
+
+// DeleteRegistry 删除注册表配置
+func DeleteRegistry(id int64) error {
+    result, err := db.Exec(`DELETE FROM registries WHERE id = ?`, id)
+    if err != nil {
+        return err
+    }
+    if affected, _ := result.RowsAffected(); affected == 0 {
+        return ErrRegistryNotFound
+    }
+    return nil
+}
+
+// ReplaceRegistries 在一个事务中用给定配置替换全部注册表，任一条写入失败时保留原配置
+func ReplaceRegistries(registries []*Registry) error {
+    return withTx(func(tx *sql.Tx) error {
+        if _, err := tx.Exec(`DELETE FROM registries`); err != nil {
+            return err
+        }
+        for _, registry := range registries {
+            if registry.URL == "" {
+                return fmt.Errorf("注册表 URL 不能为空: %s", registry.Name)
+            }
+            if _, err := insertRegistry(tx, registry); err != nil {
+                return fmt.Errorf("保存注册表 %s 失败: %v", registry.URL, err)
+            }
+        }
+        return nil
+    })
+}
+
+// insertRegistry 在事务中插入一条注册表记录，密码加密保存
+func insertRegistry(tx *sql.Tx, registry *Registry) (int64, error) {
+    password, err := encryptSecret(registry.Password)
+    if err != nil {
+        return 0, fmt.Errorf("加密密码失败: %v", err)
+    }
+
+    now := time.Now().Format("2006-01-02 15:04:05")
+    result, err := tx.Exec(`
+        INSERT INTO registries
+        (name, url, username, password, is_default, created_at, updated_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?)
+    `, registry.Name, registry.URL, registry.Username, password,
+       boolToInt(registry.IsDefault), now, now)
+    if err != nil {
+        return 0, err
+    }
+    return result.LastInsertId()
+}
+
 // GetAllRegistries 获取所有注册表配置
 func GetAllRegistries() (map[string]*Registry, error) {
-    rows, err := db.Query(`
-        SELECT id, name, url, username, password, is_default, created_at, updated_at 
-        FROM registries
-    `)
+    rows, err := db.Query(`SELECT ` + registryColumns + ` FROM registries`)
     if err != nil {
         return nil, err
     }
@@ -100,24 +302,18 @@ func GetAllRegistries() (map[string]*Registry, error) {
     var count int
     
     for rows.Next() {
-        var r Registry
-        var isDefault int
-        var createdAt, updatedAt string
-        
-        err := rows.Scan(&r.ID, &r.Name, &r.URL, &r.Username, &r.Password, &isDefault, &createdAt, &updatedAt)
+        r, err := scanRegistry(rows)
         if err != nil {
             log.Printf("扫描注册表行失败: %v", err)
             return nil, err
         }
         
-        r.IsDefault = isDefault == 1
-        
         // 使用 URL 作为键，但添加日志以便调试
         log.Printf("从数据库读取注册表: ID=%d, Name=%s, URL=%s", r.ID, r.Name, r.URL)
         
         // 确保 URL 不为空
         if r.URL != "" {
-            registries[r.URL] = &r
+            registries[r.URL] = r
             count++
         } else {
             log.Printf("警告: 跳过 URL 为空的注册表: ID=%d, Name=%s", r.ID, r.Name)
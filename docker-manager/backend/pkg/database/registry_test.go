@@ -0,0 +1,58 @@
+package database
+
+import (
+    "errors"
+    "testing"
+)
+
+func TestUpdateRegistryKeepsPassword(t *testing.T) {
+    openTestDB(t)
+
+    registry := &Registry{Name: "harbor", URL: "harbor.example.com", Username: "alice", Password: "secret"}
+    if err := CreateRegistry(registry); err != nil {
+        t.Fatalf("CreateRegistry 返回错误: %v", err)
+    }
+
+    tests := []struct {
+        name     string
+        url      string
+        username string
+        password string
+        wantErr  error
+        want     string
+    }{
+        {"只改名称时沿用原密码", "harbor.example.com", "alice", "", nil, "secret"},
+        {"改地址必须提供密码", "evil.example.com", "alice", "", ErrRegistryPasswordRequired, "secret"},
+        {"改用户名必须提供密码", "harbor.example.com", "mallory", "", ErrRegistryPasswordRequired, "secret"},
+        {"提供新密码时可以改地址", "registry.example.com", "bob", "new-secret", nil, "new-secret"},
+    }
+    for _, tt := range tests {
+        err := UpdateRegistry(&Registry{ID: registry.ID, Name: tt.name, URL: tt.url, Username: tt.username, Password: tt.password})
+        if !errors.Is(err, tt.wantErr) {
+            t.Errorf("%s: err = %v, 期望 %v", tt.name, err, tt.wantErr)
+        }
+        saved, err := GetRegistry(registry.ID)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if saved.Password != tt.want {
+            t.Errorf("%s: 密码 = %q, 期望 %q", tt.name, saved.Password, tt.want)
+        }
+    }
+}
+
+func TestUpdateRegistryWithoutPassword(t *testing.T) {
+    openTestDB(t)
+
+    registry := &Registry{Name: "public", URL: "public.example.com"}
+    if err := CreateRegistry(registry); err != nil {
+        t.Fatal(err)
+    }
+    // 原来就没有密码时，改地址不会泄露任何凭据
+    if err := UpdateRegistry(&Registry{ID: registry.ID, Name: "public", URL: "mirror.example.com", Username: "anon"}); err != nil {
+        t.Errorf("没有密码时应允许修改地址: %v", err)
+    }
+    if err := UpdateRegistry(&Registry{ID: 9999, Name: "x", URL: "x.example.com"}); !errors.Is(err, ErrRegistryNotFound) {
+        t.Errorf("不存在的注册表应返回 ErrRegistryNotFound, 得到 %v", err)
+    }
+}
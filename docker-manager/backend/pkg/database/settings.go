@@ -0,0 +1,51 @@
+package database
+
+import (
+    "database/sql"
+    "strconv"
+)
+
+// 面板设置项
+const (
+    // 注册表凭据变更时同步写入 docker CLI 的 config.json
+    SettingDockerConfigSync = "docker_config_sync"
+    // 容器 json-file 日志超过该大小时在列表中提示，格式同 max-size，例如 100m
+    SettingLogSizeWarning = "log_size_warning"
+    // 从地址导入镜像时额外允许访问的内网地址段，逗号分隔的 CIDR 或 IP，例如 10.0.0.0/8
+    SettingImportAllowedNetworks = "import_allowed_networks"
+)
+
+// GetSetting 读取设置项，不存在时返回默认值
+func GetSetting(key, defaultValue string) (string, error) {
+    var value string
+    err := db.QueryRow(`SELECT value FROM settings WHERE key = ?`, key).Scan(&value)
+    if err != nil {
+        if err == sql.ErrNoRows {
+            return defaultValue, nil
+        }
+        return "", err
+    }
+    return value, nil
+}
+
+// GetBoolSetting 读取布尔类型的设置项，读取失败或格式错误时返回默认值
+func GetBoolSetting(key string, defaultValue bool) bool {
+    value, err := GetSetting(key, "")
+    if err != nil || value == "" {
+        return defaultValue
+    }
+    b, err := strconv.ParseBool(value)
+    if err != nil {
+        return defaultValue
+    }
+    return b
+}
+
+// SetSetting 保存设置项
+func SetSetting(key, value string) error {
+    _, err := db.Exec(`
+        INSERT INTO settings (key, value, updated_at) VALUES (?, ?, CURRENT_TIMESTAMP)
+        ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = CURRENT_TIMESTAMP`,
+        key, value)
+    return err
+}
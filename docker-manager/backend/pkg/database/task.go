@@ -0,0 +1,345 @@
+package database
+
+import (
+    "database/sql"
+    "errors"
+    "fmt"
+    "strings"
+    "time"
+)
+
+// 计划任务类型
+const (
+    TaskRestartContainer = "restart_container" // 重启容器，Target 为容器名称
+    TaskRestartProject   = "restart_project"   // 重启 compose 项目，Target 为项目名称
+    TaskUpdateContainer  = "update_container"  // 镜像有更新时拉取并重建容器
+    TaskUpdateProject    = "update_project"    // docker compose pull 后 up -d，只重建镜像有变化的服务
+    TaskPruneImages      = "prune_images"      // All 为 true 时同时清理未使用的非悬空镜像
+    TaskPruneNetworks    = "prune_networks"
+    TaskPruneVolumes     = "prune_volumes"     // All 为 true 时同时清理未使用的命名卷
+    TaskBackupVolume     = "backup_volume"     // 打包卷内容到面板数据目录，保留最近 Keep 份
+    TaskExec             = "exec"              // 在容器中通过 /bin/sh -c 执行 Command
+)
+
+// 执行状态
+const (
+    TaskRunning = "running"
+    TaskSuccess = "success"
+    TaskFailure = "failure"
+    TaskTimeout = "timeout"
+    TaskSkipped = "skipped" // 上一次执行尚未结束
+)
+
+// 触发方式
+const (
+    TaskTriggerSchedule = "schedule"
+    TaskTriggerManual   = "manual"
+)
+
+// 每个任务保留的执行记录条数
+const taskRunsKept = 200
+
+var (
+    ErrTaskNotFound = errors.New("计划任务不存在")
+    ErrTaskExists   = errors.New("同名计划任务已存在")
+
+    ErrTaskRunNotFound = errors.New("执行记录不存在")
+)
+
+// ScheduledTask 按 cron 表达式定期执行的任务
+type ScheduledTask struct {
+    ID         int64  `json:"id"`
+    Name       string `json:"name"`
+    Type       string `json:"type"`
+    HostID     int64  `json:"host_id"` // 0 为本机
+    Target     string `json:"target"`
+    Command    string `json:"command"`
+    All        bool   `json:"all"`
+    Keep       int    `json:"keep"`     // 卷备份保留份数
+    Schedule   string `json:"schedule"` // 标准 5 段 cron 表达式，也支持 @daily、@every 1h 等写法
+    Timeout    int    `json:"timeout"`  // 秒
+    Enabled    bool   `json:"enabled"`
+    LastRunAt  string `json:"last_run_at"`
+    LastStatus string `json:"last_status"`
+    CreatedAt  string `json:"created_at"`
+    UpdatedAt  string `json:"updated_at"`
+}
+
+// TaskRun 一次执行记录，输出超出长度限制时截断
+type TaskRun struct {
+    ID         int64  `json:"id"`
+    TaskID     int64  `json:"task_id"`
+    TaskName   string `json:"task_name"`
+    TaskType   string `json:"task_type"`
+    HostID     int64  `json:"host_id"`
+    Trigger    string `json:"trigger"`
+    Status     string `json:"status"`
+    Output     string `json:"output,omitempty"`
+    Error      string `json:"error"`
+    StartedAt  string `json:"started_at"`
+    FinishedAt string `json:"finished_at"`
+    DurationMs int64  `json:"duration_ms"`
+}
+
+// createTaskTables 创建计划任务与执行记录表
+func createTaskTables() error {
+    tables := map[string]string{
+        "scheduled_tasks": `
+        CREATE TABLE IF NOT EXISTS scheduled_tasks (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            name TEXT NOT NULL UNIQUE,
+            type TEXT NOT NULL,
+            host_id INTEGER NOT NULL DEFAULT 0,
+            target TEXT,
+            command TEXT,
+            all_resources INTEGER DEFAULT 0,
+            keep INTEGER DEFAULT 0,
+            schedule TEXT NOT NULL,
+            timeout INTEGER DEFAULT 0,
+            enabled INTEGER DEFAULT 1,
+            last_run_at DATETIME,
+            last_status TEXT,
+            created_at DATETIME,
+            updated_at DATETIME
+        )`,
+        "task_runs": `
+        CREATE TABLE IF NOT EXISTS task_runs (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            task_id INTEGER NOT NULL,
+            task_name TEXT NOT NULL,
+            task_type TEXT NOT NULL,
+            host_id INTEGER NOT NULL DEFAULT 0,
+            trigger_type TEXT NOT NULL,
+            status TEXT NOT NULL,
+            output TEXT,
+            error TEXT,
+            started_at DATETIME NOT NULL,
+            finished_at DATETIME,
+            duration_ms INTEGER DEFAULT 0
+        )`,
+    }
+    for _, name := range []string{"scheduled_tasks", "task_runs"} {
+        if _, err := db.Exec(tables[name]); err != nil {
+            return fmt.Errorf("创建 %s 表失败: %v", name, err)
+        }
+    }
+    if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_task_runs_task_id ON task_runs(task_id)`); err != nil {
+        return fmt.Errorf("创建计划任务索引失败: %v", err)
+    }
+    return nil
+}
+
+const scheduledTaskColumns = `id, name, type, host_id, COALESCE(target, ''), COALESCE(command, ''), all_resources, keep,
+    schedule, timeout, enabled, COALESCE(last_run_at, ''), COALESCE(last_status, ''), created_at, updated_at`
+
+func scanScheduledTask(row rowScanner) (*ScheduledTask, error) {
+    var t ScheduledTask
+    var all, enabled int
+    var createdAt, updatedAt sql.NullString
+    if err := row.Scan(&t.ID, &t.Name, &t.Type, &t.HostID, &t.Target, &t.Command, &all, &t.Keep,
+        &t.Schedule, &t.Timeout, &enabled, &t.LastRunAt, &t.LastStatus, &createdAt, &updatedAt); err != nil {
+        return nil, err
+    }
+    t.All = all == 1
+    t.Enabled = enabled == 1
+    t.CreatedAt = createdAt.String
+    t.UpdatedAt = updatedAt.String
+    return &t, nil
+}
+
+// ListScheduledTasks 返回所有计划任务
+func ListScheduledTasks() ([]*ScheduledTask, error) {
+    rows, err := db.Query("SELECT " + scheduledTaskColumns + " FROM scheduled_tasks ORDER BY id")
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    tasks := []*ScheduledTask{}
+    for rows.Next() {
+        t, err := scanScheduledTask(rows)
+        if err != nil {
+            return nil, err
+        }
+        tasks = append(tasks, t)
+    }
+    return tasks, rows.Err()
+}
+
+// GetScheduledTask 按 ID 查询计划任务
+func GetScheduledTask(id int64) (*ScheduledTask, error) {
+    t, err := scanScheduledTask(db.QueryRow("SELECT "+scheduledTaskColumns+" FROM scheduled_tasks WHERE id = ?", id))
+    if err == sql.ErrNoRows {
+        return nil, ErrTaskNotFound
+    }
+    return t, err
+}
+
+// CreateScheduledTask 添加计划任务
+func CreateScheduledTask(t *ScheduledTask) error {
+    now := time.Now().Format("2006-01-02 15:04:05")
+    result, err := db.Exec(`
+        INSERT INTO scheduled_tasks
+        (name, type, host_id, target, command, all_resources, keep, schedule, timeout, enabled, created_at, updated_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    `, t.Name, t.Type, t.HostID, t.Target, t.Command, boolToInt(t.All), t.Keep, t.Schedule, t.Timeout,
+        boolToInt(t.Enabled), now, now)
+    if err != nil {
+        if strings.Contains(err.Error(), "UNIQUE") {
+            return ErrTaskExists
+        }
+        return err
+    }
+    t.ID, _ = result.LastInsertId()
+    t.CreatedAt = now
+    t.UpdatedAt = now
+    return nil
+}
+
+// UpdateScheduledTask 修改计划任务，最近执行状态由 FinishTaskRun 更新
+func UpdateScheduledTask(t *ScheduledTask) error {
+    now := time.Now().Format("2006-01-02 15:04:05")
+    result, err := db.Exec(`
+        UPDATE scheduled_tasks
+        SET name = ?, type = ?, host_id = ?, target = ?, command = ?, all_resources = ?, keep = ?,
+            schedule = ?, timeout = ?, enabled = ?, updated_at = ?
+        WHERE id = ?
+    `, t.Name, t.Type, t.HostID, t.Target, t.Command, boolToInt(t.All), t.Keep,
+        t.Schedule, t.Timeout, boolToInt(t.Enabled), now, t.ID)
+    if err != nil {
+        if strings.Contains(err.Error(), "UNIQUE") {
+            return ErrTaskExists
+        }
+        return err
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        return ErrTaskNotFound
+    }
+    return nil
+}
+
+// DeleteScheduledTask 删除计划任务及其执行记录
+func DeleteScheduledTask(id int64) error {
+    result, err := db.Exec("DELETE FROM scheduled_tasks WHERE id = ?", id)
+    if err != nil {
+        return err
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        return ErrTaskNotFound
+    }
+    _, err = db.Exec("DELETE FROM task_runs WHERE task_id = ?", id)
+    return err
+}
+
+// InsertTaskRun 记录一次执行，Status 为 running 时由 FinishTaskRun 补全结果。
+// 跳过的执行不改变任务的最近执行状态
+func InsertTaskRun(r *TaskRun) error {
+    result, err := db.Exec(`
+        INSERT INTO task_runs
+        (task_id, task_name, task_type, host_id, trigger_type, status, output, error, started_at, finished_at, duration_ms)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    `, r.TaskID, r.TaskName, r.TaskType, r.HostID, r.Trigger, r.Status, r.Output, r.Error,
+        r.StartedAt, r.FinishedAt, r.DurationMs)
+    if err != nil {
+        return err
+    }
+    r.ID, _ = result.LastInsertId()
+    if r.Status == TaskSkipped {
+        return nil
+    }
+    return updateTaskLastRun(r)
+}
+
+// FinishTaskRun 保存执行结果并更新任务的最近执行状态，清理超出保留条数的旧记录
+func FinishTaskRun(r *TaskRun) error {
+    if _, err := db.Exec(`
+        UPDATE task_runs SET status = ?, output = ?, error = ?, finished_at = ?, duration_ms = ?
+        WHERE id = ?
+    `, r.Status, r.Output, r.Error, r.FinishedAt, r.DurationMs, r.ID); err != nil {
+        return err
+    }
+    if err := updateTaskLastRun(r); err != nil {
+        return err
+    }
+    _, err := db.Exec(`
+        DELETE FROM task_runs WHERE task_id = ? AND id <= (
+            SELECT id FROM task_runs WHERE task_id = ? ORDER BY id DESC LIMIT 1 OFFSET ?
+        )
+    `, r.TaskID, r.TaskID, taskRunsKept)
+    return err
+}
+
+func updateTaskLastRun(r *TaskRun) error {
+    _, err := db.Exec("UPDATE scheduled_tasks SET last_run_at = ?, last_status = ? WHERE id = ?",
+        r.StartedAt, r.Status, r.TaskID)
+    return err
+}
+
+// FailInterruptedTaskRuns 面板重启前未结束的执行无法继续跟踪，标记为失败
+func FailInterruptedTaskRuns() error {
+    now := time.Now().Format("2006-01-02 15:04:05")
+    if _, err := db.Exec(`
+        UPDATE scheduled_tasks SET last_status = ?
+        WHERE id IN (SELECT task_id FROM task_runs WHERE status = ?)
+    `, TaskFailure, TaskRunning); err != nil {
+        return err
+    }
+    _, err := db.Exec("UPDATE task_runs SET status = ?, error = ?, finished_at = ? WHERE status = ?",
+        TaskFailure, "面板重启，执行中断", now, TaskRunning)
+    return err
+}
+
+// ListTaskRuns 分页查询执行记录，taskID 为 0 时查询全部任务。列表不包含输出
+func ListTaskRuns(taskID int64, limit, offset int) ([]TaskRun, int, error) {
+    where := ""
+    var args []interface{}
+    if taskID > 0 {
+        where = " WHERE task_id = ?"
+        args = append(args, taskID)
+    }
+
+    var total int
+    if err := db.QueryRow(`SELECT COUNT(*) FROM task_runs`+where, args...).Scan(&total); err != nil {
+        return nil, 0, err
+    }
+
+    query := `SELECT id, task_id, task_name, task_type, host_id, trigger_type, status, COALESCE(error, ''),
+        started_at, COALESCE(finished_at, ''), duration_ms
+        FROM task_runs` + where + ` ORDER BY id DESC`
+    if limit > 0 {
+        query += ` LIMIT ? OFFSET ?`
+        args = append(args, limit, offset)
+    }
+    rows, err := db.Query(query, args...)
+    if err != nil {
+        return nil, 0, err
+    }
+    defer rows.Close()
+
+    runs := []TaskRun{}
+    for rows.Next() {
+        var r TaskRun
+        if err := rows.Scan(&r.ID, &r.TaskID, &r.TaskName, &r.TaskType, &r.HostID, &r.Trigger, &r.Status,
+            &r.Error, &r.StartedAt, &r.FinishedAt, &r.DurationMs); err != nil {
+            return nil, 0, err
+        }
+        runs = append(runs, r)
+    }
+    return runs, total, rows.Err()
+}
+
+// GetTaskRun 查询单次执行记录，包含输出
+func GetTaskRun(id int64) (*TaskRun, error) {
+    var r TaskRun
+    err := db.QueryRow(`SELECT id, task_id, task_name, task_type, host_id, trigger_type, status,
+        COALESCE(output, ''), COALESCE(error, ''), started_at, COALESCE(finished_at, ''), duration_ms
+        FROM task_runs WHERE id = ?`, id).Scan(&r.ID, &r.TaskID, &r.TaskName, &r.TaskType, &r.HostID,
+        &r.Trigger, &r.Status, &r.Output, &r.Error, &r.StartedAt, &r.FinishedAt, &r.DurationMs)
+    if err == sql.ErrNoRows {
+        return nil, ErrTaskRunNotFound
+    }
+    if err != nil {
+        return nil, err
+    }
+    return &r, nil
+}
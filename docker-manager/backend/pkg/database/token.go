@@ -0,0 +1,197 @@
+package database
+
+import (
+    "database/sql"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "strings"
+    "time"
+)
+
+// API 令牌类型
+const (
+    // 个人令牌以创建者身份访问，权限为创建者权限与令牌范围的交集
+    TokenPersonal = "personal"
+    // 服务令牌不属于任何用户，只拥有令牌范围内的权限，只能由管理员创建
+    TokenService = "service"
+
+    // 令牌前缀，便于在日志和代码仓库中识别泄露的令牌
+    APITokenPrefix = "dp_"
+)
+
+var ErrTokenNotFound = errors.New("令牌不存在、已过期或已吊销")
+
+type APIToken struct {
+    ID         int64        `json:"id"`
+    UserID     int64        `json:"user_id,omitempty"`
+    Username   string       `json:"username,omitempty"`
+    Name       string       `json:"name"`
+    Type       string       `json:"type"`
+    Prefix     string       `json:"prefix"` // 令牌开头几位，用于辨认
+    Scopes     []Permission `json:"scopes"`
+    ExpiresAt  string       `json:"expires_at,omitempty"`
+    LastUsedAt string       `json:"last_used_at,omitempty"`
+    LastUsedIP string       `json:"last_used_ip,omitempty"`
+    CreatedAt  string       `json:"created_at"`
+    RevokedAt  string       `json:"revoked_at,omitempty"`
+}
+
+// createTokenTables 创建 API 令牌表，令牌只保存 SHA-256
+func createTokenTables() error {
+    _, err := db.Exec(`
+    CREATE TABLE IF NOT EXISTS api_tokens (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        user_id INTEGER,
+        name TEXT NOT NULL,
+        type TEXT NOT NULL,
+        token_hash TEXT NOT NULL UNIQUE,
+        prefix TEXT NOT NULL,
+        scopes TEXT NOT NULL,
+        expires_at DATETIME,
+        last_used_at DATETIME,
+        last_used_ip TEXT,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        revoked_at DATETIME
+    )`)
+    if err != nil {
+        return fmt.Errorf("创建 api_tokens 表失败: %v", err)
+    }
+    return nil
+}
+
+// CreateAPIToken 创建令牌并返回明文，明文只在创建时返回一次。
+// 服务令牌的 userID 为 0；expiresAt 为零值表示永不过期
+func CreateAPIToken(userID int64, name, tokenType string, scopes []Permission, expiresAt time.Time) (*APIToken, string, error) {
+    name = strings.TrimSpace(name)
+    if name == "" {
+        return nil, "", fmt.Errorf("令牌名称不能为空")
+    }
+    if tokenType != TokenPersonal && tokenType != TokenService {
+        return nil, "", fmt.Errorf("无效的令牌类型: %s", tokenType)
+    }
+    if len(scopes) == 0 {
+        return nil, "", fmt.Errorf("至少需要一个授权范围")
+    }
+    for _, scope := range scopes {
+        if err := scope.Validate(); err != nil {
+            return nil, "", err
+        }
+    }
+    if !expiresAt.IsZero() && expiresAt.Before(time.Now()) {
+        return nil, "", fmt.Errorf("过期时间不能早于当前时间")
+    }
+
+    random, err := newToken()
+    if err != nil {
+        return nil, "", fmt.Errorf("生成令牌失败: %v", err)
+    }
+    token := APITokenPrefix + random
+    scopesJSON, _ := json.Marshal(scopes)
+
+    var owner, expires interface{}
+    if tokenType == TokenPersonal {
+        owner = userID
+    }
+    if !expiresAt.IsZero() {
+        expires = expiresAt.Format("2006-01-02 15:04:05")
+    }
+    now := time.Now().Format("2006-01-02 15:04:05")
+    result, err := db.Exec(`
+        INSERT INTO api_tokens (user_id, name, type, token_hash, prefix, scopes, expires_at, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+        owner, name, tokenType, HashToken(token), token[:len(APITokenPrefix)+6], string(scopesJSON), expires, now)
+    if err != nil {
+        return nil, "", err
+    }
+    id, _ := result.LastInsertId()
+    created, err := GetAPIToken(id)
+    return created, token, err
+}
+
+const tokenColumns = `
+    t.id, COALESCE(t.user_id, 0), COALESCE(u.username, ''), t.name, t.type, t.prefix, t.scopes,
+    COALESCE(t.expires_at, ''), COALESCE(t.last_used_at, ''), COALESCE(t.last_used_ip, ''),
+    t.created_at, COALESCE(t.revoked_at, '')`
+
+func scanAPIToken(row interface{ Scan(...interface{}) error }) (*APIToken, error) {
+    var t APIToken
+    var scopes string
+    err := row.Scan(&t.ID, &t.UserID, &t.Username, &t.Name, &t.Type, &t.Prefix, &scopes,
+        &t.ExpiresAt, &t.LastUsedAt, &t.LastUsedIP, &t.CreatedAt, &t.RevokedAt)
+    if err != nil {
+        if err == sql.ErrNoRows {
+            return nil, ErrTokenNotFound
+        }
+        return nil, err
+    }
+    if err := json.Unmarshal([]byte(scopes), &t.Scopes); err != nil {
+        return nil, fmt.Errorf("解析令牌范围失败: %v", err)
+    }
+    return &t, nil
+}
+
+// GetAPIToken 根据 ID 获取令牌
+func GetAPIToken(id int64) (*APIToken, error) {
+    return scanAPIToken(db.QueryRow(`SELECT `+tokenColumns+`
+        FROM api_tokens t LEFT JOIN users u ON u.id = t.user_id WHERE t.id = ?`, id))
+}
+
+// ListAPITokens 列出令牌，userID 为 0 时列出全部
+func ListAPITokens(userID int64) ([]APIToken, error) {
+    query := `SELECT ` + tokenColumns + ` FROM api_tokens t LEFT JOIN users u ON u.id = t.user_id`
+    var args []interface{}
+    if userID != 0 {
+        query += ` WHERE t.user_id = ?`
+        args = append(args, userID)
+    }
+    rows, err := db.Query(query+` ORDER BY t.id DESC`, args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    tokens := []APIToken{}
+    for rows.Next() {
+        t, err := scanAPIToken(rows)
+        if err != nil {
+            return nil, err
+        }
+        tokens = append(tokens, *t)
+    }
+    return tokens, rows.Err()
+}
+
+// AuthenticateAPIToken 校验明文令牌，返回有效的令牌并记录最后使用时间
+func AuthenticateAPIToken(token, ip string) (*APIToken, error) {
+    now := time.Now().Format("2006-01-02 15:04:05")
+    t, err := scanAPIToken(db.QueryRow(`SELECT `+tokenColumns+`
+        FROM api_tokens t LEFT JOIN users u ON u.id = t.user_id
+        WHERE t.token_hash = ? AND t.revoked_at IS NULL AND (t.expires_at IS NULL OR t.expires_at > ?)`,
+        HashToken(token), now))
+    if err != nil {
+        return nil, err
+    }
+    // 个人令牌的所有者被删除后令牌随之失效
+    if t.Type == TokenPersonal && t.Username == "" {
+        return nil, ErrTokenNotFound
+    }
+
+    db.Exec(`UPDATE api_tokens SET last_used_at = ?, last_used_ip = ? WHERE id = ?`, now, ip, t.ID)
+    t.LastUsedAt = now
+    t.LastUsedIP = ip
+    return t, nil
+}
+
+// RevokeAPIToken 吊销令牌
+func RevokeAPIToken(id int64) error {
+    now := time.Now().Format("2006-01-02 15:04:05")
+    result, err := db.Exec(`UPDATE api_tokens SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`, now, id)
+    if err != nil {
+        return err
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        return ErrTokenNotFound
+    }
+    return nil
+}
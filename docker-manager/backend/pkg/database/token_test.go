@@ -0,0 +1,116 @@
+package database
+
+import (
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestAuthenticateAPIToken(t *testing.T) {
+    openTestDB(t)
+    user, err := CreateUser("ci", "password123", RoleOperator)
+    if err != nil {
+        t.Fatal(err)
+    }
+    scopes := []Permission{{Scope: "project:web", Actions: []string{ActionOperate}}}
+
+    created, token, err := CreateAPIToken(user.ID, "deploy", TokenPersonal, scopes, time.Time{})
+    if err != nil {
+        t.Fatalf("CreateAPIToken 返回错误: %v", err)
+    }
+    if !strings.HasPrefix(token, APITokenPrefix) || !strings.HasPrefix(token, created.Prefix) {
+        t.Errorf("令牌 %s 应以 %s 和前缀 %s 开头", token, APITokenPrefix, created.Prefix)
+    }
+
+    got, err := AuthenticateAPIToken(token, "10.0.0.1")
+    if err != nil {
+        t.Fatalf("AuthenticateAPIToken 返回错误: %v", err)
+    }
+    if got.ID != created.ID || got.Username != "ci" || len(got.Scopes) != 1 || got.Scopes[0].Scope != "project:web" {
+        t.Errorf("令牌 = %+v", got)
+    }
+    if saved, _ := GetAPIToken(created.ID); saved.LastUsedIP != "10.0.0.1" || saved.LastUsedAt == "" {
+        t.Errorf("应记录最后使用时间和地址: %+v", saved)
+    }
+    if _, err := AuthenticateAPIToken(token+"x", ""); err != ErrTokenNotFound {
+        t.Errorf("错误的令牌应返回 ErrTokenNotFound, 得到 %v", err)
+    }
+
+    if err := RevokeAPIToken(created.ID); err != nil {
+        t.Fatalf("RevokeAPIToken 返回错误: %v", err)
+    }
+    if _, err := AuthenticateAPIToken(token, ""); err != ErrTokenNotFound {
+        t.Errorf("吊销后应返回 ErrTokenNotFound, 得到 %v", err)
+    }
+    if err := RevokeAPIToken(created.ID); err != ErrTokenNotFound {
+        t.Errorf("重复吊销应返回 ErrTokenNotFound, 得到 %v", err)
+    }
+}
+
+func TestAuthenticateAPITokenExpired(t *testing.T) {
+    openTestDB(t)
+    scopes := []Permission{{Scope: "*", Actions: []string{ActionRead}}}
+
+    if _, _, err := CreateAPIToken(0, "old", TokenService, scopes, time.Now().Add(-time.Minute)); err == nil {
+        t.Error("过期时间早于当前时间时应返回错误")
+    }
+
+    created, token, err := CreateAPIToken(0, "ci", TokenService, scopes, time.Now().Add(time.Hour))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := AuthenticateAPIToken(token, ""); err != nil {
+        t.Fatalf("未过期的令牌应有效: %v", err)
+    }
+    expired := time.Now().Add(-time.Second).Format("2006-01-02 15:04:05")
+    if _, err := db.Exec(`UPDATE api_tokens SET expires_at = ? WHERE id = ?`, expired, created.ID); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := AuthenticateAPIToken(token, ""); err != ErrTokenNotFound {
+        t.Errorf("过期后应返回 ErrTokenNotFound, 得到 %v", err)
+    }
+}
+
+// 个人令牌随所有者删除而失效
+func TestAuthenticateAPITokenOwnerDeleted(t *testing.T) {
+    openTestDB(t)
+    if _, err := CreateUser("admin", "password123", RoleAdmin); err != nil {
+        t.Fatal(err)
+    }
+    user, err := CreateUser("dev", "password123", RoleViewer)
+    if err != nil {
+        t.Fatal(err)
+    }
+    _, token, err := CreateAPIToken(user.ID, "dev", TokenPersonal, []Permission{{Scope: "*", Actions: []string{ActionRead}}}, time.Time{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := DeleteUser(user.ID); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := AuthenticateAPIToken(token, ""); err != ErrTokenNotFound {
+        t.Errorf("所有者删除后应返回 ErrTokenNotFound, 得到 %v", err)
+    }
+}
+
+func TestCreateAPITokenValidation(t *testing.T) {
+    openTestDB(t)
+    read := []Permission{{Scope: "*", Actions: []string{ActionRead}}}
+    tests := []struct {
+        name      string
+        tokenName string
+        tokenType string
+        scopes    []Permission
+    }{
+        {"名称为空", " ", TokenService, read},
+        {"类型无效", "ci", "robot", read},
+        {"没有范围", "ci", TokenService, nil},
+        {"范围无效", "ci", TokenService, []Permission{{Scope: "host:1", Actions: []string{ActionRead}}}},
+        {"操作无效", "ci", TokenService, []Permission{{Scope: "*", Actions: []string{"delete"}}}},
+    }
+    for _, tt := range tests {
+        if _, _, err := CreateAPIToken(0, tt.tokenName, tt.tokenType, tt.scopes, time.Time{}); err == nil {
+            t.Errorf("%s: 应返回错误", tt.name)
+        }
+    }
+}
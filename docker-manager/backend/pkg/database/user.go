@@ -0,0 +1,390 @@
+package database
+
+import (
+    "crypto/rand"
+    "crypto/sha256"
+    "database/sql"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "strings"
+    "sync"
+    "time"
+
+    "golang.org/x/crypto/bcrypt"
+)
+
+var (
+    ErrUserNotFound       = errors.New("用户不存在")
+    ErrUserExists         = errors.New("用户名已存在")
+    ErrInvalidPassword    = errors.New("用户名或密码错误")
+    ErrSessionNotFound    = errors.New("会话不存在或已过期")
+    ErrAlreadyInitialized = errors.New("管理员账号已创建")
+    ErrLastAdmin          = errors.New("至少需要保留一个管理员")
+)
+
+// 密码最短长度
+const MinPasswordLength = 8
+
+type User struct {
+    ID          int64  `json:"id"`
+    Username    string `json:"username"`
+    Role        string `json:"role"`
+    CreatedAt   string `json:"created_at"`
+    LastLoginAt string `json:"last_login_at,omitempty"`
+}
+
+// createUserTables 创建用户与会话表
+func createUserTables() error {
+    _, err := db.Exec(`
+    CREATE TABLE IF NOT EXISTS users (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        username TEXT NOT NULL UNIQUE,
+        password_hash TEXT NOT NULL,
+        role TEXT NOT NULL DEFAULT 'admin',
+        last_login_at DATETIME,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+    )`)
+    if err != nil {
+        return fmt.Errorf("创建 users 表失败: %v", err)
+    }
+
+    // 会话表只保存令牌的 SHA-256，数据库泄露时无法直接冒用会话
+    _, err = db.Exec(`
+    CREATE TABLE IF NOT EXISTS sessions (
+        token_hash TEXT PRIMARY KEY,
+        user_id INTEGER NOT NULL,
+        ip TEXT,
+        user_agent TEXT,
+        expires_at INTEGER NOT NULL,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        FOREIGN KEY (user_id) REFERENCES users(id)
+    )`)
+    if err != nil {
+        return fmt.Errorf("创建 sessions 表失败: %v", err)
+    }
+    return nil
+}
+
+// CountUsers 返回用户数量，为 0 时需要先完成初始化
+func CountUsers() (int, error) {
+    var count int
+    err := db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count)
+    return count, err
+}
+
+// ValidatePassword 校验密码强度
+func ValidatePassword(password string) error {
+    if len(password) < MinPasswordLength {
+        return fmt.Errorf("密码长度不能少于 %d 位", MinPasswordLength)
+    }
+    // bcrypt 只使用前 72 字节
+    if len(password) > 72 {
+        return fmt.Errorf("密码长度不能超过 72 字节")
+    }
+    return nil
+}
+
+// CreateUser 创建用户，密码使用 bcrypt 哈希保存
+func CreateUser(username, password, role string) (*User, error) {
+    username = strings.TrimSpace(username)
+    if username == "" {
+        return nil, fmt.Errorf("用户名不能为空")
+    }
+    if !ValidRole(role) {
+        return nil, fmt.Errorf("无效的角色: %s", role)
+    }
+    if err := ValidatePassword(password); err != nil {
+        return nil, err
+    }
+
+    hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+    if err != nil {
+        return nil, fmt.Errorf("生成密码哈希失败: %v", err)
+    }
+
+    result, err := db.Exec(`INSERT INTO users (username, password_hash, role) VALUES (?, ?, ?)`,
+        username, string(hash), role)
+    if err != nil {
+        if strings.Contains(err.Error(), "UNIQUE") {
+            return nil, ErrUserExists
+        }
+        return nil, err
+    }
+    id, _ := result.LastInsertId()
+    return GetUserByID(id)
+}
+
+// CreateInitialAdmin 首次运行时创建管理员，已有用户时返回 ErrAlreadyInitialized。
+// 在事务中检查并插入，避免并发请求创建出多个管理员
+func CreateInitialAdmin(username, password string) (*User, error) {
+    username = strings.TrimSpace(username)
+    if username == "" {
+        return nil, fmt.Errorf("用户名不能为空")
+    }
+    if err := ValidatePassword(password); err != nil {
+        return nil, err
+    }
+    hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+    if err != nil {
+        return nil, fmt.Errorf("生成密码哈希失败: %v", err)
+    }
+
+    tx, err := db.Begin()
+    if err != nil {
+        return nil, err
+    }
+    defer tx.Rollback()
+
+    var count int
+    if err := tx.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count); err != nil {
+        return nil, err
+    }
+    if count > 0 {
+        return nil, ErrAlreadyInitialized
+    }
+    result, err := tx.Exec(`INSERT INTO users (username, password_hash, role) VALUES (?, ?, ?)`,
+        username, string(hash), RoleAdmin)
+    if err != nil {
+        return nil, err
+    }
+    if err := tx.Commit(); err != nil {
+        return nil, err
+    }
+    id, _ := result.LastInsertId()
+    return GetUserByID(id)
+}
+
+func scanUser(row interface{ Scan(...interface{}) error }) (*User, error) {
+    var user User
+    var lastLogin sql.NullString
+    if err := row.Scan(&user.ID, &user.Username, &user.Role, &user.CreatedAt, &lastLogin); err != nil {
+        if err == sql.ErrNoRows {
+            return nil, ErrUserNotFound
+        }
+        return nil, err
+    }
+    user.LastLoginAt = lastLogin.String
+    return &user, nil
+}
+
+// GetUserByID 根据 ID 获取用户
+func GetUserByID(id int64) (*User, error) {
+    return scanUser(db.QueryRow(`SELECT id, username, role, created_at, last_login_at FROM users WHERE id = ?`, id))
+}
+
+var (
+    dummyHash     []byte
+    dummyHashOnce sync.Once
+)
+
+// ListUsers 获取所有用户
+func ListUsers() ([]User, error) {
+    rows, err := db.Query(`SELECT id, username, role, created_at, last_login_at FROM users ORDER BY id`)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    users := []User{}
+    for rows.Next() {
+        user, err := scanUser(rows)
+        if err != nil {
+            return nil, err
+        }
+        users = append(users, *user)
+    }
+    return users, rows.Err()
+}
+
+// 统计管理员数量，修改角色或删除用户时保证至少保留一个管理员
+func countAdmins() (int, error) {
+    var count int
+    err := db.QueryRow(`SELECT COUNT(*) FROM users WHERE role = ?`, RoleAdmin).Scan(&count)
+    return count, err
+}
+
+// UpdateUserRole 修改用户角色
+func UpdateUserRole(id int64, role string) error {
+    if !ValidRole(role) {
+        return fmt.Errorf("无效的角色: %s", role)
+    }
+    user, err := GetUserByID(id)
+    if err != nil {
+        return err
+    }
+    if user.Role == RoleAdmin && role != RoleAdmin {
+        count, err := countAdmins()
+        if err != nil {
+            return err
+        }
+        if count <= 1 {
+            return ErrLastAdmin
+        }
+    }
+    now := time.Now().Format("2006-01-02 15:04:05")
+    _, err = db.Exec(`UPDATE users SET role = ?, updated_at = ? WHERE id = ?`, role, now, id)
+    return err
+}
+
+// ResetPassword 管理员重置用户密码，该用户的所有会话都会失效
+func ResetPassword(id int64, password string) error {
+    if err := ValidatePassword(password); err != nil {
+        return err
+    }
+    hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+    if err != nil {
+        return fmt.Errorf("生成密码哈希失败: %v", err)
+    }
+    now := time.Now().Format("2006-01-02 15:04:05")
+    result, err := db.Exec(`UPDATE users SET password_hash = ?, updated_at = ? WHERE id = ?`, string(hash), now, id)
+    if err != nil {
+        return err
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        return ErrUserNotFound
+    }
+    _, err = db.Exec(`DELETE FROM sessions WHERE user_id = ?`, id)
+    return err
+}
+
+// DeleteUser 删除用户及其会话和授权，并吊销其个人令牌
+func DeleteUser(id int64) error {
+    user, err := GetUserByID(id)
+    if err != nil {
+        return err
+    }
+    if user.Role == RoleAdmin {
+        count, err := countAdmins()
+        if err != nil {
+            return err
+        }
+        if count <= 1 {
+            return ErrLastAdmin
+        }
+    }
+
+    tx, err := db.Begin()
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+    for _, query := range []string{
+        `DELETE FROM sessions WHERE user_id = ?`,
+        `DELETE FROM user_permissions WHERE user_id = ?`,
+        `UPDATE api_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = ? AND revoked_at IS NULL`,
+        `DELETE FROM users WHERE id = ?`,
+    } {
+        if _, err := tx.Exec(query, id); err != nil {
+            return err
+        }
+    }
+    return tx.Commit()
+}
+
+// Authenticate 校验用户名和密码，成功时更新最后登录时间
+func Authenticate(username, password string) (*User, error) {
+    var id int64
+    var hash string
+    err := db.QueryRow(`SELECT id, password_hash FROM users WHERE username = ?`, strings.TrimSpace(username)).Scan(&id, &hash)
+    if err != nil {
+        if err == sql.ErrNoRows {
+            // 用户不存在时同样执行一次哈希比较，避免通过响应时间枚举用户名
+            dummyHashOnce.Do(func() {
+                dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dockerpanel"), bcrypt.DefaultCost)
+            })
+            bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+            return nil, ErrInvalidPassword
+        }
+        return nil, err
+    }
+    if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+        return nil, ErrInvalidPassword
+    }
+
+    now := time.Now().Format("2006-01-02 15:04:05")
+    db.Exec(`UPDATE users SET last_login_at = ? WHERE id = ?`, now, id)
+    return GetUserByID(id)
+}
+
+// ChangePassword 校验旧密码后修改密码，并注销该用户的其他会话
+func ChangePassword(userID int64, oldPassword, newPassword, keepTokenHash string) error {
+    var hash string
+    if err := db.QueryRow(`SELECT password_hash FROM users WHERE id = ?`, userID).Scan(&hash); err != nil {
+        if err == sql.ErrNoRows {
+            return ErrUserNotFound
+        }
+        return err
+    }
+    if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(oldPassword)); err != nil {
+        return fmt.Errorf("原密码错误")
+    }
+    if err := ValidatePassword(newPassword); err != nil {
+        return err
+    }
+
+    newHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+    if err != nil {
+        return fmt.Errorf("生成密码哈希失败: %v", err)
+    }
+    now := time.Now().Format("2006-01-02 15:04:05")
+    if _, err := db.Exec(`UPDATE users SET password_hash = ?, updated_at = ? WHERE id = ?`, string(newHash), now, userID); err != nil {
+        return err
+    }
+    _, err = db.Exec(`DELETE FROM sessions WHERE user_id = ? AND token_hash != ?`, userID, keepTokenHash)
+    return err
+}
+
+// HashToken 计算令牌的 SHA-256，会话表与后续的 API 令牌都只保存哈希
+func HashToken(token string) string {
+    sum := sha256.Sum256([]byte(token))
+    return hex.EncodeToString(sum[:])
+}
+
+// 生成 32 字节随机令牌
+func newToken() (string, error) {
+    buf := make([]byte, 32)
+    if _, err := rand.Read(buf); err != nil {
+        return "", err
+    }
+    return hex.EncodeToString(buf), nil
+}
+
+// CreateSession 创建会话并返回明文令牌，令牌只在此时返回一次
+func CreateSession(userID int64, ttl time.Duration, ip, userAgent string) (string, time.Time, error) {
+    token, err := newToken()
+    if err != nil {
+        return "", time.Time{}, fmt.Errorf("生成会话令牌失败: %v", err)
+    }
+    expiresAt := time.Now().Add(ttl)
+    _, err = db.Exec(`INSERT INTO sessions (token_hash, user_id, ip, user_agent, expires_at) VALUES (?, ?, ?, ?, ?)`,
+        HashToken(token), userID, ip, userAgent, expiresAt.Unix())
+    if err != nil {
+        return "", time.Time{}, err
+    }
+
+    // 顺便清理过期会话
+    db.Exec(`DELETE FROM sessions WHERE expires_at < ?`, time.Now().Unix())
+    return token, expiresAt, nil
+}
+
+// GetSessionUser 根据会话令牌获取用户
+func GetSessionUser(token string) (*User, error) {
+    row := db.QueryRow(`
+        SELECT u.id, u.username, u.role, u.created_at, u.last_login_at
+        FROM sessions s JOIN users u ON u.id = s.user_id
+        WHERE s.token_hash = ? AND s.expires_at > ?`,
+        HashToken(token), time.Now().Unix())
+    user, err := scanUser(row)
+    if err == ErrUserNotFound {
+        return nil, ErrSessionNotFound
+    }
+    return user, err
+}
+
+// DeleteSession 注销会话
+func DeleteSession(token string) error {
+    _, err := db.Exec(`DELETE FROM sessions WHERE token_hash = ?`, HashToken(token))
+    return err
+}
@@ -0,0 +1,146 @@
+package database
+
+import (
+    "testing"
+    "time"
+)
+
+func TestCreateInitialAdminOnce(t *testing.T) {
+    openTestDB(t)
+    if _, err := CreateInitialAdmin("admin", "short"); err == nil {
+        t.Error("密码过短时应返回错误")
+    }
+    admin, err := CreateInitialAdmin(" admin ", "password123")
+    if err != nil {
+        t.Fatalf("CreateInitialAdmin 返回错误: %v", err)
+    }
+    if admin.Username != "admin" || admin.Role != RoleAdmin {
+        t.Errorf("管理员 = %+v", admin)
+    }
+    if _, err := CreateInitialAdmin("other", "password123"); err != ErrAlreadyInitialized {
+        t.Errorf("已有用户时应返回 ErrAlreadyInitialized, 得到 %v", err)
+    }
+}
+
+func TestAuthenticate(t *testing.T) {
+    openTestDB(t)
+    if _, err := CreateUser("alice", "password123", RoleViewer); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := CreateUser("alice", "password123", RoleViewer); err != ErrUserExists {
+        t.Errorf("重复的用户名应返回 ErrUserExists, 得到 %v", err)
+    }
+
+    tests := []struct {
+        username string
+        password string
+        err      error
+    }{
+        {"alice", "password123", nil},
+        {" alice ", "password123", nil},
+        {"alice", "wrong-password", ErrInvalidPassword},
+        {"bob", "password123", ErrInvalidPassword},
+    }
+    for _, tt := range tests {
+        user, err := Authenticate(tt.username, tt.password)
+        if err != tt.err {
+            t.Errorf("Authenticate(%q, %q) 错误 = %v, 期望 %v", tt.username, tt.password, err, tt.err)
+        }
+        if err == nil && (user.Username != "alice" || user.LastLoginAt == "") {
+            t.Errorf("登录成功应返回用户并记录登录时间: %+v", user)
+        }
+    }
+}
+
+func TestSessionExpiry(t *testing.T) {
+    openTestDB(t)
+    user, err := CreateUser("alice", "password123", RoleViewer)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    token, expiresAt, err := CreateSession(user.ID, time.Hour, "10.0.0.1", "test")
+    if err != nil {
+        t.Fatalf("CreateSession 返回错误: %v", err)
+    }
+    if time.Until(expiresAt) <= 0 {
+        t.Errorf("过期时间 %v 应在当前时间之后", expiresAt)
+    }
+    if got, err := GetSessionUser(token); err != nil || got.ID != user.ID {
+        t.Fatalf("GetSessionUser = %+v, %v", got, err)
+    }
+
+    expired, _, err := CreateSession(user.ID, -time.Second, "", "")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := GetSessionUser(expired); err != ErrSessionNotFound {
+        t.Errorf("过期会话应返回 ErrSessionNotFound, 得到 %v", err)
+    }
+
+    if err := DeleteSession(token); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := GetSessionUser(token); err != ErrSessionNotFound {
+        t.Errorf("注销后应返回 ErrSessionNotFound, 得到 %v", err)
+    }
+}
+
+// 修改密码保留当前会话，注销其他会话；重置密码注销全部会话
+func TestPasswordChangeRevokesSessions(t *testing.T) {
+    openTestDB(t)
+    user, err := CreateUser("alice", "password123", RoleViewer)
+    if err != nil {
+        t.Fatal(err)
+    }
+    current, _, _ := CreateSession(user.ID, time.Hour, "", "")
+    other, _, _ := CreateSession(user.ID, time.Hour, "", "")
+
+    if err := ChangePassword(user.ID, "wrong-password", "newpassword1", HashToken(current)); err == nil {
+        t.Error("原密码错误时应返回错误")
+    }
+    if err := ChangePassword(user.ID, "password123", "newpassword1", HashToken(current)); err != nil {
+        t.Fatalf("ChangePassword 返回错误: %v", err)
+    }
+    if _, err := GetSessionUser(current); err != nil {
+        t.Errorf("当前会话应保留: %v", err)
+    }
+    if _, err := GetSessionUser(other); err != ErrSessionNotFound {
+        t.Errorf("其他会话应注销, 得到 %v", err)
+    }
+    if _, err := Authenticate("alice", "newpassword1"); err != nil {
+        t.Errorf("应能用新密码登录: %v", err)
+    }
+
+    if err := ResetPassword(user.ID, "resetpassword"); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := GetSessionUser(current); err != ErrSessionNotFound {
+        t.Errorf("重置密码后所有会话应注销, 得到 %v", err)
+    }
+}
+
+func TestLastAdminProtected(t *testing.T) {
+    openTestDB(t)
+    admin, err := CreateUser("admin", "password123", RoleAdmin)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := UpdateUserRole(admin.ID, RoleViewer); err != ErrLastAdmin {
+        t.Errorf("降级唯一的管理员应返回 ErrLastAdmin, 得到 %v", err)
+    }
+    if err := DeleteUser(admin.ID); err != ErrLastAdmin {
+        t.Errorf("删除唯一的管理员应返回 ErrLastAdmin, 得到 %v", err)
+    }
+
+    second, err := CreateUser("admin2", "password123", RoleAdmin)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := DeleteUser(admin.ID); err != nil {
+        t.Errorf("还有其他管理员时应允许删除: %v", err)
+    }
+    if err := UpdateUserRole(second.ID, RoleOperator); err != ErrLastAdmin {
+        t.Errorf("降级剩下的管理员应返回 ErrLastAdmin, 得到 %v", err)
+    }
+}
@@ -1,5 +1,7 @@
 package database
 
+import "database/sql"
+
 // boolToInt converts a boolean value to an integer (1 for true, 0 for false)
 func boolToInt(b bool) int {
     if b {
@@ -7,3 +9,16 @@ func boolToInt(b bool) int {
     }
     return 0
 }
+
+// withTx 在事务中执行 fn，fn 返回错误时回滚
+func withTx(fn func(tx *sql.Tx) error) error {
+    tx, err := db.Begin()
+    if err != nil {
+        return err
+    }
+    if err := fn(tx); err != nil {
+        tx.Rollback()
+        return err
+    }
+    return tx.Commit()
+}
@@ -0,0 +1,143 @@
+package docker
+
+import (
+    "bufio"
+    "bytes"
+    "compress/gzip"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "strings"
+    "sync/atomic"
+
+    "github.com/docker/docker/pkg/jsonmessage"
+    "github.com/klauspost/compress/zstd"
+)
+
+var (
+    gzipMagic = []byte{0x1f, 0x8b}
+    zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// DecompressStream 根据文件头自动识别 gzip / zstd 压缩，返回解压后的 tar 流及识别到的格式
+func DecompressStream(r io.Reader) (io.ReadCloser, string, error) {
+    br := bufio.NewReader(r)
+    header, err := br.Peek(4)
+    if err != nil && err != io.EOF {
+        return nil, "", fmt.Errorf("读取归档头失败: %v", err)
+    }
+
+    switch {
+    case bytes.HasPrefix(header, gzipMagic):
+        gz, err := gzip.NewReader(br)
+        if err != nil {
+            return nil, "", fmt.Errorf("打开 gzip 归档失败: %v", err)
+        }
+        return gz, "gzip", nil
+    case bytes.HasPrefix(header, zstdMagic):
+        zr, err := zstd.NewReader(br)
+        if err != nil {
+            return nil, "", fmt.Errorf("打开 zstd 归档失败: %v", err)
+        }
+        return zr.IOReadCloser(), "zstd", nil
+    }
+    return io.NopCloser(br), "tar", nil
+}
+
+// CountingReader 统计已读取的字节数，可在其他 goroutine 中读取计数
+type CountingReader struct {
+    r io.Reader
+    n int64
+}
+
+func NewCountingReader(r io.Reader) *CountingReader {
+    return &CountingReader{r: r}
+}
+
+func (c *CountingReader) Read(p []byte) (int, error) {
+    n, err := c.r.Read(p)
+    atomic.AddInt64(&c.n, int64(n))
+    return n, err
+}
+
+// BytesRead 返回已读取的字节数
+func (c *CountingReader) BytesRead() int64 {
+    return atomic.LoadInt64(&c.n)
+}
+
+// LoadedImage 导入后得到的镜像
+type LoadedImage struct {
+    ID   string   `json:"id"`
+    Tags []string `json:"tags"`
+}
+
+// LoadImages 将 tar 流导入 Docker，解析返回的 "Loaded image" 消息并按镜像 ID 汇总标签。
+// onMessage 用于接收守护进程的逐条输出，可以为 nil。
+func (c *Client) LoadImages(ctx context.Context, input io.Reader, onMessage func(jsonmessage.JSONMessage)) ([]LoadedImage, error) {
+    response, err := c.ImageLoad(ctx, input, false)
+    if err != nil {
+        return nil, err
+    }
+    defer response.Body.Close()
+
+    var tags, ids []string
+    decoder := json.NewDecoder(response.Body)
+    for {
+        var msg jsonmessage.JSONMessage
+        if err := decoder.Decode(&msg); err != nil {
+            if err == io.EOF {
+                break
+            }
+            return nil, fmt.Errorf("读取导入结果失败: %v", err)
+        }
+        if msg.Error != nil {
+            return nil, msg.Error
+        }
+        if msg.ErrorMessage != "" {
+            return nil, fmt.Errorf("%s", msg.ErrorMessage)
+        }
+        if onMessage != nil {
+            onMessage(msg)
+        }
+
+        line := strings.TrimSpace(msg.Stream)
+        switch {
+        case strings.HasPrefix(line, "Loaded image ID:"):
+            ids = append(ids, strings.TrimSpace(strings.TrimPrefix(line, "Loaded image ID:")))
+        case strings.HasPrefix(line, "Loaded image:"):
+            tags = append(tags, strings.TrimSpace(strings.TrimPrefix(line, "Loaded image:")))
+        }
+    }
+
+    // 带标签的镜像只返回标签，需要查询对应的镜像 ID
+    byID := make(map[string]*LoadedImage)
+    var result []LoadedImage
+    var order []string
+    add := func(id, tag string) {
+        image, ok := byID[id]
+        if !ok {
+            image = &LoadedImage{ID: id, Tags: []string{}}
+            byID[id] = image
+            order = append(order, id)
+        }
+        if tag != "" {
+            image.Tags = append(image.Tags, tag)
+        }
+    }
+    for _, tag := range tags {
+        inspect, _, err := c.ImageInspectWithRaw(ctx, tag)
+        if err != nil {
+            add(tag, tag)
+            continue
+        }
+        add(inspect.ID, tag)
+    }
+    for _, id := range ids {
+        add(id, "")
+    }
+    for _, id := range order {
+        result = append(result, *byID[id])
+    }
+    return result, nil
+}
@@ -0,0 +1,69 @@
+package docker
+
+import (
+    "bytes"
+    "compress/gzip"
+    "io"
+    "testing"
+
+    "github.com/klauspost/compress/zstd"
+)
+
+func TestDecompressStream(t *testing.T) {
+    payload := bytes.Repeat([]byte("layer.tar"), 1000)
+
+    var gz bytes.Buffer
+    gw := gzip.NewWriter(&gz)
+    gw.Write(payload)
+    gw.Close()
+
+    var zs bytes.Buffer
+    zw, err := zstd.NewWriter(&zs)
+    if err != nil {
+        t.Fatal(err)
+    }
+    zw.Write(payload)
+    zw.Close()
+
+    tests := []struct {
+        name   string
+        input  []byte
+        format string
+        want   []byte
+    }{
+        {"tar", payload, "tar", payload},
+        {"gzip", gz.Bytes(), "gzip", payload},
+        {"zstd", zs.Bytes(), "zstd", payload},
+        {"短于文件头", []byte("ab"), "tar", []byte("ab")},
+        {"空输入", nil, "tar", nil},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            counter := NewCountingReader(bytes.NewReader(tt.input))
+            r, format, err := DecompressStream(counter)
+            if err != nil {
+                t.Fatalf("DecompressStream 返回错误: %v", err)
+            }
+            defer r.Close()
+            if format != tt.format {
+                t.Errorf("格式 = %s, 期望 %s", format, tt.format)
+            }
+            got, err := io.ReadAll(r)
+            if err != nil {
+                t.Fatalf("读取解压内容失败: %v", err)
+            }
+            if !bytes.Equal(got, tt.want) {
+                t.Errorf("解压后内容长度 %d, 期望 %d", len(got), len(tt.want))
+            }
+            if counter.BytesRead() != int64(len(tt.input)) {
+                t.Errorf("BytesRead = %d, 期望 %d", counter.BytesRead(), len(tt.input))
+            }
+        })
+    }
+}
+
+func TestDecompressStreamCorruptGzip(t *testing.T) {
+    if _, _, err := DecompressStream(bytes.NewReader([]byte{0x1f, 0x8b, 0x00, 0x00})); err == nil {
+        t.Error("损坏的 gzip 文件头应返回错误")
+    }
+}
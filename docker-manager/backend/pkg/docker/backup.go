@@ -0,0 +1,82 @@
+package docker
+
+import (
+    "compress/gzip"
+    "context"
+    "fmt"
+    "io"
+
+    "github.com/docker/docker/api/types"
+    "github.com/docker/docker/api/types/container"
+    "github.com/docker/docker/api/types/mount"
+    "github.com/docker/docker/client"
+)
+
+// 备份卷时挂载卷的辅助镜像，辅助容器只创建不启动，镜像只需要能创建容器
+const VolumeBackupImage = "busybox:latest"
+
+// BackupVolume 将卷的内容打包为 tar.gz 写入 w，归档内的文件位于 volume/ 目录下。
+// 通过只读挂载该卷的辅助容器读取，远程主机上的卷同样会备份到调用方所在的机器；
+// 卷正在被容器写入时备份内容可能不一致
+func (c *Client) BackupVolume(ctx context.Context, name string, w io.Writer) (int64, error) {
+    if _, err := c.VolumeInspect(ctx, name); err != nil {
+        return 0, fmt.Errorf("卷 %s 不存在: %v", name, err)
+    }
+    if err := c.ensureImage(ctx, VolumeBackupImage); err != nil {
+        return 0, err
+    }
+
+    resp, err := c.ContainerCreate(ctx, &container.Config{
+        Image:           VolumeBackupImage,
+        NetworkDisabled: true,
+        Labels:          map[string]string{"dockerpanel.volume-backup": name},
+    }, &container.HostConfig{
+        Mounts: []mount.Mount{{
+            Type:     mount.TypeVolume,
+            Source:   name,
+            Target:   "/volume",
+            ReadOnly: true,
+        }},
+    }, nil, nil, "")
+    if err != nil {
+        return 0, fmt.Errorf("创建备份容器失败: %v", err)
+    }
+    // 备份超时取消后仍要删除辅助容器
+    defer c.ContainerRemove(context.Background(), resp.ID, types.ContainerRemoveOptions{Force: true})
+
+    reader, _, err := c.CopyFromContainer(ctx, resp.ID, "/volume")
+    if err != nil {
+        return 0, fmt.Errorf("读取卷内容失败: %v", err)
+    }
+    defer reader.Close()
+
+    counter := NewCountingReader(reader)
+    gz := gzip.NewWriter(w)
+    if _, err := io.Copy(gz, counter); err != nil {
+        return 0, fmt.Errorf("写入备份失败: %v", err)
+    }
+    if err := gz.Close(); err != nil {
+        return 0, fmt.Errorf("写入备份失败: %v", err)
+    }
+    return counter.BytesRead(), nil
+}
+
+// 本地没有镜像时从仓库拉取
+func (c *Client) ensureImage(ctx context.Context, ref string) error {
+    _, _, err := c.ImageInspectWithRaw(ctx, ref)
+    if err == nil {
+        return nil
+    }
+    if !client.IsErrNotFound(err) {
+        return fmt.Errorf("获取镜像 %s 信息失败: %v", ref, err)
+    }
+    reader, err := c.ImagePull(ctx, ref, types.ImagePullOptions{})
+    if err != nil {
+        return fmt.Errorf("拉取镜像 %s 失败: %v", ref, err)
+    }
+    defer reader.Close()
+    if err := ConsumeProgress(reader, NewProgressAggregator(ref), func(Progress) {}); err != nil {
+        return fmt.Errorf("拉取镜像 %s 失败: %v", ref, err)
+    }
+    return nil
+}
@@ -19,6 +19,9 @@ import (
 // 新增Client结构体封装Docker客户端
 type Client struct {
     *client.Client
+
+    shared bool          // 由 SharedClient 管理的共享客户端，Close 不会关闭连接
+    cache  *inspectCache // 容器详情缓存，只有共享客户端有
 }
 
 type ComposeConfig struct {
@@ -36,6 +39,7 @@ type ServiceConfig struct {
     Restart       string
     Networks      []string
     ContainerName string `yaml:"container_name"`
+    Logging       *LogConfig `yaml:"logging"`
 }
 
 func (c *Client) DeployCompose(ctx context.Context, composePath string) error {
@@ -107,6 +111,12 @@ func (c *Client) deployService(ctx context.Context, name string, service Service
         Binds:        service.Volumes,
         PortBindings: parsePorts(service.Ports),
     }
+    if service.Logging != nil {
+        if err := service.Logging.Validate(); err != nil {
+            return fmt.Errorf("服务%s日志配置无效: %w", name, err)
+        }
+        hostConfig.LogConfig = service.Logging.HostLogConfig()
+    }
 
     networkingConfig := &network.NetworkingConfig{
         EndpointsConfig: make(map[string]*network.EndpointSettings),
@@ -166,10 +176,13 @@ func NewDockerClient() (*Client, error) {
     if err != nil {
         return nil, err
     }
-    return &Client{cli}, nil
+    return &Client{Client: cli}, nil
 }
 
-// 关闭Client
+// 关闭Client，共享客户端由面板统一管理，这里不做处理
 func (cli *Client) Close() error {
+    if cli.shared {
+        return nil
+    }
     return cli.Client.Close()
 }
\ No newline at end of file
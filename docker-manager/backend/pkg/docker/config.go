@@ -14,6 +14,8 @@ type DaemonConfig struct {
     RegistryMirrors []string     `json:"registry-mirrors,omitempty"`
     Proxies        *ProxyConfig  `json:"proxies,omitempty"`
     Registries     map[string]Registry  `json:"registries,omitempty"`
+    LogDriver      string               `json:"log-driver,omitempty"`
+    LogOpts        map[string]string    `json:"log-opts,omitempty"`
 }
 
 // ProxyConfig 定义代理配置结构
@@ -23,63 +25,89 @@ type ProxyConfig struct {
     NoProxy    string `json:"no-proxy,omitempty"`
 }
 
-// UpdateDaemonConfig 更新 Docker daemon.json 配置
+// UpdateDaemonConfig 更新 Docker daemon.json 中的镜像加速、代理与默认日志配置，
+// 其他配置项原样保留，写入前自动备份
 func UpdateDaemonConfig(config *DaemonConfig) error {
-    configPath, err := GetDaemonConfigPath()
+    existing, _, err := ReadDaemonConfigRaw()
     if err != nil {
-        return fmt.Errorf("获取配置路径失败: %v", err)
+        return err
     }
-    
-    // 读取现有配置
-    existingConfig, err := GetDaemonConfig()
-    if err != nil {
-        existingConfig = &DaemonConfig{}
+
+    // 日志配置：LogOpts 不为 nil 表示提交了完整的日志设置，
+    // 此时空驱动和空 map 分别删除 log-driver / log-opts，恢复 Docker 默认值
+    if config.LogDriver != "" || config.LogOpts != nil {
+        driver := config.LogDriver
+        if driver == "" && config.LogOpts == nil {
+            driver, _ = existing["log-driver"].(string)
+        }
+        if err := (LogConfig{Driver: driver, Options: config.LogOpts}).Validate(); err != nil {
+            return err
+        }
+    }
+    if config.LogDriver != "" {
+        existing["log-driver"] = config.LogDriver
+    } else if config.LogOpts != nil {
+        delete(existing, "log-driver")
+    }
+    if config.LogOpts != nil {
+        if len(config.LogOpts) == 0 {
+            delete(existing, "log-opts")
+        } else {
+            opts := make(map[string]interface{}, len(config.LogOpts))
+            for k, v := range config.LogOpts {
+                opts[k] = v
+            }
+            existing["log-opts"] = opts
+        }
     }
 
     // 合并配置
     if config.RegistryMirrors != nil {
-        existingConfig.RegistryMirrors = config.RegistryMirrors
+        mirrors := make([]interface{}, 0, len(config.RegistryMirrors))
+        for _, mirror := range config.RegistryMirrors {
+            mirrors = append(mirrors, mirror)
+        }
+        existing["registry-mirrors"] = mirrors
     }
 
     // 处理代理配置
     if config.Proxies != nil {
-        if existingConfig.Proxies == nil {
-            existingConfig.Proxies = &ProxyConfig{}
+        proxies, _ := existing["proxies"].(map[string]interface{})
+        if proxies == nil {
+            proxies = map[string]interface{}{}
         }
         if config.Proxies.HTTPProxy != "" {
-            existingConfig.Proxies.HTTPProxy = config.Proxies.HTTPProxy
+            proxies["http-proxy"] = config.Proxies.HTTPProxy
         }
         if config.Proxies.HTTPSProxy != "" {
-            existingConfig.Proxies.HTTPSProxy = config.Proxies.HTTPSProxy
+            proxies["https-proxy"] = config.Proxies.HTTPSProxy
         }
         if config.Proxies.NoProxy != "" {
-            existingConfig.Proxies.NoProxy = config.Proxies.NoProxy
+            proxies["no-proxy"] = config.Proxies.NoProxy
         }
+        existing["proxies"] = proxies
     }
 
-    // 格式化 JSON 数据
-    data, err := json.MarshalIndent(existingConfig, "", "    ")
-    if err != nil {
+    if _, err := WriteDaemonConfig(existing); err != nil {
         return err
     }
-
-    // 写入文件
-    if err := ioutil.WriteFile(configPath, data, 0644); err != nil {
-        return fmt.Errorf("写入配置文件失败: %v", err)
-    }
-
     return nil
 }
 
 type Registry struct {
-    Name     string `json:"name"`
-    URL      string `json:"url"`
-    Username string `json:"username,omitempty"`
-    Password string `json:"password,omitempty"`
+    Name        string `json:"name"`
+    URL         string `json:"url"`
+    Username    string `json:"username,omitempty"`
+    Password    string `json:"password,omitempty"` // 只写字段，不在接口响应中返回
+    HasPassword bool   `json:"has_password,omitempty"`
 }
 
 // GetDaemonConfigPath 获取 daemon.json 文件路径
 func GetDaemonConfigPath() (string, error) {
+    if configPath := os.Getenv(daemonConfigPathEnv); configPath != "" {
+        return configPath, nil
+    }
+
     var configPath string
 
     switch runtime.GOOS {
@@ -0,0 +1,695 @@
+package docker
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "log"
+    "net"
+    "net/url"
+    "os"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/pmezard/go-difflib/difflib"
+)
+
+const (
+    // daemon.json 路径环境变量，便于测试或非标准安装时指向其他位置
+    daemonConfigPathEnv = "DOCKERPANEL_DAEMON_CONFIG"
+
+    // 备份文件名格式为 daemon.json.<时间戳>.bak
+    daemonBackupTimeFormat = "20060102-150405"
+    // 最多保留的备份数量
+    maxDaemonBackups = 20
+)
+
+// DaemonOptionType daemon.json 选项的值类型
+type DaemonOptionType string
+
+const (
+    OptionString     DaemonOptionType = "string"
+    OptionBool       DaemonOptionType = "bool"
+    OptionInt        DaemonOptionType = "int"
+    OptionStringList DaemonOptionType = "string[]"
+    OptionStringMap  DaemonOptionType = "map[string]string"
+    OptionObject     DaemonOptionType = "object"
+    OptionList       DaemonOptionType = "object[]"
+)
+
+// DaemonOption daemon.json 中一个选项的定义
+type DaemonOption struct {
+    Type        DaemonOptionType `json:"type"`
+    Description string           `json:"description"`
+    Enum        []string         `json:"enum,omitempty"`
+    validate    func(value interface{}) error
+}
+
+// DaemonOptions 常用的 dockerd 配置项，参考 dockerd --help 与官方 daemon.json 文档
+var DaemonOptions = map[string]DaemonOption{
+    "registry-mirrors":           {Type: OptionStringList, Description: "镜像加速地址", validate: eachString(validateURL)},
+    "insecure-registries":        {Type: OptionStringList, Description: "允许使用 HTTP 或自签名证书的注册表"},
+    "proxies":                    {Type: OptionObject, Description: "守护进程使用的代理", validate: validateProxies},
+    "data-root":                  {Type: OptionString, Description: "Docker 数据目录", validate: validateAbsPath},
+    "exec-root":                  {Type: OptionString, Description: "执行状态目录", validate: validateAbsPath},
+    "log-driver":                 {Type: OptionString, Description: "容器默认日志驱动"},
+    "log-opts":                   {Type: OptionStringMap, Description: "日志驱动选项，例如 max-size、max-file"},
+    "log-level":                  {Type: OptionString, Description: "守护进程日志级别", Enum: []string{"debug", "info", "warn", "error", "fatal"}},
+    "log-format":                 {Type: OptionString, Description: "守护进程日志格式", Enum: []string{"text", "json"}},
+    "storage-driver":             {Type: OptionString, Description: "存储驱动"},
+    "storage-opts":               {Type: OptionStringList, Description: "存储驱动选项"},
+    "exec-opts":                  {Type: OptionStringList, Description: "运行时选项，例如 native.cgroupdriver=systemd"},
+    "default-address-pools":      {Type: OptionList, Description: "自动分配网络使用的地址池", validate: validateAddressPools},
+    "bip":                        {Type: OptionString, Description: "docker0 网桥地址", validate: validateCIDR},
+    "fixed-cidr":                 {Type: OptionString, Description: "默认网桥容器地址段", validate: validateCIDR},
+    "fixed-cidr-v6":              {Type: OptionString, Description: "默认网桥 IPv6 地址段", validate: validateCIDR},
+    "default-gateway":            {Type: OptionString, Description: "默认网桥网关", validate: validateIP},
+    "default-gateway-v6":         {Type: OptionString, Description: "默认网桥 IPv6 网关", validate: validateIP},
+    "mtu":                        {Type: OptionInt, Description: "默认网桥 MTU", validate: intRange(68, 65535)},
+    "ipv6":                       {Type: OptionBool, Description: "默认网桥启用 IPv6"},
+    "ip6tables":                  {Type: OptionBool, Description: "启用 ip6tables 规则"},
+    "iptables":                   {Type: OptionBool, Description: "允许 Docker 修改 iptables 规则"},
+    "ip-forward":                 {Type: OptionBool, Description: "启用 IP 转发"},
+    "ip-masq":                    {Type: OptionBool, Description: "启用 IP 伪装"},
+    "icc":                        {Type: OptionBool, Description: "允许容器间通信"},
+    "ip":                         {Type: OptionString, Description: "端口映射默认绑定地址", validate: validateIP},
+    "dns":                        {Type: OptionStringList, Description: "容器默认 DNS 服务器", validate: eachString(validateIP)},
+    "dns-opts":                   {Type: OptionStringList, Description: "容器默认 DNS 选项"},
+    "dns-search":                 {Type: OptionStringList, Description: "容器默认 DNS 搜索域"},
+    "hosts":                      {Type: OptionStringList, Description: "守护进程监听地址"},
+    "labels":                     {Type: OptionStringList, Description: "守护进程标签"},
+    "live-restore":               {Type: OptionBool, Description: "守护进程停止时保持容器运行"},
+    "debug":                      {Type: OptionBool, Description: "调试模式"},
+    "experimental":               {Type: OptionBool, Description: "启用实验性功能"},
+    "features":                   {Type: OptionObject, Description: "功能开关，例如 buildkit"},
+    "builder":                    {Type: OptionObject, Description: "构建器配置"},
+    "max-concurrent-downloads":   {Type: OptionInt, Description: "每次拉取的最大并发下载数", validate: intRange(1, 1000)},
+    "max-concurrent-uploads":     {Type: OptionInt, Description: "每次推送的最大并发上传数", validate: intRange(1, 1000)},
+    "max-download-attempts":      {Type: OptionInt, Description: "每层最大下载重试次数", validate: intRange(1, 1000)},
+    "shutdown-timeout":           {Type: OptionInt, Description: "关闭守护进程时等待容器停止的秒数", validate: intRange(0, 86400)},
+    "default-ulimits":            {Type: OptionObject, Description: "容器默认 ulimit"},
+    "default-shm-size":           {Type: OptionString, Description: "容器默认 /dev/shm 大小"},
+    "default-runtime":            {Type: OptionString, Description: "容器默认运行时"},
+    "runtimes":                   {Type: OptionObject, Description: "额外的 OCI 运行时"},
+    "default-cgroupns-mode":      {Type: OptionString, Description: "容器默认 cgroup 命名空间模式", Enum: []string{"host", "private"}},
+    "cgroup-parent":              {Type: OptionString, Description: "容器默认父 cgroup"},
+    "userns-remap":               {Type: OptionString, Description: "用户命名空间映射"},
+    "no-new-privileges":          {Type: OptionBool, Description: "容器默认禁止提升权限"},
+    "seccomp-profile":            {Type: OptionString, Description: "默认 seccomp 配置文件"},
+    "selinux-enabled":            {Type: OptionBool, Description: "启用 SELinux 支持"},
+    "userland-proxy":             {Type: OptionBool, Description: "端口映射使用用户态代理"},
+    "userland-proxy-path":        {Type: OptionString, Description: "用户态代理程序路径"},
+    "containerd":                 {Type: OptionString, Description: "containerd 套接字路径"},
+    "containerd-namespace":       {Type: OptionString, Description: "containerd 命名空间"},
+    "metrics-addr":               {Type: OptionString, Description: "Prometheus 指标监听地址"},
+    "group":                      {Type: OptionString, Description: "Unix 套接字所属用户组"},
+    "pidfile":                    {Type: OptionString, Description: "PID 文件路径", validate: validateAbsPath},
+    "tls":                        {Type: OptionBool, Description: "启用 TLS"},
+    "tlsverify":                  {Type: OptionBool, Description: "启用 TLS 并验证客户端"},
+    "tlscacert":                  {Type: OptionString, Description: "CA 证书路径"},
+    "tlscert":                    {Type: OptionString, Description: "TLS 证书路径"},
+    "tlskey":                     {Type: OptionString, Description: "TLS 私钥路径"},
+    "allow-nondistributable-artifacts": {Type: OptionStringList, Description: "允许推送不可分发层的注册表"},
+}
+
+// DaemonValidationIssue 校验发现的问题
+type DaemonValidationIssue struct {
+    Key     string `json:"key"`
+    Message string `json:"message"`
+}
+
+// DaemonValidation 校验结果，Errors 非空时不能写入，Warnings 只做提示
+type DaemonValidation struct {
+    Valid    bool                    `json:"valid"`
+    Errors   []DaemonValidationIssue `json:"errors"`
+    Warnings []DaemonValidationIssue `json:"warnings"`
+}
+
+// DaemonBackup daemon.json 的备份文件
+type DaemonBackup struct {
+    Name      string    `json:"name"`
+    Size      int64     `json:"size"`
+    CreatedAt time.Time `json:"createdAt"`
+    seq       int       // 同一秒内的序号，daemon.json.<时间戳>.bak 为 0
+}
+
+// 自上次成功重启以来第一次写入时生成的备份，即守护进程当前实际使用的配置。
+// 重启失败时回滚到这里，而不是最新的备份（多次写入时最新的备份本身也没有生效过）。
+// 备份名记录在 daemon.json 同目录的 daemon.json.pending 中，面板重启后仍可回滚；
+// 文件存在但内容为空表示写入前没有原文件
+var pendingBackupMu sync.Mutex
+
+func pendingBackupPath() (string, error) {
+    configPath, err := GetDaemonConfigPath()
+    if err != nil {
+        return "", err
+    }
+    return configPath + ".pending", nil
+}
+
+// 读取待回滚的备份，ok 表示自上次重启以来已写入过。调用方需持有 pendingBackupMu
+func readPendingBackup() (name string, ok bool) {
+    path, err := pendingBackupPath()
+    if err != nil {
+        return "", false
+    }
+    data, err := os.ReadFile(path)
+    if err != nil {
+        if !os.IsNotExist(err) {
+            log.Printf("读取待回滚的 daemon.json 备份失败: %v", err)
+        }
+        return "", false
+    }
+    return strings.TrimSpace(string(data)), true
+}
+
+// PendingDaemonBackup 返回重启失败时应回滚到的备份，自上次重启以来没有写入或写入前没有原文件时为空
+func PendingDaemonBackup() string {
+    pendingBackupMu.Lock()
+    defer pendingBackupMu.Unlock()
+    name, _ := readPendingBackup()
+    return name
+}
+
+// MarkDaemonConfigApplied 守护进程已使用当前 daemon.json 成功启动后调用
+func MarkDaemonConfigApplied() {
+    pendingBackupMu.Lock()
+    defer pendingBackupMu.Unlock()
+    path, err := pendingBackupPath()
+    if err != nil {
+        return
+    }
+    if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+        log.Printf("清除待回滚的 daemon.json 备份失败: %v", err)
+    }
+}
+
+// ReadDaemonConfigRaw 读取 daemon.json 的全部配置项，返回解析后的配置和原始内容。
+// 数字以 json.Number 保存，写回时不会丢失精度
+func ReadDaemonConfigRaw() (map[string]interface{}, []byte, error) {
+    configPath, err := GetDaemonConfigPath()
+    if err != nil {
+        return nil, nil, err
+    }
+
+    data, err := os.ReadFile(configPath)
+    if err != nil {
+        if os.IsNotExist(err) {
+            return map[string]interface{}{}, nil, nil
+        }
+        return nil, nil, fmt.Errorf("读取 daemon.json 失败: %v", err)
+    }
+
+    config, err := ParseDaemonConfig(data)
+    if err != nil {
+        return nil, data, err
+    }
+    return config, data, nil
+}
+
+// ParseDaemonConfig 解析 daemon.json 内容，空内容视为空配置
+func ParseDaemonConfig(data []byte) (map[string]interface{}, error) {
+    config := map[string]interface{}{}
+    if len(bytes.TrimSpace(data)) == 0 {
+        return config, nil
+    }
+    decoder := json.NewDecoder(bytes.NewReader(data))
+    decoder.UseNumber()
+    if err := decoder.Decode(&config); err != nil {
+        return nil, fmt.Errorf("解析 daemon.json 失败: %v", err)
+    }
+    return config, nil
+}
+
+// MarshalDaemonConfig 按 daemon.json 的惯用格式序列化配置
+func MarshalDaemonConfig(config map[string]interface{}) ([]byte, error) {
+    var buf bytes.Buffer
+    encoder := json.NewEncoder(&buf)
+    encoder.SetEscapeHTML(false)
+    encoder.SetIndent("", "    ")
+    if err := encoder.Encode(config); err != nil {
+        return nil, err
+    }
+    return buf.Bytes(), nil
+}
+
+// ValidateDaemonConfig 按 DaemonOptions 校验配置。
+// 未收录的配置项只给出警告，以免因版本差异拦截合法配置
+func ValidateDaemonConfig(config map[string]interface{}) DaemonValidation {
+    result := DaemonValidation{
+        Errors:   []DaemonValidationIssue{},
+        Warnings: []DaemonValidationIssue{},
+    }
+
+    keys := make([]string, 0, len(config))
+    for key := range config {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+
+    for _, key := range keys {
+        value := config[key]
+        option, ok := DaemonOptions[key]
+        if !ok {
+            result.Warnings = append(result.Warnings, DaemonValidationIssue{Key: key, Message: "未知的配置项，dockerd 可能拒绝启动"})
+            continue
+        }
+        if err := checkOptionType(option.Type, value); err != nil {
+            result.Errors = append(result.Errors, DaemonValidationIssue{Key: key, Message: err.Error()})
+            continue
+        }
+        if len(option.Enum) > 0 {
+            if s, _ := value.(string); !containsString(option.Enum, s) {
+                result.Errors = append(result.Errors, DaemonValidationIssue{
+                    Key:     key,
+                    Message: fmt.Sprintf("取值必须为 %s 之一", strings.Join(option.Enum, "、")),
+                })
+                continue
+            }
+        }
+        if option.validate != nil {
+            if err := option.validate(value); err != nil {
+                result.Errors = append(result.Errors, DaemonValidationIssue{Key: key, Message: err.Error()})
+            }
+        }
+    }
+
+    // log-opts 的轮转选项依赖 log-driver
+    if opts, ok := config["log-opts"].(map[string]interface{}); ok {
+        logConfig := LogConfig{Options: map[string]string{}}
+        logConfig.Driver, _ = config["log-driver"].(string)
+        for k, v := range opts {
+            if s, ok := v.(string); ok {
+                logConfig.Options[k] = s
+            }
+        }
+        if err := logConfig.Validate(); err != nil {
+            result.Errors = append(result.Errors, DaemonValidationIssue{Key: "log-opts", Message: err.Error()})
+        }
+    }
+
+    // tlsverify 需要证书
+    if v, _ := config["tlsverify"].(bool); v {
+        for _, key := range []string{"tlscacert", "tlscert", "tlskey"} {
+            if _, ok := config[key]; !ok {
+                result.Warnings = append(result.Warnings, DaemonValidationIssue{Key: key, Message: "启用 tlsverify 时通常需要配置该项"})
+            }
+        }
+    }
+
+    result.Valid = len(result.Errors) == 0
+    return result
+}
+
+// DiffDaemonConfig 生成两份 daemon.json 内容的统一格式差异，内容相同时返回空字符串
+func DiffDaemonConfig(oldData, newData []byte) string {
+    if bytes.Equal(oldData, newData) {
+        return ""
+    }
+    diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+        A:        difflib.SplitLines(string(oldData)),
+        B:        difflib.SplitLines(string(newData)),
+        FromFile: "daemon.json (当前)",
+        ToFile:   "daemon.json (修改后)",
+        Context:  3,
+    })
+    if err != nil {
+        return ""
+    }
+    return diff
+}
+
+// WriteDaemonConfig 写入完整的 daemon.json：先备份当前文件，再写临时文件并重命名。
+// 返回本次生成的备份文件名，原文件不存在时为空
+func WriteDaemonConfig(config map[string]interface{}) (string, error) {
+    data, err := MarshalDaemonConfig(config)
+    if err != nil {
+        return "", err
+    }
+    return writeDaemonConfigData(data)
+}
+
+func writeDaemonConfigData(data []byte) (string, error) {
+    configPath, err := GetDaemonConfigPath()
+    if err != nil {
+        return "", fmt.Errorf("获取配置路径失败: %v", err)
+    }
+    if err := checkConfigPermissions(configPath); err != nil {
+        return "", err
+    }
+
+    backup, err := backupDaemonConfig(configPath)
+    if err != nil {
+        return "", err
+    }
+
+    if err := atomicWriteFile(configPath, data, 0644); err != nil {
+        return backup, fmt.Errorf("写入配置文件失败: %v", err)
+    }
+
+    pendingBackupMu.Lock()
+    defer pendingBackupMu.Unlock()
+    if _, ok := readPendingBackup(); !ok {
+        path, err := pendingBackupPath()
+        if err == nil {
+            err = atomicWriteFile(path, []byte(backup), 0600)
+        }
+        if err != nil {
+            log.Printf("记录待回滚的 daemon.json 备份失败: %v", err)
+        }
+    }
+    return backup, nil
+}
+
+// ListDaemonBackups 列出 daemon.json 的备份，最新的在前
+func ListDaemonBackups() ([]DaemonBackup, error) {
+    configPath, err := GetDaemonConfigPath()
+    if err != nil {
+        return nil, err
+    }
+
+    entries, err := os.ReadDir(filepath.Dir(configPath))
+    if err != nil {
+        if os.IsNotExist(err) {
+            return []DaemonBackup{}, nil
+        }
+        return nil, err
+    }
+
+    prefix := filepath.Base(configPath) + "."
+    backups := []DaemonBackup{}
+    for _, entry := range entries {
+        name := entry.Name()
+        if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ".bak") {
+            continue
+        }
+        stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".bak")
+        stamp, seqText, hasSeq := strings.Cut(stamp, ".")
+        created, err := time.ParseInLocation(daemonBackupTimeFormat, stamp, time.Local)
+        if err != nil {
+            continue
+        }
+        seq := 0
+        if hasSeq {
+            if seq, err = strconv.Atoi(seqText); err != nil {
+                continue
+            }
+        }
+        backup := DaemonBackup{Name: name, CreatedAt: created, seq: seq}
+        if info, err := entry.Info(); err == nil {
+            backup.Size = info.Size()
+        }
+        backups = append(backups, backup)
+    }
+    // 按时间和序号排序，不能按名称：X.bak 在字符串比较中排在 X.1.bak 之后
+    sort.Slice(backups, func(i, j int) bool {
+        if !backups[i].CreatedAt.Equal(backups[j].CreatedAt) {
+            return backups[i].CreatedAt.After(backups[j].CreatedAt)
+        }
+        return backups[i].seq > backups[j].seq
+    })
+    return backups, nil
+}
+
+// ReadDaemonBackup 读取备份内容
+func ReadDaemonBackup(name string) ([]byte, error) {
+    path, err := daemonBackupPath(name)
+    if err != nil {
+        return nil, err
+    }
+    data, err := os.ReadFile(path)
+    if err != nil {
+        if os.IsNotExist(err) {
+            return nil, fmt.Errorf("备份不存在: %s", name)
+        }
+        return nil, err
+    }
+    return data, nil
+}
+
+// RestoreDaemonBackup 用备份覆盖 daemon.json，覆盖前同样会备份当前文件。
+// 返回恢复前生成的备份文件名
+func RestoreDaemonBackup(name string) (string, error) {
+    data, err := ReadDaemonBackup(name)
+    if err != nil {
+        return "", err
+    }
+    if _, err := ParseDaemonConfig(data); err != nil {
+        return "", fmt.Errorf("备份内容无效: %v", err)
+    }
+    return writeDaemonConfigData(data)
+}
+
+func daemonBackupPath(name string) (string, error) {
+    configPath, err := GetDaemonConfigPath()
+    if err != nil {
+        return "", err
+    }
+    if name == "" || name != filepath.Base(name) ||
+        !strings.HasPrefix(name, filepath.Base(configPath)+".") || !strings.HasSuffix(name, ".bak") {
+        return "", fmt.Errorf("无效的备份名称: %s", name)
+    }
+    return filepath.Join(filepath.Dir(configPath), name), nil
+}
+
+// backupDaemonConfig 将当前 daemon.json 复制为带时间戳的备份，并清理过旧的备份
+func backupDaemonConfig(configPath string) (string, error) {
+    data, err := os.ReadFile(configPath)
+    if err != nil {
+        if os.IsNotExist(err) {
+            return "", nil
+        }
+        return "", fmt.Errorf("读取 daemon.json 失败: %v", err)
+    }
+
+    base := filepath.Base(configPath) + "." + time.Now().Format(daemonBackupTimeFormat)
+    name := base + ".bak"
+    // 同一秒内多次保存时加序号
+    for i := 1; ; i++ {
+        if _, err := os.Stat(filepath.Join(filepath.Dir(configPath), name)); os.IsNotExist(err) {
+            break
+        }
+        name = fmt.Sprintf("%s.%d.bak", base, i)
+    }
+
+    if err := os.WriteFile(filepath.Join(filepath.Dir(configPath), name), data, 0600); err != nil {
+        return "", fmt.Errorf("备份 daemon.json 失败: %v", err)
+    }
+
+    if backups, err := ListDaemonBackups(); err == nil && len(backups) > maxDaemonBackups {
+        pending := PendingDaemonBackup()
+        for _, old := range backups[maxDaemonBackups:] {
+            // 回滚仍需要的备份不清理
+            if old.Name == pending {
+                continue
+            }
+            os.Remove(filepath.Join(filepath.Dir(configPath), old.Name))
+        }
+    }
+    return name, nil
+}
+
+// atomicWriteFile 写入同目录下的临时文件后重命名，保证文件内容始终完整
+func atomicWriteFile(path string, data []byte, perm os.FileMode) error {
+    dir := filepath.Dir(path)
+    if err := os.MkdirAll(dir, 0755); err != nil {
+        return err
+    }
+    tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+    if err != nil {
+        return err
+    }
+    defer os.Remove(tmp.Name())
+
+    if _, err := tmp.Write(data); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Sync(); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Chmod(perm); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Close(); err != nil {
+        return err
+    }
+    return os.Rename(tmp.Name(), path)
+}
+
+func checkOptionType(t DaemonOptionType, value interface{}) error {
+    switch t {
+    case OptionString:
+        if _, ok := value.(string); !ok {
+            return fmt.Errorf("应为字符串")
+        }
+    case OptionBool:
+        if _, ok := value.(bool); !ok {
+            return fmt.Errorf("应为布尔值")
+        }
+    case OptionInt:
+        if _, err := toInt(value); err != nil {
+            return err
+        }
+    case OptionStringList:
+        list, ok := value.([]interface{})
+        if !ok {
+            return fmt.Errorf("应为字符串数组")
+        }
+        for i, item := range list {
+            if _, ok := item.(string); !ok {
+                return fmt.Errorf("第 %d 项应为字符串", i+1)
+            }
+        }
+    case OptionStringMap:
+        m, ok := value.(map[string]interface{})
+        if !ok {
+            return fmt.Errorf("应为对象")
+        }
+        for k, v := range m {
+            if _, ok := v.(string); !ok {
+                return fmt.Errorf("%s 的值应为字符串", k)
+            }
+        }
+    case OptionObject:
+        if _, ok := value.(map[string]interface{}); !ok {
+            return fmt.Errorf("应为对象")
+        }
+    case OptionList:
+        list, ok := value.([]interface{})
+        if !ok {
+            return fmt.Errorf("应为数组")
+        }
+        for i, item := range list {
+            if _, ok := item.(map[string]interface{}); !ok {
+                return fmt.Errorf("第 %d 项应为对象", i+1)
+            }
+        }
+    }
+    return nil
+}
+
+func toInt(value interface{}) (int64, error) {
+    switch v := value.(type) {
+    case json.Number:
+        n, err := v.Int64()
+        if err != nil {
+            return 0, fmt.Errorf("应为整数")
+        }
+        return n, nil
+    case float64:
+        if v != float64(int64(v)) {
+            return 0, fmt.Errorf("应为整数")
+        }
+        return int64(v), nil
+    case int:
+        return int64(v), nil
+    case int64:
+        return v, nil
+    }
+    return 0, fmt.Errorf("应为整数")
+}
+
+func intRange(min, max int64) func(interface{}) error {
+    return func(value interface{}) error {
+        n, _ := toInt(value)
+        if n < min || n > max {
+            return fmt.Errorf("取值范围为 %d-%d", min, max)
+        }
+        return nil
+    }
+}
+
+func eachString(fn func(interface{}) error) func(interface{}) error {
+    return func(value interface{}) error {
+        for _, item := range value.([]interface{}) {
+            if err := fn(item); err != nil {
+                return err
+            }
+        }
+        return nil
+    }
+}
+
+func validateURL(value interface{}) error {
+    s := value.(string)
+    u, err := url.Parse(s)
+    if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+        return fmt.Errorf("%q 不是有效的 http(s) 地址", s)
+    }
+    return nil
+}
+
+func validateIP(value interface{}) error {
+    s := value.(string)
+    if net.ParseIP(s) == nil {
+        return fmt.Errorf("%q 不是有效的 IP 地址", s)
+    }
+    return nil
+}
+
+func validateCIDR(value interface{}) error {
+    s := value.(string)
+    if _, _, err := net.ParseCIDR(s); err != nil {
+        return fmt.Errorf("%q 不是有效的 CIDR 地址段", s)
+    }
+    return nil
+}
+
+func validateAbsPath(value interface{}) error {
+    s := value.(string)
+    if !filepath.IsAbs(s) {
+        return fmt.Errorf("%q 必须是绝对路径", s)
+    }
+    return nil
+}
+
+func validateProxies(value interface{}) error {
+    for key, v := range value.(map[string]interface{}) {
+        switch key {
+        case "http-proxy", "https-proxy", "no-proxy":
+            if _, ok := v.(string); !ok {
+                return fmt.Errorf("%s 应为字符串", key)
+            }
+        default:
+            return fmt.Errorf("未知的代理配置项 %s", key)
+        }
+    }
+    return nil
+}
+
+// validateAddressPools 校验 [{"base": "172.80.0.0/16", "size": 24}] 格式的地址池
+func validateAddressPools(value interface{}) error {
+    for i, item := range value.([]interface{}) {
+        pool := item.(map[string]interface{})
+        base, _ := pool["base"].(string)
+        _, network, err := net.ParseCIDR(base)
+        if err != nil {
+            return fmt.Errorf("第 %d 个地址池的 base 不是有效的 CIDR", i+1)
+        }
+        size, err := toInt(pool["size"])
+        if err != nil {
+            return fmt.Errorf("第 %d 个地址池的 size 应为整数", i+1)
+        }
+        ones, bits := network.Mask.Size()
+        if size < int64(ones) || size > int64(bits) {
+            return fmt.Errorf("第 %d 个地址池的 size 应在 %d-%d 之间", i+1, ones, bits)
+        }
+    }
+    return nil
+}
+
+func containsString(list []string, s string) bool {
+    for _, item := range list {
+        if item == s {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,217 @@
+package docker
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+// useTempDaemonConfig 将 daemon.json 指向临时目录，并清空待回滚的备份
+func useTempDaemonConfig(t *testing.T) string {
+    dir := t.TempDir()
+    t.Setenv(daemonConfigPathEnv, filepath.Join(dir, "daemon.json"))
+    MarkDaemonConfigApplied()
+    t.Cleanup(MarkDaemonConfigApplied)
+    return dir
+}
+
+func TestValidateDaemonConfig(t *testing.T) {
+    tests := []struct {
+        name    string
+        config  string
+        errKey  string
+        warnKey string
+    }{
+        {"合法配置", `{"registry-mirrors":["https://mirror.example.com"],"log-driver":"json-file","log-opts":{"max-size":"10m","max-file":"3"},"mtu":1500}`, "", ""},
+        {"类型错误", `{"live-restore":"yes"}`, "live-restore", ""},
+        {"枚举取值", `{"log-level":"verbose"}`, "log-level", ""},
+        {"加速地址不是 URL", `{"registry-mirrors":["mirror.example.com"]}`, "registry-mirrors", ""},
+        {"整数范围", `{"mtu":10}`, "mtu", ""},
+        {"整数不能是小数", `{"max-concurrent-downloads":2.5}`, "max-concurrent-downloads", ""},
+        {"地址池大小", `{"default-address-pools":[{"base":"172.80.0.0/16","size":8}]}`, "default-address-pools", ""},
+        {"日志大小无效", `{"log-opts":{"max-size":"ten"}}`, "log-opts", ""},
+        {"未知配置项只警告", `{"no-such-option":true}`, "", "no-such-option"},
+        {"tlsverify 缺少证书", `{"tlsverify":true}`, "", "tlscacert"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            config, err := ParseDaemonConfig([]byte(tt.config))
+            if err != nil {
+                t.Fatalf("解析失败: %v", err)
+            }
+            result := ValidateDaemonConfig(config)
+            if result.Valid != (tt.errKey == "") {
+                t.Errorf("Valid = %v, 错误: %+v", result.Valid, result.Errors)
+            }
+            if tt.errKey != "" && (len(result.Errors) == 0 || result.Errors[0].Key != tt.errKey) {
+                t.Errorf("错误 = %+v, 期望 %s", result.Errors, tt.errKey)
+            }
+            if tt.warnKey != "" && (len(result.Warnings) == 0 || result.Warnings[0].Key != tt.warnKey) {
+                t.Errorf("警告 = %+v, 期望 %s", result.Warnings, tt.warnKey)
+            }
+        })
+    }
+}
+
+func TestParseDaemonConfigKeepsNumbers(t *testing.T) {
+    config, err := ParseDaemonConfig([]byte(`{"max-download-attempts": 9007199254740993}`))
+    if err != nil {
+        t.Fatal(err)
+    }
+    data, _ := MarshalDaemonConfig(config)
+    if !strings.Contains(string(data), "9007199254740993") {
+        t.Errorf("写回后数字精度丢失: %s", data)
+    }
+    if _, err := ParseDaemonConfig([]byte("  \n")); err != nil {
+        t.Errorf("空内容应视为空配置: %v", err)
+    }
+    if _, err := ParseDaemonConfig([]byte("{")); err == nil {
+        t.Error("无效的 JSON 应返回错误")
+    }
+}
+
+func TestDiffDaemonConfig(t *testing.T) {
+    old := []byte("{\n    \"debug\": false\n}\n")
+    if diff := DiffDaemonConfig(old, old); diff != "" {
+        t.Errorf("内容相同时应返回空字符串, 得到 %q", diff)
+    }
+    diff := DiffDaemonConfig(old, []byte("{\n    \"debug\": true\n}\n"))
+    if !strings.Contains(diff, "-    \"debug\": false") || !strings.Contains(diff, "+    \"debug\": true") {
+        t.Errorf("差异内容不正确:\n%s", diff)
+    }
+}
+
+func TestWriteDaemonConfigBackups(t *testing.T) {
+    dir := useTempDaemonConfig(t)
+    configPath := filepath.Join(dir, "daemon.json")
+
+    // 原文件不存在时不生成备份
+    backup, err := WriteDaemonConfig(map[string]interface{}{"debug": false})
+    if err != nil {
+        t.Fatalf("WriteDaemonConfig 返回错误: %v", err)
+    }
+    if backup != "" {
+        t.Errorf("原文件不存在时备份应为空, 得到 %s", backup)
+    }
+    MarkDaemonConfigApplied()
+    original, _ := os.ReadFile(configPath)
+
+    first, err := WriteDaemonConfig(map[string]interface{}{"debug": true})
+    if err != nil || first == "" {
+        t.Fatalf("第一次写入: backup = %q, err = %v", first, err)
+    }
+    second, err := WriteDaemonConfig(map[string]interface{}{"debug": true, "live-restore": true})
+    if err != nil || second == "" || second == first {
+        t.Fatalf("第二次写入: backup = %q, err = %v", second, err)
+    }
+
+    // 回滚目标是守护进程实际使用的配置，即第一次写入前的备份
+    if pending := PendingDaemonBackup(); pending != first {
+        t.Errorf("PendingDaemonBackup = %s, 期望 %s", pending, first)
+    }
+
+    backups, err := ListDaemonBackups()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(backups) != 2 || backups[0].Name != second || backups[1].Name != first {
+        t.Errorf("备份顺序 = %+v, 期望 %s, %s", backups, second, first)
+    }
+
+    restoredFrom, err := RestoreDaemonBackup(first)
+    if err != nil {
+        t.Fatalf("RestoreDaemonBackup 返回错误: %v", err)
+    }
+    if restoredFrom == "" {
+        t.Error("恢复前应备份当前文件")
+    }
+    if data, _ := os.ReadFile(configPath); string(data) != string(original) {
+        t.Errorf("恢复后内容 = %s, 期望 %s", data, original)
+    }
+    if pending := PendingDaemonBackup(); pending != first {
+        t.Errorf("恢复不应改变回滚目标, 得到 %s", pending)
+    }
+
+    MarkDaemonConfigApplied()
+    if pending := PendingDaemonBackup(); pending != "" {
+        t.Errorf("成功重启后不应有待回滚的备份, 得到 %s", pending)
+    }
+}
+
+func TestListDaemonBackupsOrder(t *testing.T) {
+    dir := useTempDaemonConfig(t)
+    names := []string{
+        "daemon.json.20240101-120000.bak",
+        "daemon.json.20240101-120000.1.bak",
+        "daemon.json.20240101-120000.2.bak",
+        "daemon.json.20240101-115959.bak",
+        "daemon.json.invalid.bak",
+        "other.json.20240101-120000.bak",
+    }
+    for _, name := range names {
+        if err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0600); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    backups, err := ListDaemonBackups()
+    if err != nil {
+        t.Fatal(err)
+    }
+    var got []string
+    for _, b := range backups {
+        got = append(got, b.Name)
+    }
+    want := "daemon.json.20240101-120000.2.bak,daemon.json.20240101-120000.1.bak,daemon.json.20240101-120000.bak,daemon.json.20240101-115959.bak"
+    if strings.Join(got, ",") != want {
+        t.Errorf("备份顺序 = %v", got)
+    }
+}
+
+func TestDaemonBackupPathRejectsTraversal(t *testing.T) {
+    useTempDaemonConfig(t)
+    for _, name := range []string{"", "../daemon.json.20240101-120000.bak", "daemon.json", "other.json.20240101-120000.bak"} {
+        if _, err := ReadDaemonBackup(name); err == nil {
+            t.Errorf("ReadDaemonBackup(%q) 应返回错误", name)
+        }
+    }
+}
+
+// 待回滚的备份记录在文件中，面板进程重启后仍然有效，成功重启后清除
+func TestPendingDaemonBackupPersisted(t *testing.T) {
+    dir := useTempDaemonConfig(t)
+    if err := os.WriteFile(filepath.Join(dir, "daemon.json"), []byte(`{"debug":false}`), 0644); err != nil {
+        t.Fatal(err)
+    }
+    first, err := WriteDaemonConfig(map[string]interface{}{"debug": true})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    marker := filepath.Join(dir, "daemon.json.pending")
+    if data, err := os.ReadFile(marker); err != nil || string(data) != first {
+        t.Fatalf("待回滚记录 = %q, err = %v, 期望 %s", data, err, first)
+    }
+    // 模拟面板重启后由上一个进程留下的记录
+    if err := os.WriteFile(marker, []byte("daemon.json.20240101-120000.bak\n"), 0600); err != nil {
+        t.Fatal(err)
+    }
+    if pending := PendingDaemonBackup(); pending != "daemon.json.20240101-120000.bak" {
+        t.Errorf("PendingDaemonBackup = %s", pending)
+    }
+    if _, err := WriteDaemonConfig(map[string]interface{}{"debug": false}); err != nil {
+        t.Fatal(err)
+    }
+    if pending := PendingDaemonBackup(); pending != "daemon.json.20240101-120000.bak" {
+        t.Errorf("已有记录时再次写入不应覆盖, 得到 %s", pending)
+    }
+
+    MarkDaemonConfigApplied()
+    if _, err := os.Stat(marker); !os.IsNotExist(err) {
+        t.Errorf("成功重启后应删除待回滚记录: %v", err)
+    }
+    if backups, _ := ListDaemonBackups(); len(backups) != 2 {
+        t.Errorf("待回滚记录不应出现在备份列表中: %+v", backups)
+    }
+}
@@ -0,0 +1,291 @@
+package docker
+
+import (
+    "bytes"
+    "context"
+    "encoding/base64"
+    "encoding/json"
+    "fmt"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "sort"
+    "strings"
+    "time"
+)
+
+// Docker Hub 在 config.json 中使用的服务器地址
+const dockerHubConfigKey = "https://index.docker.io/v1/"
+
+// DockerConfigDir 返回 docker CLI 配置目录，优先使用 DOCKER_CONFIG，默认 ~/.docker
+func DockerConfigDir() string {
+    if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+        return dir
+    }
+    home, err := os.UserHomeDir()
+    if err != nil || home == "" {
+        home = "/root"
+    }
+    return filepath.Join(home, ".docker")
+}
+
+// ComposeEnv 返回执行 docker compose 等 CLI 命令时使用的环境变量，
+// 确保 CLI 与面板读写同一个 config.json
+func ComposeEnv() []string {
+    return append(os.Environ(), "DOCKER_CONFIG="+DockerConfigDir())
+}
+
+// ConfigServerKey 将注册表地址转换为 config.json 中的服务器键
+func ConfigServerKey(registryURL string) string {
+    u := strings.TrimRight(strings.TrimSpace(registryURL), "/")
+    switch u {
+    case "", "docker.io", "index.docker.io", "registry-1.docker.io", "https://index.docker.io/v1":
+        return dockerHubConfigKey
+    }
+    u = strings.TrimPrefix(u, "https://")
+    u = strings.TrimPrefix(u, "http://")
+    return u
+}
+
+type configAuthEntry struct {
+    Auth          string `json:"auth,omitempty"`
+    IdentityToken string `json:"identitytoken,omitempty"`
+}
+
+// DockerConfigFile docker CLI 的 config.json，只解析凭据相关字段，其余字段原样保留
+type DockerConfigFile struct {
+    path        string
+    raw         map[string]json.RawMessage
+    auths       map[string]configAuthEntry
+    CredsStore  string            `json:"credsStore,omitempty"`
+    CredHelpers map[string]string `json:"credHelpers,omitempty"`
+}
+
+// DockerCredential 从 config.json 读取到的凭据
+type DockerCredential struct {
+    Server   string `json:"server"`
+    Username string `json:"username"`
+    Password string `json:"-"`
+    Helper   string `json:"helper,omitempty"` // 为空表示保存在 auths 中
+}
+
+// LoadDockerConfig 读取配置目录下的 config.json，文件不存在时返回空配置
+func LoadDockerConfig(dir string) (*DockerConfigFile, error) {
+    f := &DockerConfigFile{
+        path:        filepath.Join(dir, "config.json"),
+        raw:         make(map[string]json.RawMessage),
+        auths:       make(map[string]configAuthEntry),
+        CredHelpers: make(map[string]string),
+    }
+
+    data, err := os.ReadFile(f.path)
+    if err != nil {
+        if os.IsNotExist(err) {
+            return f, nil
+        }
+        return nil, fmt.Errorf("读取 %s 失败: %v", f.path, err)
+    }
+    if len(bytes.TrimSpace(data)) == 0 {
+        return f, nil
+    }
+    if err := json.Unmarshal(data, &f.raw); err != nil {
+        return nil, fmt.Errorf("解析 %s 失败: %v", f.path, err)
+    }
+    if v, ok := f.raw["auths"]; ok {
+        if err := json.Unmarshal(v, &f.auths); err != nil {
+            return nil, fmt.Errorf("解析 auths 失败: %v", err)
+        }
+    }
+    if v, ok := f.raw["credsStore"]; ok {
+        json.Unmarshal(v, &f.CredsStore)
+    }
+    if v, ok := f.raw["credHelpers"]; ok {
+        json.Unmarshal(v, &f.CredHelpers)
+    }
+    return f, nil
+}
+
+// Path 返回 config.json 的路径
+func (f *DockerConfigFile) Path() string {
+    return f.path
+}
+
+// Servers 返回配置中出现过的所有服务器
+func (f *DockerConfigFile) Servers() []string {
+    seen := make(map[string]bool)
+    for server := range f.auths {
+        seen[server] = true
+    }
+    for server := range f.CredHelpers {
+        seen[server] = true
+    }
+    servers := make([]string, 0, len(seen))
+    for server := range seen {
+        servers = append(servers, server)
+    }
+    sort.Strings(servers)
+    return servers
+}
+
+// helperFor 返回服务器使用的凭据助手，credHelpers 优先于 credsStore
+func (f *DockerConfigFile) helperFor(server string) string {
+    if helper, ok := f.CredHelpers[server]; ok {
+        return helper
+    }
+    return f.CredsStore
+}
+
+// GetCredential 读取服务器的凭据，未找到时返回 nil
+func (f *DockerConfigFile) GetCredential(registryURL string) (*DockerCredential, error) {
+    server := ConfigServerKey(registryURL)
+
+    if helper := f.helperFor(server); helper != "" {
+        username, secret, err := credentialHelperGet(helper, server)
+        if err != nil {
+            return nil, err
+        }
+        if secret == "" {
+            return nil, nil
+        }
+        return &DockerCredential{Server: server, Username: username, Password: secret, Helper: helper}, nil
+    }
+
+    entry, ok := f.auths[server]
+    if !ok || entry.Auth == "" {
+        return nil, nil
+    }
+    decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+    if err != nil {
+        return nil, fmt.Errorf("解析 %s 的凭据失败: %v", server, err)
+    }
+    username, password, ok := strings.Cut(string(decoded), ":")
+    if !ok {
+        return nil, fmt.Errorf("%s 的凭据格式错误", server)
+    }
+    return &DockerCredential{Server: server, Username: username, Password: password}, nil
+}
+
+// SetCredential 保存服务器的凭据，配置了凭据助手时写入助手，否则写入 auths
+func (f *DockerConfigFile) SetCredential(registryURL, username, password string) error {
+    server := ConfigServerKey(registryURL)
+
+    if helper := f.helperFor(server); helper != "" {
+        if err := credentialHelperStore(helper, server, username, password); err != nil {
+            return err
+        }
+        // 使用助手保存时，auths 中只保留空条目
+        if _, ok := f.auths[server]; ok {
+            f.auths[server] = configAuthEntry{}
+        }
+        return nil
+    }
+
+    f.auths[server] = configAuthEntry{
+        Auth: base64.StdEncoding.EncodeToString([]byte(username + ":" + password)),
+    }
+    return nil
+}
+
+// RemoveCredential 删除服务器的凭据，配置了凭据助手时同时从助手中删除
+func (f *DockerConfigFile) RemoveCredential(registryURL string) error {
+    server := ConfigServerKey(registryURL)
+
+    if helper := f.helperFor(server); helper != "" {
+        if _, err := runCredentialHelper(helper, "erase", server); err != nil && !strings.Contains(err.Error(), "credentials not found") {
+            return err
+        }
+    }
+    delete(f.auths, server)
+    return nil
+}
+
+// Save 写回 config.json，先写临时文件再重命名，避免 CLI 读到半截文件
+func (f *DockerConfigFile) Save() error {
+    auths, err := json.Marshal(f.auths)
+    if err != nil {
+        return err
+    }
+    f.raw["auths"] = auths
+
+    data, err := json.MarshalIndent(f.raw, "", "\t")
+    if err != nil {
+        return err
+    }
+
+    dir := filepath.Dir(f.path)
+    if err := os.MkdirAll(dir, 0700); err != nil {
+        return fmt.Errorf("创建配置目录失败: %v", err)
+    }
+    tmp, err := os.CreateTemp(dir, "config.json.*")
+    if err != nil {
+        return fmt.Errorf("创建临时文件失败: %v", err)
+    }
+    defer os.Remove(tmp.Name())
+
+    if _, err := tmp.Write(data); err != nil {
+        tmp.Close()
+        return fmt.Errorf("写入配置失败: %v", err)
+    }
+    if err := tmp.Chmod(0600); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Close(); err != nil {
+        return err
+    }
+    if err := os.Rename(tmp.Name(), f.path); err != nil {
+        return fmt.Errorf("保存 %s 失败: %v", f.path, err)
+    }
+    return nil
+}
+
+// credentialHelperGet 调用 docker-credential-<helper> get 读取凭据，未保存时返回空字符串
+func credentialHelperGet(helper, server string) (string, string, error) {
+    out, err := runCredentialHelper(helper, "get", server)
+    if err != nil {
+        if strings.Contains(err.Error(), "credentials not found") {
+            return "", "", nil
+        }
+        return "", "", err
+    }
+
+    var cred struct {
+        Username string `json:"Username"`
+        Secret   string `json:"Secret"`
+    }
+    if err := json.Unmarshal(out, &cred); err != nil {
+        return "", "", fmt.Errorf("解析凭据助手 %s 的输出失败: %v", helper, err)
+    }
+    return cred.Username, cred.Secret, nil
+}
+
+// credentialHelperStore 调用 docker-credential-<helper> store 保存凭据
+func credentialHelperStore(helper, server, username, password string) error {
+    payload, err := json.Marshal(map[string]string{
+        "ServerURL": server,
+        "Username":  username,
+        "Secret":    password,
+    })
+    if err != nil {
+        return err
+    }
+    _, err = runCredentialHelper(helper, "store", string(payload))
+    return err
+}
+
+func runCredentialHelper(helper, action, input string) ([]byte, error) {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    cmd := exec.CommandContext(ctx, "docker-credential-"+helper, action)
+    cmd.Stdin = strings.NewReader(input)
+    var stdout, stderr bytes.Buffer
+    cmd.Stdout = &stdout
+    cmd.Stderr = &stderr
+    if err := cmd.Run(); err != nil {
+        // 助手把错误信息写到标准输出
+        msg := strings.TrimSpace(stdout.String() + " " + stderr.String())
+        return nil, fmt.Errorf("凭据助手 docker-credential-%s %s 失败: %v %s", helper, action, err, msg)
+    }
+    return stdout.Bytes(), nil
+}
@@ -4,31 +4,68 @@ package docker // 必须作为第一行
 import (
 	"context"
 	"log"
+	"time"
+
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/events"
-	"github.com/docker/docker/client"
 )
 
-// 使用正确的类型 events.Message
-var BroadcastFunc func(events.Message)
+// BroadcastFunc 接收所有主机的 Docker 事件，hostID 为 0 表示本机
+var BroadcastFunc func(hostID int64, event events.Message)
+
+// StreamStatusFunc 在主机的事件流连接或中断时调用，订阅方可以据此在重连后刷新数据
+var StreamStatusFunc func(hostID int64, connected bool, err error)
 
-func SetBroadcastHandler(fn func(events.Message)) {
+func SetBroadcastHandler(fn func(hostID int64, event events.Message)) {
     BroadcastFunc = fn
 }
 
-func WatchDockerEvents(cli *client.Client) {
-    ctx := context.Background()
-    eventsChan, errChan := cli.Events(ctx, types.EventsOptions{})  // 现在可以使用 types.EventsOptions
+func SetStreamStatusHandler(fn func(hostID int64, connected bool, err error)) {
+    StreamStatusFunc = fn
+}
+
+// WatchDockerEvents 持续监听 Docker 事件并交给 handle 处理，事件流出错后按退避时间自动重连，
+// ctx 取消后返回。onStatus 在每次连接和中断时调用
+func WatchDockerEvents(ctx context.Context, cli *Client, handle func(events.Message), onStatus func(connected bool, err error)) {
+    backoff := time.Second
+    for {
+        started := time.Now()
+        err := consumeEvents(ctx, cli, handle, onStatus)
+        if ctx.Err() != nil {
+            return
+        }
+        onStatus(false, err)
+
+        // 连接维持超过一分钟视为恢复正常，重新从最短间隔开始
+        if time.Since(started) > time.Minute {
+            backoff = time.Second
+        }
+        log.Printf("事件监听错误，%v 后重连: %v", backoff, err)
+        select {
+        case <-ctx.Done():
+            return
+        case <-time.After(backoff):
+        }
+        if backoff < 30*time.Second {
+            backoff *= 2
+        }
+    }
+}
+
+func consumeEvents(ctx context.Context, cli *Client, handle func(events.Message), onStatus func(connected bool, err error)) error {
+    // 连接建立前先确认守护进程可用，避免每次重连都误报为已连接
+    if _, err := cli.Ping(ctx); err != nil {
+        return err
+    }
+    eventsChan, errChan := cli.Events(ctx, types.EventsOptions{})
+    onStatus(true, nil)
 
     for {
         select {
         case event := <-eventsChan:
-            if BroadcastFunc != nil {
-                BroadcastFunc(event)
-            }
+            handle(event)
         case err := <-errChan:
-            log.Printf("事件监听错误: %v", err)
-            return
+            return err
         }
     }
 }
@@ -0,0 +1,530 @@
+package docker
+
+import (
+    "context"
+    "crypto/sha256"
+    "crypto/tls"
+    "crypto/x509"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "io"
+    "log"
+    "net"
+    "net/http"
+    "net/url"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/docker/docker/client"
+    "golang.org/x/crypto/ssh"
+)
+
+// 主机连接方式
+const (
+    HostUnix = "unix"
+    HostTCP  = "tcp"
+    HostSSH  = "ssh"
+)
+
+// 本机默认的 Docker 套接字
+const defaultDockerHost = "unix:///var/run/docker.sock"
+
+// Host 一个 Docker 守护进程的连接信息。ID 为 0 表示面板所在的本机
+type Host struct {
+    ID      int64
+    Name    string
+    Type    string
+    Address string // unix:///var/run/docker.sock、tcp://10.0.0.2:2376、ssh://root@10.0.0.3:22[/var/run/docker.sock]
+
+    // TCP 连接的 TLS 配置，均为 PEM 内容
+    TLSCA     string
+    TLSCert   string
+    TLSKey    string
+    TLSVerify bool // 校验服务端证书，未提供 CA 时使用系统根证书；提供了 CA 时总是校验
+
+    // SSH 连接的认证信息，私钥与密码至少提供一个
+    SSHKey      string
+    SSHPassword string
+    // 已信任的服务端公钥（authorized_keys 格式），为空时拒绝连接，需要管理员确认指纹后保存
+    SSHHostKey string
+}
+
+// LocalHost 返回本机的连接信息
+func LocalHost() *Host {
+    return &Host{Name: "本机", Type: HostUnix, Address: defaultDockerHost}
+}
+
+// ValidateHost 校验连接信息是否完整
+func ValidateHost(h *Host) error {
+    u, err := url.Parse(h.Address)
+    if err != nil {
+        return fmt.Errorf("无效的地址: %v", err)
+    }
+    switch h.Type {
+    case HostUnix:
+        if u.Scheme != "unix" || u.Path == "" {
+            return fmt.Errorf("unix 地址格式应为 unix:///var/run/docker.sock")
+        }
+    case HostTCP:
+        if u.Scheme != "tcp" || u.Host == "" {
+            return fmt.Errorf("tcp 地址格式应为 tcp://主机:端口")
+        }
+        if (h.TLSCert == "") != (h.TLSKey == "") {
+            return fmt.Errorf("客户端证书和私钥需要同时提供")
+        }
+    case HostSSH:
+        if u.Scheme != "ssh" || u.Host == "" || u.User == nil || u.User.Username() == "" {
+            return fmt.Errorf("ssh 地址格式应为 ssh://用户@主机[:端口]")
+        }
+        if h.SSHKey == "" && h.SSHPassword == "" {
+            return fmt.Errorf("ssh 连接需要私钥或密码")
+        }
+        if h.SSHKey != "" {
+            if _, err := ssh.ParsePrivateKey([]byte(h.SSHKey)); err != nil {
+                return fmt.Errorf("无法解析 ssh 私钥: %v", err)
+            }
+        }
+    default:
+        return fmt.Errorf("不支持的连接方式: %s", h.Type)
+    }
+    return nil
+}
+
+// UsesTLS 判断 TCP 连接是否启用 TLS
+func (h *Host) UsesTLS() bool {
+    return h.Type == HostTCP && (h.TLSVerify || h.TLSCA != "" || h.TLSCert != "")
+}
+
+// VerifiesTLS 判断是否校验服务端证书，提供了 CA 证书就意味着需要用它校验
+func (h *Host) VerifiesTLS() bool {
+    return h.TLSVerify || h.TLSCA != ""
+}
+
+// 连接配置的指纹，配置变化后缓存的 SSH 连接和转发会重建
+func (h *Host) fingerprint() string {
+    sum := sha256.New()
+    for _, part := range []string{h.Type, h.Address, h.TLSCA, h.TLSCert, h.TLSKey, fmt.Sprint(h.TLSVerify), h.SSHKey, h.SSHPassword, h.SSHHostKey} {
+        sum.Write([]byte(part))
+        sum.Write([]byte{0})
+    }
+    return hex.EncodeToString(sum.Sum(nil))
+}
+
+// NewClientForHost 创建连接到指定主机的客户端，host 为 nil 时连接本机
+func NewClientForHost(h *Host) (*Client, error) {
+    if h == nil || h.ID == 0 {
+        return NewDockerClient()
+    }
+
+    opts := []client.Opt{client.WithAPIVersionNegotiation()}
+    switch h.Type {
+    case HostUnix:
+        opts = append(opts, client.WithHost(h.Address))
+    case HostTCP:
+        if h.UsesTLS() {
+            tlsConfig, err := hostTLSConfig(h)
+            if err != nil {
+                return nil, err
+            }
+            httpClient := &http.Client{
+                Transport: &http.Transport{TLSClientConfig: tlsConfig},
+            }
+            opts = append(opts, client.WithHTTPClient(httpClient))
+        }
+        opts = append(opts, client.WithHost(h.Address))
+    case HostSSH:
+        // 通过 SSH 转发到远端的 Docker 套接字，HTTP 请求中的主机名不会被使用
+        opts = append(opts,
+            client.WithHost("http://docker.example.com"),
+            client.WithDialContext(func(ctx context.Context, network, addr string) (net.Conn, error) {
+                return dialSSHDocker(h)
+            }),
+        )
+    default:
+        return nil, fmt.Errorf("不支持的连接方式: %s", h.Type)
+    }
+
+    cli, err := client.NewClientWithOpts(opts...)
+    if err != nil {
+        return nil, err
+    }
+    return &Client{Client: cli}, nil
+}
+
+func hostTLSConfig(h *Host) (*tls.Config, error) {
+    config := &tls.Config{
+        MinVersion:         tls.VersionTLS12,
+        InsecureSkipVerify: !h.VerifiesTLS(),
+    }
+    if h.TLSCA != "" {
+        pool := x509.NewCertPool()
+        if !pool.AppendCertsFromPEM([]byte(h.TLSCA)) {
+            return nil, fmt.Errorf("无法解析 CA 证书")
+        }
+        config.RootCAs = pool
+    }
+    if h.TLSCert != "" {
+        cert, err := tls.X509KeyPair([]byte(h.TLSCert), []byte(h.TLSKey))
+        if err != nil {
+            return nil, fmt.Errorf("无法解析客户端证书: %v", err)
+        }
+        config.Certificates = []tls.Certificate{cert}
+    }
+    return config, nil
+}
+
+// SSH 连接按主机复用，一个 SSH 连接上可以打开多个到 Docker 套接字的通道
+type sshConn struct {
+    fingerprint string
+    client      *ssh.Client
+}
+
+var (
+    sshConnsMu sync.Mutex
+    sshConns   = make(map[int64]*sshConn)
+)
+
+// 打开一个到远端 Docker 套接字的通道，缓存的连接断开时重连一次
+func dialSSHDocker(h *Host) (net.Conn, error) {
+    socket := "/var/run/docker.sock"
+    if u, err := url.Parse(h.Address); err == nil && u.Path != "" && u.Path != "/" {
+        socket = u.Path
+    }
+
+    for attempt := 0; attempt < 2; attempt++ {
+        sshClient, err := sshClientFor(h)
+        if err != nil {
+            return nil, err
+        }
+        conn, err := sshClient.Dial("unix", socket)
+        if err == nil {
+            return conn, nil
+        }
+        dropSSHClient(h.ID, sshClient)
+        if attempt == 1 {
+            return nil, fmt.Errorf("通过 ssh 连接 %s 失败: %v", socket, err)
+        }
+    }
+    return nil, fmt.Errorf("通过 ssh 连接 %s 失败", socket)
+}
+
+func sshClientFor(h *Host) (*ssh.Client, error) {
+    fingerprint := h.fingerprint()
+
+    sshConnsMu.Lock()
+    defer sshConnsMu.Unlock()
+    if cached, ok := sshConns[h.ID]; ok {
+        if cached.fingerprint == fingerprint {
+            return cached.client, nil
+        }
+        cached.client.Close()
+        delete(sshConns, h.ID)
+    }
+
+    u, err := url.Parse(h.Address)
+    if err != nil {
+        return nil, err
+    }
+    addr := u.Host
+    if u.Port() == "" {
+        addr = net.JoinHostPort(u.Hostname(), "22")
+    }
+
+    var auth []ssh.AuthMethod
+    if h.SSHKey != "" {
+        signer, err := ssh.ParsePrivateKey([]byte(h.SSHKey))
+        if err != nil {
+            return nil, fmt.Errorf("无法解析 ssh 私钥: %v", err)
+        }
+        auth = append(auth, ssh.PublicKeys(signer))
+    }
+    if h.SSHPassword != "" {
+        auth = append(auth, ssh.Password(h.SSHPassword))
+    }
+
+    config := &ssh.ClientConfig{
+        User:            u.User.Username(),
+        Auth:            auth,
+        HostKeyCallback: hostKeyCallback(h),
+        Timeout:         10 * time.Second,
+    }
+    sshClient, err := ssh.Dial("tcp", addr, config)
+    if err != nil {
+        return nil, fmt.Errorf("ssh 连接 %s 失败: %v", addr, err)
+    }
+    sshConns[h.ID] = &sshConn{fingerprint: fingerprint, client: sshClient}
+    return sshClient, nil
+}
+
+// 服务端公钥必须与保存的公钥一致，尚未保存时拒绝连接，由管理员在测试连接时确认指纹
+func hostKeyCallback(h *Host) ssh.HostKeyCallback {
+    return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+        presented := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+        if h.SSHHostKey == "" {
+            return fmt.Errorf("尚未信任主机 %s 的公钥（%s），请在主机管理中测试连接并确认指纹", h.Name, ssh.FingerprintSHA256(key))
+        }
+        if presented != strings.TrimSpace(h.SSHHostKey) {
+            return fmt.Errorf("主机 %s 的公钥已变化（%s），可能存在中间人攻击，确认无误后请在主机设置中重置公钥", hostname, ssh.FingerprintSHA256(key))
+        }
+        return nil
+    }
+}
+
+func dropSSHClient(id int64, c *ssh.Client) {
+    sshConnsMu.Lock()
+    defer sshConnsMu.Unlock()
+    if cached, ok := sshConns[id]; ok && cached.client == c {
+        c.Close()
+        delete(sshConns, id)
+    }
+}
+
+// CloseHost 关闭主机的共享客户端、SSH 连接和本地转发，主机被修改或删除时调用
+func CloseHost(id int64) {
+    closeSharedClient(id)
+
+    sshConnsMu.Lock()
+    if cached, ok := sshConns[id]; ok {
+        cached.client.Close()
+        delete(sshConns, id)
+    }
+    sshConnsMu.Unlock()
+
+    forwardersMu.Lock()
+    if f, ok := forwarders[id]; ok {
+        f.listener.Close()
+        delete(forwarders, id)
+    }
+    forwardersMu.Unlock()
+}
+
+// 主机相关的运行时文件（compose 使用的证书、SSH 转发套接字）所在目录
+var hostsDir = filepath.Join("data", "hosts")
+
+// SetHostsDir 设置主机运行时文件目录
+func SetHostsDir(dir string) {
+    hostsDir = dir
+}
+
+// HostDir 返回主机的运行时文件目录
+func HostDir(id int64) string {
+    return filepath.Join(hostsDir, fmt.Sprint(id))
+}
+
+// ComposeEnvForHost 返回对指定主机执行 docker compose 时使用的环境变量。
+// TLS 证书写入主机目录；SSH 主机在本地开一个 unix 套接字转发到远端，
+// 这样 compose 不需要本机的 ssh 配置即可使用面板中保存的凭据
+func ComposeEnvForHost(h *Host) ([]string, error) {
+    env := ComposeEnv()
+    if h == nil || h.ID == 0 {
+        return env, nil
+    }
+
+    // 去掉面板进程自身的连接配置，避免与目标主机混用
+    filtered := env[:0]
+    for _, kv := range env {
+        if strings.HasPrefix(kv, "DOCKER_HOST=") || strings.HasPrefix(kv, "DOCKER_TLS_VERIFY=") ||
+            strings.HasPrefix(kv, "DOCKER_CERT_PATH=") || strings.HasPrefix(kv, "DOCKER_CONTEXT=") {
+            continue
+        }
+        filtered = append(filtered, kv)
+    }
+    env = filtered
+
+    dir := HostDir(h.ID)
+    if err := os.MkdirAll(dir, 0700); err != nil {
+        return nil, fmt.Errorf("创建主机目录失败: %v", err)
+    }
+
+    switch h.Type {
+    case HostUnix:
+        env = append(env, "DOCKER_HOST="+h.Address)
+    case HostTCP:
+        env = append(env, "DOCKER_HOST="+h.Address)
+        if h.UsesTLS() {
+            files := map[string]string{"ca.pem": h.TLSCA, "cert.pem": h.TLSCert, "key.pem": h.TLSKey}
+            for name, content := range files {
+                path := filepath.Join(dir, name)
+                if content == "" {
+                    os.Remove(path)
+                    continue
+                }
+                if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+                    return nil, fmt.Errorf("写入证书失败: %v", err)
+                }
+            }
+            env = append(env, "DOCKER_CERT_PATH="+dir)
+            if h.VerifiesTLS() {
+                env = append(env, "DOCKER_TLS_VERIFY=1")
+            }
+        }
+    case HostSSH:
+        socket, err := ensureSSHForwarder(h, dir)
+        if err != nil {
+            return nil, err
+        }
+        env = append(env, "DOCKER_HOST=unix://"+socket)
+    }
+    return env, nil
+}
+
+type sshForwarder struct {
+    fingerprint string
+    listener    net.Listener
+}
+
+var (
+    forwardersMu sync.Mutex
+    forwarders   = make(map[int64]*sshForwarder)
+)
+
+// 启动（或复用）SSH 主机的本地转发套接字
+func ensureSSHForwarder(h *Host, dir string) (string, error) {
+    socket := filepath.Join(dir, "docker.sock")
+    fingerprint := h.fingerprint()
+
+    forwardersMu.Lock()
+    defer forwardersMu.Unlock()
+    if f, ok := forwarders[h.ID]; ok {
+        if f.fingerprint == fingerprint {
+            return socket, nil
+        }
+        f.listener.Close()
+        delete(forwarders, h.ID)
+    }
+
+    os.Remove(socket)
+    listener, err := net.Listen("unix", socket)
+    if err != nil {
+        return "", fmt.Errorf("创建转发套接字失败: %v", err)
+    }
+    os.Chmod(socket, 0600)
+    forwarders[h.ID] = &sshForwarder{fingerprint: fingerprint, listener: listener}
+
+    go func() {
+        for {
+            local, err := listener.Accept()
+            if err != nil {
+                return
+            }
+            go func() {
+                defer local.Close()
+                remote, err := dialSSHDocker(h)
+                if err != nil {
+                    log.Printf("转发到主机 %s 失败: %v", h.Name, err)
+                    return
+                }
+                defer remote.Close()
+                done := make(chan struct{}, 2)
+                go func() { io.Copy(remote, local); done <- struct{}{} }()
+                go func() { io.Copy(local, remote); done <- struct{}{} }()
+                <-done
+            }()
+        }
+    }()
+    return socket, nil
+}
+
+// HostHealth 主机连通性与运行状态
+type HostHealth struct {
+    Reachable         bool     `json:"reachable"`
+    LatencyMs         int64    `json:"latencyMs"`
+    ServerVersion     string   `json:"serverVersion,omitempty"`
+    APIVersion        string   `json:"apiVersion,omitempty"`
+    OS                string   `json:"os,omitempty"`
+    Arch              string   `json:"arch,omitempty"`
+    Containers        int      `json:"containers"`
+    ContainersRunning int      `json:"containersRunning"`
+    Images            int      `json:"images"`
+    Warnings          []string `json:"warnings,omitempty"`
+    Error             string   `json:"error,omitempty"`
+    CheckedAt         string   `json:"checkedAt"`
+    // SSH 主机尚未信任公钥时返回服务端公钥及其指纹，供管理员确认
+    HostKey            string `json:"hostKey,omitempty"`
+    HostKeyFingerprint string `json:"hostKeyFingerprint,omitempty"`
+}
+
+// CheckHost 检查主机是否可以连接，并返回版本与容器数量
+func CheckHost(ctx context.Context, h *Host) HostHealth {
+    health := HostHealth{CheckedAt: time.Now().Format("2006-01-02 15:04:05")}
+
+    if h != nil && h.Type == HostSSH && h.SSHHostKey == "" {
+        key, fingerprint, err := ScanSSHHostKey(h)
+        if err != nil {
+            health.Error = err.Error()
+            return health
+        }
+        health.HostKey = key
+        health.HostKeyFingerprint = fingerprint
+        health.Error = "尚未信任主机公钥，请确认指纹 " + fingerprint
+        return health
+    }
+
+    cli, err := NewClientForHost(h)
+    if err != nil {
+        health.Error = err.Error()
+        return health
+    }
+    defer cli.Close()
+
+    start := time.Now()
+    ping, err := cli.Ping(ctx)
+    if err != nil {
+        health.Error = err.Error()
+        return health
+    }
+    health.Reachable = true
+    health.LatencyMs = time.Since(start).Milliseconds()
+    health.APIVersion = ping.APIVersion
+
+    info, err := cli.Info(ctx)
+    if err != nil {
+        health.Error = "获取主机信息失败: " + err.Error()
+        return health
+    }
+    health.ServerVersion = info.ServerVersion
+    health.OS = info.OperatingSystem
+    health.Arch = info.Architecture
+    health.Containers = info.Containers
+    health.ContainersRunning = info.ContainersRunning
+    health.Images = info.Images
+    health.Warnings = info.Warnings
+    return health
+}
+
+var errHostKeyScanned = errors.New("已获取主机公钥")
+
+// ScanSSHHostKey 连接 SSH 主机读取服务端公钥，返回 authorized_keys 格式的公钥及 SHA256 指纹。
+// 读到公钥后即中止握手，不会进行认证
+func ScanSSHHostKey(h *Host) (string, string, error) {
+    u, err := url.Parse(h.Address)
+    if err != nil {
+        return "", "", err
+    }
+    addr := u.Host
+    if u.Port() == "" {
+        addr = net.JoinHostPort(u.Hostname(), "22")
+    }
+
+    var key ssh.PublicKey
+    config := &ssh.ClientConfig{
+        User: u.User.Username(),
+        HostKeyCallback: func(hostname string, remote net.Addr, presented ssh.PublicKey) error {
+            key = presented
+            return errHostKeyScanned
+        },
+        Timeout: 10 * time.Second,
+    }
+    client, err := ssh.Dial("tcp", addr, config)
+    if err == nil {
+        client.Close()
+    }
+    if key == nil {
+        return "", "", fmt.Errorf("获取主机 %s 的公钥失败: %v", addr, err)
+    }
+    return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key))), ssh.FingerprintSHA256(key), nil
+}
@@ -0,0 +1,97 @@
+package docker
+
+import (
+    "fmt"
+    "os"
+    "strconv"
+    "strings"
+
+    "github.com/docker/docker/api/types/container"
+    "github.com/docker/go-units"
+)
+
+// LogConfig 容器日志配置，对应 daemon.json 的 log-driver / log-opts 及容器的 --log-driver / --log-opt
+type LogConfig struct {
+    Driver  string            `json:"driver" yaml:"driver"`
+    Options map[string]string `json:"options,omitempty" yaml:"options"`
+}
+
+// 支持 max-size / max-file 轮转选项的驱动
+func rotatingDriver(driver string) bool {
+    return driver == "" || driver == "json-file" || driver == "local"
+}
+
+// ParseLogSize 解析 max-size 格式的大小，例如 10m、10mb、1g、500k，不带单位时按字节。
+// 与 dockerd 一致使用 units.RAMInBytes，单位按 1024 进制
+func ParseLogSize(s string) (int64, error) {
+    s = strings.TrimSpace(s)
+    if s == "" {
+        return 0, fmt.Errorf("大小不能为空")
+    }
+
+    n, err := units.RAMInBytes(s)
+    if err != nil || n <= 0 {
+        return 0, fmt.Errorf("无效的大小: %s", s)
+    }
+    return n, nil
+}
+
+// Validate 校验日志配置中的轮转选项
+func (l LogConfig) Validate() error {
+    if strings.ContainsAny(l.Driver, " \t") {
+        return fmt.Errorf("无效的日志驱动: %s", l.Driver)
+    }
+    for key, value := range l.Options {
+        switch key {
+        case "max-size":
+            if !rotatingDriver(l.Driver) {
+                return fmt.Errorf("日志驱动 %s 不支持 max-size", l.Driver)
+            }
+            if _, err := ParseLogSize(value); err != nil {
+                return fmt.Errorf("max-size %v", err)
+            }
+        case "max-file":
+            if !rotatingDriver(l.Driver) {
+                return fmt.Errorf("日志驱动 %s 不支持 max-file", l.Driver)
+            }
+            n, err := strconv.Atoi(value)
+            if err != nil || n < 1 {
+                return fmt.Errorf("max-file 必须为正整数")
+            }
+        case "compress":
+            if _, err := strconv.ParseBool(value); err != nil {
+                return fmt.Errorf("compress 必须为 true 或 false")
+            }
+        }
+    }
+    return nil
+}
+
+// HostLogConfig 转换为创建容器使用的日志配置，驱动为空时使用守护进程默认值
+func (l LogConfig) HostLogConfig() container.LogConfig {
+    return container.LogConfig{
+        Type:   l.Driver,
+        Config: l.Options,
+    }
+}
+
+// ContainerLogSize 返回 json-file 日志文件的大小，包括轮转出的历史文件。
+// 面板与 Docker 不在同一文件系统（例如面板运行在容器中）时返回错误
+func ContainerLogSize(logPath string) (int64, error) {
+    if logPath == "" {
+        return 0, fmt.Errorf("日志路径为空")
+    }
+    info, err := os.Stat(logPath)
+    if err != nil {
+        return 0, err
+    }
+    size := info.Size()
+    for i := 1; ; i++ {
+        rotated, err := os.Stat(fmt.Sprintf("%s.%d", logPath, i))
+        if err != nil {
+            break
+        }
+        size += rotated.Size()
+    }
+    return size, nil
+}
@@ -0,0 +1,49 @@
+package docker
+
+import "testing"
+
+func TestParseLogSize(t *testing.T) {
+    tests := []struct {
+        input string
+        want  int64
+        ok    bool
+    }{
+        {"1024", 1024, true},
+        {"500k", 500 * 1024, true},
+        {"10m", 10 << 20, true},
+        {"10mb", 10 << 20, true},
+        {"10MB", 10 << 20, true},
+        {"1g", 1 << 30, true},
+        {" 1gb ", 1 << 30, true},
+        {"", 0, false},
+        {"ten", 0, false},
+        {"0", 0, false},
+        {"-1m", 0, false},
+    }
+    for _, tt := range tests {
+        got, err := ParseLogSize(tt.input)
+        if (err == nil) != tt.ok || got != tt.want {
+            t.Errorf("ParseLogSize(%q) = %d, %v, 期望 %d", tt.input, got, err, tt.want)
+        }
+    }
+}
+
+func TestLogConfigValidate(t *testing.T) {
+    tests := []struct {
+        name   string
+        config LogConfig
+        ok     bool
+    }{
+        {"默认驱动轮转", LogConfig{Options: map[string]string{"max-size": "10mb", "max-file": "3"}}, true},
+        {"local 驱动压缩", LogConfig{Driver: "local", Options: map[string]string{"compress": "true"}}, true},
+        {"大小无效", LogConfig{Driver: "json-file", Options: map[string]string{"max-size": "ten"}}, false},
+        {"文件数无效", LogConfig{Driver: "json-file", Options: map[string]string{"max-file": "0"}}, false},
+        {"驱动不支持轮转", LogConfig{Driver: "syslog", Options: map[string]string{"max-size": "10m"}}, false},
+        {"驱动名称含空格", LogConfig{Driver: "json file"}, false},
+    }
+    for _, tt := range tests {
+        if err := tt.config.Validate(); (err == nil) != tt.ok {
+            t.Errorf("%s: Validate() = %v", tt.name, err)
+        }
+    }
+}
@@ -0,0 +1,221 @@
+package docker
+
+import (
+    "context"
+    "log"
+    "sync"
+    "time"
+
+    "github.com/docker/docker/api/types"
+    "github.com/docker/docker/api/types/events"
+)
+
+// 每个主机共用一个长期存在的客户端，底层 HTTP 连接在请求之间复用。
+// 同时为每个主机监听 Docker 事件，用于让容器详情缓存失效并推送给浏览器
+type managedClient struct {
+    fingerprint string
+    client      *Client
+    cancel      context.CancelFunc
+}
+
+var (
+    managedMu sync.Mutex
+    managed   = make(map[int64]*managedClient)
+)
+
+// 容器详情缓存的最长有效期，事件流中断期间缓存不生效，这里只作为兜底
+const inspectCacheTTL = 2 * time.Minute
+
+// 替换下来的共享客户端延迟关闭，给仍在使用它的请求留出时间
+const retiredClientGrace = 5 * time.Minute
+
+// SharedClient 返回指定主机的共享客户端，host 为 nil 时为本机。
+// 共享客户端由面板统一管理，调用方不需要也不应该关闭
+func SharedClient(h *Host) (*Client, error) {
+    var id int64
+    fingerprint := ""
+    if h != nil {
+        id = h.ID
+        fingerprint = h.fingerprint()
+    }
+
+    managedMu.Lock()
+    defer managedMu.Unlock()
+    if m, ok := managed[id]; ok {
+        if m.fingerprint == fingerprint {
+            return m.client, nil
+        }
+        // 连接配置已修改，停止旧客户端的事件监听后重建
+        m.retire()
+        delete(managed, id)
+    }
+
+    cli, err := NewClientForHost(h)
+    if err != nil {
+        return nil, err
+    }
+    cli.shared = true
+    cli.cache = newInspectCache()
+
+    ctx, cancel := context.WithCancel(context.Background())
+    managed[id] = &managedClient{fingerprint: fingerprint, client: cli, cancel: cancel}
+    go cli.watchEvents(ctx, id)
+    return cli, nil
+}
+
+// 关闭主机的共享客户端，主机被修改或删除时调用
+func closeSharedClient(id int64) {
+    managedMu.Lock()
+    defer managedMu.Unlock()
+    if m, ok := managed[id]; ok {
+        m.retire()
+        delete(managed, id)
+    }
+}
+
+// retire 立即停止事件监听，处理中的请求可能仍持有该客户端，连接在宽限期后再关闭
+func (m *managedClient) retire() {
+    m.cancel()
+    cli := m.client
+    time.AfterFunc(retiredClientGrace, func() {
+        cli.Client.Close()
+    })
+}
+
+// 监听主机的 Docker 事件：容器事件使缓存失效，所有事件转交给 BroadcastFunc。
+// 事件流中断期间缓存停用并清空，避免使用断开期间已经过期的数据
+func (c *Client) watchEvents(ctx context.Context, hostID int64) {
+    WatchDockerEvents(ctx, c, func(event events.Message) {
+        if event.Type == events.ContainerEventType {
+            c.cache.invalidate(event.Actor.ID)
+        }
+        if BroadcastFunc != nil {
+            BroadcastFunc(hostID, event)
+        }
+    }, func(connected bool, err error) {
+        c.cache.reset(connected)
+        if StreamStatusFunc != nil {
+            StreamStatusFunc(hostID, connected, err)
+        }
+    })
+}
+
+type inspectEntry struct {
+    inspect types.ContainerJSON
+    expires time.Time
+}
+
+// 容器详情缓存，只在事件监听正常时生效
+type inspectCache struct {
+    mu      sync.RWMutex
+    active  bool
+    entries map[string]inspectEntry
+}
+
+func newInspectCache() *inspectCache {
+    return &inspectCache{entries: make(map[string]inspectEntry)}
+}
+
+func (c *inspectCache) get(id string) (types.ContainerJSON, bool) {
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+    entry, ok := c.entries[id]
+    if !ok || !c.active || time.Now().After(entry.expires) {
+        return types.ContainerJSON{}, false
+    }
+    return entry.inspect, true
+}
+
+func (c *inspectCache) put(id string, inspect types.ContainerJSON) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    if !c.active {
+        return
+    }
+    c.entries[id] = inspectEntry{inspect: inspect, expires: time.Now().Add(inspectCacheTTL)}
+}
+
+// 容器事件中的 ID 为完整 ID，缓存同样以完整 ID 为键
+func (c *inspectCache) invalidate(id string) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    delete(c.entries, id)
+}
+
+func (c *inspectCache) reset(active bool) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.active = active
+    c.entries = make(map[string]inspectEntry)
+}
+
+// InspectContainer 获取容器详情，共享客户端在事件监听正常时使用缓存。
+// 按名称或短 ID 查询时不使用缓存，结果按完整 ID 缓存
+func (c *Client) InspectContainer(ctx context.Context, id string) (types.ContainerJSON, error) {
+    if c.cache != nil {
+        if inspect, ok := c.cache.get(id); ok {
+            return inspect, nil
+        }
+    }
+    inspect, err := c.ContainerInspect(ctx, id)
+    if err != nil {
+        return inspect, err
+    }
+    if c.cache != nil && inspect.ContainerJSONBase != nil && inspect.ID == id {
+        c.cache.put(id, inspect)
+    }
+    return inspect, nil
+}
+
+// 并发访问 Docker 接口时的最大并发数
+const maxConcurrency = 8
+
+// InspectContainers 以有限并发获取多个容器的详情，获取失败的容器不在结果中
+func (c *Client) InspectContainers(ctx context.Context, ids []string) map[string]types.ContainerJSON {
+    result := make(map[string]types.ContainerJSON, len(ids))
+    var mu sync.Mutex
+    forEachLimit(len(ids), maxConcurrency, func(i int) {
+        inspect, err := c.InspectContainer(ctx, ids[i])
+        if err != nil {
+            log.Printf("获取容器 %s 详情失败: %v", ids[i], err)
+            return
+        }
+        mu.Lock()
+        result[ids[i]] = inspect
+        mu.Unlock()
+    })
+    return result
+}
+
+// InspectImages 以有限并发获取多个镜像的详情，获取失败的镜像不在结果中
+func (c *Client) InspectImages(ctx context.Context, ids []string) map[string]types.ImageInspect {
+    result := make(map[string]types.ImageInspect, len(ids))
+    var mu sync.Mutex
+    forEachLimit(len(ids), maxConcurrency, func(i int) {
+        inspect, _, err := c.ImageInspectWithRaw(ctx, ids[i])
+        if err != nil {
+            log.Printf("获取镜像 %s 详情失败: %v", ids[i], err)
+            return
+        }
+        mu.Lock()
+        result[ids[i]] = inspect
+        mu.Unlock()
+    })
+    return result
+}
+
+// forEachLimit 以最多 limit 个并发执行 fn(0..n-1)，全部完成后返回
+func forEachLimit(n, limit int, fn func(i int)) {
+    sem := make(chan struct{}, limit)
+    var wg sync.WaitGroup
+    for i := 0; i < n; i++ {
+        wg.Add(1)
+        sem <- struct{}{}
+        go func(i int) {
+            defer wg.Done()
+            defer func() { <-sem }()
+            fn(i)
+        }(i)
+    }
+    wg.Wait()
+}
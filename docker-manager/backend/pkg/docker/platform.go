@@ -0,0 +1,64 @@
+package docker
+
+import (
+    "fmt"
+    "strings"
+)
+
+// Platform 镜像平台，格式为 os/arch[/variant]，例如 linux/arm64、linux/arm/v7
+type Platform struct {
+    OS           string `json:"os"`
+    Architecture string `json:"architecture"`
+    Variant      string `json:"variant,omitempty"`
+}
+
+// ParsePlatform 解析平台字符串，并统一常见的架构别名
+func ParsePlatform(s string) (Platform, error) {
+    parts := strings.Split(strings.ToLower(strings.TrimSpace(s)), "/")
+    if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+        return Platform{}, fmt.Errorf("无效的平台格式 %q，应为 os/arch[/variant]", s)
+    }
+
+    p := Platform{OS: parts[0]}
+    p.Architecture, p.Variant = NormalizeArch(parts[1], "")
+    if len(parts) == 3 {
+        p.Variant = parts[2]
+    }
+    return p, nil
+}
+
+// NormalizeArch 将 x86_64、aarch64 等别名统一为 Docker 使用的架构名称
+func NormalizeArch(arch, variant string) (string, string) {
+    switch strings.ToLower(arch) {
+    case "x86_64", "x86-64", "amd64":
+        return "amd64", variant
+    case "aarch64", "arm64":
+        if variant == "8" || variant == "v8" {
+            variant = ""
+        }
+        return "arm64", variant
+    case "armhf":
+        return "arm", "v7"
+    case "armel":
+        return "arm", "v6"
+    case "i386", "i686", "386":
+        return "386", variant
+    }
+    return strings.ToLower(arch), variant
+}
+
+// Matches 判断镜像的 os/arch/variant 是否属于该平台，未指定 variant 时不比较 variant
+func (p Platform) Matches(os, arch, variant string) bool {
+    arch, variant = NormalizeArch(arch, variant)
+    if !strings.EqualFold(p.OS, os) || p.Architecture != arch {
+        return false
+    }
+    return p.Variant == "" || p.Variant == variant
+}
+
+func (p Platform) String() string {
+    if p.Variant != "" {
+        return p.OS + "/" + p.Architecture + "/" + p.Variant
+    }
+    return p.OS + "/" + p.Architecture
+}
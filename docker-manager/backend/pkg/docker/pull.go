@@ -0,0 +1,333 @@
+package docker
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "log"
+    "strings"
+    "sync"
+
+    "github.com/docker/docker/api/types"
+    "github.com/docker/docker/pkg/jsonmessage"
+)
+
+// LayerProgress 单个镜像层的进度
+type LayerProgress struct {
+    ID      string `json:"id"`
+    Status  string `json:"status"`
+    Current int64  `json:"current"`
+    Total   int64  `json:"total"`
+}
+
+// Progress 汇总后的拉取/推送进度
+type Progress struct {
+    Image   string          `json:"image"`
+    Status  string          `json:"status"`
+    Percent float64         `json:"percent"`
+    Current int64           `json:"current"`
+    Total   int64           `json:"total"`
+    Layers  []LayerProgress `json:"layers"`
+    Done    bool            `json:"done"`
+    Error   string          `json:"error,omitempty"`
+}
+
+// ProgressAggregator 将 Docker 返回的 JSON 消息流按层汇总为整体进度
+type ProgressAggregator struct {
+    image  string
+    status string
+    order  []string
+    layers map[string]*LayerProgress
+}
+
+func NewProgressAggregator(image string) *ProgressAggregator {
+    return &ProgressAggregator{
+        image:  image,
+        layers: make(map[string]*LayerProgress),
+    }
+}
+
+// Add 处理一条进度消息，消息中包含错误时返回该错误
+func (a *ProgressAggregator) Add(msg jsonmessage.JSONMessage) error {
+    if msg.Error != nil {
+        return msg.Error
+    }
+    if msg.ErrorMessage != "" {
+        return fmt.Errorf("%s", msg.ErrorMessage)
+    }
+
+    // 没有层 ID 的消息是整体状态，例如 "Pulling from library/nginx"、"Digest: ..."
+    if msg.ID == "" || !isLayerStatus(msg.Status) {
+        if msg.Status != "" {
+            a.status = msg.Status
+            if msg.ID != "" {
+                a.status = msg.ID + ": " + msg.Status
+            }
+        }
+        return nil
+    }
+
+    layer, ok := a.layers[msg.ID]
+    if !ok {
+        layer = &LayerProgress{ID: msg.ID}
+        a.layers[msg.ID] = layer
+        a.order = append(a.order, msg.ID)
+    }
+    layer.Status = msg.Status
+
+    switch msg.Status {
+    case "Downloading", "Pushing":
+        if msg.Progress != nil {
+            layer.Current = msg.Progress.Current
+            if msg.Progress.Total > 0 {
+                layer.Total = msg.Progress.Total
+            }
+        }
+    case "Verifying Checksum", "Download complete", "Extracting", "Pull complete",
+        "Pushed", "Layer already exists", "Already exists":
+        // 下载/上传阶段已结束，该层按完成计算
+        layer.Current = layer.Total
+    }
+    if strings.HasPrefix(msg.Status, "Mounted from") {
+        layer.Current = layer.Total
+    }
+
+    return nil
+}
+
+// Snapshot 返回当前汇总进度
+func (a *ProgressAggregator) Snapshot() Progress {
+    p := Progress{
+        Image:  a.image,
+        Status: a.status,
+        Layers: make([]LayerProgress, 0, len(a.order)),
+    }
+
+    finished := 0
+    for _, id := range a.order {
+        layer := a.layers[id]
+        p.Layers = append(p.Layers, *layer)
+        p.Current += layer.Current
+        p.Total += layer.Total
+        if isLayerFinished(layer.Status) {
+            finished++
+        }
+    }
+
+    switch {
+    case p.Total > 0:
+        p.Percent = float64(p.Current) / float64(p.Total) * 100
+    case len(a.order) > 0:
+        p.Percent = float64(finished) / float64(len(a.order)) * 100
+    }
+    if p.Percent > 100 {
+        p.Percent = 100
+    }
+    return p
+}
+
+func isLayerStatus(status string) bool {
+    switch status {
+    case "Pulling fs layer", "Waiting", "Downloading", "Verifying Checksum", "Download complete",
+        "Extracting", "Pull complete", "Already exists", "Preparing", "Pushing", "Pushed",
+        "Layer already exists":
+        return true
+    }
+    return strings.HasPrefix(status, "Mounted from") || strings.HasPrefix(status, "Retrying")
+}
+
+func isLayerFinished(status string) bool {
+    switch status {
+    case "Pull complete", "Already exists", "Pushed", "Layer already exists":
+        return true
+    }
+    return strings.HasPrefix(status, "Mounted from")
+}
+
+// ConsumeProgress 读取 Docker 进度流直至结束，每条消息处理后调用 onUpdate
+func ConsumeProgress(reader io.Reader, aggregator *ProgressAggregator, onUpdate func(Progress)) error {
+    decoder := json.NewDecoder(reader)
+    for {
+        var msg jsonmessage.JSONMessage
+        if err := decoder.Decode(&msg); err != nil {
+            if err == io.EOF {
+                return nil
+            }
+            return err
+        }
+        if err := aggregator.Add(msg); err != nil {
+            return err
+        }
+        onUpdate(aggregator.Snapshot())
+    }
+}
+
+// PullJob 一个正在进行的镜像拉取任务，同一镜像的并发拉取共享同一个任务
+type PullJob struct {
+    key         string
+    mu          sync.Mutex
+    progress    Progress
+    subscribers map[chan Progress]struct{}
+    cancel      context.CancelFunc
+    finished    bool
+    cancelled   bool // 所有订阅者都已退出，拉取正在取消，不能再加入
+}
+
+var (
+    pullJobsMu sync.Mutex
+    pullJobs   = make(map[string]*PullJob)
+)
+
+// SubscribePull 订阅镜像拉取进度，如已有同一主机上相同镜像的拉取任务则直接加入。
+// host 为 nil 时拉取到本机。
+// 返回的通道在任务结束后关闭，最后一条消息的 Done 为 true；
+// 调用 unsubscribe 退出订阅，所有订阅者退出时拉取会被取消。
+func SubscribePull(host *Host, image string, options types.ImagePullOptions) (<-chan Progress, func()) {
+    key := image
+    if options.Platform != "" {
+        key = image + "@" + options.Platform
+    }
+    if host != nil && host.ID != 0 {
+        key = fmt.Sprintf("%d/%s", host.ID, key)
+    }
+
+    ch := make(chan Progress, 1)
+
+    pullJobsMu.Lock()
+    job, ok := pullJobs[key]
+    if ok {
+        job.mu.Lock()
+        if job.finished || job.cancelled {
+            // 已取消的任务即将结束，断线重连时需要重新开始拉取
+            job.mu.Unlock()
+            ok = false
+        }
+    }
+    if !ok {
+        ctx, cancel := context.WithCancel(context.Background())
+        job = &PullJob{
+            key:         key,
+            progress:    Progress{Image: image, Status: "等待拉取"},
+            subscribers: make(map[chan Progress]struct{}),
+            cancel:      cancel,
+        }
+        pullJobs[key] = job
+        go job.run(ctx, host, image, options)
+        job.mu.Lock()
+    } else {
+        log.Printf("加入已有的镜像拉取任务: %s", key)
+    }
+    job.subscribers[ch] = struct{}{}
+    sendLatest(ch, job.progress)
+    job.mu.Unlock()
+    pullJobsMu.Unlock()
+
+    var once sync.Once
+    unsubscribe := func() {
+        once.Do(func() { job.unsubscribe(ch) })
+    }
+    return ch, unsubscribe
+}
+
+func (j *PullJob) run(ctx context.Context, host *Host, image string, options types.ImagePullOptions) {
+    defer j.cancel()
+
+    aggregator := NewProgressAggregator(image)
+    err := func() error {
+        cli, err := NewClientForHost(host)
+        if err != nil {
+            return err
+        }
+        defer cli.Close()
+
+        reader, err := cli.ImagePull(ctx, image, options)
+        if err != nil {
+            return err
+        }
+        defer reader.Close()
+
+        return ConsumeProgress(reader, aggregator, j.publish)
+    }()
+
+    final := aggregator.Snapshot()
+    final.Done = true
+    if err != nil {
+        if ctx.Err() != nil {
+            err = fmt.Errorf("拉取已取消")
+        }
+        log.Printf("拉取镜像 %s 失败: %v", image, err)
+        final.Error = err.Error()
+    } else {
+        final.Percent = 100
+        log.Printf("镜像拉取成功: %s", image)
+    }
+
+    j.forget()
+
+    j.mu.Lock()
+    j.finished = true
+    j.progress = final
+    for ch := range j.subscribers {
+        sendLatest(ch, final)
+        close(ch)
+    }
+    j.subscribers = nil
+    j.mu.Unlock()
+}
+
+func (j *PullJob) publish(p Progress) {
+    j.mu.Lock()
+    defer j.mu.Unlock()
+    j.progress = p
+    for ch := range j.subscribers {
+        sendLatest(ch, p)
+    }
+}
+
+func (j *PullJob) unsubscribe(ch chan Progress) {
+    j.mu.Lock()
+    if j.finished {
+        j.mu.Unlock()
+        return
+    }
+    delete(j.subscribers, ch)
+    cancelled := len(j.subscribers) == 0
+    if cancelled {
+        log.Printf("所有订阅者已断开，取消拉取: %s", j.key)
+        j.cancelled = true
+        j.cancel()
+    }
+    j.mu.Unlock()
+
+    // 加锁顺序为 pullJobsMu 在前，需在释放 j.mu 之后再移除任务
+    if cancelled {
+        j.forget()
+    }
+}
+
+// forget 从任务表中移除该任务，同一镜像已有新任务时不做处理
+func (j *PullJob) forget() {
+    pullJobsMu.Lock()
+    if pullJobs[j.key] == j {
+        delete(pullJobs, j.key)
+    }
+    pullJobsMu.Unlock()
+}
+
+// sendLatest 向缓冲为 1 的通道发送最新进度，丢弃尚未被读取的旧进度
+func sendLatest(ch chan Progress, p Progress) {
+    select {
+    case ch <- p:
+        return
+    default:
+    }
+    select {
+    case <-ch:
+    default:
+    }
+    select {
+    case ch <- p:
+    default:
+    }
+}
@@ -0,0 +1,158 @@
+package docker
+
+import (
+    "strings"
+    "testing"
+
+    "github.com/docker/docker/pkg/jsonmessage"
+)
+
+func TestProgressAggregator(t *testing.T) {
+    progress := func(current, total int64) *jsonmessage.JSONProgress {
+        return &jsonmessage.JSONProgress{Current: current, Total: total}
+    }
+
+    tests := []struct {
+        name     string
+        messages []jsonmessage.JSONMessage
+        status   string
+        current  int64
+        total    int64
+        percent  float64
+        layers   int
+    }{
+        {
+            name: "只有整体状态",
+            messages: []jsonmessage.JSONMessage{
+                {Status: "Pulling from library/nginx", ID: "latest"},
+            },
+            status: "latest: Pulling from library/nginx",
+        },
+        {
+            name: "按字节汇总下载进度",
+            messages: []jsonmessage.JSONMessage{
+                {ID: "a", Status: "Pulling fs layer"},
+                {ID: "b", Status: "Pulling fs layer"},
+                {ID: "a", Status: "Downloading", Progress: progress(50, 100)},
+                {ID: "b", Status: "Downloading", Progress: progress(100, 300)},
+            },
+            current: 150,
+            total:   400,
+            percent: 37.5,
+            layers:  2,
+        },
+        {
+            name: "下载完成后按层大小计入",
+            messages: []jsonmessage.JSONMessage{
+                {ID: "a", Status: "Downloading", Progress: progress(10, 100)},
+                {ID: "a", Status: "Download complete"},
+                {ID: "a", Status: "Extracting", Progress: progress(5, 100)},
+            },
+            current: 100,
+            total:   100,
+            percent: 100,
+            layers:  1,
+        },
+        {
+            name: "没有大小时按完成的层数计算",
+            messages: []jsonmessage.JSONMessage{
+                {ID: "a", Status: "Already exists"},
+                {ID: "b", Status: "Waiting"},
+                {ID: "c", Status: "Mounted from library/alpine"},
+                {ID: "d", Status: "Preparing"},
+            },
+            percent: 50,
+            layers:  4,
+        },
+        {
+            name: "后到的总大小为 0 时保留原值",
+            messages: []jsonmessage.JSONMessage{
+                {ID: "a", Status: "Downloading", Progress: progress(10, 200)},
+                {ID: "a", Status: "Downloading", Progress: progress(20, 0)},
+            },
+            current: 20,
+            total:   200,
+            percent: 10,
+            layers:  1,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            a := NewProgressAggregator("nginx")
+            for _, msg := range tt.messages {
+                if err := a.Add(msg); err != nil {
+                    t.Fatalf("Add(%+v) 返回错误: %v", msg, err)
+                }
+            }
+            p := a.Snapshot()
+            if p.Image != "nginx" {
+                t.Errorf("Image = %q, 期望 nginx", p.Image)
+            }
+            if tt.status != "" && p.Status != tt.status {
+                t.Errorf("Status = %q, 期望 %q", p.Status, tt.status)
+            }
+            if p.Current != tt.current || p.Total != tt.total {
+                t.Errorf("Current/Total = %d/%d, 期望 %d/%d", p.Current, p.Total, tt.current, tt.total)
+            }
+            if p.Percent != tt.percent {
+                t.Errorf("Percent = %v, 期望 %v", p.Percent, tt.percent)
+            }
+            if len(p.Layers) != tt.layers {
+                t.Errorf("层数 = %d, 期望 %d", len(p.Layers), tt.layers)
+            }
+        })
+    }
+}
+
+func TestProgressAggregatorKeepsLayerOrder(t *testing.T) {
+    a := NewProgressAggregator("nginx")
+    for _, id := range []string{"c", "a", "b", "a"} {
+        a.Add(jsonmessage.JSONMessage{ID: id, Status: "Waiting"})
+    }
+    var ids []string
+    for _, layer := range a.Snapshot().Layers {
+        ids = append(ids, layer.ID)
+    }
+    if got := strings.Join(ids, ","); got != "c,a,b" {
+        t.Errorf("层顺序 = %s, 期望 c,a,b", got)
+    }
+}
+
+func TestProgressAggregatorError(t *testing.T) {
+    a := NewProgressAggregator("nginx")
+    if err := a.Add(jsonmessage.JSONMessage{ErrorMessage: "manifest unknown"}); err == nil || err.Error() != "manifest unknown" {
+        t.Errorf("ErrorMessage 应作为错误返回, 得到 %v", err)
+    }
+    err := a.Add(jsonmessage.JSONMessage{Error: &jsonmessage.JSONError{Code: 1, Message: "denied"}})
+    if err == nil || !strings.Contains(err.Error(), "denied") {
+        t.Errorf("Error 应作为错误返回, 得到 %v", err)
+    }
+}
+
+func TestConsumeProgress(t *testing.T) {
+    stream := `{"status":"Pulling from library/nginx","id":"latest"}
+{"status":"Downloading","id":"a","progressDetail":{"current":5,"total":10}}
+{"status":"Pull complete","id":"a"}
+`
+    var updates []Progress
+    a := NewProgressAggregator("nginx")
+    if err := ConsumeProgress(strings.NewReader(stream), a, func(p Progress) { updates = append(updates, p) }); err != nil {
+        t.Fatalf("ConsumeProgress 返回错误: %v", err)
+    }
+    if len(updates) != 3 {
+        t.Fatalf("收到 %d 次更新, 期望 3", len(updates))
+    }
+    if last := updates[len(updates)-1]; last.Percent != 100 || last.Current != 10 {
+        t.Errorf("最后的进度 = %+v, 期望 100%%", last)
+    }
+
+    stream = `{"status":"Downloading","id":"a","progressDetail":{"current":5,"total":10}}
+{"errorDetail":{"message":"unexpected EOF"},"error":"unexpected EOF"}
+{"status":"Pull complete","id":"a"}
+`
+    err := ConsumeProgress(strings.NewReader(stream), NewProgressAggregator("nginx"), func(Progress) {})
+    if err == nil || !strings.Contains(err.Error(), "unexpected EOF") {
+        t.Errorf("流中的错误应中止读取, 得到 %v", err)
+    }
+}
@@ -0,0 +1,90 @@
+package docker
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "strings"
+
+    "github.com/docker/docker/api/types"
+    "github.com/docker/docker/api/types/container"
+    "github.com/docker/docker/api/types/network"
+)
+
+// RecreateContainer 按原容器的配置重新创建容器，logConfig 不为空时替换日志配置。
+// 旧容器先停止并重命名为 <name>_old 保留，新容器启动成功后才删除；
+// 任一步骤失败都会删除新容器、恢复旧容器名称并按原状态启动
+func (c *Client) RecreateContainer(ctx context.Context, old types.ContainerJSON, logConfig *container.LogConfig) (string, error) {
+    if old.ContainerJSONBase == nil || old.Config == nil || old.HostConfig == nil {
+        return "", fmt.Errorf("容器信息不完整")
+    }
+    name := strings.TrimPrefix(old.Name, "/")
+    backupName := name + "_old"
+    wasRunning := old.State != nil && old.State.Running
+
+    hostConfig := *old.HostConfig
+    if logConfig != nil {
+        hostConfig.LogConfig = *logConfig
+    }
+
+    // 只保留用户指定的网络设置，运行时分配的地址由 Docker 重新分配
+    networkingConfig := &network.NetworkingConfig{
+        EndpointsConfig: make(map[string]*network.EndpointSettings),
+    }
+    if old.NetworkSettings != nil {
+        for netName, endpoint := range old.NetworkSettings.Networks {
+            if endpoint == nil {
+                continue
+            }
+            networkingConfig.EndpointsConfig[netName] = &network.EndpointSettings{
+                IPAMConfig: endpoint.IPAMConfig,
+                Links:      endpoint.Links,
+                Aliases:    endpoint.Aliases,
+            }
+        }
+    }
+
+    if wasRunning {
+        if err := c.ContainerStop(ctx, old.ID, container.StopOptions{}); err != nil {
+            return "", fmt.Errorf("停止容器失败: %w", err)
+        }
+    }
+    if err := c.ContainerRename(ctx, old.ID, backupName); err != nil {
+        c.restoreOldContainer(ctx, old.ID, "", wasRunning)
+        return "", fmt.Errorf("重命名旧容器失败: %w", err)
+    }
+
+    resp, err := c.ContainerCreate(ctx, old.Config, &hostConfig, networkingConfig, nil, name)
+    if err != nil {
+        c.restoreOldContainer(ctx, old.ID, name, wasRunning)
+        return "", fmt.Errorf("创建容器失败: %w", err)
+    }
+
+    if wasRunning {
+        if err := c.ContainerStart(ctx, resp.ID, types.ContainerStartOptions{}); err != nil {
+            c.ContainerRemove(ctx, resp.ID, types.ContainerRemoveOptions{Force: true})
+            c.restoreOldContainer(ctx, old.ID, name, wasRunning)
+            return "", fmt.Errorf("启动新容器失败: %w", err)
+        }
+    }
+
+    if err := c.ContainerRemove(ctx, old.ID, types.ContainerRemoveOptions{}); err != nil {
+        // 新容器已经在运行，旧容器保留给用户手动处理
+        log.Printf("删除旧容器 %s 失败: %v", backupName, err)
+    }
+    return resp.ID, nil
+}
+
+// 回滚时恢复旧容器的名称和运行状态，name 为空表示无需改名
+func (c *Client) restoreOldContainer(ctx context.Context, id, name string, start bool) {
+    if name != "" {
+        if err := c.ContainerRename(ctx, id, name); err != nil {
+            log.Printf("恢复容器名称 %s 失败: %v", name, err)
+        }
+    }
+    if start {
+        if err := c.ContainerStart(ctx, id, types.ContainerStartOptions{}); err != nil {
+            log.Printf("重新启动旧容器 %s 失败: %v", id, err)
+        }
+    }
+}
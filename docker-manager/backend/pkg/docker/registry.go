@@ -0,0 +1,181 @@
+package docker
+
+import (
+    "context"
+    "crypto/tls"
+    "crypto/x509"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/url"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "golang.org/x/net/http/httpproxy"
+)
+
+// RegistryEndpoint 返回注册表 API 的基础地址，docker.io 映射到 Docker Hub 的实际地址
+func RegistryEndpoint(registryURL string) string {
+    u := strings.TrimRight(strings.TrimSpace(registryURL), "/")
+    switch u {
+    case "", "docker.io", "index.docker.io", "registry-1.docker.io":
+        return "https://registry-1.docker.io"
+    }
+    if strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://") {
+        return u
+    }
+    return "https://" + u
+}
+
+// TLSInfo 注册表证书检查结果
+type TLSInfo struct {
+    Enabled  bool      `json:"enabled"`
+    Valid    bool      `json:"valid"`
+    Subject  string    `json:"subject,omitempty"`
+    Issuer   string    `json:"issuer,omitempty"`
+    NotAfter time.Time `json:"notAfter,omitempty"`
+    Error    string    `json:"error,omitempty"`
+}
+
+// RegistryProbe 访问注册表 /v2/ 接口的结果
+type RegistryProbe struct {
+    URL           string  `json:"url"`
+    Reachable     bool    `json:"reachable"`
+    StatusCode    int     `json:"statusCode,omitempty"`
+    LatencyMs     int64   `json:"latencyMs"`
+    TLS           TLSInfo `json:"tls"`
+    AuthChallenge string  `json:"authChallenge,omitempty"` // 401 时返回的 WWW-Authenticate
+    Error         string  `json:"error,omitempty"`
+}
+
+// ProbeRegistry 请求 <endpoint>/v2/ 检查连通性、证书和认证方式。
+// transport 为 nil 时使用默认传输层，可传入带代理的传输层
+func ProbeRegistry(ctx context.Context, endpoint string, transport *http.Transport) RegistryProbe {
+    endpoint = RegistryEndpoint(endpoint)
+    result := RegistryProbe{URL: endpoint + "/v2/"}
+
+    if transport == nil {
+        transport = http.DefaultTransport.(*http.Transport).Clone()
+    }
+    client := &http.Client{
+        Transport: transport,
+        Timeout:   15 * time.Second,
+        // 只关心 /v2/ 本身的响应
+        CheckRedirect: func(req *http.Request, via []*http.Request) error {
+            return http.ErrUseLastResponse
+        },
+    }
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, result.URL, nil)
+    if err != nil {
+        result.Error = err.Error()
+        return result
+    }
+
+    start := time.Now()
+    resp, err := client.Do(req)
+    result.LatencyMs = time.Since(start).Milliseconds()
+    result.TLS.Enabled = strings.HasPrefix(endpoint, "https://")
+    if err != nil {
+        result.Error = err.Error()
+        if isCertificateError(err) {
+            // 能建立连接但证书无效
+            result.Reachable = true
+            result.TLS.Error = err.Error()
+        }
+        return result
+    }
+    defer resp.Body.Close()
+
+    result.Reachable = true
+    result.StatusCode = resp.StatusCode
+    if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+        cert := resp.TLS.PeerCertificates[0]
+        result.TLS.Valid = true
+        result.TLS.Subject = cert.Subject.CommonName
+        result.TLS.Issuer = cert.Issuer.CommonName
+        result.TLS.NotAfter = cert.NotAfter
+    }
+    if resp.StatusCode == http.StatusUnauthorized {
+        result.AuthChallenge = resp.Header.Get("WWW-Authenticate")
+    }
+    return result
+}
+
+func isCertificateError(err error) bool {
+    var unknownAuthority x509.UnknownAuthorityError
+    var hostnameErr x509.HostnameError
+    var invalidErr x509.CertificateInvalidError
+    var verifyErr *tls.CertificateVerificationError
+    return errors.As(err, &unknownAuthority) || errors.As(err, &hostnameErr) ||
+        errors.As(err, &invalidErr) || errors.As(err, &verifyErr)
+}
+
+// ProxyTransport 创建使用指定代理的传输层，代理均为空时直连
+func ProxyTransport(httpProxy, httpsProxy, noProxy string) (*http.Transport, error) {
+    transport := http.DefaultTransport.(*http.Transport).Clone()
+    for _, p := range []string{httpProxy, httpsProxy} {
+        if p == "" {
+            continue
+        }
+        if u, err := url.Parse(p); err != nil || u.Host == "" {
+            return nil, fmt.Errorf("无效的代理地址: %s", p)
+        }
+    }
+
+    config := &httpproxy.Config{
+        HTTPProxy:  httpProxy,
+        HTTPSProxy: httpsProxy,
+        NoProxy:    noProxy,
+    }
+    proxyFunc := config.ProxyFunc()
+    transport.Proxy = func(req *http.Request) (*url.URL, error) {
+        return proxyFunc(req.URL)
+    }
+    return transport, nil
+}
+
+// MirrorProbe 镜像加速地址的测试结果
+type MirrorProbe struct {
+    RegistryProbe
+    Mirror string `json:"mirror"`
+    Usable bool   `json:"usable"` // 可访问、证书有效且 /v2/ 返回 200 或 401
+    Rank   int    `json:"rank"`   // 按延迟排序的名次，不可用时为 0
+}
+
+// ProbeMirrors 并发测试多个镜像加速地址，可用的按延迟从低到高排在前面
+func ProbeMirrors(ctx context.Context, mirrors []string, transport *http.Transport) []MirrorProbe {
+    results := make([]MirrorProbe, len(mirrors))
+    var wg sync.WaitGroup
+    for i, mirror := range mirrors {
+        wg.Add(1)
+        go func(i int, mirror string) {
+            defer wg.Done()
+            probe := ProbeRegistry(ctx, mirror, transport)
+            results[i] = MirrorProbe{
+                RegistryProbe: probe,
+                Mirror:        mirror,
+                Usable: probe.Reachable && probe.Error == "" && probe.TLS.Error == "" &&
+                    (probe.StatusCode == http.StatusOK || probe.StatusCode == http.StatusUnauthorized),
+            }
+        }(i, mirror)
+    }
+    wg.Wait()
+
+    sort.SliceStable(results, func(i, j int) bool {
+        if results[i].Usable != results[j].Usable {
+            return results[i].Usable
+        }
+        return results[i].Usable && results[i].LatencyMs < results[j].LatencyMs
+    })
+    rank := 0
+    for i := range results {
+        if results[i].Usable {
+            rank++
+            results[i].Rank = rank
+        }
+    }
+    return results
+}
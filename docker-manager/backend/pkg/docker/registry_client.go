@@ -0,0 +1,507 @@
+package docker
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// 清单媒体类型
+const (
+    MediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+    MediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+    MediaTypeOCIManifest        = "application/vnd.oci.image.manifest.v1+json"
+    MediaTypeOCIIndex           = "application/vnd.oci.image.index.v1+json"
+)
+
+var manifestAccept = strings.Join([]string{
+    MediaTypeOCIIndex,
+    MediaTypeDockerManifestList,
+    MediaTypeOCIManifest,
+    MediaTypeDockerManifest,
+}, ", ")
+
+// ErrRegistryDeleteDisabled 注册表未开启删除功能（registry:2 需设置 REGISTRY_STORAGE_DELETE_ENABLED）
+var ErrRegistryDeleteDisabled = errors.New("注册表不允许删除镜像")
+
+// RegistryError 注册表 API 返回的错误
+type RegistryError struct {
+    StatusCode int
+    Code       string
+    Message    string
+}
+
+func (e *RegistryError) Error() string {
+    if e.Code != "" {
+        return fmt.Sprintf("注册表返回错误 %d %s: %s", e.StatusCode, e.Code, e.Message)
+    }
+    return fmt.Sprintf("注册表返回错误 %d: %s", e.StatusCode, e.Message)
+}
+
+// RegistryClient 注册表 v2 API 客户端，支持 Basic 和 Bearer Token 认证
+type RegistryClient struct {
+    endpoint string
+    hub      bool
+    username string
+    password string
+    client   *http.Client
+
+    mu     sync.Mutex
+    tokens map[string]string // scope -> token
+    basic  bool              // 注册表要求 Basic 认证
+}
+
+// NewRegistryClient 创建注册表客户端，registryURL 与 registries 表中的 url 字段格式一致
+func NewRegistryClient(registryURL, username, password string) *RegistryClient {
+    endpoint := RegistryEndpoint(registryURL)
+    return &RegistryClient{
+        endpoint: endpoint,
+        hub:      endpoint == "https://registry-1.docker.io",
+        username: username,
+        password: password,
+        client:   &http.Client{Timeout: 30 * time.Second},
+        tokens:   make(map[string]string),
+    }
+}
+
+// ManifestLayer 镜像层
+type ManifestLayer struct {
+    Digest    string `json:"digest"`
+    MediaType string `json:"mediaType"`
+    Size      int64  `json:"size"`
+}
+
+// ManifestInfo 标签对应的清单信息。清单列表的各平台清单放在 Manifests 中
+type ManifestInfo struct {
+    Repository string          `json:"repository"`
+    Reference  string          `json:"reference"`
+    Digest     string          `json:"digest"`
+    MediaType  string          `json:"mediaType"`
+    Size       int64           `json:"size"` // 配置与所有层的压缩大小之和
+    Platform   *Platform       `json:"platform,omitempty"`
+    Created    string          `json:"created,omitempty"`
+    Layers     []ManifestLayer `json:"layers,omitempty"`
+    Manifests  []ManifestInfo  `json:"manifests,omitempty"`
+}
+
+type manifestDescriptor struct {
+    MediaType string `json:"mediaType"`
+    Digest    string `json:"digest"`
+    Size      int64  `json:"size"`
+    Platform  *struct {
+        OS           string `json:"os"`
+        Architecture string `json:"architecture"`
+        Variant      string `json:"variant"`
+    } `json:"platform"`
+}
+
+type rawManifest struct {
+    MediaType string               `json:"mediaType"`
+    Config    manifestDescriptor   `json:"config"`
+    Layers    []manifestDescriptor `json:"layers"`
+    Manifests []manifestDescriptor `json:"manifests"`
+}
+
+// Catalog 列出仓库，n 为每页数量，last 为上一页最后一个仓库名。
+// 返回的 next 非空时表示还有下一页
+func (r *RegistryClient) Catalog(ctx context.Context, last string, n int) ([]string, string, error) {
+    if r.hub {
+        return nil, "", fmt.Errorf("Docker Hub 不支持列出仓库")
+    }
+
+    query := url.Values{}
+    if n > 0 {
+        query.Set("n", strconv.Itoa(n))
+    }
+    if last != "" {
+        query.Set("last", last)
+    }
+
+    var body struct {
+        Repositories []string `json:"repositories"`
+    }
+    resp, err := r.getJSON(ctx, "/v2/_catalog?"+query.Encode(), "registry:catalog:*", "", &body)
+    if err != nil {
+        return nil, "", err
+    }
+    if body.Repositories == nil {
+        body.Repositories = []string{}
+    }
+    return body.Repositories, nextLast(resp.Header.Get("Link")), nil
+}
+
+// Tags 列出仓库的所有标签，自动处理分页
+func (r *RegistryClient) Tags(ctx context.Context, repo string) ([]string, error) {
+    repo = r.repository(repo)
+    tags := []string{}
+    path := "/v2/" + repo + "/tags/list?n=1000"
+    for page := 0; path != "" && page < 100; page++ {
+        var body struct {
+            Tags []string `json:"tags"`
+        }
+        resp, err := r.getJSON(ctx, path, pullScope(repo), "", &body)
+        if err != nil {
+            return nil, err
+        }
+        tags = append(tags, body.Tags...)
+
+        path = ""
+        if last := nextLast(resp.Header.Get("Link")); last != "" {
+            path = "/v2/" + repo + "/tags/list?n=1000&last=" + url.QueryEscape(last)
+        }
+    }
+    return tags, nil
+}
+
+// Manifest 获取标签或摘要对应的清单，清单列表会逐个读取各平台清单以计算大小
+func (r *RegistryClient) Manifest(ctx context.Context, repo, reference string) (*ManifestInfo, error) {
+    repo = r.repository(repo)
+    info, raw, err := r.fetchManifest(ctx, repo, reference)
+    if err != nil {
+        return nil, err
+    }
+
+    switch info.MediaType {
+    case MediaTypeDockerManifestList, MediaTypeOCIIndex:
+        for _, desc := range raw.Manifests {
+            // 跳过 buildx 生成的证明清单
+            if desc.Platform != nil && desc.Platform.OS == "unknown" {
+                continue
+            }
+            child, _, err := r.fetchManifest(ctx, repo, desc.Digest)
+            if err != nil {
+                return nil, fmt.Errorf("读取平台清单 %s 失败: %v", desc.Digest, err)
+            }
+            if desc.Platform != nil {
+                arch, variant := NormalizeArch(desc.Platform.Architecture, desc.Platform.Variant)
+                child.Platform = &Platform{OS: desc.Platform.OS, Architecture: arch, Variant: variant}
+            }
+            info.Manifests = append(info.Manifests, *child)
+        }
+    default:
+        // 单平台清单的平台信息保存在配置 blob 中
+        var config struct {
+            OS           string `json:"os"`
+            Architecture string `json:"architecture"`
+            Variant      string `json:"variant"`
+            Created      string `json:"created"`
+        }
+        if raw.Config.Digest != "" {
+            if _, err := r.getJSON(ctx, "/v2/"+repo+"/blobs/"+raw.Config.Digest, pullScope(repo), "", &config); err == nil {
+                arch, variant := NormalizeArch(config.Architecture, config.Variant)
+                info.Platform = &Platform{OS: config.OS, Architecture: arch, Variant: variant}
+                info.Created = config.Created
+            }
+        }
+    }
+    return info, nil
+}
+
+// DeleteTag 删除标签对应的清单。注册表按摘要删除，同一摘要的其他标签也会一并删除
+func (r *RegistryClient) DeleteTag(ctx context.Context, repo, tag string) (string, error) {
+    repo = r.repository(repo)
+    scope := "repository:" + repo + ":pull,push,delete"
+
+    resp, err := r.do(ctx, http.MethodHead, "/v2/"+repo+"/manifests/"+tag, scope, manifestAccept)
+    if err != nil {
+        return "", err
+    }
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return "", &RegistryError{StatusCode: resp.StatusCode, Message: "获取清单摘要失败"}
+    }
+    digest := resp.Header.Get("Docker-Content-Digest")
+    if digest == "" {
+        return "", fmt.Errorf("注册表未返回清单摘要")
+    }
+
+    resp, err = r.do(ctx, http.MethodDelete, "/v2/"+repo+"/manifests/"+digest, scope, "")
+    if err != nil {
+        return "", err
+    }
+    defer resp.Body.Close()
+    switch resp.StatusCode {
+    case http.StatusAccepted, http.StatusOK:
+        return digest, nil
+    case http.StatusMethodNotAllowed:
+        return "", ErrRegistryDeleteDisabled
+    }
+    return "", readRegistryError(resp)
+}
+
+func (r *RegistryClient) fetchManifest(ctx context.Context, repo, reference string) (*ManifestInfo, *rawManifest, error) {
+    resp, err := r.do(ctx, http.MethodGet, "/v2/"+repo+"/manifests/"+reference, pullScope(repo), manifestAccept)
+    if err != nil {
+        return nil, nil, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return nil, nil, readRegistryError(resp)
+    }
+
+    var raw rawManifest
+    if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+        return nil, nil, fmt.Errorf("解析清单失败: %v", err)
+    }
+
+    info := &ManifestInfo{
+        Repository: repo,
+        Reference:  reference,
+        Digest:     resp.Header.Get("Docker-Content-Digest"),
+        MediaType:  raw.MediaType,
+    }
+    if info.MediaType == "" {
+        info.MediaType = strings.TrimSpace(strings.Split(resp.Header.Get("Content-Type"), ";")[0])
+    }
+    if len(raw.Layers) > 0 {
+        info.Size = raw.Config.Size
+        info.Layers = make([]ManifestLayer, 0, len(raw.Layers))
+        for _, layer := range raw.Layers {
+            info.Size += layer.Size
+            info.Layers = append(info.Layers, ManifestLayer{Digest: layer.Digest, MediaType: layer.MediaType, Size: layer.Size})
+        }
+    }
+    return info, &raw, nil
+}
+
+// repository Docker Hub 的官方镜像需要加上 library/ 前缀
+func (r *RegistryClient) repository(repo string) string {
+    repo = strings.Trim(repo, "/")
+    if r.hub && !strings.Contains(repo, "/") {
+        return "library/" + repo
+    }
+    return repo
+}
+
+func (r *RegistryClient) getJSON(ctx context.Context, path, scope, accept string, v interface{}) (*http.Response, error) {
+    resp, err := r.do(ctx, http.MethodGet, path, scope, accept)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return nil, readRegistryError(resp)
+    }
+    if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+        return nil, fmt.Errorf("解析注册表响应失败: %v", err)
+    }
+    return resp, nil
+}
+
+// do 发送请求，收到 401 时按 WWW-Authenticate 获取凭据后重试一次
+func (r *RegistryClient) do(ctx context.Context, method, path, scope, accept string) (*http.Response, error) {
+    resp, err := r.send(ctx, method, path, scope, accept)
+    if err != nil {
+        return nil, err
+    }
+    if resp.StatusCode != http.StatusUnauthorized {
+        return resp, nil
+    }
+
+    challenge := resp.Header.Get("WWW-Authenticate")
+    io.Copy(io.Discard, resp.Body)
+    resp.Body.Close()
+
+    scheme, params := parseChallenge(challenge)
+    switch scheme {
+    case "bearer":
+        // 注册表在质询中给出的 scope 比我们推测的更准确
+        tokenScope := scope
+        if s := params["scope"]; s != "" {
+            tokenScope = s
+        }
+        token, err := r.fetchToken(ctx, params["realm"], params["service"], tokenScope)
+        if err != nil {
+            return nil, err
+        }
+        r.mu.Lock()
+        r.tokens[scope] = token
+        r.mu.Unlock()
+    case "basic":
+        if r.username == "" {
+            return nil, &RegistryError{StatusCode: http.StatusUnauthorized, Message: "注册表需要登录"}
+        }
+        r.mu.Lock()
+        r.basic = true
+        r.mu.Unlock()
+    default:
+        return nil, &RegistryError{StatusCode: http.StatusUnauthorized, Message: "不支持的认证方式: " + challenge}
+    }
+
+    resp, err = r.send(ctx, method, path, scope, accept)
+    if err != nil {
+        return nil, err
+    }
+    if resp.StatusCode == http.StatusUnauthorized {
+        defer resp.Body.Close()
+        return nil, readRegistryError(resp)
+    }
+    return resp, nil
+}
+
+func (r *RegistryClient) send(ctx context.Context, method, path, scope, accept string) (*http.Response, error) {
+    req, err := http.NewRequestWithContext(ctx, method, r.endpoint+path, nil)
+    if err != nil {
+        return nil, err
+    }
+    if accept != "" {
+        req.Header.Set("Accept", accept)
+    }
+
+    r.mu.Lock()
+    token, basic := r.tokens[scope], r.basic
+    r.mu.Unlock()
+    switch {
+    case token != "":
+        req.Header.Set("Authorization", "Bearer "+token)
+    case basic:
+        req.SetBasicAuth(r.username, r.password)
+    }
+
+    resp, err := r.client.Do(req)
+    if err != nil {
+        return nil, fmt.Errorf("请求注册表失败: %v", err)
+    }
+    return resp, nil
+}
+
+// fetchToken 向认证服务申请访问令牌，配置了用户名时使用 Basic 认证
+func (r *RegistryClient) fetchToken(ctx context.Context, realm, service, scope string) (string, error) {
+    if realm == "" {
+        return "", fmt.Errorf("认证质询缺少 realm")
+    }
+    u, err := url.Parse(realm)
+    if err != nil {
+        return "", fmt.Errorf("无效的认证地址: %v", err)
+    }
+    query := u.Query()
+    if service != "" {
+        query.Set("service", service)
+    }
+    for _, s := range strings.Split(scope, " ") {
+        if s != "" {
+            query.Add("scope", s)
+        }
+    }
+    u.RawQuery = query.Encode()
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+    if err != nil {
+        return "", err
+    }
+    if r.username != "" {
+        req.SetBasicAuth(r.username, r.password)
+    }
+    resp, err := r.client.Do(req)
+    if err != nil {
+        return "", fmt.Errorf("获取访问令牌失败: %v", err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return "", readRegistryError(resp)
+    }
+
+    var body struct {
+        Token       string `json:"token"`
+        AccessToken string `json:"access_token"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+        return "", fmt.Errorf("解析访问令牌失败: %v", err)
+    }
+    if body.Token != "" {
+        return body.Token, nil
+    }
+    if body.AccessToken != "" {
+        return body.AccessToken, nil
+    }
+    return "", fmt.Errorf("认证服务未返回访问令牌")
+}
+
+func pullScope(repo string) string {
+    return "repository:" + repo + ":pull"
+}
+
+// parseChallenge 解析 WWW-Authenticate，例如
+// Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/nginx:pull"
+func parseChallenge(header string) (string, map[string]string) {
+    params := make(map[string]string)
+    header = strings.TrimSpace(header)
+    idx := strings.IndexByte(header, ' ')
+    if idx < 0 {
+        return strings.ToLower(header), params
+    }
+    scheme := strings.ToLower(header[:idx])
+    rest := header[idx+1:]
+
+    for rest != "" {
+        rest = strings.TrimLeft(rest, " ,")
+        eq := strings.IndexByte(rest, '=')
+        if eq < 0 {
+            break
+        }
+        key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+        rest = rest[eq+1:]
+
+        var value string
+        if strings.HasPrefix(rest, `"`) {
+            end := strings.IndexByte(rest[1:], '"')
+            if end < 0 {
+                value, rest = rest[1:], ""
+            } else {
+                value, rest = rest[1:end+1], rest[end+2:]
+            }
+        } else {
+            end := strings.IndexByte(rest, ',')
+            if end < 0 {
+                value, rest = rest, ""
+            } else {
+                value, rest = rest[:end], rest[end+1:]
+            }
+        }
+        params[key] = strings.TrimSpace(value)
+    }
+    return scheme, params
+}
+
+// nextLast 从分页的 Link 头中取出下一页的 last 参数，例如 </v2/_catalog?last=b&n=100>; rel="next"
+func nextLast(link string) string {
+    if link == "" || !strings.Contains(link, `rel="next"`) {
+        return ""
+    }
+    start := strings.IndexByte(link, '<')
+    end := strings.IndexByte(link, '>')
+    if start < 0 || end <= start {
+        return ""
+    }
+    u, err := url.Parse(link[start+1 : end])
+    if err != nil {
+        return ""
+    }
+    return u.Query().Get("last")
+}
+
+func readRegistryError(resp *http.Response) error {
+    data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+    var body struct {
+        Errors []struct {
+            Code    string `json:"code"`
+            Message string `json:"message"`
+        } `json:"errors"`
+    }
+    if err := json.Unmarshal(data, &body); err == nil && len(body.Errors) > 0 {
+        return &RegistryError{StatusCode: resp.StatusCode, Code: body.Errors[0].Code, Message: body.Errors[0].Message}
+    }
+    message := strings.TrimSpace(string(data))
+    if message == "" {
+        message = http.StatusText(resp.StatusCode)
+    }
+    return &RegistryError{StatusCode: resp.StatusCode, Message: message}
+}
@@ -0,0 +1,281 @@
+package docker
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+// fakeRegistry 一个最小的 v2 注册表：Bearer 令牌认证，令牌服务校验 Basic 凭据
+type fakeRegistry struct {
+    server        *httptest.Server
+    deleteEnabled bool
+    deleted       []string
+}
+
+const (
+    fakeUser  = "alice"
+    fakePass  = "secret"
+    fakeToken = "token-123"
+)
+
+func newFakeRegistry(t *testing.T) *fakeRegistry {
+    f := &fakeRegistry{deleteEnabled: true}
+    mux := http.NewServeMux()
+
+    mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+        user, pass, ok := r.BasicAuth()
+        if !ok || user != fakeUser || pass != fakePass {
+            w.WriteHeader(http.StatusUnauthorized)
+            json.NewEncoder(w).Encode(map[string]interface{}{
+                "errors": []map[string]string{{"code": "UNAUTHORIZED", "message": "bad credentials"}},
+            })
+            return
+        }
+        if r.URL.Query().Get("service") != "fake" {
+            t.Errorf("令牌请求缺少 service 参数: %s", r.URL.RawQuery)
+        }
+        json.NewEncoder(w).Encode(map[string]string{"token": fakeToken})
+    })
+
+    mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+        if r.Header.Get("Authorization") != "Bearer "+fakeToken {
+            w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="fake"`, f.server.URL))
+            w.WriteHeader(http.StatusUnauthorized)
+            return
+        }
+        f.serveV2(w, r)
+    })
+
+    f.server = httptest.NewServer(mux)
+    t.Cleanup(f.server.Close)
+    return f
+}
+
+func (f *fakeRegistry) serveV2(w http.ResponseWriter, r *http.Request) {
+    path := strings.TrimPrefix(r.URL.Path, "/v2/")
+    switch {
+    case path == "_catalog":
+        if r.URL.Query().Get("last") == "" {
+            w.Header().Set("Link", `</v2/_catalog?last=b&n=2>; rel="next"`)
+            json.NewEncoder(w).Encode(map[string][]string{"repositories": {"a", "b"}})
+            return
+        }
+        json.NewEncoder(w).Encode(map[string][]string{"repositories": {"c"}})
+
+    case path == "app/tags/list":
+        if r.URL.Query().Get("last") == "" {
+            w.Header().Set("Link", `</v2/app/tags/list?last=v2&n=1000>; rel="next"`)
+            json.NewEncoder(w).Encode(map[string]interface{}{"name": "app", "tags": []string{"v1", "v2"}})
+            return
+        }
+        json.NewEncoder(w).Encode(map[string]interface{}{"name": "app", "tags": []string{"v3"}})
+
+    case path == "app/manifests/multi":
+        w.Header().Set("Docker-Content-Digest", "sha256:list")
+        json.NewEncoder(w).Encode(map[string]interface{}{
+            "mediaType": MediaTypeOCIIndex,
+            "manifests": []map[string]interface{}{
+                {"digest": "sha256:amd64", "platform": map[string]string{"os": "linux", "architecture": "amd64"}},
+                {"digest": "sha256:arm64", "platform": map[string]string{"os": "linux", "architecture": "aarch64"}},
+                {"digest": "sha256:attest", "platform": map[string]string{"os": "unknown", "architecture": "unknown"}},
+            },
+        })
+
+    case path == "app/manifests/sha256:amd64", path == "app/manifests/sha256:arm64", path == "app/manifests/single":
+        if r.Method == http.MethodHead || path == "app/manifests/single" {
+            w.Header().Set("Docker-Content-Digest", "sha256:single")
+        } else {
+            w.Header().Set("Docker-Content-Digest", strings.TrimPrefix(path, "app/manifests/"))
+        }
+        if r.Method == http.MethodHead {
+            return
+        }
+        json.NewEncoder(w).Encode(map[string]interface{}{
+            "mediaType": MediaTypeDockerManifest,
+            "config":    map[string]interface{}{"digest": "sha256:config", "size": 100},
+            "layers": []map[string]interface{}{
+                {"digest": "sha256:l1", "size": 1000},
+                {"digest": "sha256:l2", "size": 2000},
+            },
+        })
+
+    case path == "app/blobs/sha256:config":
+        json.NewEncoder(w).Encode(map[string]string{
+            "os": "linux", "architecture": "arm", "variant": "v7", "created": "2024-01-01T00:00:00Z",
+        })
+
+    case path == "app/manifests/sha256:single" && r.Method == http.MethodDelete:
+        if !f.deleteEnabled {
+            w.WriteHeader(http.StatusMethodNotAllowed)
+            return
+        }
+        f.deleted = append(f.deleted, "sha256:single")
+        w.WriteHeader(http.StatusAccepted)
+
+    default:
+        w.WriteHeader(http.StatusNotFound)
+        json.NewEncoder(w).Encode(map[string]interface{}{
+            "errors": []map[string]string{{"code": "MANIFEST_UNKNOWN", "message": "manifest unknown"}},
+        })
+    }
+}
+
+func TestRegistryClientCatalogAndTags(t *testing.T) {
+    f := newFakeRegistry(t)
+    client := NewRegistryClient(f.server.URL, fakeUser, fakePass)
+    ctx := context.Background()
+
+    repos, next, err := client.Catalog(ctx, "", 2)
+    if err != nil {
+        t.Fatalf("Catalog 返回错误: %v", err)
+    }
+    if !reflect.DeepEqual(repos, []string{"a", "b"}) || next != "b" {
+        t.Errorf("第一页 = %v, next = %q", repos, next)
+    }
+    repos, next, err = client.Catalog(ctx, next, 2)
+    if err != nil {
+        t.Fatalf("Catalog 返回错误: %v", err)
+    }
+    if !reflect.DeepEqual(repos, []string{"c"}) || next != "" {
+        t.Errorf("第二页 = %v, next = %q", repos, next)
+    }
+
+    tags, err := client.Tags(ctx, "app")
+    if err != nil {
+        t.Fatalf("Tags 返回错误: %v", err)
+    }
+    if !reflect.DeepEqual(tags, []string{"v1", "v2", "v3"}) {
+        t.Errorf("Tags = %v, 应自动翻页", tags)
+    }
+}
+
+func TestRegistryClientManifest(t *testing.T) {
+    f := newFakeRegistry(t)
+    client := NewRegistryClient(f.server.URL, fakeUser, fakePass)
+    ctx := context.Background()
+
+    single, err := client.Manifest(ctx, "app", "single")
+    if err != nil {
+        t.Fatalf("Manifest 返回错误: %v", err)
+    }
+    if single.Digest != "sha256:single" || single.Size != 3100 || len(single.Layers) != 2 {
+        t.Errorf("单平台清单 = %+v", single)
+    }
+    if single.Platform == nil || single.Platform.Architecture != "arm" || single.Platform.Variant != "v7" {
+        t.Errorf("平台信息应从配置 blob 读取: %+v", single.Platform)
+    }
+
+    list, err := client.Manifest(ctx, "app", "multi")
+    if err != nil {
+        t.Fatalf("Manifest 返回错误: %v", err)
+    }
+    if list.MediaType != MediaTypeOCIIndex || len(list.Manifests) != 2 {
+        t.Fatalf("清单列表应包含 2 个平台（跳过证明清单）: %+v", list)
+    }
+    if arch := list.Manifests[1].Platform.Architecture; arch != "arm64" {
+        t.Errorf("aarch64 应规范为 arm64, 得到 %s", arch)
+    }
+
+    _, err = client.Manifest(ctx, "app", "missing")
+    var regErr *RegistryError
+    if !errors.As(err, &regErr) || regErr.StatusCode != http.StatusNotFound || regErr.Code != "MANIFEST_UNKNOWN" {
+        t.Errorf("不存在的标签应返回 404 RegistryError, 得到 %v", err)
+    }
+}
+
+func TestRegistryClientDeleteTag(t *testing.T) {
+    f := newFakeRegistry(t)
+    client := NewRegistryClient(f.server.URL, fakeUser, fakePass)
+
+    digest, err := client.DeleteTag(context.Background(), "app", "single")
+    if err != nil {
+        t.Fatalf("DeleteTag 返回错误: %v", err)
+    }
+    if digest != "sha256:single" || !reflect.DeepEqual(f.deleted, []string{"sha256:single"}) {
+        t.Errorf("应按摘要删除: digest = %s, deleted = %v", digest, f.deleted)
+    }
+
+    f.deleteEnabled = false
+    if _, err := client.DeleteTag(context.Background(), "app", "single"); !errors.Is(err, ErrRegistryDeleteDisabled) {
+        t.Errorf("未开启删除时应返回 ErrRegistryDeleteDisabled, 得到 %v", err)
+    }
+}
+
+func TestRegistryClientBadCredentials(t *testing.T) {
+    f := newFakeRegistry(t)
+    client := NewRegistryClient(f.server.URL, fakeUser, "wrong")
+
+    _, err := client.Tags(context.Background(), "app")
+    var regErr *RegistryError
+    if !errors.As(err, &regErr) || regErr.StatusCode != http.StatusUnauthorized || regErr.Code != "UNAUTHORIZED" {
+        t.Errorf("错误的凭据应返回 401 RegistryError, 得到 %v", err)
+    }
+}
+
+func TestRegistryClientBasicAuth(t *testing.T) {
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        user, pass, ok := r.BasicAuth()
+        if !ok || user != fakeUser || pass != fakePass {
+            w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+            w.WriteHeader(http.StatusUnauthorized)
+            return
+        }
+        json.NewEncoder(w).Encode(map[string][]string{"repositories": {"x"}})
+    }))
+    defer server.Close()
+
+    repos, _, err := NewRegistryClient(server.URL, fakeUser, fakePass).Catalog(context.Background(), "", 0)
+    if err != nil || !reflect.DeepEqual(repos, []string{"x"}) {
+        t.Errorf("Basic 认证: repos = %v, err = %v", repos, err)
+    }
+
+    _, _, err = NewRegistryClient(server.URL, "", "").Catalog(context.Background(), "", 0)
+    var regErr *RegistryError
+    if !errors.As(err, &regErr) || regErr.StatusCode != http.StatusUnauthorized {
+        t.Errorf("未配置凭据时应返回 401, 得到 %v", err)
+    }
+}
+
+func TestParseChallenge(t *testing.T) {
+    tests := []struct {
+        header string
+        scheme string
+        params map[string]string
+    }{
+        {
+            `Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/nginx:pull"`,
+            "bearer",
+            map[string]string{"realm": "https://auth.docker.io/token", "service": "registry.docker.io", "scope": "repository:library/nginx:pull"},
+        },
+        {`Basic realm="Registry Realm"`, "basic", map[string]string{"realm": "Registry Realm"}},
+        {`Bearer realm=https://r/token, service=r`, "bearer", map[string]string{"realm": "https://r/token", "service": "r"}},
+        {`Basic`, "basic", map[string]string{}},
+    }
+    for _, tt := range tests {
+        scheme, params := parseChallenge(tt.header)
+        if scheme != tt.scheme || !reflect.DeepEqual(params, tt.params) {
+            t.Errorf("parseChallenge(%q) = %s %v, 期望 %s %v", tt.header, scheme, params, tt.scheme, tt.params)
+        }
+    }
+}
+
+func TestNextLast(t *testing.T) {
+    tests := map[string]string{
+        `</v2/_catalog?last=b&n=100>; rel="next"`:         "b",
+        `</v2/app/tags/list?n=10&last=v%2B1>; rel="next"`: "v+1",
+        `</v2/_catalog?last=b>; rel="prev"`:               "",
+        ``:                                                "",
+    }
+    for link, want := range tests {
+        if got := nextLast(link); got != want {
+            t.Errorf("nextLast(%q) = %q, 期望 %q", link, got, want)
+        }
+    }
+}
@@ -0,0 +1,140 @@
+package docker
+
+import (
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "sync"
+    "testing"
+)
+
+func TestRegistryEndpoint(t *testing.T) {
+    tests := map[string]string{
+        "":                      "https://registry-1.docker.io",
+        "docker.io":             "https://registry-1.docker.io",
+        "index.docker.io/":      "https://registry-1.docker.io",
+        "harbor.example.com":    "https://harbor.example.com",
+        "http://10.0.0.1:5000/": "http://10.0.0.1:5000",
+        " https://ghcr.io ":     "https://ghcr.io",
+    }
+    for input, want := range tests {
+        if got := RegistryEndpoint(input); got != want {
+            t.Errorf("RegistryEndpoint(%q) = %s, 期望 %s", input, got, want)
+        }
+    }
+}
+
+func TestProxyTransportSelectsProxy(t *testing.T) {
+    transport, err := ProxyTransport("http://proxy.example.test:3128", "http://secure-proxy.example.test:3128", "internal.example.test,.corp.test")
+    if err != nil {
+        t.Fatalf("ProxyTransport 返回错误: %v", err)
+    }
+
+    tests := []struct {
+        target string
+        proxy  string
+    }{
+        {"http://registry.example.test/v2/", "proxy.example.test:3128"},
+        {"https://registry.example.test/v2/", "secure-proxy.example.test:3128"},
+        {"https://internal.example.test/v2/", ""},
+        {"https://hub.corp.test/v2/", ""},
+    }
+    for _, tt := range tests {
+        req, _ := http.NewRequest(http.MethodGet, tt.target, nil)
+        u, err := transport.Proxy(req)
+        if err != nil {
+            t.Fatalf("Proxy(%s) 返回错误: %v", tt.target, err)
+        }
+        got := ""
+        if u != nil {
+            got = u.Host
+        }
+        if got != tt.proxy {
+            t.Errorf("Proxy(%s) = %q, 期望 %q", tt.target, got, tt.proxy)
+        }
+    }
+}
+
+func TestProxyTransportIgnoresEnvironment(t *testing.T) {
+    t.Setenv("HTTPS_PROXY", "http://env-proxy.example.test:8080")
+    t.Setenv("HTTP_PROXY", "http://env-proxy.example.test:8080")
+
+    transport, err := ProxyTransport("", "", "")
+    if err != nil {
+        t.Fatal(err)
+    }
+    req, _ := http.NewRequest(http.MethodGet, "https://registry.example.test/v2/", nil)
+    if u, _ := transport.Proxy(req); u != nil {
+        t.Errorf("未配置代理时应直连, 得到 %s", u)
+    }
+}
+
+func TestProxyTransportInvalidProxy(t *testing.T) {
+    for _, proxy := range []string{"proxy.example.test:3128", "http://", "://bad"} {
+        if _, err := ProxyTransport(proxy, "", ""); err == nil {
+            t.Errorf("ProxyTransport(%q) 应返回错误", proxy)
+        }
+    }
+}
+
+// 代理服务器同时充当被访问的注册表，按请求的目标主机返回不同结果
+func TestProbeMirrorsThroughProxy(t *testing.T) {
+    var mu sync.Mutex
+    var hosts []string
+    proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        mu.Lock()
+        hosts = append(hosts, r.URL.Host)
+        mu.Unlock()
+        if r.URL.Path != "/v2/" {
+            w.WriteHeader(http.StatusNotFound)
+            return
+        }
+        switch r.URL.Host {
+        case "mirror-auth.example.test":
+            w.Header().Set("WWW-Authenticate", `Bearer realm="https://auth.example.test/token"`)
+            w.WriteHeader(http.StatusUnauthorized)
+        case "mirror-open.example.test":
+            w.WriteHeader(http.StatusOK)
+        default:
+            w.WriteHeader(http.StatusBadGateway)
+        }
+    }))
+    defer proxy.Close()
+
+    transport, err := ProxyTransport(proxy.URL, proxy.URL, "")
+    if err != nil {
+        t.Fatal(err)
+    }
+    mirrors := []string{
+        "http://mirror-broken.example.test",
+        "http://mirror-auth.example.test",
+        "http://mirror-open.example.test/",
+    }
+    results := ProbeMirrors(context.Background(), mirrors, transport)
+    if len(results) != 3 {
+        t.Fatalf("结果数量 = %d, 期望 3", len(results))
+    }
+
+    byMirror := make(map[string]MirrorProbe)
+    for _, r := range results {
+        byMirror[r.Mirror] = r
+    }
+    if r := byMirror["http://mirror-auth.example.test"]; !r.Usable || r.StatusCode != 401 || r.AuthChallenge == "" || r.Rank == 0 {
+        t.Errorf("需要认证的加速地址 = %+v", r)
+    }
+    if r := byMirror["http://mirror-open.example.test/"]; !r.Usable || r.URL != "http://mirror-open.example.test/v2/" || r.Rank == 0 {
+        t.Errorf("公开的加速地址 = %+v", r)
+    }
+    if r := byMirror["http://mirror-broken.example.test"]; r.Usable || r.Rank != 0 || r.StatusCode != 502 {
+        t.Errorf("不可用的加速地址 = %+v", r)
+    }
+    if results[2].Mirror != "http://mirror-broken.example.test" {
+        t.Errorf("不可用的地址应排在最后, 得到 %+v", results)
+    }
+
+    mu.Lock()
+    defer mu.Unlock()
+    if len(hosts) != 3 {
+        t.Errorf("代理收到 %d 个请求, 期望 3: %v", len(hosts), hosts)
+    }
+}
@@ -0,0 +1,115 @@
+package docker
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "os"
+    "os/exec"
+    "time"
+
+    systemd "github.com/coreos/go-systemd/v22/dbus"
+)
+
+const (
+    // 自定义重启命令环境变量，设置后不再通过 systemd 重启，例如 "service docker restart"
+    restartCommandEnv = "DOCKERPANEL_DOCKER_RESTART_CMD"
+    // 自定义重载命令环境变量，例如 "kill -HUP $(pidof dockerd)"
+    reloadCommandEnv = "DOCKERPANEL_DOCKER_RELOAD_CMD"
+    // systemd 单元名称环境变量，默认 docker.service
+    systemdUnitEnv = "DOCKERPANEL_DOCKER_UNIT"
+)
+
+// 守护进程操作方式
+const (
+    DaemonRestart = "restart"
+    // reload 只会让 dockerd 重新读取支持热加载的配置项，例如 registry-mirrors、debug、labels
+    DaemonReload = "reload"
+)
+
+// RestartDaemon 重启或重载 Docker 守护进程。
+// 配置了自定义命令时执行该命令，否则通过 D-Bus 调用 systemd
+func RestartDaemon(ctx context.Context, mode string) error {
+    if mode != DaemonRestart && mode != DaemonReload {
+        return fmt.Errorf("不支持的操作: %s", mode)
+    }
+
+    envName := restartCommandEnv
+    if mode == DaemonReload {
+        envName = reloadCommandEnv
+    }
+    if command := os.Getenv(envName); command != "" {
+        log.Printf("执行自定义命令%s Docker: %s", modeText(mode), command)
+        output, err := exec.CommandContext(ctx, "sh", "-c", command).CombinedOutput()
+        if err != nil {
+            return fmt.Errorf("执行 %s 失败: %v %s", command, err, string(output))
+        }
+        return nil
+    }
+
+    unit := os.Getenv(systemdUnitEnv)
+    if unit == "" {
+        unit = "docker.service"
+    }
+
+    conn, err := systemd.NewSystemConnectionContext(ctx)
+    if err != nil {
+        return fmt.Errorf("连接 systemd 失败，可设置 %s 使用自定义命令: %v", envName, err)
+    }
+    defer conn.Close()
+
+    done := make(chan string, 1)
+    if mode == DaemonReload {
+        _, err = conn.ReloadUnitContext(ctx, unit, "replace", done)
+    } else {
+        _, err = conn.RestartUnitContext(ctx, unit, "replace", done)
+    }
+    if err != nil {
+        return fmt.Errorf("%s %s 失败: %v", modeText(mode), unit, err)
+    }
+
+    log.Printf("已请求 systemd %s %s", modeText(mode), unit)
+    select {
+    case result := <-done:
+        if result != "done" {
+            return fmt.Errorf("%s %s 失败: %s", modeText(mode), unit, result)
+        }
+        return nil
+    case <-ctx.Done():
+        return fmt.Errorf("等待 %s %s 超时", unit, modeText(mode))
+    }
+}
+
+// WaitForDaemon 轮询 Docker 套接字直到守护进程响应或超时
+func WaitForDaemon(ctx context.Context) error {
+    ticker := time.NewTicker(time.Second)
+    defer ticker.Stop()
+
+    var lastErr error
+    for {
+        cli, err := NewDockerClient()
+        if err == nil {
+            pingCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+            _, err = cli.Ping(pingCtx)
+            cancel()
+            cli.Close()
+            if err == nil {
+                return nil
+            }
+        }
+        lastErr = err
+
+        select {
+        case <-ctx.Done():
+            return fmt.Errorf("等待 Docker 恢复超时: %v", lastErr)
+        case <-ticker.C:
+        }
+    }
+}
+
+func modeText(mode string) string {
+    if mode == DaemonReload {
+        return "重载"
+    }
+    return "重启"
+}
@@ -0,0 +1,105 @@
+package docker
+
+import (
+    "context"
+    "encoding/json"
+    "log"
+    "sync"
+
+    "github.com/docker/docker/api/types"
+)
+
+// 获取容器统计时的最大并发数。守护进程每秒统一采样一次，
+// 同一时刻的多个请求会在同一轮采样中返回，所以这里比其他接口允许更高的并发
+const statsConcurrency = 32
+
+// ContainerStatsOnce 获取一次容器资源统计，读取后立即关闭响应体
+func (c *Client) ContainerStatsOnce(ctx context.Context, id string) (types.StatsJSON, error) {
+    var stats types.StatsJSON
+    resp, err := c.ContainerStats(ctx, id, false)
+    if err != nil {
+        return stats, err
+    }
+    defer resp.Body.Close()
+
+    err = json.NewDecoder(resp.Body).Decode(&stats)
+    return stats, err
+}
+
+// CollectStats 以有限并发获取多个容器的资源统计，获取失败的容器不在结果中
+func (c *Client) CollectStats(ctx context.Context, ids []string) map[string]types.StatsJSON {
+    result := make(map[string]types.StatsJSON, len(ids))
+    var mu sync.Mutex
+    forEachLimit(len(ids), statsConcurrency, func(i int) {
+        stats, err := c.ContainerStatsOnce(ctx, ids[i])
+        if err != nil {
+            if ctx.Err() == nil {
+                log.Printf("获取容器 %s 统计信息失败: %v", ids[i], err)
+            }
+            return
+        }
+        mu.Lock()
+        result[ids[i]] = stats
+        mu.Unlock()
+    })
+    return result
+}
+
+// CPUPercent 计算两次采样之间的 CPU 使用率，100% 表示占满一个核心。
+// cgroup v2 下没有 PercpuUsage，使用 OnlineCPUs
+func CPUPercent(stats *types.StatsJSON) float64 {
+    cpuDelta := float64(stats.CPUStats.CPUUsage.TotalUsage) - float64(stats.PreCPUStats.CPUUsage.TotalUsage)
+    systemDelta := float64(stats.CPUStats.SystemUsage) - float64(stats.PreCPUStats.SystemUsage)
+    cpus := float64(stats.CPUStats.OnlineCPUs)
+    if cpus == 0 {
+        cpus = float64(len(stats.CPUStats.CPUUsage.PercpuUsage))
+    }
+    if systemDelta <= 0 || cpuDelta <= 0 {
+        return 0
+    }
+    return cpuDelta / systemDelta * cpus * 100.0
+}
+
+// MemoryUsage 返回容器实际使用的内存，与 docker stats 一致，不计入可回收的文件缓存
+func MemoryUsage(stats *types.StatsJSON) float64 {
+    usage := float64(stats.MemoryStats.Usage)
+    // cgroup v1 为 total_inactive_file，cgroup v2 为 inactive_file
+    if v, ok := stats.MemoryStats.Stats["total_inactive_file"]; ok && float64(v) < usage {
+        return usage - float64(v)
+    }
+    if v, ok := stats.MemoryStats.Stats["inactive_file"]; ok && float64(v) < usage {
+        return usage - float64(v)
+    }
+    return usage
+}
+
+// MemoryPercent 返回内存使用量占限制的百分比
+func MemoryPercent(stats *types.StatsJSON) float64 {
+    limit := float64(stats.MemoryStats.Limit)
+    if limit <= 0 {
+        return 0
+    }
+    return MemoryUsage(stats) / limit * 100.0
+}
+
+// NetworkIO 汇总所有网卡的收发字节数
+func NetworkIO(stats *types.StatsJSON) (rx, tx uint64) {
+    for _, n := range stats.Networks {
+        rx += n.RxBytes
+        tx += n.TxBytes
+    }
+    return rx, tx
+}
+
+// BlockIO 汇总块设备的读写字节数
+func BlockIO(stats *types.StatsJSON) (read, write uint64) {
+    for _, entry := range stats.BlkioStats.IoServiceBytesRecursive {
+        switch entry.Op {
+        case "Read", "read":
+            read += entry.Value
+        case "Write", "write":
+            write += entry.Value
+        }
+    }
+    return read, write
+}
@@ -0,0 +1,52 @@
+package docker
+
+import (
+    "context"
+    "fmt"
+    "strings"
+
+    "github.com/docker/distribution/reference"
+)
+
+// ImageUpdateAvailable 判断本地镜像在仓库中同一标签下是否已有新版本。
+// 由 Docker 守护进程查询仓库中标签当前指向的摘要，与本地镜像拉取时记录的摘要比较；
+// 按摘要引用的镜像、本地构建的镜像（没有仓库摘要）视为没有更新
+func (c *Client) ImageUpdateAvailable(ctx context.Context, ref, encodedAuth string) (bool, error) {
+    named, err := reference.ParseNormalizedNamed(ref)
+    if err != nil {
+        return false, fmt.Errorf("无效的镜像名称 %s: %v", ref, err)
+    }
+    if _, ok := named.(reference.Digested); ok {
+        return false, nil
+    }
+    tagged := reference.TagNameOnly(named)
+
+    inspect, _, err := c.ImageInspectWithRaw(ctx, ref)
+    if err != nil {
+        return false, fmt.Errorf("获取镜像 %s 信息失败: %v", ref, err)
+    }
+    if len(inspect.RepoDigests) == 0 {
+        return false, nil
+    }
+
+    dist, err := c.DistributionInspect(ctx, tagged.String(), encodedAuth)
+    if err != nil {
+        return false, fmt.Errorf("查询镜像 %s 的仓库摘要失败: %v", tagged.String(), err)
+    }
+    remote := dist.Descriptor.Digest.String()
+    for _, digest := range inspect.RepoDigests {
+        if strings.HasSuffix(digest, "@"+remote) {
+            return false, nil
+        }
+    }
+    return true, nil
+}
+
+// ImageRegistry 返回镜像所在仓库的域名，Docker Hub 为 docker.io
+func ImageRegistry(ref string) string {
+    named, err := reference.ParseNormalizedNamed(ref)
+    if err != nil {
+        return ""
+    }
+    return reference.Domain(named)
+}
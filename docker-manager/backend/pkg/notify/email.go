@@ -0,0 +1,104 @@
+package notify
+
+import (
+    "bytes"
+    "context"
+    "crypto/tls"
+    "fmt"
+    "mime"
+    "net"
+    "net/smtp"
+    "strings"
+    "time"
+)
+
+// 通过 SMTP 发送邮件。配置项：host、port、username、password、from、
+// to（逗号分隔）、tls（none 明文，starttls 服务器支持时升级，tls 直接使用 TLS 连接，默认 starttls）、
+// insecure（true 时不校验服务器证书）
+func sendEmail(ctx context.Context, config map[string]string, msg Message) error {
+    host := config["host"]
+    addr := net.JoinHostPort(host, config["port"])
+    tlsConfig := &tls.Config{ServerName: host, InsecureSkipVerify: config["insecure"] == "true"}
+
+    deadline, ok := ctx.Deadline()
+    if !ok {
+        deadline = time.Now().Add(30 * time.Second)
+    }
+    dialer := &net.Dialer{Deadline: deadline}
+
+    var conn net.Conn
+    var err error
+    if config["tls"] == "tls" {
+        conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+    } else {
+        conn, err = dialer.DialContext(ctx, "tcp", addr)
+    }
+    if err != nil {
+        return fmt.Errorf("连接邮件服务器失败: %v", err)
+    }
+    conn.SetDeadline(deadline)
+
+    client, err := smtp.NewClient(conn, host)
+    if err != nil {
+        conn.Close()
+        return fmt.Errorf("连接邮件服务器失败: %v", err)
+    }
+    defer client.Close()
+
+    mode := config["tls"]
+    if mode == "" || mode == "starttls" {
+        if ok, _ := client.Extension("STARTTLS"); ok {
+            if err := client.StartTLS(tlsConfig); err != nil {
+                return fmt.Errorf("STARTTLS 失败: %v", err)
+            }
+        }
+    }
+    if username := config["username"]; username != "" {
+        // PlainAuth 只允许在 TLS 连接或本机上发送密码
+        if err := client.Auth(smtp.PlainAuth("", username, config["password"], host)); err != nil {
+            return fmt.Errorf("邮件服务器认证失败: %v", err)
+        }
+    }
+
+    var recipients []string
+    for _, to := range strings.Split(config["to"], ",") {
+        if to = strings.TrimSpace(to); to != "" {
+            recipients = append(recipients, to)
+        }
+    }
+    if err := client.Mail(config["from"]); err != nil {
+        return fmt.Errorf("设置发件人失败: %v", err)
+    }
+    for _, to := range recipients {
+        if err := client.Rcpt(to); err != nil {
+            return fmt.Errorf("设置收件人 %s 失败: %v", to, err)
+        }
+    }
+
+    w, err := client.Data()
+    if err != nil {
+        return fmt.Errorf("发送邮件失败: %v", err)
+    }
+    if _, err := w.Write(buildEmail(config["from"], recipients, msg)); err != nil {
+        w.Close()
+        return fmt.Errorf("发送邮件失败: %v", err)
+    }
+    if err := w.Close(); err != nil {
+        return fmt.Errorf("发送邮件失败: %v", err)
+    }
+    return client.Quit()
+}
+
+func buildEmail(from string, to []string, msg Message) []byte {
+    var b bytes.Buffer
+    fmt.Fprintf(&b, "From: %s\r\n", from)
+    fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+    fmt.Fprintf(&b, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", msg.Title))
+    fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+    b.WriteString("MIME-Version: 1.0\r\n")
+    b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+    b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+    b.WriteString(strings.ReplaceAll(plainText(msg), "\n", "\r\n"))
+    b.WriteString("\r\n")
+    return b.Bytes()
+}
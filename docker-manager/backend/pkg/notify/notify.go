@@ -0,0 +1,101 @@
+package notify
+
+import (
+    "context"
+    "fmt"
+    "strings"
+)
+
+// 通知渠道类型
+const (
+    TypeWebhook  = "webhook"  // 通用 webhook，POST JSON 格式的 Message
+    TypeEmail    = "email"    // SMTP 邮件
+    TypeSlack    = "slack"    // Slack incoming webhook
+    TypeDiscord  = "discord"  // Discord webhook
+    TypeDingTalk = "dingtalk" // 钉钉自定义机器人
+    TypeFeishu   = "feishu"   // 飞书自定义机器人
+)
+
+// Message 一条告警通知
+type Message struct {
+    Status  string `json:"status"` // firing、resolved、test
+    Title   string `json:"title"`
+    Text    string `json:"text"`
+    Rule    string `json:"rule"`
+    Type    string `json:"type"` // 规则类型
+    Host    string `json:"host"`
+    Subject string `json:"subject"`
+    Time    string `json:"time"`
+}
+
+// Channel 发送时使用的渠道配置，配置项见各类型的说明
+type Channel struct {
+    Type   string
+    Config map[string]string
+}
+
+// Validate 检查渠道配置是否完整
+func Validate(ch Channel) error {
+    required := map[string][]string{
+        TypeWebhook:  {"url"},
+        TypeSlack:    {"url"},
+        TypeDiscord:  {"url"},
+        TypeDingTalk: {"url"},
+        TypeFeishu:   {"url"},
+        TypeEmail:    {"host", "port", "from", "to"},
+    }
+    keys, ok := required[ch.Type]
+    if !ok {
+        return fmt.Errorf("不支持的通知类型: %s", ch.Type)
+    }
+    for _, key := range keys {
+        if strings.TrimSpace(ch.Config[key]) == "" {
+            return fmt.Errorf("缺少配置项: %s", key)
+        }
+    }
+    if ch.Type != TypeEmail {
+        url := ch.Config["url"]
+        if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+            return fmt.Errorf("webhook 地址必须以 http:// 或 https:// 开头")
+        }
+    }
+    if ch.Type == TypeEmail {
+        switch ch.Config["tls"] {
+        case "", "none", "starttls", "tls":
+        default:
+            return fmt.Errorf("不支持的加密方式: %s", ch.Config["tls"])
+        }
+    }
+    return nil
+}
+
+// Send 通过渠道发送通知
+func Send(ctx context.Context, ch Channel, msg Message) error {
+    switch ch.Type {
+    case TypeEmail:
+        return sendEmail(ctx, ch.Config, msg)
+    case TypeWebhook:
+        return sendWebhook(ctx, ch.Config, msg)
+    case TypeSlack, TypeDiscord, TypeDingTalk, TypeFeishu:
+        return sendChat(ctx, ch.Type, ch.Config, msg)
+    }
+    return fmt.Errorf("不支持的通知类型: %s", ch.Type)
+}
+
+// 纯文本正文，邮件和聊天消息共用
+func plainText(msg Message) string {
+    var b strings.Builder
+    b.WriteString(msg.Text)
+    b.WriteString("\n")
+    if msg.Rule != "" {
+        fmt.Fprintf(&b, "\n规则: %s", msg.Rule)
+    }
+    if msg.Host != "" {
+        fmt.Fprintf(&b, "\n主机: %s", msg.Host)
+    }
+    if msg.Subject != "" {
+        fmt.Fprintf(&b, "\n对象: %s", msg.Subject)
+    }
+    fmt.Fprintf(&b, "\n时间: %s", msg.Time)
+    return b.String()
+}
@@ -0,0 +1,321 @@
+package notify
+
+import (
+    "bufio"
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/hex"
+    "encoding/json"
+    "io"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "testing"
+)
+
+var testMessage = Message{
+    Status:  "firing",
+    Title:   "容器已退出",
+    Text:    "容器 web 已退出",
+    Rule:    "容器退出",
+    Type:    "container_exited",
+    Host:    "local",
+    Subject: "web",
+    Time:    "2024-01-01 12:00:00",
+}
+
+func TestValidate(t *testing.T) {
+    tests := []struct {
+        name    string
+        channel Channel
+        wantErr bool
+    }{
+        {"webhook", Channel{Type: TypeWebhook, Config: map[string]string{"url": "https://example.com/hook"}}, false},
+        {"缺少地址", Channel{Type: TypeSlack, Config: map[string]string{}}, true},
+        {"地址协议", Channel{Type: TypeDingTalk, Config: map[string]string{"url": "ftp://example.com"}}, true},
+        {"邮件", Channel{Type: TypeEmail, Config: map[string]string{"host": "smtp.example.com", "port": "25", "from": "a@example.com", "to": "b@example.com"}}, false},
+        {"邮件缺少收件人", Channel{Type: TypeEmail, Config: map[string]string{"host": "smtp.example.com", "port": "25", "from": "a@example.com"}}, true},
+        {"加密方式", Channel{Type: TypeEmail, Config: map[string]string{"host": "h", "port": "25", "from": "a", "to": "b", "tls": "ssl"}}, true},
+        {"未知类型", Channel{Type: "sms", Config: map[string]string{}}, true},
+    }
+    for _, tt := range tests {
+        if err := Validate(tt.channel); (err != nil) != tt.wantErr {
+            t.Errorf("%s: err = %v, 期望出错: %v", tt.name, err, tt.wantErr)
+        }
+    }
+}
+
+// captureServer 记录收到的最后一个请求，并返回指定的状态码和响应体
+type captureServer struct {
+    *httptest.Server
+    mu       sync.Mutex
+    request  *http.Request
+    body     []byte
+    status   int
+    response string
+}
+
+func newCaptureServer(t *testing.T) *captureServer {
+    s := &captureServer{status: http.StatusOK}
+    s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        body, _ := io.ReadAll(r.Body)
+        s.mu.Lock()
+        s.request, s.body = r, body
+        status, response := s.status, s.response
+        s.mu.Unlock()
+        w.WriteHeader(status)
+        io.WriteString(w, response)
+    }))
+    t.Cleanup(s.Close)
+    return s
+}
+
+func TestSendWebhook(t *testing.T) {
+    s := newCaptureServer(t)
+    err := Send(context.Background(), Channel{Type: TypeWebhook, Config: map[string]string{"url": s.URL, "secret": "key"}}, testMessage)
+    if err != nil {
+        t.Fatalf("Send 返回错误: %v", err)
+    }
+
+    var got Message
+    if err := json.Unmarshal(s.body, &got); err != nil || got != testMessage {
+        t.Errorf("请求体 = %s, err = %v", s.body, err)
+    }
+    mac := hmac.New(sha256.New, []byte("key"))
+    mac.Write(s.body)
+    if sig := s.request.Header.Get("X-DockerPanel-Signature"); sig != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+        t.Errorf("签名 = %s", sig)
+    }
+    if ct := s.request.Header.Get("Content-Type"); ct != "application/json" {
+        t.Errorf("Content-Type = %s", ct)
+    }
+
+    s.status, s.response = http.StatusInternalServerError, "boom"
+    err = Send(context.Background(), Channel{Type: TypeWebhook, Config: map[string]string{"url": s.URL}}, testMessage)
+    if err == nil || !strings.Contains(err.Error(), "HTTP 500: boom") {
+        t.Errorf("非 2xx 响应应返回错误, 得到 %v", err)
+    }
+}
+
+func TestSendChat(t *testing.T) {
+    tests := []struct {
+        chatType string
+        secret   string
+        check    func(t *testing.T, r *http.Request, payload map[string]interface{})
+    }{
+        {TypeSlack, "", func(t *testing.T, r *http.Request, payload map[string]interface{}) {
+            if text, _ := payload["text"].(string); !strings.HasPrefix(text, "*容器已退出*\n容器 web 已退出") {
+                t.Errorf("Slack 消息 = %q", text)
+            }
+        }},
+        {TypeDiscord, "", func(t *testing.T, r *http.Request, payload map[string]interface{}) {
+            if content, _ := payload["content"].(string); !strings.Contains(content, "主机: local") {
+                t.Errorf("Discord 消息 = %q", content)
+            }
+        }},
+        {TypeDingTalk, "SECxxx", func(t *testing.T, r *http.Request, payload map[string]interface{}) {
+            q := r.URL.Query()
+            if q.Get("timestamp") == "" || q.Get("sign") == "" {
+                t.Errorf("钉钉加签参数缺失: %s", r.URL.RawQuery)
+            }
+            mac := hmac.New(sha256.New, []byte("SECxxx"))
+            mac.Write([]byte(q.Get("timestamp") + "\nSECxxx"))
+            if q.Get("sign") != base64.StdEncoding.EncodeToString(mac.Sum(nil)) {
+                t.Errorf("钉钉签名不正确")
+            }
+            if payload["msgtype"] != "markdown" {
+                t.Errorf("钉钉消息类型 = %v", payload["msgtype"])
+            }
+        }},
+        {TypeFeishu, "secret", func(t *testing.T, r *http.Request, payload map[string]interface{}) {
+            timestamp, _ := payload["timestamp"].(string)
+            mac := hmac.New(sha256.New, []byte(timestamp+"\nsecret"))
+            if payload["sign"] != base64.StdEncoding.EncodeToString(mac.Sum(nil)) {
+                t.Errorf("飞书签名不正确: %v", payload)
+            }
+            if payload["msg_type"] != "text" {
+                t.Errorf("飞书消息类型 = %v", payload["msg_type"])
+            }
+        }},
+    }
+    for _, tt := range tests {
+        t.Run(tt.chatType, func(t *testing.T) {
+            s := newCaptureServer(t)
+            s.response = `{"errcode":0,"code":0}`
+            config := map[string]string{"url": s.URL + "/send?access_token=abc", "secret": tt.secret}
+            if err := Send(context.Background(), Channel{Type: tt.chatType, Config: config}, testMessage); err != nil {
+                t.Fatalf("Send 返回错误: %v", err)
+            }
+            if s.request.URL.Query().Get("access_token") != "abc" {
+                t.Errorf("原有查询参数丢失: %s", s.request.URL.RawQuery)
+            }
+            var payload map[string]interface{}
+            if err := json.Unmarshal(s.body, &payload); err != nil {
+                t.Fatalf("解析请求体失败: %v", err)
+            }
+            tt.check(t, s.request, payload)
+        })
+    }
+}
+
+func TestSendChatTruncatesDiscord(t *testing.T) {
+    s := newCaptureServer(t)
+    msg := testMessage
+    msg.Text = strings.Repeat("长", 3000)
+    if err := Send(context.Background(), Channel{Type: TypeDiscord, Config: map[string]string{"url": s.URL}}, msg); err != nil {
+        t.Fatal(err)
+    }
+    var payload map[string]string
+    json.Unmarshal(s.body, &payload)
+    if n := len([]rune(payload["content"])); n != 2000 {
+        t.Errorf("Discord 消息长度 = %d, 期望 2000", n)
+    }
+}
+
+func TestSendChatErrorInBody(t *testing.T) {
+    tests := map[string]string{
+        TypeDingTalk: `{"errcode":310000,"errmsg":"sign not match"}`,
+        TypeFeishu:   `{"code":19021,"msg":"sign match fail"}`,
+    }
+    for chatType, response := range tests {
+        s := newCaptureServer(t)
+        s.response = response
+        err := Send(context.Background(), Channel{Type: chatType, Config: map[string]string{"url": s.URL}}, testMessage)
+        if err == nil || !strings.Contains(err.Error(), "sign") {
+            t.Errorf("%s: 响应体中的错误码应返回错误, 得到 %v", chatType, err)
+        }
+    }
+}
+
+// smtpSession 本地 SMTP 服务收到的内容
+type smtpSession struct {
+    auth       string
+    from       string
+    recipients []string
+    data       string
+}
+
+// startSMTPServer 启动只处理一个连接的最小 SMTP 服务，支持 AUTH PLAIN，不支持 STARTTLS
+func startSMTPServer(t *testing.T) (string, <-chan smtpSession) {
+    ln, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { ln.Close() })
+
+    done := make(chan smtpSession, 1)
+    go func() {
+        conn, err := ln.Accept()
+        if err != nil {
+            return
+        }
+        defer conn.Close()
+
+        var session smtpSession
+        r := bufio.NewReader(conn)
+        reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+        reply("220 localhost ESMTP")
+        for {
+            line, err := r.ReadString('\n')
+            if err != nil {
+                return
+            }
+            line = strings.TrimRight(line, "\r\n")
+            verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+            switch verb {
+            case "EHLO":
+                reply("250-localhost")
+                reply("250 AUTH PLAIN")
+            case "AUTH":
+                session.auth = line
+                reply("235 2.7.0 Authentication successful")
+            case "MAIL":
+                session.from = line
+                reply("250 OK")
+            case "RCPT":
+                session.recipients = append(session.recipients, line)
+                reply("250 OK")
+            case "DATA":
+                reply("354 End data with <CR><LF>.<CR><LF>")
+                var data strings.Builder
+                for {
+                    l, err := r.ReadString('\n')
+                    if err != nil {
+                        return
+                    }
+                    if l == ".\r\n" {
+                        break
+                    }
+                    data.WriteString(l)
+                }
+                session.data = data.String()
+                reply("250 OK")
+            case "QUIT":
+                reply("221 Bye")
+                done <- session
+                return
+            default:
+                reply("502 Command not implemented")
+            }
+        }
+    }()
+    return ln.Addr().String(), done
+}
+
+func TestSendEmail(t *testing.T) {
+    addr, done := startSMTPServer(t)
+    host, port, _ := net.SplitHostPort(addr)
+    config := map[string]string{
+        "host":     host,
+        "port":     port,
+        "username": "alert",
+        "password": "pass",
+        "from":     "panel@example.com",
+        "to":       "ops@example.com, dev@example.com",
+    }
+    if err := Send(context.Background(), Channel{Type: TypeEmail, Config: config}, testMessage); err != nil {
+        t.Fatalf("Send 返回错误: %v", err)
+    }
+
+    session := <-done
+    if want := "AUTH PLAIN " + base64.StdEncoding.EncodeToString([]byte("\x00alert\x00pass")); session.auth != want {
+        t.Errorf("AUTH = %q, 期望 %q", session.auth, want)
+    }
+    if session.from != "MAIL FROM:<panel@example.com>" && !strings.HasPrefix(session.from, "MAIL FROM:<panel@example.com> ") {
+        t.Errorf("MAIL = %q", session.from)
+    }
+    if len(session.recipients) != 2 || session.recipients[1] != "RCPT TO:<dev@example.com>" {
+        t.Errorf("RCPT = %v", session.recipients)
+    }
+    for _, want := range []string{
+        "To: ops@example.com, dev@example.com\r\n",
+        "Subject: =?UTF-8?b?",
+        "Content-Type: text/plain; charset=UTF-8\r\n",
+        "容器 web 已退出\r\n",
+        "对象: web\r\n",
+    } {
+        if !strings.Contains(session.data, want) {
+            t.Errorf("邮件内容缺少 %q:\n%s", want, session.data)
+        }
+    }
+}
+
+func TestSendEmailConnectionRefused(t *testing.T) {
+    ln, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    addr := ln.Addr().String()
+    ln.Close()
+
+    host, port, _ := net.SplitHostPort(addr)
+    config := map[string]string{"host": host, "port": port, "from": "a@example.com", "to": "b@example.com"}
+    err = Send(context.Background(), Channel{Type: TypeEmail, Config: config}, testMessage)
+    if err == nil || !strings.Contains(err.Error(), "连接邮件服务器失败") {
+        t.Errorf("连接失败时应返回错误, 得到 %v", err)
+    }
+}
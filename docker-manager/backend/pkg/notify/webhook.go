@@ -0,0 +1,149 @@
+package notify
+
+import (
+    "bytes"
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+    "time"
+)
+
+var httpClient = &http.Client{Timeout: 15 * time.Second}
+
+// 通用 webhook：POST Message 的 JSON。配置 secret 时在 X-DockerPanel-Signature
+// 请求头中附带 sha256=<请求体的 HMAC-SHA256>，接收方可据此校验来源
+func sendWebhook(ctx context.Context, config map[string]string, msg Message) error {
+    body, err := json.Marshal(msg)
+    if err != nil {
+        return err
+    }
+    headers := map[string]string{}
+    if secret := config["secret"]; secret != "" {
+        mac := hmac.New(sha256.New, []byte(secret))
+        mac.Write(body)
+        headers["X-DockerPanel-Signature"] = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+    }
+    _, err = postJSON(ctx, config["url"], body, headers)
+    return err
+}
+
+// 聊天机器人 webhook，按各平台的消息格式组装。
+// 钉钉和飞书配置 secret 时按平台规则加签
+func sendChat(ctx context.Context, chatType string, config map[string]string, msg Message) error {
+    target := config["url"]
+    text := plainText(msg)
+    var payload interface{}
+
+    switch chatType {
+    case TypeSlack:
+        payload = map[string]string{"text": "*" + msg.Title + "*\n" + text}
+    case TypeDiscord:
+        // Discord 单条消息最多 2000 个字符
+        content := "**" + msg.Title + "**\n" + text
+        if runes := []rune(content); len(runes) > 2000 {
+            content = string(runes[:2000])
+        }
+        payload = map[string]string{"content": content}
+    case TypeDingTalk:
+        if secret := config["secret"]; secret != "" {
+            timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+            mac := hmac.New(sha256.New, []byte(secret))
+            mac.Write([]byte(timestamp + "\n" + secret))
+            sign := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+            sep := "?"
+            if strings.Contains(target, "?") {
+                sep = "&"
+            }
+            target += sep + "timestamp=" + timestamp + "&sign=" + url.QueryEscape(sign)
+        }
+        payload = map[string]interface{}{
+            "msgtype": "markdown",
+            "markdown": map[string]string{
+                "title": msg.Title,
+                "text":  "### " + msg.Title + "\n\n" + strings.ReplaceAll(text, "\n", "\n\n"),
+            },
+        }
+    case TypeFeishu:
+        body := map[string]interface{}{
+            "msg_type": "text",
+            "content":  map[string]string{"text": msg.Title + "\n" + text},
+        }
+        if secret := config["secret"]; secret != "" {
+            timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+            mac := hmac.New(sha256.New, []byte(timestamp+"\n"+secret))
+            body["timestamp"] = timestamp
+            body["sign"] = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+        }
+        payload = body
+    default:
+        return fmt.Errorf("不支持的通知类型: %s", chatType)
+    }
+
+    data, err := json.Marshal(payload)
+    if err != nil {
+        return err
+    }
+    respBody, err := postJSON(ctx, target, data, nil)
+    if err != nil {
+        return err
+    }
+    return checkChatResponse(chatType, respBody)
+}
+
+// 钉钉和飞书在 HTTP 200 的响应体中返回错误码
+func checkChatResponse(chatType string, body []byte) error {
+    var result struct {
+        ErrCode int    `json:"errcode"`
+        ErrMsg  string `json:"errmsg"`
+        Code    int    `json:"code"`
+        Msg     string `json:"msg"`
+    }
+    switch chatType {
+    case TypeDingTalk:
+        if json.Unmarshal(body, &result) == nil && result.ErrCode != 0 {
+            return fmt.Errorf("钉钉返回错误 %d: %s", result.ErrCode, result.ErrMsg)
+        }
+    case TypeFeishu:
+        if json.Unmarshal(body, &result) == nil && result.Code != 0 {
+            return fmt.Errorf("飞书返回错误 %d: %s", result.Code, result.Msg)
+        }
+    }
+    return nil
+}
+
+// 发送 JSON 请求，非 2xx 响应视为失败，返回响应体供调用方进一步检查
+func postJSON(ctx context.Context, target string, body []byte, headers map[string]string) ([]byte, error) {
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("User-Agent", "DockerPanel-Alert")
+    for key, value := range headers {
+        req.Header.Set(key, value)
+    }
+
+    resp, err := httpClient.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+    respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+    if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+        detail := strings.TrimSpace(string(respBody))
+        if len(detail) > 200 {
+            detail = detail[:200]
+        }
+        return respBody, fmt.Errorf("HTTP %d: %s", resp.StatusCode, detail)
+    }
+    return respBody, nil
+}
@@ -0,0 +1,94 @@
+import request from '../utils/request'
+
+export function listAlertRules() {
+  return request({
+    url: '/api/alerts/rules',
+    method: 'get'
+  })
+}
+
+export function createAlertRule(data) {
+  return request({
+    url: '/api/alerts/rules',
+    method: 'post',
+    data
+  })
+}
+
+export function updateAlertRule(id, data) {
+  return request({
+    url: `/api/alerts/rules/${id}`,
+    method: 'put',
+    data
+  })
+}
+
+export function deleteAlertRule(id) {
+  return request({
+    url: `/api/alerts/rules/${id}`,
+    method: 'delete'
+  })
+}
+
+// minutes 为 0 时取消静默
+export function muteAlertRule(id, minutes) {
+  return request({
+    url: `/api/alerts/rules/${id}/mute`,
+    method: 'post',
+    data: { minutes }
+  })
+}
+
+export function listAlertChannels() {
+  return request({
+    url: '/api/alerts/channels',
+    method: 'get'
+  })
+}
+
+export function createAlertChannel(data) {
+  return request({
+    url: '/api/alerts/channels',
+    method: 'post',
+    data
+  })
+}
+
+// 密码和密钥留空时保留原值
+export function updateAlertChannel(id, data) {
+  return request({
+    url: `/api/alerts/channels/${id}`,
+    method: 'put',
+    data
+  })
+}
+
+export function deleteAlertChannel(id) {
+  return request({
+    url: `/api/alerts/channels/${id}`,
+    method: 'delete'
+  })
+}
+
+export function testAlertChannel(id) {
+  return request({
+    url: `/api/alerts/channels/${id}/test`,
+    method: 'post'
+  })
+}
+
+export function listAlertEvents(params) {
+  return request({
+    url: '/api/alerts/events',
+    method: 'get',
+    params
+  })
+}
+
+export function listAlertDeliveries(params) {
+  return request({
+    url: '/api/alerts/deliveries',
+    method: 'get',
+    params
+  })
+}
@@ -0,0 +1,20 @@
+import request from '../utils/request'
+
+// 分页查询审计日志，params: actor, action, target, result, q, from, to, page, pageSize
+export function listAuditLogs(params) {
+  return request({
+    url: '/api/audit',
+    method: 'get',
+    params
+  })
+}
+
+// 按相同条件导出 CSV
+export function exportAuditLogs(params) {
+  return request({
+    url: '/api/audit',
+    method: 'get',
+    params: { ...params, format: 'csv' },
+    responseType: 'blob'
+  })
+}
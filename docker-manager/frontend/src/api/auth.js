@@ -0,0 +1,41 @@
+import request from '../utils/request'
+
+// 是否已初始化管理员、当前是否已登录
+export function getAuthStatus() {
+  return request({
+    url: '/api/auth/status',
+    method: 'get'
+  })
+}
+
+// 首次运行时创建管理员账号
+export function setupAdmin(username, password) {
+  return request({
+    url: '/api/auth/setup',
+    method: 'post',
+    data: { username, password }
+  })
+}
+
+export function login(username, password) {
+  return request({
+    url: '/api/auth/login',
+    method: 'post',
+    data: { username, password }
+  })
+}
+
+export function logout() {
+  return request({
+    url: '/api/auth/logout',
+    method: 'post'
+  })
+}
+
+export function changePassword(oldPassword, newPassword) {
+  return request({
+    url: '/api/auth/password',
+    method: 'put',
+    data: { oldPassword, newPassword }
+  })
+}
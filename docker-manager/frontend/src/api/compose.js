@@ -1,4 +1,5 @@
 import request from '../utils/request'
+import { postEventStream } from '../utils/sse'
 
 export default {
   list() {
@@ -8,6 +9,16 @@ export default {
     })
   },
   
+  // 部署项目并逐条回调部署日志 onMessage({ type, message })
+  deployEvents(name, compose, onMessage, signal) {
+    return postEventStream(
+      `/api/compose/deploy/events?name=${encodeURIComponent(name)}`,
+      { compose },
+      (event, data) => onMessage(data),
+      signal
+    )
+  },
+
   deploy(data) {
     return request({
       url: '/api/compose/project',
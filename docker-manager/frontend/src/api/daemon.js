@@ -0,0 +1,64 @@
+import request from '../utils/request'
+
+// 获取 daemon.json 的路径、内容及校验结果
+export function getDaemonConfig() {
+  return request({
+    url: '/api/daemon-config',
+    method: 'get'
+  })
+}
+
+// 写入完整的 daemon.json，dryRun 为 true 时只返回校验结果和差异
+export function updateDaemonConfig(config, dryRun = false) {
+  return request({
+    url: '/api/daemon-config',
+    method: 'put',
+    data: { config, dryRun }
+  })
+}
+
+export function validateDaemonConfig(config) {
+  return request({
+    url: '/api/daemon-config/validate',
+    method: 'post',
+    data: { config }
+  })
+}
+
+export function getDaemonSchema() {
+  return request({
+    url: '/api/daemon-config/schema',
+    method: 'get'
+  })
+}
+
+export function listDaemonBackups() {
+  return request({
+    url: '/api/daemon-config/backups',
+    method: 'get'
+  })
+}
+
+export function getDaemonBackup(name) {
+  return request({
+    url: `/api/daemon-config/backups/${encodeURIComponent(name)}`,
+    method: 'get'
+  })
+}
+
+export function restoreDaemonBackup(name) {
+  return request({
+    url: `/api/daemon-config/backups/${encodeURIComponent(name)}/restore`,
+    method: 'post'
+  })
+}
+
+// 重启或重载 Docker，mode 为 restart / reload；守护进程未恢复时默认回滚到修改前的 daemon.json
+export function restartDaemon(mode = 'restart', rollback = true) {
+  return request({
+    url: '/api/daemon-config/restart',
+    method: 'post',
+    data: { mode, rollback },
+    timeout: 180000
+  })
+}
@@ -0,0 +1,34 @@
+import request from '../utils/request'
+
+// 获取 docker CLI config.json 的路径、凭据助手及已保存的服务器
+export function getDockerConfig() {
+  return request({
+    url: '/api/docker-config',
+    method: 'get'
+  })
+}
+
+// 开启后，注册表凭据保存时自动写入 config.json
+export function updateDockerConfigSync(sync) {
+  return request({
+    url: '/api/docker-config',
+    method: 'put',
+    data: { sync }
+  })
+}
+
+export function exportDockerConfig() {
+  return request({
+    url: '/api/docker-config/export',
+    method: 'post'
+  })
+}
+
+// servers 为空时导入 config.json 中的全部凭据
+export function importDockerConfig(servers) {
+  return request({
+    url: '/api/docker-config/import',
+    method: 'post',
+    data: servers ? { servers } : undefined
+  })
+}
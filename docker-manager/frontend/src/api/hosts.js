@@ -0,0 +1,57 @@
+import request from '../utils/request'
+
+// 第一项固定为本机（id 为 0）
+export function listHosts() {
+  return request({
+    url: '/api/hosts',
+    method: 'get'
+  })
+}
+
+// 并发检查所有主机的连通性
+export function getHostsHealth() {
+  return request({
+    url: '/api/hosts/health',
+    method: 'get'
+  })
+}
+
+export function createHost(data) {
+  return request({
+    url: '/api/hosts',
+    method: 'post',
+    data
+  })
+}
+
+// 私钥和密码留空时保留原值
+export function updateHost(id, data) {
+  return request({
+    url: `/api/hosts/${id}`,
+    method: 'put',
+    data
+  })
+}
+
+export function deleteHost(id) {
+  return request({
+    url: `/api/hosts/${id}`,
+    method: 'delete'
+  })
+}
+
+export function testHost(id) {
+  return request({
+    url: `/api/hosts/${id}/test`,
+    method: 'post'
+  })
+}
+
+// 信任 SSH 主机公钥，key 为测试连接时返回的 hostKey
+export function trustHostKey(id, key) {
+  return request({
+    url: `/api/hosts/${id}/host-key`,
+    method: 'post',
+    data: { key }
+  })
+}
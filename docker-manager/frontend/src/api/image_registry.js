@@ -8,9 +8,80 @@ export function getRegistries() {
 }
 
 export function updateRegistries(data) {
+  return request({
+    url: '/api/image-registry',
+    method: 'put',
+    data
+  })
+}
+
+export function getRegistry(id) {
+  return request({
+    url: `/api/image-registry/${id}`,
+    method: 'get'
+  })
+}
+
+export function createRegistry(data) {
   return request({
     url: '/api/image-registry',
     method: 'post',
     data
   })
-}
\ No newline at end of file
+}
+
+export function updateRegistry(id, data) {
+  return request({
+    url: `/api/image-registry/${id}`,
+    method: 'put',
+    data
+  })
+}
+
+export function deleteRegistry(id) {
+  return request({
+    url: `/api/image-registry/${id}`,
+    method: 'delete'
+  })
+}
+
+// 测试连通性与登录凭据，credentials 可选，用于在保存前验证新填写的账号密码
+export function testRegistry(id, credentials) {
+  return request({
+    url: `/api/image-registry/${id}/test`,
+    method: 'post',
+    data: credentials
+  })
+}
+// 通过注册表 _catalog 接口列出仓库，params 支持 n / last 分页
+export function listRepositories(id, params) {
+  return request({
+    url: `/api/image-registry/${id}/repositories`,
+    method: 'get',
+    params
+  })
+}
+
+export function listTags(id, repo) {
+  return request({
+    url: `/api/image-registry/${id}/tags`,
+    method: 'get',
+    params: { repo }
+  })
+}
+
+export function getManifest(id, repo, tag) {
+  return request({
+    url: `/api/image-registry/${id}/manifest`,
+    method: 'get',
+    params: { repo, tag }
+  })
+}
+
+export function deleteTag(id, repo, tag) {
+  return request({
+    url: `/api/image-registry/${id}/tags`,
+    method: 'delete',
+    params: { repo, tag }
+  })
+}
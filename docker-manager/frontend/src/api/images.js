@@ -1,4 +1,5 @@
 import request from '../utils/request'
+import { postEventStream } from '../utils/sse'
 
 // 获取 Docker 配置
 export const getProxy = () => {
@@ -17,6 +18,16 @@ export const updateProxy = (data) => {
   })
 }
 
+// 测试镜像加速地址及代理，参数与 updateProxy 相同，不会写入配置
+export const testProxy = (data) => {
+  return request({
+    url: '/api/images/proxy/test',
+    method: 'post',
+    data,
+    timeout: 60000
+  })
+}
+
 // 导出默认对象，包含所有镜像相关API
 const imagesApi = {
   list: () => {
@@ -25,10 +36,19 @@ const imagesApi = {
       method: 'get'
     })
   },
-  remove: (id) => {
+  // force 为 true 时即使镜像仍被容器或项目使用也删除
+  remove: (id, force = false) => {
     return request({
       url: `/api/images/${id}`,
-      method: 'delete'
+      method: 'delete',
+      params: force ? { force: true } : undefined
+    })
+  },
+  // 获取镜像分层历史及运行配置
+  history: (id) => {
+    return request({
+      url: `/api/images/${id}/history`,
+      method: 'get'
     })
   },
   
@@ -42,13 +62,20 @@ const imagesApi = {
     })
   },
   
-  // 添加拉取镜像进度监听方法
-  pullProgress: (name, registry) => {
-    const params = new URLSearchParams()
-    if (name) params.append('name', name)
-    if (registry) params.append('registry', registry)
-    
-    return `/api/images/pull/progress?${params.toString()}`
+  // 拉取镜像并监听进度，data 为 { name, registry, platform }，onEvent(event, progress)
+  pullProgress: (data, onEvent, signal) => {
+    return postEventStream('/api/images/pull/progress', data, onEvent, signal)
+  },
+  
+  // 推送镜像到注册表，响应为逐层推送进度
+  push: (data) => {
+    return request({
+      url: '/api/images/push',
+      method: 'post',
+      data,
+      responseType: 'text',
+      timeout: 600000 // 10分钟超时
+    })
   },
   
   // 添加修改标签方法
@@ -60,13 +87,28 @@ const imagesApi = {
     })
   },
   // 添加导出镜像方法
-  export: (id) => {
+  // options: { platform: 'linux/arm64', compress: 'gzip' }，platform 只校验本地镜像的平台，不会转换
+  export: (id, options = {}) => {
     return request({
       url: `/api/images/export/${id}`,
       method: 'get',
+      params: options,
       responseType: 'blob'
     })
   },
+  // 将多个镜像导出到同一个归档
+  exportMany: (ids, options = {}) => {
+    const params = new URLSearchParams()
+    ids.forEach(id => params.append('id', id))
+    if (options.platform) params.append('platform', options.platform)
+    if (options.compress) params.append('compress', options.compress)
+    return request({
+      url: `/api/images/export?${params.toString()}`,
+      method: 'get',
+      responseType: 'blob',
+      timeout: 1800000 // 30分钟超时
+    })
+  },
   // 添加导入镜像方法
   import: (formData) => {
     return request({
@@ -78,6 +120,29 @@ const imagesApi = {
       },
       timeout: 600000 // 10分钟超时
     })
+  },
+  // 从 HTTP(S) 地址导入镜像，支持 .tar / .tar.gz / .tar.zst
+  importFromUrl: (url) => {
+    return request({
+      url: '/api/images/import/url',
+      method: 'post',
+      data: { url },
+      timeout: 1800000 // 30分钟超时
+    })
+  },
+  // 从地址导入时允许访问的内网地址段，默认只允许公网地址
+  getImportSettings: () => {
+    return request({
+      url: '/api/images/import/settings',
+      method: 'get'
+    })
+  },
+  updateImportSettings: (allowedNetworks) => {
+    return request({
+      url: '/api/images/import/settings',
+      method: 'put',
+      data: { allowedNetworks }
+    })
   }
 }
 
@@ -6,6 +6,8 @@ import networksApi from './networks'
 import compose from './compose'
 import axios from 'axios'
 import { ElMessage } from 'element-plus'
+import { handleUnauthorized } from '../utils/auth'
+import { applyHostHeader } from '../utils/host'
 
 // 创建 axios 实例
 const instance = axios.create({
@@ -19,7 +21,7 @@ const instance = axios.create({
 // 添加请求拦截器
 instance.interceptors.request.use(
   config => {
-    return config
+    return applyHostHeader(config)
   },
   error => {
     return Promise.reject(error)
@@ -31,6 +33,9 @@ instance.interceptors.response.use(
   response => response.data,
   error => {
     console.error('API Error:', error.response?.data?.error || error.message)
+    if (error.response?.status === 401) {
+      handleUnauthorized(error.config?.url)
+    }
     ElMessage.error(error.response?.data?.error || '请求失败')
     return Promise.reject(error)
   }
@@ -47,6 +52,8 @@ const api = {
     pause: (id) => instance.post(`/api/containers/${id}/pause`),
     unpause: (id) => instance.post(`/api/containers/${id}/unpause`),
     remove: (id) => instance.delete(`/api/containers/${id}`),
+    create: (data) => instance.post('/api/containers/create', data),
+    recreate: (id, data) => instance.post(`/api/containers/${id}/recreate`, data),
     logs: (id) => instance.get(`/api/containers/${id}/logs`, {
       responseType: 'text',
       timeout: 0
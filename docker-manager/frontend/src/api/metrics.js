@@ -0,0 +1,55 @@
+import request from '../utils/request'
+
+// target 为 host 或 container:<容器名称>，range 为 1h、6h、24h、7d、30d
+export function getMetricsHistory(target, range) {
+  return request({
+    url: '/api/metrics/history',
+    method: 'get',
+    params: { target, range }
+  })
+}
+
+// 当前主机有历史数据的目标
+export function getMetricsTargets() {
+  return request({
+    url: '/api/metrics/targets',
+    method: 'get'
+  })
+}
+
+export function getMetricsSettings() {
+  return request({
+    url: '/api/metrics/settings',
+    method: 'get'
+  })
+}
+
+export function updateMetricsSettings(data) {
+  return request({
+    url: '/api/metrics/settings',
+    method: 'put',
+    data
+  })
+}
+
+// Prometheus 抓取令牌，生成时明文只返回一次
+export function getPrometheusSettings() {
+  return request({
+    url: '/api/metrics/prometheus',
+    method: 'get'
+  })
+}
+
+export function createScrapeToken() {
+  return request({
+    url: '/api/metrics/prometheus/token',
+    method: 'post'
+  })
+}
+
+export function deleteScrapeToken() {
+  return request({
+    url: '/api/metrics/prometheus/token',
+    method: 'delete'
+  })
+}
@@ -10,8 +10,47 @@ export function getRegistries() {
 
 export function updateRegistries(data) {
   return request({
-    url: '/api/image-registry',  // 更新路径
+    url: '/api/image-registry',
+    method: 'put',
+    data
+  })
+}
+
+export function getRegistry(id) {
+  return request({
+    url: `/api/image-registry/${id}`,
+    method: 'get'
+  })
+}
+
+export function createRegistry(data) {
+  return request({
+    url: '/api/image-registry',
     method: 'post',
     data
   })
+}
+
+export function updateRegistry(id, data) {
+  return request({
+    url: `/api/image-registry/${id}`,
+    method: 'put',
+    data
+  })
+}
+
+export function deleteRegistry(id) {
+  return request({
+    url: `/api/image-registry/${id}`,
+    method: 'delete'
+  })
+}
+
+// 测试连通性与登录凭据，credentials 可选，用于在保存前验证新填写的账号密码
+export function testRegistry(id, credentials) {
+  return request({
+    url: `/api/image-registry/${id}/test`,
+    method: 'post',
+    data: credentials
+  })
 }
\ No newline at end of file
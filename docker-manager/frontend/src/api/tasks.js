@@ -0,0 +1,61 @@
+import request from '../utils/request'
+
+export function listTasks() {
+  return request({
+    url: '/api/tasks',
+    method: 'get'
+  })
+}
+
+export function createTask(data) {
+  return request({
+    url: '/api/tasks',
+    method: 'post',
+    data
+  })
+}
+
+export function updateTask(id, data) {
+  return request({
+    url: `/api/tasks/${id}`,
+    method: 'put',
+    data
+  })
+}
+
+export function deleteTask(id) {
+  return request({
+    url: `/api/tasks/${id}`,
+    method: 'delete'
+  })
+}
+
+// 立即执行一次，返回执行记录
+export function runTask(id) {
+  return request({
+    url: `/api/tasks/${id}/run`,
+    method: 'post'
+  })
+}
+
+export function cancelTask(id) {
+  return request({
+    url: `/api/tasks/${id}/cancel`,
+    method: 'post'
+  })
+}
+
+export function listTaskRuns(params) {
+  return request({
+    url: '/api/tasks/runs',
+    method: 'get',
+    params
+  })
+}
+
+export function getTaskRun(id) {
+  return request({
+    url: `/api/tasks/runs/${id}`,
+    method: 'get'
+  })
+}
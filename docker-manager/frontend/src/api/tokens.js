@@ -0,0 +1,25 @@
+import request from '../utils/request'
+
+// 普通用户返回自己的令牌，管理员返回全部令牌
+export function listTokens() {
+  return request({
+    url: '/api/tokens',
+    method: 'get'
+  })
+}
+
+// 返回的明文令牌只在创建时出现一次
+export function createToken(data) {
+  return request({
+    url: '/api/tokens',
+    method: 'post',
+    data
+  })
+}
+
+export function revokeToken(id) {
+  return request({
+    url: `/api/tokens/${id}`,
+    method: 'delete'
+  })
+}
@@ -0,0 +1,48 @@
+import request from '../utils/request'
+
+export function listUsers() {
+  return request({
+    url: '/api/users',
+    method: 'get'
+  })
+}
+
+export function createUser(data) {
+  return request({
+    url: '/api/users',
+    method: 'post',
+    data
+  })
+}
+
+// 修改角色或重置密码，未填写的字段不修改
+export function updateUser(id, data) {
+  return request({
+    url: `/api/users/${id}`,
+    method: 'put',
+    data
+  })
+}
+
+export function deleteUser(id) {
+  return request({
+    url: `/api/users/${id}`,
+    method: 'delete'
+  })
+}
+
+export function getUserPermissions(id) {
+  return request({
+    url: `/api/users/${id}/permissions`,
+    method: 'get'
+  })
+}
+
+// 整体替换用户的额外授权
+export function updateUserPermissions(id, permissions) {
+  return request({
+    url: `/api/users/${id}/permissions`,
+    method: 'put',
+    data: permissions
+  })
+}
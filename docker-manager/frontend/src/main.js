@@ -6,11 +6,16 @@ import router from './router'
 import * as ElementPlusIconsVue from '@element-plus/icons-vue'
 import axios from 'axios'
 import { ElMessage } from 'element-plus'  // 添加这行
+import { handleUnauthorized } from './utils/auth'
+import { applyHostHeader } from './utils/host'
 
 // 配置 axios 默认值
 axios.defaults.baseURL = import.meta.env.PROD ? '' : ''  // 移除 '/api'
 axios.defaults.headers.common['Content-Type'] = 'application/json'
 
+// 按当前选择的主机添加请求头
+axios.interceptors.request.use(applyHostHeader)
+
 // 添加响应拦截器
 axios.interceptors.response.use(
   response => response.data,
@@ -22,6 +27,9 @@ axios.interceptors.response.use(
       data: error.response?.data,
       message: error.message
     })
+    if (error.response?.status === 401) {
+      handleUnauthorized(error.config?.url)
+    }
     ElMessage.error(error.response?.data?.message || '请求失败')
     return Promise.reject(error)
   }
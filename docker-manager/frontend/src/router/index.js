@@ -10,10 +10,24 @@ import Navigation from '../views/Navigation.vue'
 import Projects from '../views/Projects.vue'
 import ProjectDetail from '../views/ProjectDetail.vue'
 import DockerDetail from '../views/DockerDetail.vue'
+import Login from '../views/Login.vue'
+import Users from '../views/Users.vue'
+import Tokens from '../views/Tokens.vue'
+import Audit from '../views/Audit.vue'
+import Hosts from '../views/Hosts.vue'
+import Alerts from '../views/Alerts.vue'
+import Tasks from '../views/Tasks.vue'
+import { getAuthStatus } from '../api/auth'
+import { authState } from '../utils/auth'
 
 const router = createRouter({
   history: createWebHistory(),
   routes: [
+    {
+      path: '/login',
+      component: Login,
+      meta: { public: true }
+    },
     {
       path: '/',
       component: MainLayout,
@@ -61,10 +75,66 @@ const router = createRouter({
         {
           path: 'projects/:name',
           component: ProjectDetail
+        },
+        {
+          path: 'users',
+          component: Users,
+          meta: { admin: true }
+        },
+        {
+          path: 'tokens',
+          component: Tokens
+        },
+        {
+          path: 'audit',
+          component: Audit,
+          meta: { admin: true }
+        },
+        {
+          path: 'hosts',
+          component: Hosts
+        },
+        {
+          path: 'alerts',
+          component: Alerts,
+          meta: { admin: true }
+        },
+        {
+          path: 'tasks',
+          component: Tasks,
+          meta: { admin: true }
         }
       ]
     }
   ]
 })
 
+// 登录校验：首次导航时向后端查询登录状态，未登录时跳转到登录页
+router.beforeEach(async (to) => {
+  if (!authState.loaded) {
+    try {
+      const status = await getAuthStatus()
+      authState.initialized = status.initialized
+      authState.user = status.authenticated ? status.user : null
+      authState.loaded = true
+    } catch (error) {
+      console.error('获取登录状态失败:', error)
+    }
+  }
+
+  if (to.meta.public) {
+    if (to.path === '/login' && authState.user) {
+      return to.query.redirect || '/'
+    }
+    return true
+  }
+  if (!authState.user) {
+    return { path: '/login', query: { redirect: to.fullPath } }
+  }
+  if (to.meta.admin && authState.user.role !== 'admin') {
+    return '/'
+  }
+  return true
+})
+
 export default router
\ No newline at end of file
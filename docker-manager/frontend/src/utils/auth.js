@@ -0,0 +1,27 @@
+import { reactive } from 'vue'
+
+// 当前登录状态，由路由守卫在首次导航时加载
+export const authState = reactive({
+  loaded: false,
+  initialized: true,
+  user: null
+})
+
+export const setAuthUser = (user) => {
+  authState.user = user
+  authState.loaded = true
+  authState.initialized = true
+}
+
+// 接口返回 401 时跳转到登录页，登录接口本身的 401 由页面处理
+export const handleUnauthorized = (url = '') => {
+  if (url.includes('/api/auth/')) return
+  authState.user = null
+  // 延迟导入路由，避免 router -> views -> api -> request 的循环依赖
+  import('../router').then(({ default: router }) => {
+    const current = router.currentRoute.value
+    if (current.path !== '/login') {
+      router.push({ path: '/login', query: { redirect: current.fullPath } })
+    }
+  })
+}
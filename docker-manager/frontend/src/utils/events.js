@@ -0,0 +1,23 @@
+import { withHost } from './host'
+
+// 订阅当前主机的 Docker 事件，params 可包含 type、container、project。
+// 连接断开后 EventSource 会自动重连，服务端在重新连接时推送 stream/connected 事件。
+// 返回取消订阅的函数
+export const subscribeEvents = (params, onEvent) => {
+  const query = new URLSearchParams()
+  Object.entries(params || {}).forEach(([key, value]) => {
+    if (value) query.set(key, value)
+  })
+  const qs = query.toString()
+  const source = new EventSource(withHost(`/api/events${qs ? `?${qs}` : ''}`))
+
+  source.onmessage = (event) => {
+    try {
+      onEvent(JSON.parse(event.data))
+    } catch (error) {
+      console.error('解析事件失败:', error)
+    }
+  }
+
+  return () => source.close()
+}
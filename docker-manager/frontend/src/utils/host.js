@@ -0,0 +1,34 @@
+import { reactive } from 'vue'
+
+const STORAGE_KEY = 'dockerpanel_host'
+
+// 当前操作的 Docker 主机，0 表示面板所在的本机
+export const hostState = reactive({
+  current: Number(localStorage.getItem(STORAGE_KEY) || 0),
+  hosts: []
+})
+
+// 切换主机后刷新页面，各页面按新主机重新加载数据
+export const setCurrentHost = (id, reload = true) => {
+  hostState.current = Number(id) || 0
+  localStorage.setItem(STORAGE_KEY, String(hostState.current))
+  if (reload) {
+    window.location.reload()
+  }
+}
+
+// axios 请求拦截器使用，按当前主机添加请求头
+export const applyHostHeader = (config) => {
+  if (hostState.current) {
+    config.headers = config.headers || {}
+    config.headers['X-Docker-Host'] = String(hostState.current)
+  }
+  return config
+}
+
+// WebSocket、EventSource 和 fetch 无法统一设置请求头，通过查询参数传递主机
+export const withHost = (url) => {
+  if (!hostState.current) return url
+  const separator = url.includes('?') ? '&' : '?'
+  return `${url}${separator}host=${hostState.current}`
+}
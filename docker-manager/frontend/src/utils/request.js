@@ -1,5 +1,7 @@
 import axios from 'axios'
 import { ElMessage } from 'element-plus'
+import { handleUnauthorized } from './auth'
+import { applyHostHeader } from './host'
 
 const service = axios.create({
   baseURL: import.meta.env.VITE_API_BASE_URL || '',
@@ -17,7 +19,7 @@ service.interceptors.request.use(
       config.timeout = 300000  // 5分钟
       config.responseType = 'text'  // 使用 text 类型接收流数据
     }
-    return config
+    return applyHostHeader(config)
   },
   error => {
     console.error('请求错误:', error)
@@ -47,6 +49,13 @@ service.interceptors.response.use(
       const data = error.response.data
     
       switch (status) {
+        case 401:
+          errorMessage = data.error || '未登录或登录已过期'
+          handleUnauthorized(error.config?.url)
+          break
+        case 403:
+          errorMessage = data.error || '没有权限执行该操作'
+          break
         case 404:
           errorMessage = '请求的资源不存在'
           break
@@ -0,0 +1,62 @@
+import { hostState } from './host'
+import { handleUnauthorized } from './auth'
+
+// 以 POST 请求打开返回 SSE 的接口，逐条回调 onEvent(eventName, data)。
+// EventSource 只能发 GET，而有副作用的接口需要使用 POST 并在请求体中传参。
+// 返回的 Promise 在流结束时完成；调用 signal 对应的 abort 可提前断开
+export const postEventStream = async (url, data, onEvent, signal) => {
+  const headers = { 'Content-Type': 'application/json' }
+  if (hostState.current) {
+    headers['X-Docker-Host'] = String(hostState.current)
+  }
+  const baseUrl = import.meta.env.VITE_API_BASE_URL || ''
+  const response = await fetch(`${baseUrl}${url}`, {
+    method: 'POST',
+    headers,
+    body: JSON.stringify(data),
+    credentials: 'include',
+    signal
+  })
+  if (!response.ok) {
+    if (response.status === 401) {
+      handleUnauthorized(url)
+    }
+    let message = `请求失败 (${response.status})`
+    try {
+      message = (await response.json()).error || message
+    } catch {
+      // 响应不是 JSON 时使用默认提示
+    }
+    throw new Error(message)
+  }
+
+  const reader = response.body.getReader()
+  const decoder = new TextDecoder()
+  let buffer = ''
+  for (;;) {
+    const { value, done } = await reader.read()
+    if (done) break
+    buffer += decoder.decode(value, { stream: true })
+
+    // 事件之间以空行分隔
+    let index
+    while ((index = buffer.indexOf('\n\n')) >= 0) {
+      const block = buffer.slice(0, index)
+      buffer = buffer.slice(index + 2)
+      let event = 'message'
+      const lines = []
+      block.split('\n').forEach(line => {
+        if (line.startsWith('event:')) event = line.slice(6).trim()
+        else if (line.startsWith('data:')) lines.push(line.slice(5))
+      })
+      if (lines.length === 0) continue
+      let payload = lines.join('\n')
+      try {
+        payload = JSON.parse(payload)
+      } catch {
+        // 非 JSON 数据按原文传递
+      }
+      onEvent(event, payload)
+    }
+  }
+}
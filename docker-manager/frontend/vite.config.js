@@ -10,7 +10,8 @@ export default defineConfig({
       '/api': {
         target: 'http://0.0.0.0:8080',  // 修改为后端地址，使用 0.0.0.0
         changeOrigin: true,
-        secure: false
+        secure: false,
+        ws: true  // 转发容器终端的 WebSocket 连接
       }
     }
   }